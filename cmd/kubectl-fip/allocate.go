@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+func newAllocateCmd(o *fipOptions) *cobra.Command {
+	var fipRangeName, ipAddress, clusterSource string
+	var wait time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "allocate <cluster> -n <cluster namespace> --range <fiprange>",
+		Short: "Create the FloatingIP of a cluster, the operator allocates the ip address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := o.namespace()
+			if namespace == "" {
+				return fmt.Errorf("the namespace of the cluster must be set with --namespace")
+			}
+
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			fip := KubefipV2.FloatingIP{}
+			fip.ObjectMeta.Name = fmt.Sprintf("%s-kubevip", args[0])
+			fip.ObjectMeta.Namespace = namespace
+			fip.ObjectMeta.Annotations = map[string]string{
+				"fiprangeReason": "allocated with kubectl-fip",
+			}
+			if clusterSource != "" {
+				fip.ObjectMeta.Annotations["clustersource"] = clusterSource
+			}
+			fip.Spec.ClusterName = args[0]
+			fip.Spec.FipRange = fipRangeName
+			fip.Spec.IPAddress = ipAddress
+			fip.Spec.UpdateConfigMap = true
+
+			for _, existingFip := range kubefip.GetAllFips() {
+				if existingFip.Spec.ClusterName == fip.Spec.ClusterName {
+					return fmt.Errorf("cluster [%s] already has fip [%s/%s]", fip.Spec.ClusterName, existingFip.ObjectMeta.Namespace,
+						existingFip.ObjectMeta.Name)
+				}
+			}
+
+			// the same checks as the operator and the webhook
+			if err := kubefip.ValidateFip(&fip); err != nil {
+				return err
+			}
+			if err := kubefip.ValidateFipAddressAvailable(&fip); err != nil {
+				return err
+			}
+
+			if _, err := o.clientset.KubefipV2().FloatingIPs(namespace).Create(context.TODO(), &fip, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "fip [%s/%s] created for cluster [%s]\n", namespace, fip.ObjectMeta.Name, fip.Spec.ClusterName)
+
+			return o.waitForIPAddress(cmd, namespace, fip.ObjectMeta.Name, "", wait)
+		},
+	}
+
+	cmd.Flags().StringVar(&fipRangeName, "range", "", "the FloatingIPRange which the ip address is allocated from")
+	cmd.Flags().StringVar(&ipAddress, "ip", "", "allocate this ip address instead of the next free one")
+	cmd.Flags().StringVar(&clusterSource, "cluster-source", "", "the source of the cluster, rancher (default) or capi")
+	cmd.Flags().DurationVar(&wait, "wait", 30*time.Second, "wait this long for the operator to allocate the ip address, 0 doesn't wait")
+	_ = cmd.MarkFlagRequired("range")
+
+	return cmd
+}
+
+func newReleaseCmd(o *fipOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "release <cluster|ip>",
+		Short: "Delete the FloatingIP of a cluster or ip address, the operator releases the ip address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			fip, err := o.findFip(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := o.clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Delete(context.TODO(), fip.ObjectMeta.Name, metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "fip [%s/%s] of cluster [%s] with ip address [%s] deleted\n", fip.ObjectMeta.Namespace,
+				fip.ObjectMeta.Name, fip.Spec.ClusterName, fip.Spec.IPAddress)
+
+			return nil
+		},
+	}
+}
+
+// findFip returns the fip of a cluster name or ip address, the --namespace flag narrows the search
+func (o *fipOptions) findFip(clusterOrIP string) (KubefipV2.FloatingIP, error) {
+	var matches []KubefipV2.FloatingIP
+
+	_, err := netip.ParseAddr(clusterOrIP)
+	isIP := err == nil
+
+	for _, fip := range kubefip.GetAllFips() {
+		if o.namespace() != "" && fip.ObjectMeta.Namespace != o.namespace() {
+			continue
+		}
+
+		if (isIP && fip.Spec.IPAddress == clusterOrIP) || (!isIP && fip.Spec.ClusterName == clusterOrIP) {
+			matches = append(matches, fip)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return KubefipV2.FloatingIP{}, fmt.Errorf("no fip found for [%s]", clusterOrIP)
+	case 1:
+		return matches[0], nil
+	}
+
+	var names []string
+	for _, fip := range matches {
+		names = append(names, fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name))
+	}
+
+	return KubefipV2.FloatingIP{}, fmt.Errorf("found several fips for [%s], select one with --namespace: %s", clusterOrIP, strings.Join(names, ", "))
+}
+
+// waitForIPAddress waits until the operator has allocated an ip address, which differs from the previous address
+func (o *fipOptions) waitForIPAddress(cmd *cobra.Command, namespace string, name string, previousIPAddress string, wait time.Duration) error {
+	if wait == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		fip, err := o.clientset.KubefipV2().FloatingIPs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if fip.Spec.IPAddress != "" && fip.Spec.IPAddress != previousIPAddress {
+			fmt.Fprintf(cmd.OutOrStdout(), "fip [%s/%s] has ip address [%s] from fiprange [%s]\n", namespace, name, fip.Spec.IPAddress,
+				fip.Spec.FipRange)
+
+			return nil
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	return fmt.Errorf("the operator didn't allocate an ip address for fip [%s/%s] within %s, check the operator logs", namespace, name, wait)
+}
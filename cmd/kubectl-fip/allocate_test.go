@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindFip(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   string
+		clusterOrIP string
+		want        string
+		wantErr     string
+	}{
+		{"cluster name", "", "cluster1", "ns1/cluster1-kubevip", ""},
+		{"ip address", "", "192.168.10.11", "ns2/cluster2-kubevip", ""},
+		{"unknown cluster", "", "cluster3", "", "no fip found for [cluster3]"},
+		{"unknown ip address", "", "192.168.10.99", "", "no fip found for [192.168.10.99]"},
+		{"cluster name in several namespaces", "", "cluster2", "", "found several fips for [cluster2]"},
+		{"cluster name narrowed by the namespace", "ns3", "cluster2", "ns3/cluster2-kubevip", ""},
+		{"cluster name in another namespace", "ns1", "cluster2", "", "no fip found for [cluster2]"},
+	}
+
+	for _, tt := range tests {
+		o := newTestOptions(t, tt.namespace,
+			newTestFip("ns1", "cluster1", "192.168.10.10"),
+			newTestFip("ns2", "cluster2", "192.168.10.11"),
+			newTestFip("ns3", "cluster2", "192.168.10.12"),
+		)
+
+		fip, err := o.findFip(tt.clusterOrIP)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error containing [%s], got [%v]", tt.name, tt.wantErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+
+			continue
+		}
+
+		if got := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name); got != tt.want {
+			t.Errorf("%s: expected fip [%s], got [%s]", tt.name, tt.want, got)
+		}
+	}
+}
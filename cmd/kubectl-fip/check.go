@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+type checkProblem struct {
+	Kind    string
+	Name    string
+	Problem string
+}
+
+func newCheckCmd(o *fipOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Check the consistency between the FloatingIPs, FloatingIPRanges and FloatingIPReservations",
+		Long: "Check the consistency between the FloatingIPs, FloatingIPRanges and FloatingIPReservations with the same checks as the " +
+			"operator and the validating webhook, without the operator. The command exits with 1 when problems are found.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			problems := checkConsistency()
+			if len(problems) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "no problems found in %d fipranges, %d fips and %d fipreservations\n",
+					len(kubefip.GetAllFipRanges()), len(kubefip.GetAllFips()), len(kubefip.GetAllFipReservations()))
+
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "KIND\tNAME\tPROBLEM")
+			for _, problem := range problems {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", problem.Kind, problem.Name, problem.Problem)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("found %d problems", len(problems))
+		},
+	}
+}
+
+func checkConsistency() []checkProblem {
+	var problems []checkProblem
+
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := range fipRanges {
+		fipRange := &fipRanges[i]
+
+		if err := kubefip.ValidateFipRange(fipRange); err != nil {
+			problems = append(problems, checkProblem{"FloatingIPRange", fipRange.ObjectMeta.Name, err.Error()})
+
+			continue
+		}
+
+		if err := kubefip.ValidateFipRangeOverlap(fipRange); err != nil {
+			problems = append(problems, checkProblem{"FloatingIPRange", fipRange.ObjectMeta.Name, err.Error()})
+		}
+	}
+
+	clusters := make(map[string][]string)
+
+	fips := kubefip.GetAllFips()
+	for i := range fips {
+		fip := &fips[i]
+		name := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+		if fip.Spec.ClusterName != "" {
+			clusters[fip.Spec.ClusterName] = append(clusters[fip.Spec.ClusterName], name)
+		}
+
+		if err := kubefip.ValidateFip(fip); err != nil {
+			problems = append(problems, checkProblem{"FloatingIP", name, err.Error()})
+
+			continue
+		}
+
+		if fip.Spec.IPAddress == "" {
+			problems = append(problems, checkProblem{"FloatingIP", name, "no ip address allocated"})
+
+			continue
+		}
+
+		if err := kubefip.ValidateFipAddressAvailable(fip); err != nil {
+			problems = append(problems, checkProblem{"FloatingIP", name, err.Error()})
+		}
+
+		if fip.ObjectMeta.Annotations["orphanedSince"] != "" {
+			problems = append(problems, checkProblem{"FloatingIP", name,
+				fmt.Sprintf("cluster [%s] is gone since [%s]", fip.Spec.ClusterName, fip.ObjectMeta.Annotations["orphanedSince"])})
+		}
+	}
+
+	for clusterName, fips := range clusters {
+		if len(fips) > 1 {
+			problems = append(problems, checkProblem{"FloatingIP", strings.Join(fips, ","),
+				fmt.Sprintf("cluster [%s] has %d fips", clusterName, len(fips))})
+		}
+	}
+
+	fipReservations := kubefip.GetAllFipReservations()
+	for i := range fipReservations {
+		fipReservation := &fipReservations[i]
+
+		if fipReservation.Status.BoundTo != "" {
+			if !fipExists(fipReservation.Status.BoundTo) {
+				problems = append(problems, checkProblem{"FloatingIPReservation", fipReservation.ObjectMeta.Name,
+					fmt.Sprintf("bound fip [%s] does not exist", fipReservation.Status.BoundTo)})
+			}
+
+			continue
+		}
+
+		if !kubefip.IsFipReservationActive(fipReservation) {
+			problems = append(problems, checkProblem{"FloatingIPReservation", fipReservation.ObjectMeta.Name,
+				fmt.Sprintf("expired since [%s]", fipReservation.Spec.Expires.String())})
+
+			continue
+		}
+
+		if err := kubefip.ValidateFipReservation(fipReservation); err != nil {
+			problems = append(problems, checkProblem{"FloatingIPReservation", fipReservation.ObjectMeta.Name, err.Error()})
+		}
+	}
+
+	return problems
+}
+
+func fipExists(namespacedName string) bool {
+	for _, fip := range kubefip.GetAllFips() {
+		if fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name) == namespacedName {
+			return true
+		}
+	}
+
+	return false
+}
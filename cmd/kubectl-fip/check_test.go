@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipfake "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestFipRange(name string, ipRange string) *KubefipV2.FloatingIPRange {
+	return &KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: ipRange},
+	}
+}
+
+func newTestFip(namespace string, clusterName string, ipAddress string) *KubefipV2.FloatingIP {
+	return &KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName + "-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: clusterName, FipRange: "range1", IPAddress: ipAddress},
+	}
+}
+
+func newTestFipReservation(name string, ipAddress string) *KubefipV1.FloatingIPReservation {
+	return &KubefipV1.FloatingIPReservation{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       KubefipV1.FloatingIPReservationSpec{FipRange: "range1", IPAddress: ipAddress},
+	}
+}
+
+// newTestOptions returns the options of a plugin run against a fake clientset with the objects, which are gathered
+func newTestOptions(t *testing.T, namespace string, objects ...runtime.Object) *fipOptions {
+	t.Helper()
+
+	log.SetLevel(log.WarnLevel)
+
+	o := &fipOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		clientset:   kubefipfake.NewSimpleClientset(objects...),
+	}
+	*o.configFlags.Namespace = namespace
+
+	if err := o.gather(); err != nil {
+		t.Fatalf("error gathering the objects: %s", err.Error())
+	}
+
+	return o
+}
+
+func TestCheckConsistency(t *testing.T) {
+	orphaned := newTestFip("ns1", "cluster2", "192.168.10.11")
+	orphaned.ObjectMeta.Annotations = map[string]string{"orphanedSince": "2026-01-01T00:00:00Z"}
+
+	bound := newTestFipReservation("bound", "192.168.10.20")
+	bound.Status.BoundTo = "ns1/gone-kubevip"
+
+	expired := newTestFipReservation("expired", "192.168.10.21")
+	expired.Spec.Expires = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name         string
+		objects      []runtime.Object
+		wantProblems []string
+	}{
+		{
+			name: "consistent",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFip("ns1", "cluster1", "192.168.10.10"),
+				newTestFipReservation("reserved", "192.168.10.20"),
+			},
+		},
+		{
+			name: "invalid fiprange",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFipRange("range2", "192.168.11.1/32"),
+			},
+			wantProblems: []string{"FloatingIPRange range2: iprange [192.168.11.1/32] of fiprange [range2] has no usable ip addresses"},
+		},
+		{
+			name: "overlapping fipranges",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFipRange("range2", "192.168.10.128/25"),
+			},
+			wantProblems: []string{
+				"FloatingIPRange range1: iprange [192.168.10.0/24] of fiprange [range1] overlaps with iprange [192.168.10.128/25] of fiprange [range2]",
+				"FloatingIPRange range2: iprange [192.168.10.128/25] of fiprange [range2] overlaps with iprange [192.168.10.0/24] of fiprange [range1]",
+			},
+		},
+		{
+			name: "fip problems",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFip("ns1", "cluster1", ""),
+				orphaned,
+				newTestFip("ns2", "cluster3", "192.168.10.11"),
+				newTestFip("ns3", "cluster4", "192.168.11.10"),
+			},
+			wantProblems: []string{
+				"FloatingIP ns1/cluster1-kubevip: no ip address allocated",
+				"FloatingIP ns1/cluster2-kubevip: ip address [192.168.10.11] is already taken by fip [ns2/cluster3-kubevip]",
+				"FloatingIP ns1/cluster2-kubevip: cluster [cluster2] is gone since [2026-01-01T00:00:00Z]",
+				"FloatingIP ns2/cluster3-kubevip: ip address [192.168.10.11] is already taken by fip [ns1/cluster2-kubevip]",
+				"FloatingIP ns3/cluster4-kubevip: ip address [192.168.11.10] is not a part of iprange [192.168.10.0/24]",
+			},
+		},
+		{
+			name: "cluster with several fips",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFip("ns1", "cluster1", "192.168.10.10"),
+				newTestFip("ns2", "cluster1", "192.168.10.11"),
+			},
+			wantProblems: []string{"cluster [cluster1] has 2 fips"},
+		},
+		{
+			name: "fipreservation problems",
+			objects: []runtime.Object{
+				newTestFipRange("range1", "192.168.10.0/24"),
+				newTestFip("ns1", "cluster1", "192.168.10.10"),
+				bound,
+				expired,
+				newTestFipReservation("taken", "192.168.10.10"),
+				newTestFipReservation("unknown-range", "192.168.11.10"),
+			},
+			wantProblems: []string{
+				"FloatingIP ns1/cluster1-kubevip: ip address [192.168.10.10] is reserved by fipreservation [taken]",
+				"FloatingIPReservation bound: bound fip [ns1/gone-kubevip] does not exist",
+				"FloatingIPReservation expired: expired since",
+				"FloatingIPReservation taken: ip address [192.168.10.10] is already taken by fip [ns1/cluster1-kubevip]",
+				"FloatingIPReservation unknown-range: ip address [192.168.11.10] is not a part of iprange [192.168.10.0/24]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		newTestOptions(t, "", tt.objects...)
+
+		var problems []string
+		for _, problem := range checkConsistency() {
+			problems = append(problems, problem.Kind+" "+problem.Name+": "+problem.Problem)
+		}
+
+		if len(problems) != len(tt.wantProblems) {
+			t.Errorf("%s: expected %d problems, got %d: %v", tt.name, len(tt.wantProblems), len(problems), problems)
+
+			continue
+		}
+
+		for _, wantProblem := range tt.wantProblems {
+			found := false
+			for _, problem := range problems {
+				if strings.Contains(problem, wantProblem) {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("%s: expected problem [%s], got %v", tt.name, wantProblem, problems)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Command kubectl-fip is a kubectl plugin for the floating ip operations of the kube-fip-operator. Put the binary in
+// the PATH and run it as "kubectl fip <command>".
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+type fipOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	clientset   kubefipclientset.Interface
+	debug       bool
+}
+
+func main() {
+	o := &fipOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+	}
+
+	cmd := &cobra.Command{
+		Use:          "kubectl-fip",
+		Short:        "Inspect and manage the FloatingIPs of the kube-fip-operator",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the gather functions of the operator log every object at the info level
+			log.SetLevel(log.WarnLevel)
+			if o.debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			return o.complete()
+		},
+	}
+
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().BoolVar(&o.debug, "debug", false, "log the debug messages of the operator functions")
+
+	cmd.AddCommand(
+		newUsageCmd(o),
+		newWhoHasCmd(o),
+		newAllocateCmd(o),
+		newReleaseCmd(o),
+		newReserveCmd(o),
+		newMoveCmd(o),
+		newCheckCmd(o),
+	)
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func (o *fipOptions) complete() error {
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	o.clientset, err = kubefipclientset.NewForConfig(config)
+
+	return err
+}
+
+// gather stores all fipranges, fips and fipreservations in the kubefip package lists, so the checks of the operator
+// can be used
+func (o *fipOptions) gather() error {
+	if err := kubefip.GatherAllFipRanges(o.clientset); err != nil {
+		return fmt.Errorf("error gathering the fipranges: %s", err.Error())
+	}
+
+	if err := kubefip.GatherAllFips(o.clientset); err != nil {
+		return fmt.Errorf("error gathering the fips: %s", err.Error())
+	}
+
+	if err := kubefip.GatherAllFipReservations(o.clientset); err != nil {
+		return fmt.Errorf("error gathering the fipreservations: %s", err.Error())
+	}
+
+	return nil
+}
+
+// namespace returns the namespace of the --namespace flag, or an empty string when it's not set
+func (o *fipOptions) namespace() string {
+	if o.configFlags.Namespace == nil {
+		return ""
+	}
+
+	return *o.configFlags.Namespace
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+func newMoveCmd(o *fipOptions) *cobra.Command {
+	var toRange, ipAddress string
+	var wait time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "move <cluster> --to-range <fiprange>",
+		Short: "Move the FloatingIP of a cluster to another FloatingIPRange",
+		Long: "Move the FloatingIP of a cluster to another FloatingIPRange. The operator releases the old ip address and allocates a new " +
+			"one, which is pushed to the kube-vip ConfigMap of the guest cluster. A kubefip.k8s.binbash.org/fiprange annotation on the " +
+			"cluster moves the FloatingIP back, change that annotation instead for those clusters.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			fip, err := o.findFip(args[0])
+			if err != nil {
+				return err
+			}
+
+			if fip.Spec.FipRange == toRange && (ipAddress == "" || ipAddress == fip.Spec.IPAddress) {
+				return fmt.Errorf("fip [%s/%s] is already in fiprange [%s]", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, toRange)
+			}
+
+			fipObj, err := o.clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			previousIPAddress := fipObj.Spec.IPAddress
+			newFip := fipObj.DeepCopy()
+			newFip.Spec.FipRange = toRange
+			newFip.Spec.IPAddress = ipAddress
+			if newFip.ObjectMeta.Annotations == nil {
+				newFip.ObjectMeta.Annotations = make(map[string]string)
+			}
+			newFip.ObjectMeta.Annotations["fiprangeReason"] = "moved with kubectl-fip"
+
+			// the same checks as the operator and the webhook
+			if err := kubefip.ValidateFip(newFip); err != nil {
+				return err
+			}
+			if err := kubefip.ValidateFipAddressAvailable(newFip); err != nil {
+				return err
+			}
+
+			if _, err := o.clientset.KubefipV2().FloatingIPs(newFip.ObjectMeta.Namespace).Update(context.TODO(), newFip, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "fip [%s/%s] of cluster [%s] moved from fiprange [%s] to [%s]\n", newFip.ObjectMeta.Namespace,
+				newFip.ObjectMeta.Name, newFip.Spec.ClusterName, fipObj.Spec.FipRange, toRange)
+
+			return o.waitForIPAddress(cmd, newFip.ObjectMeta.Namespace, newFip.ObjectMeta.Name, previousIPAddress, wait)
+		},
+	}
+
+	cmd.Flags().StringVar(&toRange, "to-range", "", "the FloatingIPRange to move the FloatingIP to")
+	cmd.Flags().StringVar(&ipAddress, "ip", "", "use this ip address of the new range instead of the next free one")
+	cmd.Flags().DurationVar(&wait, "wait", 30*time.Second, "wait this long for the operator to allocate the new ip address, 0 doesn't wait")
+	_ = cmd.MarkFlagRequired("to-range")
+
+	return cmd
+}
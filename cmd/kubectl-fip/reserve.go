@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+func newReserveCmd(o *fipOptions) *cobra.Command {
+	var name, fipRangeName, clusterName string
+	var expires time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "reserve <ip> --range <fiprange>",
+		Short: "Create a FloatingIPReservation which holds an ip address for a future cluster",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			fipReservation := KubefipV1.FloatingIPReservation{}
+			fipReservation.ObjectMeta.Name = name
+			if fipReservation.ObjectMeta.Name == "" {
+				fipReservation.ObjectMeta.Name = fmt.Sprintf("%s-%s", fipRangeName, strings.ReplaceAll(args[0], ".", "-"))
+			}
+			fipReservation.Spec.IPAddress = args[0]
+			fipReservation.Spec.FipRange = fipRangeName
+			fipReservation.Spec.ClusterName = clusterName
+			if expires > 0 {
+				fipReservation.Spec.Expires = &metav1.Time{Time: time.Now().Add(expires).Truncate(time.Second)}
+			}
+
+			if err := kubefip.ValidateFipReservation(&fipReservation); err != nil {
+				return err
+			}
+
+			if _, err := o.clientset.KubefipV1().FloatingIPReservations().Create(context.TODO(), &fipReservation, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "fipreservation [%s] created for ip address [%s] in fiprange [%s]\n", fipReservation.ObjectMeta.Name,
+				fipReservation.Spec.IPAddress, fipReservation.Spec.FipRange)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "the name of the reservation, defaults to <fiprange>-<ip>")
+	cmd.Flags().StringVar(&fipRangeName, "range", "", "the FloatingIPRange of the ip address")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "bind the reservation to the new cluster with this name")
+	cmd.Flags().DurationVar(&expires, "expires", 0, "remove the reservation when it's not bound within this time, 0 never expires")
+	_ = cmd.MarkFlagRequired("range")
+
+	return cmd
+}
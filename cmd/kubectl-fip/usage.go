@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+func newUsageCmd(o *fipOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "usage",
+		Short: "Show the capacity, used, reserved and free ip addresses per FloatingIPRange",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tIPRANGE\tHARVESTER-CLUSTER\tNETWORK\tCAPACITY\tUSED\tRESERVED\tFREE\tUTILIZATION")
+
+			for _, fipRange := range kubefip.GetAllFipRanges() {
+				usage := kubefip.GetFipRangeUsage(&fipRange)
+
+				utilization := "-"
+				if usage.Capacity > 0 {
+					utilization = fmt.Sprintf("%.0f%%", float64(usage.Used+usage.Reserved)/float64(usage.Capacity)*100)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", fipRange.ObjectMeta.Name, fipRange.Spec.IPRange,
+					valueOrDash(fipRange.Spec.HarvesterClusterName), valueOrDash(fipRange.Spec.HarvesterNetworkName),
+					usage.Capacity, usage.Used, usage.Reserved, usage.Free, utilization)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
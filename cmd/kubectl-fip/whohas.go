@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+)
+
+func newWhoHasCmd(o *fipOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "who-has <ip>",
+		Short: "Show the FloatingIP, cluster and FloatingIPReservation which use an ip address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := netip.ParseAddr(args[0])
+			if err != nil {
+				return fmt.Errorf("[%s] is not a valid ip address", args[0])
+			}
+			ip := addr.String()
+
+			if err := o.gather(); err != nil {
+				return err
+			}
+
+			var fipRanges []string
+			for _, fipRange := range kubefip.GetAllFipRanges() {
+				if prefix, err := netip.ParsePrefix(fipRange.Spec.IPRange); err == nil && prefix.Masked().Contains(addr) {
+					fipRanges = append(fipRanges, fipRange.ObjectMeta.Name)
+				}
+			}
+
+			if len(fipRanges) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "ip address [%s] is not a part of a fiprange\n", ip)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "ip address [%s] is a part of fiprange %v\n", ip, fipRanges)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			found := false
+
+			for _, fip := range kubefip.GetAllFips() {
+				if fip.Spec.IPAddress != ip {
+					continue
+				}
+
+				if !found {
+					fmt.Fprintln(w, "KIND\tNAME\tCLUSTER\tFIPRANGE\tSTATUS")
+					found = true
+				}
+
+				status := "allocated"
+				if fip.ObjectMeta.Annotations["orphanedSince"] != "" {
+					status = "orphaned since " + fip.ObjectMeta.Annotations["orphanedSince"]
+				}
+
+				fmt.Fprintf(w, "FloatingIP\t%s/%s\t%s\t%s\t%s\n", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, fip.Spec.ClusterName,
+					fip.Spec.FipRange, status)
+			}
+
+			fipReservations := kubefip.GetAllFipReservations()
+			for i, fipReservation := range fipReservations {
+				if fipReservation.Spec.IPAddress != ip {
+					continue
+				}
+
+				if !found {
+					fmt.Fprintln(w, "KIND\tNAME\tCLUSTER\tFIPRANGE\tSTATUS")
+					found = true
+				}
+
+				status := "reserved"
+				if fipReservation.Status.BoundTo != "" {
+					status = "bound to " + fipReservation.Status.BoundTo
+				} else if !kubefip.IsFipReservationActive(&fipReservations[i]) {
+					status = "expired"
+				}
+
+				fmt.Fprintf(w, "FloatingIPReservation\t%s\t%s\t%s\t%s\n", fipReservation.ObjectMeta.Name, valueOrDash(fipReservation.Spec.ClusterName),
+					fipReservation.Spec.FipRange, status)
+			}
+
+			if !found {
+				fmt.Fprintf(cmd.OutOrStdout(), "ip address [%s] is not used by a fip or fipreservation\n", ip)
+
+				return nil
+			}
+
+			return w.Flush()
+		},
+	}
+}
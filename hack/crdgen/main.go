@@ -0,0 +1,23 @@
+// Command crdgen writes deployments/crds.yaml from the types in pkg/apis, run it from the repository root:
+//
+//	go run ./hack/crdgen
+package main
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/crdgen"
+)
+
+func main() {
+	crds, err := crdgen.Generate(".")
+	if err != nil {
+		log.Fatalf("(main) error generating the crds: %s", err.Error())
+	}
+
+	if err := os.WriteFile("deployments/crds.yaml", crds, 0644); err != nil {
+		log.Fatalf("(main) error writing deployments/crds.yaml: %s", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+#!/usr/bin/env bash
+
+# Regenerates the deepcopy functions, apply configurations, clientset, listers, informers and CRDs of the kubefip API.
+#
+# usage: hack/update-codegen.sh
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+CODEGEN_VERSION="${CODEGEN_VERSION:-v0.32.2}"
+MODULE="github.com/joeyloman/kube-fip-operator"
+APIS=(
+  "./pkg/apis/kubefip.k8s.binbash.org/v1"
+  "./pkg/apis/kubefip.k8s.binbash.org/v2"
+)
+
+# the virtual resources of the aggregated ipam api only get deepcopy functions, the operator serves them itself
+VIRTUAL_APIS=(
+  "./pkg/apis/ipam.kubefip.k8s.binbash.org/v1alpha1"
+)
+
+SCRIPT_ROOT="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+BOILERPLATE="${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+GOBIN="${GOBIN:-$(go env GOPATH)/bin}"
+
+cd "${SCRIPT_ROOT}"
+
+for gen in deepcopy-gen applyconfiguration-gen client-gen lister-gen informer-gen; do
+  if [ ! -x "${GOBIN}/${gen}" ]; then
+    GOFLAGS=-mod=mod go install "k8s.io/code-generator/cmd/${gen}@${CODEGEN_VERSION}"
+  fi
+done
+
+export GOFLAGS=-mod=vendor
+
+INPUTS=()
+for api in "${APIS[@]}"; do
+  INPUTS+=("${MODULE}/${api#./}")
+done
+
+"${GOBIN}/deepcopy-gen" \
+  --go-header-file "${BOILERPLATE}" \
+  --output-file zz_generated.deepcopy.go \
+  "${APIS[@]}" "${VIRTUAL_APIS[@]}"
+
+"${GOBIN}/applyconfiguration-gen" \
+  --go-header-file "${BOILERPLATE}" \
+  --output-dir pkg/generated/applyconfiguration \
+  --output-pkg "${MODULE}/pkg/generated/applyconfiguration" \
+  "${APIS[@]}"
+
+"${GOBIN}/client-gen" \
+  --go-header-file "${BOILERPLATE}" \
+  --clientset-name versioned \
+  --input-base "" \
+  --input "$(IFS=,; echo "${INPUTS[*]}")" \
+  --output-dir pkg/generated/clientset \
+  --output-pkg "${MODULE}/pkg/generated/clientset" \
+  --apply-configuration-package "${MODULE}/pkg/generated/applyconfiguration"
+
+"${GOBIN}/lister-gen" \
+  --go-header-file "${BOILERPLATE}" \
+  --output-dir pkg/generated/listers \
+  --output-pkg "${MODULE}/pkg/generated/listers" \
+  "${APIS[@]}"
+
+"${GOBIN}/informer-gen" \
+  --go-header-file "${BOILERPLATE}" \
+  --output-dir pkg/generated/informers \
+  --output-pkg "${MODULE}/pkg/generated/informers" \
+  --versioned-clientset-package "${MODULE}/pkg/generated/clientset/versioned" \
+  --listers-package "${MODULE}/pkg/generated/listers" \
+  "${APIS[@]}"
+
+go run ./hack/crdgen
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -66,5 +67,11 @@ func main() {
 		panic(err.Error())
 	}
 
-	app.Run(kubefip_clientset, k8s_clientset)
+	// create the dynamic client for the objects without a clientset
+	dynamic_clientset, err := dynamic.NewForConfig(config)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	app.Run(kubefip_clientset, k8s_clientset, dynamic_clientset)
 }
@@ -0,0 +1,6 @@
+// +k8s:deepcopy-gen=package
+
+// Package v1alpha1 contains the read-only virtual resources of the aggregated ipam api. The objects are computed from
+// the live ipam data of the operator and are never stored in etcd.
+// +groupName=ipam.kubefip.k8s.binbash.org
+package v1alpha1
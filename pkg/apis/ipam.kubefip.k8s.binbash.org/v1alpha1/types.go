@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the labels of the virtual objects, they can be used in label selectors
+	LabelFipRange = "ipam.kubefip.k8s.binbash.org/fiprange"
+	LabelState    = "ipam.kubefip.k8s.binbash.org/state"
+
+	IPAddressStateAllocated = "allocated"
+	IPAddressStateReserved  = "reserved"
+	IPAddressStateFree      = "free"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FloatingIPUsage is the capacity and usage of a FloatingIPRange, it has the same name as the range
+type FloatingIPUsage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	IPRange              string `json:"ipRange"`
+	HarvesterClusterName string `json:"harvesterClusterName,omitempty"`
+	HarvesterNetworkName string `json:"harvesterNetworkName,omitempty"`
+
+	// Capacity is the number of addresses in the range without the network and broadcast address
+	Capacity int64 `json:"capacity"`
+	Used     int64 `json:"used"`
+	Reserved int64 `json:"reserved"`
+	Free     int64 `json:"free"`
+
+	// Utilization is the percentage of the used and reserved addresses
+	Utilization int64 `json:"utilization"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type FloatingIPUsageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []FloatingIPUsage `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IPAddress is an allocated, reserved or free address of a FloatingIPRange, the name is the address
+type IPAddress struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	FipRange string `json:"fipRange"`
+
+	// State is allocated, reserved or free
+	State string `json:"state"`
+
+	// FloatingIP is the <namespace>/<name> of the fip which uses the address
+	FloatingIP  string `json:"floatingIP,omitempty"`
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// FloatingIPReservation is the name of the reservation which holds the address
+	FloatingIPReservation string `json:"floatingIPReservation,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type IPAddressList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []IPAddress `json:"items"`
+}
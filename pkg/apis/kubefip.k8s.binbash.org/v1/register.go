@@ -44,6 +44,18 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&FloatingIPRangeList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&FloatingIPReservation{},
+		&FloatingIPReservationList{},
+	)
+
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&KubeVipProfile{},
+		&KubeVipProfileList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&metav1.Status{},
@@ -6,6 +6,12 @@ import (
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=fip;fips
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="IP",type=string,JSONPath=".spec.ipaddress"
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".metadata.annotations.clustername"
+// +kubebuilder:printcolumn:name="FipRange",type=string,JSONPath=".metadata.annotations.fiprange"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 type FloatingIP struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -16,10 +22,14 @@ type FloatingIP struct {
 }
 
 type FloatingIPSpec struct {
+	// IPAddress is allocated from the fiprange annotation when it's not set
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`
 	IPAddress string `json:"ipaddress,omitempty"`
 }
 
 type FloatingIPStatus struct {
+	// +optional
 	Name string
 }
 
@@ -36,6 +46,14 @@ type FloatingIPList struct {
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=fiprange;fipranges
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="IPRange",type=string,JSONPath=".spec.iprange"
+// +kubebuilder:printcolumn:name="Harvester Cluster",type=string,JSONPath=".metadata.annotations.harvesterClusterName"
+// +kubebuilder:printcolumn:name="Network",type=string,JSONPath=".metadata.annotations.harvesterNetworkName"
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=".spec.priority"
+// +kubebuilder:printcolumn:name="Weight",type=integer,JSONPath=".spec.weight"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 type FloatingIPRange struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -45,12 +63,31 @@ type FloatingIPRange struct {
 	Status FloatingIPRangeStatus `json:"status,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:rule="!has(self.iprange) || self.iprange.matches('/([0-9]|[12][0-9]|30)$')",message="the prefix length of iprange must be 30 or less"
+
 type FloatingIPRangeSpec struct {
+	// +kubebuilder:validation:MaxLength=18
+	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])/\d{1,2}$`
 	IPRange string `json:"iprange,omitempty"`
 	//IpRanges []string `json:"ipranges"`
+
+	// ClusterSelector selects the clusters which get a fip from this range. The selector is matched against the
+	// cluster labels plus the kubefip.k8s.binbash.org/harvester-cluster-name, kubefip.k8s.binbash.org/harvester-network-name,
+	// kubefip.k8s.binbash.org/cluster-name and kubefip.k8s.binbash.org/cluster-source labels. When it's not set,
+	// the harvesterClusterName and harvesterNetworkName annotations are used.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Priority decides which range is used when several ranges match a cluster, the highest priority wins
+	Priority int `json:"priority,omitempty"`
+
+	// Weight is used by the weighted fiprange selection policy, a range with weight 2 gets twice as many fips
+	// as a range with weight 1. Ranges without a weight have weight 1.
+	// +kubebuilder:validation:Minimum=0
+	Weight int `json:"weight,omitempty"`
 }
 
 type FloatingIPRangeStatus struct {
+	// +optional
 	Name string
 }
 
@@ -63,3 +100,118 @@ type FloatingIPRangeList struct {
 	// List of Fips.
 	Items []FloatingIPRange `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=fipreservation;fipreservations
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="IP",type=string,JSONPath=".spec.ipaddress"
+// +kubebuilder:printcolumn:name="FipRange",type=string,JSONPath=".spec.fiprange"
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterName"
+// +kubebuilder:printcolumn:name="Bound To",type=string,JSONPath=".status.boundTo"
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=".spec.expires"
+
+type FloatingIPReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FloatingIPReservationSpec   `json:"spec,omitempty"`
+	Status FloatingIPReservationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.clusterName) || !has(self.clusterSelector)",message="clusterName and clusterSelector are mutually exclusive"
+
+type FloatingIPReservationSpec struct {
+	// IPAddress is the reserved address, it must be a part of the FipRange
+	// +required
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`
+	IPAddress string `json:"ipaddress,omitempty"`
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	FipRange string `json:"fiprange,omitempty"`
+
+	// ClusterName binds the reservation to the new cluster with this name
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// ClusterSelector binds the reservation to the first new cluster which matches the selector, it's matched against
+	// the same labels as the FloatingIPRange clusterSelector
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Expires removes the reservation when it's not bound to a cluster before this time
+	Expires *metav1.Time `json:"expires,omitempty"`
+}
+
+type FloatingIPReservationStatus struct {
+	// BoundTo is the <namespace>/<name> of the fip which got the reserved address
+	BoundTo string `json:"boundTo,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type FloatingIPReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of Fip reservations.
+	Items []FloatingIPReservation `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=kvp;kubevipprofiles
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Harvester Cluster",type=string,JSONPath=".spec.harvesterClusterName"
+// +kubebuilder:printcolumn:name="Kube-Vip Version",type=string,JSONPath=".spec.kubevip.chartVersion"
+// +kubebuilder:printcolumn:name="Cloud Provider Version",type=string,JSONPath=".spec.cloudProvider.chartVersion"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+type KubeVipProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KubeVipProfileSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.clusterSelector) || has(self.harvesterClusterName)",message="a profile needs a clusterSelector or a harvesterClusterName"
+
+type KubeVipProfileSpec struct {
+	// ClusterSelector selects the clusters of the profile, it's matched against the same labels as the
+	// FloatingIPRange clusterSelector
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// HarvesterClusterName selects the clusters on this Harvester cluster, when it's set together with the
+	// ClusterSelector both must match
+	HarvesterClusterName string `json:"harvesterClusterName,omitempty"`
+
+	// Kubevip overrides the kubevipChartRef, kubevipChartVersion and kubevipChartValues options
+	Kubevip *KubeVipProfileChart `json:"kubevip,omitempty"`
+
+	// CloudProvider overrides the kubevipCloudProviderChartRef, kubevipCloudProviderChartVersion and
+	// kubevipCloudProviderChartValues options
+	CloudProvider *KubeVipProfileChart `json:"cloudProvider,omitempty"`
+}
+
+// KubeVipProfileChart holds the chart options of a release, the empty fields fall back to the kube-fip-config options
+type KubeVipProfileChart struct {
+	// ChartRef is the chart reference, for example "oci://some-oci-repo-fqdn/kube-vip"
+	ChartRef string `json:"chartRef,omitempty"`
+
+	// ChartVersion is the chart version
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Values is the values.yaml content of the chart, it replaces the values of the kube-fip-config
+	Values string `json:"values,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type KubeVipProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of kube-vip profiles.
+	Items []KubeVipProfile `json:"items"`
+}
@@ -0,0 +1,132 @@
+package v2
+
+import (
+	"strconv"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+)
+
+// the v1 annotations which are spec fields in v2
+const (
+	AnnotationClusterName          = "clustername"
+	AnnotationFipRange             = "fiprange"
+	AnnotationUpdateConfigMap      = "updateConfigMap"
+	AnnotationHarvesterClusterName = "harvesterClusterName"
+	AnnotationHarvesterNetworkName = "harvesterNetworkName"
+)
+
+// ConvertFloatingIPFromV1 moves the clustername, fiprange and updateConfigMap annotations into the spec
+func ConvertFloatingIPFromV1(in *KubefipV1.FloatingIP) *FloatingIP {
+	out := &FloatingIP{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+	out.Kind = "FloatingIP"
+
+	out.Spec.IPAddress = in.Spec.IPAddress
+	out.Spec.ClusterName = popAnnotation(&out.ObjectMeta.Annotations, AnnotationClusterName)
+	out.Spec.FipRange = popAnnotation(&out.ObjectMeta.Annotations, AnnotationFipRange)
+
+	// values which are not a bool are kept as annotation, so they survive the conversion back to v1
+	if updateConfigMap, err := strconv.ParseBool(out.ObjectMeta.Annotations[AnnotationUpdateConfigMap]); err == nil {
+		out.Spec.UpdateConfigMap = updateConfigMap
+		popAnnotation(&out.ObjectMeta.Annotations, AnnotationUpdateConfigMap)
+	}
+
+	out.Status.Name = in.Status.Name
+
+	return out
+}
+
+// ConvertFloatingIPToV1 moves the clusterName, fipRange and updateConfigMap spec fields back into the annotations
+func ConvertFloatingIPToV1(in *FloatingIP) *KubefipV1.FloatingIP {
+	out := &KubefipV1.FloatingIP{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = KubefipV1.SchemeGroupVersion.String()
+	out.Kind = "FloatingIP"
+
+	out.Spec.IPAddress = in.Spec.IPAddress
+	pushAnnotation(&out.ObjectMeta.Annotations, AnnotationClusterName, in.Spec.ClusterName)
+	pushAnnotation(&out.ObjectMeta.Annotations, AnnotationFipRange, in.Spec.FipRange)
+	if in.Spec.UpdateConfigMap {
+		pushAnnotation(&out.ObjectMeta.Annotations, AnnotationUpdateConfigMap, strconv.FormatBool(in.Spec.UpdateConfigMap))
+	}
+
+	out.Status.Name = in.Status.Name
+
+	return out
+}
+
+// ConvertFloatingIPRangeFromV1 moves the harvesterClusterName and harvesterNetworkName annotations into the spec
+func ConvertFloatingIPRangeFromV1(in *KubefipV1.FloatingIPRange) *FloatingIPRange {
+	out := &FloatingIPRange{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+	out.Kind = "FloatingIPRange"
+
+	out.Spec.IPRange = in.Spec.IPRange
+	out.Spec.HarvesterClusterName = popAnnotation(&out.ObjectMeta.Annotations, AnnotationHarvesterClusterName)
+	out.Spec.HarvesterNetworkName = popAnnotation(&out.ObjectMeta.Annotations, AnnotationHarvesterNetworkName)
+	if in.Spec.ClusterSelector != nil {
+		out.Spec.ClusterSelector = in.Spec.ClusterSelector.DeepCopy()
+	}
+	out.Spec.Priority = in.Spec.Priority
+	out.Spec.Weight = in.Spec.Weight
+
+	out.Status.Name = in.Status.Name
+
+	return out
+}
+
+// ConvertFloatingIPRangeToV1 moves the harvesterClusterName and harvesterNetworkName spec fields back into the annotations
+func ConvertFloatingIPRangeToV1(in *FloatingIPRange) *KubefipV1.FloatingIPRange {
+	out := &KubefipV1.FloatingIPRange{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+	}
+	out.APIVersion = KubefipV1.SchemeGroupVersion.String()
+	out.Kind = "FloatingIPRange"
+
+	out.Spec.IPRange = in.Spec.IPRange
+	pushAnnotation(&out.ObjectMeta.Annotations, AnnotationHarvesterClusterName, in.Spec.HarvesterClusterName)
+	pushAnnotation(&out.ObjectMeta.Annotations, AnnotationHarvesterNetworkName, in.Spec.HarvesterNetworkName)
+	if in.Spec.ClusterSelector != nil {
+		out.Spec.ClusterSelector = in.Spec.ClusterSelector.DeepCopy()
+	}
+	out.Spec.Priority = in.Spec.Priority
+	out.Spec.Weight = in.Spec.Weight
+
+	out.Status.Name = in.Status.Name
+
+	return out
+}
+
+func popAnnotation(annotations *map[string]string, key string) string {
+	value, ok := (*annotations)[key]
+	if !ok {
+		return ""
+	}
+
+	delete(*annotations, key)
+	if len(*annotations) == 0 {
+		*annotations = nil
+	}
+
+	return value
+}
+
+func pushAnnotation(annotations *map[string]string, key string, value string) {
+	if value == "" {
+		return
+	}
+
+	if *annotations == nil {
+		*annotations = make(map[string]string)
+	}
+	(*annotations)[key] = value
+}
@@ -0,0 +1,207 @@
+package v2
+
+import (
+	"reflect"
+	"testing"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newV1FloatingIP(annotations map[string]string) *KubefipV1.FloatingIP {
+	return &KubefipV1.FloatingIP{
+		TypeMeta:   metav1.TypeMeta{APIVersion: KubefipV1.SchemeGroupVersion.String(), Kind: "FloatingIP"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip", Annotations: annotations},
+		Spec:       KubefipV1.FloatingIPSpec{IPAddress: "192.168.10.10"},
+		Status:     KubefipV1.FloatingIPStatus{Name: "cluster1"},
+	}
+}
+
+func newV1FloatingIPRange(annotations map[string]string) *KubefipV1.FloatingIPRange {
+	return &KubefipV1.FloatingIPRange{
+		TypeMeta:   metav1.TypeMeta{APIVersion: KubefipV1.SchemeGroupVersion.String(), Kind: "FloatingIPRange"},
+		ObjectMeta: metav1.ObjectMeta{Name: "range1", Annotations: annotations},
+		Spec:       KubefipV1.FloatingIPRangeSpec{IPRange: "192.168.10.0/24"},
+		Status:     KubefipV1.FloatingIPRangeStatus{Name: "range1"},
+	}
+}
+
+func TestFloatingIPRoundTrip(t *testing.T) {
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		wantSpec        FloatingIPSpec
+		wantAnnotations map[string]string
+		// the annotations after the round trip, when they differ from the v1 annotations
+		wantV1Annotations map[string]string
+	}{
+		{
+			name:        "all fields",
+			annotations: map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "true", "other": "value"},
+			wantSpec: FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1",
+				UpdateConfigMap: true},
+			wantAnnotations: map[string]string{"other": "value"},
+		},
+		{
+			name:        "only the spec annotations",
+			annotations: map[string]string{"clustername": "cluster1", "fiprange": "range1"},
+			wantSpec:    FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1"},
+		},
+		{
+			name:            "empty annotation map",
+			annotations:     map[string]string{},
+			wantSpec:        FloatingIPSpec{IPAddress: "192.168.10.10"},
+			wantAnnotations: map[string]string{},
+		},
+		{
+			name:     "no annotations",
+			wantSpec: FloatingIPSpec{IPAddress: "192.168.10.10"},
+		},
+		{
+			name:            "updateConfigMap which is not a bool",
+			annotations:     map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "yes"},
+			wantSpec:        FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1"},
+			wantAnnotations: map[string]string{"updateConfigMap": "yes"},
+		},
+		{
+			name:            "empty updateConfigMap",
+			annotations:     map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": ""},
+			wantSpec:        FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1"},
+			wantAnnotations: map[string]string{"updateConfigMap": ""},
+		},
+		{
+			// false is the default of the v2 spec field, so the annotation isn't written back
+			name:              "updateConfigMap false",
+			annotations:       map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "false"},
+			wantSpec:          FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1"},
+			wantV1Annotations: map[string]string{"clustername": "cluster1", "fiprange": "range1"},
+		},
+		{
+			name:        "updateConfigMap in another bool notation",
+			annotations: map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "True"},
+			wantSpec: FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "range1",
+				UpdateConfigMap: true},
+			wantV1Annotations: map[string]string{"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		in := newV1FloatingIP(tt.annotations)
+		original := in.DeepCopy()
+
+		v2 := ConvertFloatingIPFromV1(in)
+		if v2.APIVersion != SchemeGroupVersion.String() || v2.Kind != "FloatingIP" {
+			t.Errorf("%s: expected a v2 FloatingIP, got [%s/%s]", tt.name, v2.APIVersion, v2.Kind)
+		}
+		if !reflect.DeepEqual(v2.Spec, tt.wantSpec) {
+			t.Errorf("%s: expected v2 spec [%+v], got [%+v]", tt.name, tt.wantSpec, v2.Spec)
+		}
+		if !reflect.DeepEqual(v2.ObjectMeta.Annotations, tt.wantAnnotations) {
+			t.Errorf("%s: expected v2 annotations %v, got %v", tt.name, tt.wantAnnotations, v2.ObjectMeta.Annotations)
+		}
+		if v2.Status.Name != in.Status.Name {
+			t.Errorf("%s: expected v2 status name [%s], got [%s]", tt.name, in.Status.Name, v2.Status.Name)
+		}
+
+		if !reflect.DeepEqual(in, original) {
+			t.Errorf("%s: the v1 object is changed by the conversion", tt.name)
+		}
+
+		want := original
+		if tt.wantV1Annotations != nil {
+			want.ObjectMeta.Annotations = tt.wantV1Annotations
+		}
+
+		if out := ConvertFloatingIPToV1(v2); !reflect.DeepEqual(out, want) {
+			t.Errorf("%s: expected v1 object [%+v] after the round trip, got [%+v]", tt.name, want, out)
+		}
+	}
+}
+
+func TestFloatingIPRangeRoundTrip(t *testing.T) {
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"env": "prod"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "kubefip.k8s.binbash.org/harvester-cluster-name", Operator: metav1.LabelSelectorOpIn, Values: []string{"harvester1", "harvester2"}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		selector        *metav1.LabelSelector
+		priority        int
+		weight          int
+		wantSpec        FloatingIPRangeSpec
+		wantAnnotations map[string]string
+	}{
+		{
+			name:        "harvester annotations",
+			annotations: map[string]string{"harvesterClusterName": "harvester1", "harvesterNetworkName": "vlan10", "other": "value"},
+			wantSpec: FloatingIPRangeSpec{IPRange: "192.168.10.0/24", HarvesterClusterName: "harvester1",
+				HarvesterNetworkName: "vlan10"},
+			wantAnnotations: map[string]string{"other": "value"},
+		},
+		{
+			name:        "only the harvester annotations",
+			annotations: map[string]string{"harvesterClusterName": "harvester1"},
+			wantSpec:    FloatingIPRangeSpec{IPRange: "192.168.10.0/24", HarvesterClusterName: "harvester1"},
+		},
+		{
+			name:            "empty annotation map",
+			annotations:     map[string]string{},
+			wantSpec:        FloatingIPRangeSpec{IPRange: "192.168.10.0/24"},
+			wantAnnotations: map[string]string{},
+		},
+		{
+			name:     "selector, priority and weight",
+			selector: selector,
+			priority: 10,
+			weight:   3,
+			wantSpec: FloatingIPRangeSpec{IPRange: "192.168.10.0/24", ClusterSelector: selector, Priority: 10, Weight: 3},
+		},
+		{
+			name:        "selector with the harvester annotations",
+			annotations: map[string]string{"harvesterClusterName": "harvester1", "harvesterNetworkName": "vlan10"},
+			selector:    selector,
+			wantSpec: FloatingIPRangeSpec{IPRange: "192.168.10.0/24", HarvesterClusterName: "harvester1",
+				HarvesterNetworkName: "vlan10", ClusterSelector: selector},
+		},
+		{
+			name:     "empty selector",
+			selector: &metav1.LabelSelector{},
+			wantSpec: FloatingIPRangeSpec{IPRange: "192.168.10.0/24", ClusterSelector: &metav1.LabelSelector{}},
+		},
+	}
+
+	for _, tt := range tests {
+		in := newV1FloatingIPRange(tt.annotations)
+		in.Spec.ClusterSelector = tt.selector.DeepCopy()
+		in.Spec.Priority = tt.priority
+		in.Spec.Weight = tt.weight
+		original := in.DeepCopy()
+
+		v2 := ConvertFloatingIPRangeFromV1(in)
+		if v2.APIVersion != SchemeGroupVersion.String() || v2.Kind != "FloatingIPRange" {
+			t.Errorf("%s: expected a v2 FloatingIPRange, got [%s/%s]", tt.name, v2.APIVersion, v2.Kind)
+		}
+		if !reflect.DeepEqual(v2.Spec, tt.wantSpec) {
+			t.Errorf("%s: expected v2 spec [%+v], got [%+v]", tt.name, tt.wantSpec, v2.Spec)
+		}
+		if !reflect.DeepEqual(v2.ObjectMeta.Annotations, tt.wantAnnotations) {
+			t.Errorf("%s: expected v2 annotations %v, got %v", tt.name, tt.wantAnnotations, v2.ObjectMeta.Annotations)
+		}
+		if in.Spec.ClusterSelector != nil && v2.Spec.ClusterSelector == in.Spec.ClusterSelector {
+			t.Errorf("%s: expected the clusterSelector to be copied", tt.name)
+		}
+
+		if !reflect.DeepEqual(in, original) {
+			t.Errorf("%s: the v1 object is changed by the conversion", tt.name)
+		}
+
+		if out := ConvertFloatingIPRangeToV1(v2); !reflect.DeepEqual(out, original) {
+			t.Errorf("%s: expected v1 object [%+v] after the round trip, got [%+v]", tt.name, original, out)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// +k8s:deepcopy-gen=package
+// +k8s:defaulter-gen=TypeMeta
+// +k8s:openapi-gen=true
+
+// Package v2 is the v2 version of the API, it replaces the annotations of the v1 FloatingIP and FloatingIPRange
+// with typed spec fields.
+// +groupName=kubefip.k8s.binbash.org
+package v2
@@ -0,0 +1,119 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=fip;fips
+// +kubebuilder:printcolumn:name="IP",type=string,JSONPath=".spec.ipAddress"
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterName"
+// +kubebuilder:printcolumn:name="FipRange",type=string,JSONPath=".spec.fipRange"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+type FloatingIP struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FloatingIPSpec   `json:"spec,omitempty"`
+	Status FloatingIPStatus `json:"status,omitempty"`
+}
+
+type FloatingIPSpec struct {
+	// IPAddress is allocated from the FipRange when it's not set
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$`
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// ClusterName is the name of the guest cluster which uses the fip, the v1 clustername annotation
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// FipRange is the name of the FloatingIPRange which the ip address is allocated from, the v1 fiprange annotation
+	// +kubebuilder:validation:MinLength=1
+	FipRange string `json:"fipRange"`
+
+	// UpdateConfigMap overwrites the kube-vip ConfigMap in the guest cluster at every operate interval, the v1
+	// updateConfigMap annotation
+	// +kubebuilder:default=false
+	UpdateConfigMap bool `json:"updateConfigMap,omitempty"`
+}
+
+type FloatingIPStatus struct {
+	// +optional
+	Name string
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type FloatingIPList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of Fips.
+	Items []FloatingIP `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=fiprange;fipranges
+// +kubebuilder:printcolumn:name="IPRange",type=string,JSONPath=".spec.ipRange"
+// +kubebuilder:printcolumn:name="Harvester Cluster",type=string,JSONPath=".spec.harvesterClusterName"
+// +kubebuilder:printcolumn:name="Network",type=string,JSONPath=".spec.harvesterNetworkName"
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=".spec.priority"
+// +kubebuilder:printcolumn:name="Weight",type=integer,JSONPath=".spec.weight"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+type FloatingIPRange struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FloatingIPRangeSpec   `json:"spec,omitempty"`
+	Status FloatingIPRangeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="self.ipRange.matches('/([0-9]|[12][0-9]|30)$')",message="the prefix length of ipRange must be 30 or less"
+// +kubebuilder:validation:XValidation:rule="!has(self.harvesterNetworkName) || has(self.harvesterClusterName)",message="harvesterNetworkName requires harvesterClusterName"
+
+type FloatingIPRangeSpec struct {
+	// +kubebuilder:validation:MaxLength=18
+	// +kubebuilder:validation:Pattern=`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])/\d{1,2}$`
+	IPRange string `json:"ipRange"`
+
+	// HarvesterClusterName and HarvesterNetworkName select the clusters which get a fip from this range when the
+	// ClusterSelector is not set, the v1 harvesterClusterName and harvesterNetworkName annotations
+	HarvesterClusterName string `json:"harvesterClusterName,omitempty"`
+	HarvesterNetworkName string `json:"harvesterNetworkName,omitempty"`
+
+	// ClusterSelector selects the clusters which get a fip from this range. The selector is matched against the
+	// cluster labels plus the kubefip.k8s.binbash.org/harvester-cluster-name, kubefip.k8s.binbash.org/harvester-network-name,
+	// kubefip.k8s.binbash.org/cluster-name and kubefip.k8s.binbash.org/cluster-source labels.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// Priority decides which range is used when several ranges match a cluster, the highest priority wins
+	// +kubebuilder:default=0
+	Priority int `json:"priority,omitempty"`
+
+	// Weight is used by the weighted fiprange selection policy, a range with weight 2 gets twice as many fips
+	// as a range with weight 1. Ranges without a weight have weight 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1
+	Weight int `json:"weight,omitempty"`
+}
+
+type FloatingIPRangeStatus struct {
+	// +optional
+	Name string
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type FloatingIPRangeList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// List of Fips.
+	Items []FloatingIPRange `json:"items"`
+}
@@ -0,0 +1,126 @@
+// Package apiserver serves the read-only ipam.kubefip.k8s.binbash.org api group as an aggregated api behind the
+// kube-apiserver. The FloatingIPUsage and IPAddress objects are computed from the live ipam data on every request, so
+// RBAC, kubectl get and the Rancher UI work without storing an object per address in etcd.
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	IpamV1alpha1 "github.com/joeyloman/kube-fip-operator/pkg/apis/ipam.kubefip.k8s.binbash.org/v1alpha1"
+	"github.com/joeyloman/kube-fip-operator/pkg/file"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the configmap with the front proxy ca of the kube-apiserver, the aggregator authenticates with a client
+	// certificate of this ca when it proxies the requests
+	authenticationConfigMapNamespace = "kube-system"
+	authenticationConfigMapName      = "extension-apiserver-authentication"
+)
+
+// getRequestHeaderConfig returns the ca and the allowed common names of the aggregator client certificates
+func getRequestHeaderConfig(k8s_clientset *kubernetes.Clientset) (*x509.CertPool, []string, error) {
+	configMap, err := k8s_clientset.CoreV1().ConfigMaps(authenticationConfigMapNamespace).Get(context.TODO(), authenticationConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM([]byte(configMap.Data["requestheader-client-ca-file"])) {
+		return nil, nil, fmt.Errorf("no requestheader-client-ca-file found in configmap [%s/%s]", authenticationConfigMapNamespace, authenticationConfigMapName)
+	}
+
+	var allowedNames []string
+	if configMap.Data["requestheader-allowed-names"] != "" {
+		if err := json.Unmarshal([]byte(configMap.Data["requestheader-allowed-names"]), &allowedNames); err != nil {
+			return nil, nil, fmt.Errorf("error parsing requestheader-allowed-names: %s", err.Error())
+		}
+	}
+
+	return clientCAs, allowedNames, nil
+}
+
+// authenticate only passes the requests which are proxied by the aggregator, the kube-apiserver already authorized the
+// user before the request is proxied
+func authenticate(next http.Handler, allowedNames []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			writeStatus(w, http.StatusUnauthorized, metav1.StatusReasonUnauthorized, "no valid client certificate found", nil)
+
+			return
+		}
+
+		commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if len(allowedNames) > 0 && !slices.Contains(allowedNames, commonName) {
+			log.Warnf("(authenticate) client certificate [%s] is not allowed to proxy requests", commonName)
+
+			writeStatus(w, http.StatusForbidden, metav1.StatusReasonForbidden, fmt.Sprintf("client certificate [%s] is not allowed", commonName), nil)
+
+			return
+		}
+
+		log.Debugf("(authenticate) request [%s %s] of user [%s]", r.Method, r.URL.Path, r.Header.Get("X-Remote-User"))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// registerHandlers adds the discovery and resource endpoints of the aggregated ipam api to the mux
+func registerHandlers(mux *http.ServeMux) {
+	groupPath := "/apis/" + IpamV1alpha1.SchemeGroupVersion.Group
+	versionPath := "/apis/" + IpamV1alpha1.SchemeGroupVersion.String()
+
+	mux.HandleFunc("GET /apis", serveAPIGroupList)
+	mux.HandleFunc("GET "+groupPath, serveAPIGroup)
+	mux.HandleFunc("GET "+versionPath, serveAPIResourceList)
+	mux.HandleFunc("GET "+versionPath+"/floatingipusages", serveFloatingIPUsages)
+	mux.HandleFunc("GET "+versionPath+"/floatingipusages/{name}", serveFloatingIPUsage)
+	mux.HandleFunc("GET "+versionPath+"/ipaddresses", serveIPAddresses)
+	mux.HandleFunc("GET "+versionPath+"/ipaddresses/{name}", serveIPAddress)
+}
+
+// StartAPIServer starts the https server of the aggregated ipam api. It uses the certificate of the webhooks and is
+// only started when the certificate and key are mounted in the certDir.
+func StartAPIServer(apiServerPort int, certDir string, k8s_clientset *kubernetes.Clientset) {
+	certFile := filepath.Join(certDir, "tls.crt")
+	keyFile := filepath.Join(certDir, "tls.key")
+
+	if !file.FileExists(certFile) || !file.FileExists(keyFile) {
+		log.Warnf("(StartAPIServer) no certificate found in [%s], the aggregated ipam api is disabled", certDir)
+
+		return
+	}
+
+	clientCAs, allowedNames, err := getRequestHeaderConfig(k8s_clientset)
+	if err != nil {
+		log.Errorf("(StartAPIServer) cannot authenticate the aggregator, the aggregated ipam api is disabled: %s", err.Error())
+
+		return
+	}
+
+	mux := http.NewServeMux()
+	registerHandlers(mux)
+
+	log.Infof("(StartAPIServer) start the aggregated ipam api [%s] on port [%d]", IpamV1alpha1.SchemeGroupVersion.String(), apiServerPort)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", apiServerPort),
+		Handler: authenticate(mux, allowedNames),
+		TLSConfig: &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  clientCAs,
+		},
+	}
+
+	log.Fatal(server.ListenAndServeTLS(certFile, keyFile))
+}
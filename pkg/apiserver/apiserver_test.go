@@ -0,0 +1,49 @@
+package apiserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name         string
+		commonName   string
+		noTLS        bool
+		allowedNames []string
+		wantCode     int
+	}{
+		{name: "no tls", noTLS: true, wantCode: http.StatusUnauthorized},
+		{name: "no client certificate", wantCode: http.StatusUnauthorized},
+		{name: "allowed name", commonName: "front-proxy-client", allowedNames: []string{"front-proxy-client"}, wantCode: http.StatusOK},
+		{name: "other name", commonName: "other", allowedNames: []string{"front-proxy-client"}, wantCode: http.StatusForbidden},
+		{name: "no allowed names configured", commonName: "other", wantCode: http.StatusOK},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/apis", nil)
+		if tt.noTLS {
+			r.TLS = nil
+		} else if tt.commonName != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.commonName}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		} else {
+			r.TLS = &tls.ConnectionState{}
+		}
+
+		w := httptest.NewRecorder()
+		authenticate(next, tt.allowedNames).ServeHTTP(w, r)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: expected status code [%d], got [%d]", tt.name, tt.wantCode, w.Code)
+		}
+	}
+}
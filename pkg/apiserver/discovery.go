@@ -0,0 +1,62 @@
+package apiserver
+
+import (
+	"net/http"
+
+	IpamV1alpha1 "github.com/joeyloman/kube-fip-operator/pkg/apis/ipam.kubefip.k8s.binbash.org/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func apiGroup() metav1.APIGroup {
+	version := metav1.GroupVersionForDiscovery{
+		GroupVersion: IpamV1alpha1.SchemeGroupVersion.String(),
+		Version:      IpamV1alpha1.SchemeGroupVersion.Version,
+	}
+
+	return metav1.APIGroup{
+		TypeMeta:         metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"},
+		Name:             IpamV1alpha1.SchemeGroupVersion.Group,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+}
+
+func serveAPIGroupList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, metav1.APIGroupList{
+		TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+		Groups:   []metav1.APIGroup{apiGroup()},
+	})
+}
+
+func serveAPIGroup(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiGroup())
+}
+
+func serveAPIResourceList(w http.ResponseWriter, r *http.Request) {
+	// the objects are computed from the ipam data, so they can only be read
+	verbs := metav1.Verbs{"get", "list"}
+
+	writeJSON(w, http.StatusOK, metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: IpamV1alpha1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{
+				Name:         "floatingipusages",
+				SingularName: "floatingipusage",
+				Namespaced:   false,
+				Kind:         "FloatingIPUsage",
+				Verbs:        verbs,
+				ShortNames:   []string{"fipusage", "fipusages"},
+			},
+			{
+				Name:         "ipaddresses",
+				SingularName: "ipaddress",
+				Namespaced:   false,
+				Kind:         "IPAddress",
+				Verbs:        verbs,
+				ShortNames:   []string{"fipaddress", "fipaddresses"},
+			},
+		},
+	})
+}
@@ -0,0 +1,379 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	IpamV1alpha1 "github.com/joeyloman/kube-fip-operator/pkg/apis/ipam.kubefip.k8s.binbash.org/v1alpha1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func newFloatingIPUsage(fipRange *KubefipV2.FloatingIPRange) IpamV1alpha1.FloatingIPUsage {
+	usage := kubefip.GetFipRangeUsage(fipRange)
+
+	fipUsage := IpamV1alpha1.FloatingIPUsage{
+		TypeMeta: metav1.TypeMeta{Kind: "FloatingIPUsage", APIVersion: IpamV1alpha1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fipRange.ObjectMeta.Name,
+			UID:               fipRange.ObjectMeta.UID,
+			CreationTimestamp: fipRange.ObjectMeta.CreationTimestamp,
+			Labels:            fipRange.ObjectMeta.Labels,
+		},
+		IPRange:              fipRange.Spec.IPRange,
+		HarvesterClusterName: fipRange.Spec.HarvesterClusterName,
+		HarvesterNetworkName: fipRange.Spec.HarvesterNetworkName,
+		Capacity:             usage.Capacity,
+		Used:                 usage.Used,
+		Reserved:             usage.Reserved,
+		Free:                 usage.Free,
+	}
+	if usage.Capacity > 0 {
+		fipUsage.Utilization = (usage.Used + usage.Reserved) * 100 / usage.Capacity
+	}
+
+	return fipUsage
+}
+
+// newIPAddresses returns the allocated, reserved and free addresses of a fiprange sorted by address
+func newIPAddresses(fipRange *KubefipV2.FloatingIPRange) []IpamV1alpha1.IPAddress {
+	var ipAddresses []IpamV1alpha1.IPAddress
+
+	newIPAddress := func(ip string, state string) IpamV1alpha1.IPAddress {
+		return IpamV1alpha1.IPAddress{
+			TypeMeta: metav1.TypeMeta{Kind: "IPAddress", APIVersion: IpamV1alpha1.SchemeGroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ip,
+				Labels: map[string]string{
+					IpamV1alpha1.LabelFipRange: fipRange.ObjectMeta.Name,
+					IpamV1alpha1.LabelState:    state,
+				},
+			},
+			FipRange: fipRange.ObjectMeta.Name,
+			State:    state,
+		}
+	}
+
+	fips := kubefip.GetAllFips()
+	for i := range fips {
+		if fips[i].Spec.FipRange != fipRange.ObjectMeta.Name || fips[i].Spec.IPAddress == "" {
+			continue
+		}
+
+		ipAddress := newIPAddress(fips[i].Spec.IPAddress, IpamV1alpha1.IPAddressStateAllocated)
+		ipAddress.ObjectMeta.CreationTimestamp = fips[i].ObjectMeta.CreationTimestamp
+		ipAddress.FloatingIP = fmt.Sprintf("%s/%s", fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name)
+		ipAddress.ClusterName = fips[i].Spec.ClusterName
+		ipAddress.FloatingIPReservation = fips[i].ObjectMeta.Annotations["fipreservation"]
+
+		ipAddresses = append(ipAddresses, ipAddress)
+	}
+
+	fipReservations := kubefip.GetAllFipReservations()
+	for i := range fipReservations {
+		if fipReservations[i].Spec.FipRange != fipRange.ObjectMeta.Name || !kubefip.IsFipReservationActive(&fipReservations[i]) {
+			continue
+		}
+
+		ipAddress := newIPAddress(fipReservations[i].Spec.IPAddress, IpamV1alpha1.IPAddressStateReserved)
+		ipAddress.ObjectMeta.CreationTimestamp = fipReservations[i].ObjectMeta.CreationTimestamp
+		ipAddress.ClusterName = fipReservations[i].Spec.ClusterName
+		ipAddress.FloatingIPReservation = fipReservations[i].ObjectMeta.Name
+
+		ipAddresses = append(ipAddresses, ipAddress)
+	}
+
+	for _, ip := range kubefip.GetFreeIPAddresses(fipRange, 0) {
+		ipAddresses = append(ipAddresses, newIPAddress(ip, IpamV1alpha1.IPAddressStateFree))
+	}
+
+	sort.SliceStable(ipAddresses, func(i, j int) bool {
+		a, _ := netip.ParseAddr(ipAddresses[i].ObjectMeta.Name)
+		b, _ := netip.ParseAddr(ipAddresses[j].ObjectMeta.Name)
+
+		return a.Less(b)
+	})
+
+	return ipAddresses
+}
+
+// parseSelectors parses the labelSelector and fieldSelector list options
+func parseSelectors(r *http.Request) (labels.Selector, fields.Selector, error) {
+	labelSelector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid labelSelector: %s", err.Error())
+	}
+
+	fieldSelector, err := fields.ParseSelector(r.URL.Query().Get("fieldSelector"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid fieldSelector: %s", err.Error())
+	}
+
+	return labelSelector, fieldSelector, nil
+}
+
+// checkListRequest rejects the watch requests and parses the selectors of a list request
+func checkListRequest(w http.ResponseWriter, r *http.Request) (labels.Selector, fields.Selector, bool) {
+	if r.URL.Query().Get("watch") == "true" || r.URL.Query().Get("watch") == "1" {
+		writeStatus(w, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed, "watch is not supported, the objects are computed on every request", nil)
+
+		return nil, nil, false
+	}
+
+	labelSelector, fieldSelector, err := parseSelectors(r)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error(), nil)
+
+		return nil, nil, false
+	}
+
+	return labelSelector, fieldSelector, true
+}
+
+func serveFloatingIPUsages(w http.ResponseWriter, r *http.Request) {
+	labelSelector, fieldSelector, ok := checkListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	list := IpamV1alpha1.FloatingIPUsageList{
+		TypeMeta: metav1.TypeMeta{Kind: "FloatingIPUsageList", APIVersion: IpamV1alpha1.SchemeGroupVersion.String()},
+		Items:    []IpamV1alpha1.FloatingIPUsage{},
+	}
+
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := range fipRanges {
+		fipUsage := newFloatingIPUsage(&fipRanges[i])
+
+		if labelSelector.Matches(labels.Set(fipUsage.ObjectMeta.Labels)) && fieldSelector.Matches(fields.Set{"metadata.name": fipUsage.ObjectMeta.Name}) {
+			list.Items = append(list.Items, fipUsage)
+		}
+	}
+
+	if wantsTable(r) {
+		writeJSON(w, http.StatusOK, floatingIPUsageTable(list.Items))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func serveFloatingIPUsage(w http.ResponseWriter, r *http.Request) {
+	fipRange, err := kubefip.GetFipRange(r.PathValue("name"))
+	if err != nil {
+		writeNotFound(w, "floatingipusages", r.PathValue("name"))
+
+		return
+	}
+
+	fipUsage := newFloatingIPUsage(&fipRange)
+
+	if wantsTable(r) {
+		writeJSON(w, http.StatusOK, floatingIPUsageTable([]IpamV1alpha1.FloatingIPUsage{fipUsage}))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fipUsage)
+}
+
+func serveIPAddresses(w http.ResponseWriter, r *http.Request) {
+	labelSelector, fieldSelector, ok := checkListRequest(w, r)
+	if !ok {
+		return
+	}
+
+	list := IpamV1alpha1.IPAddressList{
+		TypeMeta: metav1.TypeMeta{Kind: "IPAddressList", APIVersion: IpamV1alpha1.SchemeGroupVersion.String()},
+		Items:    []IpamV1alpha1.IPAddress{},
+	}
+
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := range fipRanges {
+		// skip the other ranges before all their addresses are computed
+		if fipRangeName, found := labelSelector.RequiresExactMatch(IpamV1alpha1.LabelFipRange); found && fipRangeName != fipRanges[i].ObjectMeta.Name {
+			continue
+		}
+
+		for _, ipAddress := range newIPAddresses(&fipRanges[i]) {
+			if labelSelector.Matches(labels.Set(ipAddress.ObjectMeta.Labels)) && fieldSelector.Matches(ipAddressFields(&ipAddress)) {
+				list.Items = append(list.Items, ipAddress)
+			}
+		}
+	}
+
+	if wantsTable(r) {
+		writeJSON(w, http.StatusOK, ipAddressTable(list.Items))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func serveIPAddress(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	addr, err := netip.ParseAddr(name)
+	if err != nil || !addr.Is4() {
+		writeNotFound(w, "ipaddresses", name)
+
+		return
+	}
+
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := range fipRanges {
+		prefix, err := netip.ParsePrefix(fipRanges[i].Spec.IPRange)
+		if err != nil || !prefix.Masked().Contains(addr) {
+			continue
+		}
+
+		for _, ipAddress := range newIPAddresses(&fipRanges[i]) {
+			if ipAddress.ObjectMeta.Name != addr.String() {
+				continue
+			}
+
+			if wantsTable(r) {
+				writeJSON(w, http.StatusOK, ipAddressTable([]IpamV1alpha1.IPAddress{ipAddress}))
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, ipAddress)
+
+			return
+		}
+	}
+
+	writeNotFound(w, "ipaddresses", name)
+}
+
+func ipAddressFields(ipAddress *IpamV1alpha1.IPAddress) fields.Set {
+	return fields.Set{
+		"metadata.name": ipAddress.ObjectMeta.Name,
+		"fipRange":      ipAddress.FipRange,
+		"state":         ipAddress.State,
+		"clusterName":   ipAddress.ClusterName,
+	}
+}
+
+// wantsTable returns true when the client, like kubectl get, asks for the server side printed table
+func wantsTable(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "as=Table")
+}
+
+func newTableRow(objectMeta metav1.ObjectMeta, cells ...interface{}) metav1.TableRow {
+	row := metav1.TableRow{Cells: cells}
+
+	raw, err := json.Marshal(metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{Kind: "PartialObjectMetadata", APIVersion: "meta.k8s.io/v1"},
+		ObjectMeta: objectMeta,
+	})
+	if err == nil {
+		row.Object = runtime.RawExtension{Raw: raw}
+	}
+
+	return row
+}
+
+func age(objectMeta metav1.ObjectMeta) string {
+	if objectMeta.CreationTimestamp.IsZero() {
+		return "<unknown>"
+	}
+
+	return duration.HumanDuration(time.Since(objectMeta.CreationTimestamp.Time))
+}
+
+func floatingIPUsageTable(fipUsages []IpamV1alpha1.FloatingIPUsage) metav1.Table {
+	table := metav1.Table{
+		TypeMeta: metav1.TypeMeta{Kind: "Table", APIVersion: "meta.k8s.io/v1"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "IPRange", Type: "string"},
+			{Name: "Capacity", Type: "integer"},
+			{Name: "Used", Type: "integer"},
+			{Name: "Reserved", Type: "integer"},
+			{Name: "Free", Type: "integer"},
+			{Name: "Utilization", Type: "string"},
+			{Name: "Harvester Cluster", Type: "string", Priority: 1},
+			{Name: "Network", Type: "string", Priority: 1},
+			{Name: "Age", Type: "string"},
+		},
+		Rows: []metav1.TableRow{},
+	}
+
+	for _, fipUsage := range fipUsages {
+		table.Rows = append(table.Rows, newTableRow(fipUsage.ObjectMeta, fipUsage.ObjectMeta.Name, fipUsage.IPRange,
+			fipUsage.Capacity, fipUsage.Used, fipUsage.Reserved, fipUsage.Free, fmt.Sprintf("%d%%", fipUsage.Utilization),
+			fipUsage.HarvesterClusterName, fipUsage.HarvesterNetworkName, age(fipUsage.ObjectMeta)))
+	}
+
+	return table
+}
+
+func ipAddressTable(ipAddresses []IpamV1alpha1.IPAddress) metav1.Table {
+	table := metav1.Table{
+		TypeMeta: metav1.TypeMeta{Kind: "Table", APIVersion: "meta.k8s.io/v1"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "FipRange", Type: "string"},
+			{Name: "State", Type: "string"},
+			{Name: "Cluster", Type: "string"},
+			{Name: "FloatingIP", Type: "string"},
+			{Name: "Reservation", Type: "string", Priority: 1},
+		},
+		Rows: []metav1.TableRow{},
+	}
+
+	for _, ipAddress := range ipAddresses {
+		table.Rows = append(table.Rows, newTableRow(ipAddress.ObjectMeta, ipAddress.ObjectMeta.Name, ipAddress.FipRange,
+			ipAddress.State, ipAddress.ClusterName, ipAddress.FloatingIP, ipAddress.FloatingIPReservation))
+	}
+
+	return table
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot encode the response: %s", err.Error()), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("(writeJSON) error while writing the response: %s", err.Error())
+	}
+}
+
+func writeStatus(w http.ResponseWriter, statusCode int, reason metav1.StatusReason, message string, details *metav1.StatusDetails) {
+	writeJSON(w, statusCode, metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  message,
+		Reason:   reason,
+		Details:  details,
+		Code:     int32(statusCode),
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, resource string, name string) {
+	writeStatus(w, http.StatusNotFound, metav1.StatusReasonNotFound,
+		fmt.Sprintf("%s.%s \"%s\" not found", resource, IpamV1alpha1.SchemeGroupVersion.Group, name),
+		&metav1.StatusDetails{Name: name, Group: IpamV1alpha1.SchemeGroupVersion.Group, Kind: resource})
+}
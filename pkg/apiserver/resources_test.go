@@ -0,0 +1,252 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	IpamV1alpha1 "github.com/joeyloman/kube-fip-operator/pkg/apis/ipam.kubefip.k8s.binbash.org/v1alpha1"
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipfake "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/fake"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// gatherTestObjects replaces the fipranges, fips and fipreservations of the kubefip package lists with the objects
+func gatherTestObjects(t *testing.T, objects ...runtime.Object) {
+	t.Helper()
+
+	// the gather functions log every object at the info level
+	logLevel := log.GetLevel()
+	log.SetLevel(log.WarnLevel)
+
+	gather := func(objects ...runtime.Object) {
+		kubefip_clientset := kubefipfake.NewSimpleClientset(objects...)
+
+		if err := kubefip.GatherAllFipRanges(kubefip_clientset); err != nil {
+			t.Fatalf("error gathering the fipranges: %s", err.Error())
+		}
+		if err := kubefip.GatherAllFips(kubefip_clientset); err != nil {
+			t.Fatalf("error gathering the fips: %s", err.Error())
+		}
+		if err := kubefip.GatherAllFipReservations(kubefip_clientset); err != nil {
+			t.Fatalf("error gathering the fipreservations: %s", err.Error())
+		}
+	}
+
+	gather(objects...)
+	t.Cleanup(func() {
+		gather()
+		log.SetLevel(logLevel)
+	})
+}
+
+func newTestFipRange(name string, ipRange string) *KubefipV2.FloatingIPRange {
+	return &KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": name}},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: ipRange},
+	}
+}
+
+func newTestFip(namespace string, clusterName string, fipRange string, ipAddress string) *KubefipV2.FloatingIP {
+	return &KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName + "-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: clusterName, FipRange: fipRange, IPAddress: ipAddress},
+	}
+}
+
+func newTestFipReservation(name string, fipRange string, ipAddress string) *KubefipV1.FloatingIPReservation {
+	return &KubefipV1.FloatingIPReservation{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       KubefipV1.FloatingIPReservationSpec{FipRange: fipRange, IPAddress: ipAddress},
+	}
+}
+
+func TestNewFloatingIPUsage(t *testing.T) {
+	expired := newTestFipReservation("expired", "range1", "192.168.10.5")
+	expired.Spec.Expires = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+	gatherTestObjects(t,
+		newTestFip("ns1", "cluster1", "range1", "192.168.10.1"),
+		newTestFip("ns1", "cluster2", "range1", "192.168.10.2"),
+		newTestFip("ns1", "cluster3", "range1", ""),
+		newTestFipReservation("reserved", "range1", "192.168.10.3"),
+		expired,
+		newTestFip("ns1", "cluster4", "range2", "192.168.20.1"),
+	)
+
+	tests := []struct {
+		name            string
+		fipRange        *KubefipV2.FloatingIPRange
+		wantCapacity    int64
+		wantUsed        int64
+		wantReserved    int64
+		wantFree        int64
+		wantUtilization int64
+	}{
+		{"used and reserved addresses", newTestFipRange("range1", "192.168.10.0/29"), 6, 2, 1, 3, 50},
+		{"utilization is rounded down", newTestFipRange("range2", "192.168.20.0/29"), 6, 1, 0, 5, 16},
+		{"empty range", newTestFipRange("range3", "192.168.30.0/24"), 254, 0, 0, 254, 0},
+		{"range without usable addresses", newTestFipRange("range4", "192.168.40.0/31"), 0, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := newFloatingIPUsage(tt.fipRange)
+
+		if got.Capacity != tt.wantCapacity || got.Used != tt.wantUsed || got.Reserved != tt.wantReserved ||
+			got.Free != tt.wantFree || got.Utilization != tt.wantUtilization {
+			t.Errorf("%s: expected capacity/used/reserved/free/utilization [%d/%d/%d/%d/%d], got [%d/%d/%d/%d/%d]", tt.name,
+				tt.wantCapacity, tt.wantUsed, tt.wantReserved, tt.wantFree, tt.wantUtilization,
+				got.Capacity, got.Used, got.Reserved, got.Free, got.Utilization)
+		}
+
+		if got.ObjectMeta.Name != tt.fipRange.ObjectMeta.Name || got.IPRange != tt.fipRange.Spec.IPRange ||
+			!reflect.DeepEqual(got.ObjectMeta.Labels, tt.fipRange.ObjectMeta.Labels) {
+			t.Errorf("%s: expected the name, iprange and labels of fiprange [%s], got [%+v]", tt.name, tt.fipRange.ObjectMeta.Name, got)
+		}
+	}
+}
+
+func TestNewIPAddresses(t *testing.T) {
+	fip := newTestFip("ns1", "cluster1", "range1", "192.168.10.5")
+	fip.ObjectMeta.Annotations = map[string]string{"fipreservation": "bound"}
+
+	bound := newTestFipReservation("bound", "range1", "192.168.10.5")
+	bound.Status.BoundTo = "ns1/cluster1-kubevip"
+
+	reserved := newTestFipReservation("reserved", "range1", "192.168.10.2")
+	reserved.Spec.ClusterName = "cluster2"
+
+	gatherTestObjects(t, fip, bound, reserved)
+
+	var got []string
+	for _, ipAddress := range newIPAddresses(newTestFipRange("range1", "192.168.10.0/29")) {
+		if ipAddress.ObjectMeta.Labels[IpamV1alpha1.LabelState] != ipAddress.State ||
+			ipAddress.ObjectMeta.Labels[IpamV1alpha1.LabelFipRange] != ipAddress.FipRange {
+			t.Errorf("expected the state and fiprange labels of [%s], got %v", ipAddress.ObjectMeta.Name, ipAddress.ObjectMeta.Labels)
+		}
+
+		got = append(got, ipAddress.ObjectMeta.Name+" "+ipAddress.State+" "+ipAddress.ClusterName+" "+ipAddress.FloatingIP+" "+
+			ipAddress.FloatingIPReservation)
+	}
+
+	want := []string{
+		"192.168.10.1 free   ",
+		"192.168.10.2 reserved cluster2  reserved",
+		"192.168.10.3 free   ",
+		"192.168.10.4 free   ",
+		"192.168.10.5 allocated cluster1 ns1/cluster1-kubevip bound",
+		"192.168.10.6 free   ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected addresses %q, got %q", want, got)
+	}
+}
+
+// getList requests the path and returns the status code and the names of the items or the message of the status
+func getList(t *testing.T, url string) (int, []string, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("error requesting [%s]: %s", url, err.Error())
+	}
+	defer resp.Body.Close()
+
+	body := struct {
+		Kind     string `json:"kind"`
+		Message  string `json:"message"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("error decoding the response of [%s]: %s", url, err.Error())
+	}
+
+	names := []string{}
+	if body.Metadata.Name != "" {
+		names = append(names, body.Metadata.Name)
+	}
+	for _, item := range body.Items {
+		names = append(names, item.Metadata.Name)
+	}
+
+	return resp.StatusCode, names, body.Message
+}
+
+func TestServeResources(t *testing.T) {
+	reserved := newTestFipReservation("reserved", "range1", "192.168.10.2")
+	reserved.Spec.ClusterName = "cluster2"
+
+	gatherTestObjects(t,
+		newTestFipRange("range1", "192.168.10.0/29"),
+		newTestFipRange("range2", "192.168.20.0/30"),
+		newTestFip("ns1", "cluster1", "range1", "192.168.10.1"),
+		reserved,
+	)
+
+	mux := http.NewServeMux()
+	registerHandlers(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	versionPath := "/apis/" + IpamV1alpha1.SchemeGroupVersion.String()
+
+	tests := []struct {
+		name        string
+		path        string
+		wantCode    int
+		wantNames   []string
+		wantMessage string
+	}{
+		{"floatingipusages", "/floatingipusages", http.StatusOK, []string{"range1", "range2"}, ""},
+		{"floatingipusages with a label selector", "/floatingipusages?labelSelector=env%3Drange2", http.StatusOK, []string{"range2"}, ""},
+		{"floatingipusages with a field selector", "/floatingipusages?fieldSelector=metadata.name%3Drange1", http.StatusOK, []string{"range1"}, ""},
+		{"floatingipusage", "/floatingipusages/range1", http.StatusOK, []string{"range1"}, ""},
+		{"unknown floatingipusage", "/floatingipusages/range3", http.StatusNotFound, []string{},
+			`floatingipusages.ipam.kubefip.k8s.binbash.org "range3" not found`},
+		{"watch floatingipusages", "/floatingipusages?watch=true", http.StatusMethodNotAllowed, []string{},
+			"watch is not supported, the objects are computed on every request"},
+		{"invalid label selector", "/floatingipusages?labelSelector=%3D%3D", http.StatusBadRequest, []string{}, ""},
+		{"ipaddresses", "/ipaddresses", http.StatusOK, []string{"192.168.10.1", "192.168.10.2", "192.168.10.3",
+			"192.168.10.4", "192.168.10.5", "192.168.10.6", "192.168.20.1", "192.168.20.2"}, ""},
+		{"ipaddresses of a fiprange", "/ipaddresses?labelSelector=" + IpamV1alpha1.LabelFipRange + "%3Drange2", http.StatusOK,
+			[]string{"192.168.20.1", "192.168.20.2"}, ""},
+		{"ipaddresses which are not free", "/ipaddresses?labelSelector=" + IpamV1alpha1.LabelState + "%21%3Dfree", http.StatusOK,
+			[]string{"192.168.10.1", "192.168.10.2"}, ""},
+		{"ipaddresses of a cluster", "/ipaddresses?fieldSelector=clusterName%3Dcluster2", http.StatusOK, []string{"192.168.10.2"}, ""},
+		{"ipaddress", "/ipaddresses/192.168.20.2", http.StatusOK, []string{"192.168.20.2"}, ""},
+		{"broadcast ipaddress", "/ipaddresses/192.168.20.3", http.StatusNotFound, []string{},
+			`ipaddresses.ipam.kubefip.k8s.binbash.org "192.168.20.3" not found`},
+		{"ipaddress outside the fipranges", "/ipaddresses/10.0.0.1", http.StatusNotFound, []string{}, ""},
+		{"invalid ipaddress", "/ipaddresses/fd00::1", http.StatusNotFound, []string{}, ""},
+	}
+
+	for _, tt := range tests {
+		code, names, message := getList(t, server.URL+versionPath+tt.path)
+		if code != tt.wantCode {
+			t.Errorf("%s: expected status code [%d], got [%d]", tt.name, tt.wantCode, code)
+		}
+
+		if !reflect.DeepEqual(names, tt.wantNames) {
+			t.Errorf("%s: expected objects %v, got %v", tt.name, tt.wantNames, names)
+		}
+
+		if tt.wantMessage != "" && message != tt.wantMessage {
+			t.Errorf("%s: expected message [%s], got [%s]", tt.name, tt.wantMessage, message)
+		}
+	}
+}
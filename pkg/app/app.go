@@ -1,16 +1,43 @@
 package app
 
 import (
+	"sync"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/apiserver"
 	"github.com/joeyloman/kube-fip-operator/pkg/config"
 	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
 	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+	"github.com/joeyloman/kube-fip-operator/pkg/webhook"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
-func Run(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset) {
+// the kubefipConfig is replaced by the configmap events while the guest cluster workers and the event handlers use it
+var kubefipConfigMutex sync.RWMutex
+
+// setKubefipConfig replaces the config which is shared with the guest cluster workers and the event handlers
+func setKubefipConfig(kubefipConfig *config.KubefipConfigStruct, newKubefipConfig config.KubefipConfigStruct) {
+	kubefipConfigMutex.Lock()
+	defer kubefipConfigMutex.Unlock()
+
+	*kubefipConfig = newKubefipConfig
+}
+
+// getKubefipConfig returns a copy of the config, so an operation keeps the same options when the configmap changes
+func getKubefipConfig(kubefipConfig *config.KubefipConfigStruct) *config.KubefipConfigStruct {
+	kubefipConfigMutex.RLock()
+	defer kubefipConfigMutex.RUnlock()
+
+	kubefipConfigCopy := *kubefipConfig
+
+	return &kubefipConfigCopy
+}
+
+func Run(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset, dynamic_clientset dynamic.Interface) {
 	kubefipConfigmap, err := config.GetKubefipConfigmap(k8s_clientset)
 	if err != nil {
 		log.Errorf("(Run) %s", err)
@@ -23,26 +50,46 @@ func Run(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernete
 	// update the loglevel
 	updateLoglevel(&kubefipConfig)
 
+	// the read-only ipam inspection api has its own listener, it's unauthenticated so it's not served with the metrics
+	go status.StartStatusServer(kubefipConfig.StatusAddress)
+
 	// init all metrics as a goroutine (separate thread)
 	go metrics.InitMetrics(kubefipConfig.MetricsPort)
 
-	// create an array with all the FipRange objects
+	// start the webhooks, the v2 objects are read through the conversion webhook so it must listen before they are gathered
+	if err := webhook.StartWebhookServer(kubefipConfig.WebhookPort, kubefipConfig.WebhookCertDir); err != nil {
+		log.Fatalf("(Run) error starting the webhooks: %s", err.Error())
+	}
+
+	// create an array with all the FipRange objects, without them every fip would be allocated again
 	if err := kubefip.GatherAllFipRanges(kubefip_clientset); err != nil {
-		log.Errorf("(Run) error gathering all FipRanges: %s", err.Error())
+		log.Fatalf("(Run) error gathering all FipRanges: %s", err.Error())
 	}
 
-	for i := 0; i < len(kubefip.AllFipRanges); i++ {
-		log.Infof("(Run) stored fiprange name [%s] and cidr [%s]", kubefip.AllFipRanges[i].ObjectMeta.Name, kubefip.AllFipRanges[i].Spec.IPRange)
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := 0; i < len(fipRanges); i++ {
+		log.Infof("(Run) stored fiprange name [%s] and cidr [%s]", fipRanges[i].ObjectMeta.Name, fipRanges[i].Spec.IPRange)
 	}
 
 	// create an array with all the Fip objects
-	if err := kubefip.GatherAllFips(k8s_clientset, kubefip_clientset); err != nil {
-		log.Errorf("(Run) error gathering all fips: %s", err.Error())
+	if err := kubefip.GatherAllFips(kubefip_clientset); err != nil {
+		log.Fatalf("(Run) error gathering all fips: %s", err.Error())
 	}
 
-	for i := 0; i < len(kubefip.AllFips); i++ {
-		log.Infof("(Run) stored fip name [%s/%s] and ipaddress [%s]", kubefip.AllFips[i].ObjectMeta.Namespace, kubefip.AllFips[i].ObjectMeta.Name,
-			kubefip.AllFips[i].Spec.IPAddress)
+	fips := kubefip.GetAllFips()
+	for i := 0; i < len(fips); i++ {
+		log.Infof("(Run) stored fip name [%s/%s] and ipaddress [%s]", fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name,
+			fips[i].Spec.IPAddress)
+	}
+
+	// create an array with all the FipReservation objects
+	if err := kubefip.GatherAllFipReservations(kubefip_clientset); err != nil {
+		log.Fatalf("(Run) error gathering all fipreservations: %s", err.Error())
+	}
+
+	// create an array with all the KubeVipProfile objects
+	if err := gatherAllKubeVipProfiles(kubefip_clientset); err != nil {
+		log.Errorf("(Run) error gathering all kubevipprofiles: %s", err.Error())
 	}
 
 	// init the ipam modules
@@ -54,9 +101,30 @@ func Run(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernete
 	// put all the existing fips objects in the ipam object
 	kubefip.StoreAllocatedIpsInIpamPrefixes(kubefip_clientset)
 
+	// hold the reserved ips in the ipam object, so they are never handed out dynamically
+	kubefip.StoreReservedIpsInIpamPrefixes()
+
+	// the validating webhook validates against the gathered objects
+	webhook.SetValidationReady()
+
+	// start the aggregated ipam api as a goroutine (separate thread), it serves the ipam data which is gathered above
+	go apiserver.StartAPIServer(kubefipConfig.APIServerPort, kubefipConfig.WebhookCertDir, k8s_clientset)
+
+	// initialize the sources where the guest clusters are discovered from
+	initClusterSources(k8s_clientset, &kubefipConfig)
+
+	// bind the reservations whose fip was created right before a restart, they are not held because the fip has the ip
+	rebindFipReservations(kubefip_clientset)
+
+	// load the state of the kube-vip rollout, so a halted rollout isn't continued after a restart
+	loadRolloutState(k8s_clientset)
+
 	// start the maintaining of the kubevip configs
-	startManageKubevip(k8s_clientset, &kubefipConfig)
+	startManageKubevip(kubefip_clientset, &kubefipConfig)
+
+	// start reconciling the guest clusters which are queued by the events
+	startGuestClusterReconciler(kubefip_clientset, k8s_clientset, &kubefipConfig)
 
 	// start watching the namespace and secret events
-	watchEvents(kubefip_clientset, k8s_clientset, &kubefipConfig)
+	watchEvents(kubefip_clientset, k8s_clientset, dynamic_clientset, &kubefipConfig)
 }
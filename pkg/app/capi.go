@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The capiClusterSource discovers the clusters.cluster.x-k8s.io objects. Cluster API has no notion of a Harvester
+// cluster, so the location and network are taken from the harvesterClusterName and harvesterNetworkName annotations
+// on the Cluster object (the same annotations as used on the FloatingIPRange objects).
+type capiClusterSource struct {
+	k8s_clientset *kubernetes.Clientset
+}
+
+func newCapiClusterSource(k8s_clientset *kubernetes.Clientset) *capiClusterSource {
+	return &capiClusterSource{k8s_clientset: k8s_clientset}
+}
+
+func (s *capiClusterSource) Name() string {
+	return ClusterSourceCapi
+}
+
+func (s *capiClusterSource) DiscoverClusters() ([]Cluster, error) {
+	var discoveredClusters []Cluster
+
+	log.Debugf("(capiClusterSource.DiscoverClusters) fetching all clusters.cluster.x-k8s.io objects")
+
+	clusters, err := s.k8s_clientset.RESTClient().Get().AbsPath("/apis/cluster.x-k8s.io/v1beta1").Resource("clusters").DoRaw(context.TODO())
+	if err != nil {
+		return discoveredClusters, fmt.Errorf("(capiClusterSource.DiscoverClusters) error while fetching cluster objects: %s", err.Error())
+	}
+
+	c := CapiClustersStruct{}
+	if err = json.Unmarshal(clusters, &c); err != nil {
+		return discoveredClusters, fmt.Errorf("(capiClusterSource.DiscoverClusters) error unmarshall json: %s", err.Error())
+	}
+
+	for _, item := range c.Items {
+		// skip the clusters which are being deleted
+		if item.Metadata.DeletionTimestamp != "" {
+			continue
+		}
+
+		discoveredClusters = append(discoveredClusters, getCapiClusterVariables(item))
+	}
+
+	return discoveredClusters, nil
+}
+
+func (s *capiClusterSource) GetCluster(fip KubefipV2.FloatingIP) (Cluster, error) {
+	clusterName := fip.Spec.ClusterName
+
+	log.Debugf("(capiClusterSource.GetCluster) checking if cluster [%s/%s] exists in the clusters.cluster.x-k8s.io objects",
+		fip.ObjectMeta.Namespace, clusterName)
+
+	cluster, err := s.k8s_clientset.RESTClient().Get().AbsPath("/apis/cluster.x-k8s.io/v1beta1").Namespace(fip.ObjectMeta.Namespace).Resource("clusters").Name(clusterName).DoRaw(context.TODO())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return Cluster{}, fmt.Errorf("(capiClusterSource.GetCluster) error: clustername [%s] from floatingip [%s/%s] %w",
+				clusterName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, errClusterNotFound)
+		}
+
+		return Cluster{}, fmt.Errorf("(capiClusterSource.GetCluster) error while fetching cluster [%s/%s]: %s",
+			fip.ObjectMeta.Namespace, clusterName, err.Error())
+	}
+
+	c := CapiClusterStruct{}
+	if err = json.Unmarshal(cluster, &c); err != nil {
+		return Cluster{}, fmt.Errorf("(capiClusterSource.GetCluster) error unmarshall json: %s", err.Error())
+	}
+
+	return getCapiClusterVariables(c), nil
+}
+
+func (s *capiClusterSource) GetGuestClient(cluster Cluster) (*guestClient, error) {
+	// Cluster API stores the kubeconfig in the <cluster>-kubeconfig secret next to the Cluster object
+	return getGuestClient(s.k8s_clientset, cluster, cluster.Namespace)
+}
+
+func getCapiClusterVariables(item CapiClusterStruct) Cluster {
+	cluster := Cluster{}
+	cluster.Source = ClusterSourceCapi
+	cluster.Namespace = item.Metadata.Namespace
+	cluster.ClusterName = item.Metadata.Name
+	cluster.HarvesterClusterName = item.Metadata.Annotations["harvesterClusterName"]
+	cluster.HarvesterNetworkName = item.Metadata.Annotations["harvesterNetworkName"]
+	cluster.Labels = item.Metadata.Labels
+	cluster.Annotations = item.Metadata.Annotations
+
+	log.Debugf("(getCapiClusterVariables) cluster [%s/%s] has harvesterClusterName [%s] and harvesterNetworkName [%s]",
+		cluster.Namespace, cluster.ClusterName, cluster.HarvesterClusterName, cluster.HarvesterNetworkName)
+
+	return cluster
+}
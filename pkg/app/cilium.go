@@ -0,0 +1,72 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/lbpool"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ciliumBackend hands out the fip with a CiliumLoadBalancerIPPool of the Cilium LB-IPAM. Cilium is the CNI of the guest
+// cluster, so it's never installed or removed by the operator.
+type ciliumBackend struct{}
+
+func (b ciliumBackend) Name() string {
+	return LBBackendCilium
+}
+
+func (b ciliumBackend) Install(op *lbOperation) bool {
+	log.Debugf("(ciliumBackend.Install) cilium is the cni of guest cluster [%s], it's not installed by the operator", op.fip.Spec.ClusterName)
+
+	return false
+}
+
+// ConfigurePool manages the cluster scoped CiliumLoadBalancerIPPool of the fip
+func (b ciliumBackend) ConfigurePool(op *lbOperation) error {
+	metricUpdate, err := applyGuestObject(op.guestClient, lbpool.CiliumLoadBalancerIPPoolResource, lbpool.NewCiliumLoadBalancerIPPool(&op.fip))
+	if err != nil && !errors.Is(err, errGuestObjectNotManaged) {
+		err = fmt.Errorf("error managing the cilium pool, is the cilium lb-ipam enabled? %s", err.Error())
+	}
+	op.recordEvent(metrics.EventPoolManagement, metricUpdate, err)
+
+	return err
+}
+
+// Verify checks the conditions of the pool, cilium reports a conflict when the fip is also in another pool
+func (b ciliumBackend) Verify(op *lbOperation) (string, error) {
+	pool, err := op.guestClient.dynamicClient.Resource(lbpool.CiliumLoadBalancerIPPoolResource).Get(op.guestClient.ctx, lbpool.PoolName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(pool.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if condition["type"] == "cilium.io/PoolConflict" && condition["status"] == "True" {
+			return "", fmt.Errorf("pool [%s] conflicts with another pool: %v", lbpool.PoolName, condition["message"])
+		}
+	}
+
+	return fmt.Sprintf("pool [%s] has no conflicts", lbpool.PoolName), nil
+}
+
+// Uninstall removes the pool of the fip
+func (b ciliumBackend) Uninstall(op *lbOperation, releases bool) error {
+	message, err := deleteGuestObject(op.guestClient, lbpool.CiliumLoadBalancerIPPoolResource, "", lbpool.PoolName)
+	if !op.recordEvent(metrics.EventPoolRemoval, updateMetrics, err) {
+		return fmt.Errorf("%s", metrics.EventPoolRemoval)
+	}
+
+	log.Infof("(ciliumBackend.Uninstall) guest cluster [%s]: %s", op.fip.Spec.ClusterName, message)
+
+	return nil
+}
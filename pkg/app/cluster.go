@@ -5,26 +5,94 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
 
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
 	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-func checkClusterStatus(k8s_clientset *kubernetes.Clientset, fip KubefipV1.FloatingIP) error {
+type rancherClusterSource struct {
+	k8s_clientset *kubernetes.Clientset
+}
+
+func newRancherClusterSource(k8s_clientset *kubernetes.Clientset) *rancherClusterSource {
+	return &rancherClusterSource{k8s_clientset: k8s_clientset}
+}
+
+func (s *rancherClusterSource) Name() string {
+	return ClusterSourceRancher
+}
+
+func (s *rancherClusterSource) DiscoverClusters() ([]Cluster, error) {
+	var discoveredClusters []Cluster
+
+	// the cluster and harvesterconfig objects are listed once for all clusters
+	c, err := listRancherClusters(s.k8s_clientset)
+	if err != nil {
+		return discoveredClusters, fmt.Errorf("(rancherClusterSource.DiscoverClusters) %s", err.Error())
+	}
+
+	var harvesterConfigs *HarvesterConfigsStruct
+	if h, err := listHarvesterConfigs(s.k8s_clientset); err != nil {
+		log.Errorf("(rancherClusterSource.DiscoverClusters) cannot get the harvester network interfaces: %s", err.Error())
+	} else {
+		harvesterConfigs = &h
+	}
+
+	for i := range c.Items {
+		// clusters without a status clustername don't have a cluster namespace yet
+		if c.Items[i].Status.ClusterName == "" {
+			continue
+		}
+
+		cluster, err := getRancherCluster(&c.Items[i], harvesterConfigs, s.k8s_clientset)
+		if err != nil {
+			log.Errorf("(rancherClusterSource.DiscoverClusters) %s", err.Error())
+
+			continue
+		}
+
+		discoveredClusters = append(discoveredClusters, cluster)
+	}
+
+	return discoveredClusters, nil
+}
+
+func (s *rancherClusterSource) GetCluster(fip KubefipV2.FloatingIP) (Cluster, error) {
+	// check if the floatingip object is still a part of the cluster object
+	if err := checkClusterStatus(s.k8s_clientset, fip); err != nil {
+		return Cluster{}, err
+	}
+
+	return getRancherClusterByNamespace(fip.ObjectMeta.Namespace, s.k8s_clientset)
+}
+
+func (s *rancherClusterSource) GetGuestClient(cluster Cluster) (*guestClient, error) {
+	return getGuestClient(s.k8s_clientset, cluster, "fleet-default")
+}
+
+func checkClusterStatus(k8s_clientset *kubernetes.Clientset, fip KubefipV2.FloatingIP) error {
 	var err error
 
 	log.Debugf("(checkClusterStatus) checking if cluster [%s] exists in the clusters.provisioning.cattle.io objects",
-		fip.ObjectMeta.Annotations["clustername"])
+		fip.Spec.ClusterName)
 
-	cluster, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/provisioning.cattle.io/v1").Namespace("fleet-default").Resource("clusters").Name(fip.ObjectMeta.Annotations["clustername"]).DoRaw(context.TODO())
+	cluster, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/provisioning.cattle.io/v1").Namespace("fleet-default").Resource("clusters").Name(fip.Spec.ClusterName).DoRaw(context.TODO())
 	if err != nil {
-		log.Errorf("(checkClusterStatus) error while fetching cluster objects: %s", err.Error())
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("(checkClusterStatus) error: clustername [%s] from floatingip [%s/%s] %w",
+				fip.Spec.ClusterName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, errClusterNotFound)
+		}
+
+		return fmt.Errorf("(checkClusterStatus) error while fetching cluster objects: %s", err.Error())
 	}
 
 	c := ClusterStruct{}
@@ -36,11 +104,11 @@ func checkClusterStatus(k8s_clientset *kubernetes.Clientset, fip KubefipV1.Float
 	log.Debugf("(checkClusterStatus) cluster object status clustername: [%s] / floatingip namespace: [%s]",
 		c.Status.ClusterName, fip.ObjectMeta.Namespace)
 
-	// checking if the namespace in the floatingip objects is the same as in the cluster object
+	// checking if the namespace in the floatingip objects is the same as in the cluster object, a new cluster with
+	// the same name has another namespace
 	if c.Status.ClusterName != fip.ObjectMeta.Namespace {
-		errMsg := fmt.Sprintf("(checkClusterStatus) error: clustername [%s] from floatingip [%s/%s] cannot be found in the cluster objects",
-			fip.ObjectMeta.Annotations["clustername"], fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
-		return errors.New(errMsg)
+		return fmt.Errorf("(checkClusterStatus) error: clustername [%s] from floatingip [%s/%s] %w",
+			fip.Spec.ClusterName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, errClusterNotFound)
 	}
 
 	return err
@@ -59,6 +127,12 @@ func getHarvesterClusterName(cloudCredentialSecretName string, k8s_clientset *ku
 	}
 
 	harvesterClusterId := string(cloudCredentialSecret.Data["harvestercredentialConfig-clusterId"])
+	if harvesterClusterId == "" {
+		// other node drivers (vSphere, ..) have no harvester clusterid in their cloud credential
+		log.Debugf("(getHarvesterClusterName) cloud credential [%s] is not a Harvester cloud credential", cloudCredentialSecretName)
+
+		return harvesterClusterName, err
+	}
 
 	harvesterCluster, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/management.cattle.io/v3").Resource("clusters").Name(harvesterClusterId).DoRaw(context.TODO())
 	if err != nil {
@@ -78,15 +152,15 @@ func getHarvesterClusterName(cloudCredentialSecretName string, k8s_clientset *ku
 	return harvesterClusterName, err
 }
 
-func getHarvesterClusterNameFromFipRange(fip *KubefipV1.FloatingIP, kubefip_clientset *kubefipclientset.Clientset) (string, error) {
+func getHarvesterClusterNameFromFipRange(fip *KubefipV2.FloatingIP, kubefip_clientset *kubefipclientset.Clientset) (string, error) {
 	var err error
 	var harvesterClusterName string
 
-	fipRange := string(fip.ObjectMeta.Annotations["fiprange"])
+	fipRange := string(fip.Spec.FipRange)
 
-	fipRangeObj, err := kubefip_clientset.KubefipV1().FloatingIPRanges().Get(context.TODO(), fipRange, metav1.GetOptions{})
+	fipRangeObj, err := kubefip_clientset.KubefipV2().FloatingIPRanges().Get(context.TODO(), fipRange, metav1.GetOptions{})
 	if err == nil {
-		harvesterClusterName = fipRangeObj.ObjectMeta.Annotations["harvesterClusterName"]
+		harvesterClusterName = fipRangeObj.Spec.HarvesterClusterName
 	}
 
 	log.Debugf("(getHarvesterClusterNameFromFipRange) fetched harvester clustername [%s] from the fiprange [%s]",
@@ -95,34 +169,51 @@ func getHarvesterClusterNameFromFipRange(fip *KubefipV1.FloatingIP, kubefip_clie
 	return harvesterClusterName, err
 }
 
-func getHarvesterNetworkName(machineConfigRefName string, k8s_clientset *kubernetes.Clientset) (string, error) {
-	var err error
-	var harvesterNetworkName string
+// splitHarvesterNetworkName returns the network name of the Harvester network object <namespace>/<network>
+func splitHarvesterNetworkName(networkName string) string {
+	harvesterNetworkNameSplitted := strings.Split(networkName, "/")
+
+	if len(harvesterNetworkNameSplitted) < 2 {
+		log.Errorf("(splitHarvesterNetworkName) error harvesterNetworkName [%s] format is not correct", networkName)
+
+		return ""
+	}
 
-	log.Debugf("(getHarvesterNetworkName) checking if there is a network name specified in the matching harvesterconfigs object")
+	return harvesterNetworkNameSplitted[1]
+}
+
+// listHarvesterConfigs returns the harvesterconfigs objects of the machinepools
+func listHarvesterConfigs(k8s_clientset *kubernetes.Clientset) (HarvesterConfigsStruct, error) {
+	h := HarvesterConfigsStruct{}
 
 	harvesterConfigs, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/rke-machine-config.cattle.io/v1").Namespace("fleet-default").Resource("harvesterconfigs").DoRaw(context.TODO())
 	if err != nil {
-		errMsg := fmt.Sprintf("(getHarvesterNetworkName) error while fetching harvesterConfigs objects: %s", err.Error())
-		return harvesterNetworkName, errors.New(errMsg)
+		return h, fmt.Errorf("(listHarvesterConfigs) error while fetching harvesterConfigs objects: %s", err.Error())
 	}
 
-	h := HarvesterConfigsStruct{}
 	if err = json.Unmarshal(harvesterConfigs, &h); err != nil {
-		log.Errorf("(getHarvesterNetworkName) error unmarshall json: %s", err.Error())
+		log.Errorf("(listHarvesterConfigs) error unmarshall json: %s", err.Error())
 	}
 
+	return h, nil
+}
+
+// getHarvesterNetworkInterfaces returns all the interfaces of the machinepool with their network name, in the interface order
+func getHarvesterNetworkInterfaces(machineConfigRefName string, h *HarvesterConfigsStruct) ([]HarvesterNetworkInfoInterfacesStruct, string) {
+	var harvesterNetworkInterfaces []HarvesterNetworkInfoInterfacesStruct
+	var vmNamespace string
+
+	log.Debugf("(getHarvesterNetworkInterfaces) checking if there are networks specified in the matching harvesterconfigs object")
+
 	for _, item := range h.Items {
 		// check if the harvesterconfig object name matches the machineConfigRefName
 		if item.Metadata.Name == machineConfigRefName {
-			if item.NetworkName != "" {
-				harvesterNetworkNameSplitted := strings.Split(item.NetworkName, "/")
+			// the namespace of the vms in Harvester
+			vmNamespace = item.VmNamespace
 
-				// get the network name by splitting the Harvester network object <namespace>/<network>
-				if len(harvesterNetworkNameSplitted) < 1 {
-					log.Errorf("(getHarvesterNetworkName) error harvesterNetworkName format is not correct")
-				} else {
-					harvesterNetworkName = harvesterNetworkNameSplitted[1]
+			if item.NetworkName != "" {
+				if harvesterNetworkName := splitHarvesterNetworkName(item.NetworkName); harvesterNetworkName != "" {
+					harvesterNetworkInterfaces = append(harvesterNetworkInterfaces, HarvesterNetworkInfoInterfacesStruct{NetworkName: harvesterNetworkName})
 				}
 
 				break
@@ -131,19 +222,14 @@ func getHarvesterNetworkName(machineConfigRefName string, k8s_clientset *kuberne
 				networkInfo := HarvesterNetworkInfoStruct{}
 				err := json.Unmarshal([]byte(item.NetworkInfo), &networkInfo)
 				if err != nil {
-					log.Errorf("(getHarvesterNetworkName) error converting Harvester networkInfo JSON to a HarvesterNetworkInfoStruct")
+					log.Errorf("(getHarvesterNetworkInterfaces) error converting Harvester networkInfo JSON to a HarvesterNetworkInfoStruct")
 				} else {
-					if len(networkInfo.Interfaces) > 1 {
-						log.Warnf("(getHarvesterNetworkName) machinepool has more then 1 interfaces, only using the first interface")
-					}
-
-					harvesterNetworkNameSplitted := strings.Split(networkInfo.Interfaces[0].NetworkName, "/")
-
-					// get the network name by splitting the Harvester network object <namespace>/<network>
-					if len(harvesterNetworkNameSplitted) < 1 {
-						log.Errorf("(getHarvesterNetworkName) error harvesterNetworkName format is not correct")
-					} else {
-						harvesterNetworkName = harvesterNetworkNameSplitted[1]
+					for _, iface := range networkInfo.Interfaces {
+						// keep the empty names, so the index matches the interface order in the vm
+						harvesterNetworkInterfaces = append(harvesterNetworkInterfaces, HarvesterNetworkInfoInterfacesStruct{
+							NetworkName: splitHarvesterNetworkName(iface.NetworkName),
+							MacAddress:  iface.MacAddress,
+						})
 					}
 				}
 
@@ -152,212 +238,365 @@ func getHarvesterNetworkName(machineConfigRefName string, k8s_clientset *kuberne
 		}
 	}
 
-	return harvesterNetworkName, err
-}
-
-func getClusterVariables(nsName string, k8s_clientset *kubernetes.Clientset) (Cluster, error) {
-	var err error
+	log.Debugf("(getHarvesterNetworkInterfaces) machinepool [%s] has interfaces [%+v] and vms in namespace [%s]",
+		machineConfigRefName, harvesterNetworkInterfaces, vmNamespace)
 
-	log.Debugf("(getClusterVariables) checking if the new namespace is a new cluster object")
+	return harvesterNetworkInterfaces, vmNamespace
+}
 
-	cluster := Cluster{}
+// listRancherClusters returns the provisioning cluster objects
+func listRancherClusters(k8s_clientset *kubernetes.Clientset) (ClustersStruct, error) {
+	c := ClustersStruct{}
 
 	clusters, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/provisioning.cattle.io/v1").Namespace("fleet-default").Resource("clusters").DoRaw(context.TODO())
 	if err != nil {
-		errMsg := fmt.Sprintf("(getClusterVariables) error while fetching cluster objects: %s", err.Error())
-		return cluster, errors.New(errMsg)
+		return c, fmt.Errorf("(listRancherClusters) error while fetching cluster objects: %s", err.Error())
 	}
 
-	c := ClustersStruct{}
 	if err = json.Unmarshal(clusters, &c); err != nil {
-		log.Errorf("(getClusterVariables) error unmarshall json: %s", err.Error())
+		return c, fmt.Errorf("(listRancherClusters) error unmarshall json: %s", err.Error())
 	}
 
-	for _, item := range c.Items {
-		log.Debugf("(getClusterVariables) clusterstruct object: [%+v]", item.Metadata.Name)
+	return c, nil
+}
 
-		if item.Status.ClusterName == nsName {
-			log.Debugf("(getClusterVariables) match found: status clustername [%s] matches namespace [%s]", item.Status.ClusterName, nsName)
+// getClusterVariables returns the cluster variables of the provisioning cluster object
+func getClusterVariables(item *ClusterStruct, k8s_clientset *kubernetes.Clientset) (Cluster, error) {
+	var err error
 
-			// get the machineConfigRef name so we can lookup the network in HarvesterConfig object
-			for _, mps := range item.Spec.RkeConfig.MachinePools {
-				log.Debugf("(getClusterVariables) found MachineConfigRef Kind [%s] / Name [%s] ", mps.MachineConfigRef.Kind, mps.MachineConfigRef.Name)
+	cluster := Cluster{}
+	cluster.Source = ClusterSourceRancher
+	cluster.Namespace = item.Status.ClusterName
+	cluster.ClusterName = item.Metadata.Name
+
+	// get the machineConfigRef name so we can lookup the network in HarvesterConfig object, kube-vip runs on the
+	// control-plane nodes so the control-plane pools are preferred over the first pool hit
+	for _, mps := range item.Spec.RkeConfig.MachinePools {
+		log.Debugf("(getClusterVariables) found MachineConfigRef Kind [%s] / Name [%s] / ControlPlaneRole [%t]",
+			mps.MachineConfigRef.Kind, mps.MachineConfigRef.Name, mps.ControlPlaneRole)
+
+		if mps.MachineConfigRef.Kind != "HarvesterConfig" {
+			continue
+		}
 
-				// TODO: we could also do a doublecheck if the pool has a mps.ControlPlaneRole (now it's based on the first pool hit)
-				if mps.MachineConfigRef.Kind == "HarvesterConfig" {
-					cluster.MachineConfigRefName = mps.MachineConfigRef.Name
+		if mps.ControlPlaneRole {
+			cluster.MachineConfigRefName = mps.MachineConfigRef.Name
 
-					break
-				}
-			}
+			break
+		}
 
-			// store the labels
-			cluster.Labels = item.Metadata.Labels
+		if cluster.MachineConfigRefName == "" {
+			cluster.MachineConfigRefName = mps.MachineConfigRef.Name
+		}
+	}
 
-			// check if the cloudCredentialSecretName exists
-			if item.Spec.CloudCredentialSecretName == "" {
-				log.Debugf("(getClusterVariables) cluster object has no cloudCredentialSecretName in the spec")
+	// store the labels and annotations
+	cluster.Labels = item.Metadata.Labels
+	cluster.Annotations = item.Metadata.Annotations
 
-				return cluster, err
-			} else {
-				cloudCredentialSecretNameSplitted := strings.Split(item.Spec.CloudCredentialSecretName, ":")
+	// check if the cloudCredentialSecretName exists
+	if item.Spec.CloudCredentialSecretName == "" {
+		log.Debugf("(getClusterVariables) cluster object has no cloudCredentialSecretName in the spec")
 
-				// get the cloud credential secret name by splitting the secret object <namespace>:<secret>
-				if len(cloudCredentialSecretNameSplitted) < 1 {
-					log.Errorf("(getClusterVariables) error cloudCredentialSecretName format is not correct")
+		return cluster, err
+	}
 
-					return cluster, err
-				} else {
-					cluster.CloudCredentialSecretName = cloudCredentialSecretNameSplitted[1]
+	cloudCredentialSecretNameSplitted := strings.Split(item.Spec.CloudCredentialSecretName, ":")
 
-					// get the actual clustername
-					cluster.ClusterName = item.Metadata.Name
+	// get the cloud credential secret name by splitting the secret object <namespace>:<secret>
+	if len(cloudCredentialSecretNameSplitted) < 2 {
+		log.Errorf("(getClusterVariables) error cloudCredentialSecretName format is not correct")
 
-					// get the harvester clustername
-					harvesterClusterName, err := getHarvesterClusterName(cluster.CloudCredentialSecretName, k8s_clientset)
-					if err != nil {
-						log.Errorf("%s", err)
+		return cluster, err
+	}
+	cluster.CloudCredentialSecretName = cloudCredentialSecretNameSplitted[1]
 
-						return cluster, err
-					}
-					cluster.HarvesterClusterName = harvesterClusterName
+	// get the harvester clustername
+	harvesterClusterName, err := getHarvesterClusterName(cluster.CloudCredentialSecretName, k8s_clientset)
+	if err != nil {
+		log.Errorf("%s", err)
 
-					return cluster, err
-				}
-			}
-		}
+		return cluster, err
 	}
+	cluster.HarvesterClusterName = harvesterClusterName
 
 	return cluster, err
 }
 
-func checkNewNamespace(ns *corev1.Namespace, kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset) {
-	var fipRangeName string
-	var harvesterNetworkName string
-
-	log.Debugf("(checkNewNamespace) checking if the new namespace is a new cluster object")
+// getRancherClusterByNamespace returns the cluster variables including the Harvester network of a cluster namespace
+func getRancherClusterByNamespace(nsName string, k8s_clientset *kubernetes.Clientset) (Cluster, error) {
+	log.Debugf("(getRancherClusterByNamespace) checking if namespace [%s] belongs to a cluster object", nsName)
 
-	// usually it takes some seconds before the harvester objects are created
-	time.Sleep(15 * time.Second)
+	c, err := listRancherClusters(k8s_clientset)
+	if err != nil {
+		return Cluster{}, err
+	}
 
-	if strings.HasPrefix(ns.Name, "c-m-") {
-		// guest cluster namespace found
-		log.Debugf("(checkNewNamespace) new cluster namespace [%s] detected", ns.Name)
+	for i := range c.Items {
+		if c.Items[i].Status.ClusterName != nsName {
+			continue
+		}
 
-		// get the cloud credential name to determine the fiprange
-		cluster, err := getClusterVariables(ns.Name, k8s_clientset)
-		if err != nil {
-			log.Errorf("(checkNewNamespace) cannot get cluster object for cluster namespace [%s]: %s", ns.Name, err.Error())
+		log.Debugf("(getRancherClusterByNamespace) match found: status clustername of cluster [%s] matches namespace [%s]",
+			c.Items[i].Metadata.Name, nsName)
 
-			return
+		var harvesterConfigs *HarvesterConfigsStruct
+		if h, err := listHarvesterConfigs(k8s_clientset); err != nil {
+			log.Errorf("(getRancherClusterByNamespace) cannot get harvester network interfaces for cluster namespace [%s]: %s", nsName, err.Error())
+		} else {
+			harvesterConfigs = &h
 		}
 
-		// if these objects are empty we have no match
-		if cluster.HarvesterClusterName == "" || cluster.ClusterName == "" {
-			log.Debugf("(checkNewNamespace) namespace [%s] does not exists as a cluster object", ns.Name)
+		return getRancherCluster(&c.Items[i], harvesterConfigs, k8s_clientset)
+	}
 
-			return
-		}
+	return Cluster{Namespace: nsName}, nil
+}
 
-		log.Debugf("(checkNewNamespace) harvesterClusterName [%s] and cloudCredentialSecretName [%s] and clusterName [%s] and machineConfigRefName [%s] found for namespace [%s]",
-			cluster.HarvesterClusterName, cluster.CloudCredentialSecretName, cluster.ClusterName, cluster.MachineConfigRefName, ns.Name)
+// getRancherCluster returns the cluster variables including the Harvester network of the provisioning cluster object,
+// the harvesterconfigs are listed by the caller so a discovery lists them once for all clusters
+func getRancherCluster(item *ClusterStruct, harvesterConfigs *HarvesterConfigsStruct, k8s_clientset *kubernetes.Clientset) (Cluster, error) {
+	cluster, err := getClusterVariables(item, k8s_clientset)
+	if err != nil {
+		return cluster, err
+	}
 
-		// Harvester configuration found, fetching network information
-		if cluster.MachineConfigRefName != "" {
-			harvesterNetworkName, err = getHarvesterNetworkName(cluster.MachineConfigRefName, k8s_clientset)
-			if err != nil {
-				log.Errorf("(checkNewNamespace) cannot get harvester network name for cluster namespace [%s]: %s", ns.Name, err.Error())
-			}
-		}
+	// Harvester configuration found, fetching network information
+	if cluster.MachineConfigRefName != "" && harvesterConfigs != nil {
+		harvesterNetworkInterfaces, vmNamespace := getHarvesterNetworkInterfaces(cluster.MachineConfigRefName, harvesterConfigs)
 
-		log.Debugf("(checkNewNamespace) harvesterNetworkName [%s]", harvesterNetworkName)
+		cluster.HarvesterVmNamespace = vmNamespace
 
-		// check if there is already a fip object in the namespace
-		fipList, err := kubefip_clientset.KubefipV1().FloatingIPs(ns.Name).List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			log.Errorf("(checkNewNamespace) cannot get a list of fips in namespace [%s]: %s", ns.Name, err.Error())
+		for _, iface := range harvesterNetworkInterfaces {
+			cluster.HarvesterNetworkNames = append(cluster.HarvesterNetworkNames, iface.NetworkName)
+			cluster.HarvesterMacAddresses = append(cluster.HarvesterMacAddresses, iface.MacAddress)
+		}
 
-			return
+		// the first interface is used until a fiprange is selected, see selectClusterNetwork
+		if len(cluster.HarvesterNetworkNames) > 0 {
+			cluster.HarvesterNetworkName = cluster.HarvesterNetworkNames[0]
 		}
+	}
 
-		if len(fipList.Items) > 0 {
-			log.Errorf("(checkNewNamespace) namespace [%s] already has %d fip objects registered", ns.Name, len(fipList.Items))
+	// get the rancher project of the cluster namespace, this can be used in the clusterRangeRules
+	ns, err := k8s_clientset.CoreV1().Namespaces().Get(context.TODO(), cluster.Namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("(getRancherCluster) cannot get cluster namespace [%s]: %s", cluster.Namespace, err.Error())
+	} else {
+		cluster.Project = ns.ObjectMeta.Annotations["field.cattle.io/projectId"]
+	}
 
-			return
+	return cluster, nil
+}
+
+// isRancherClusterNamespace checks if the namespace belongs to a Rancher cluster, Rancher creates the namespace of a
+// provisioned or imported cluster with the name of its clusters.management.cattle.io object
+func isRancherClusterNamespace(nsName string, k8s_clientset *kubernetes.Clientset) (bool, error) {
+	_, err := k8s_clientset.RESTClient().Get().AbsPath("/apis/management.cattle.io/v3").Resource("clusters").Name(nsName).DoRaw(context.TODO())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
 		}
 
-		// get the fipranges and check if the cloud credential has a fiprange, return a fiprange
-		fipRangeList, err := kubefip_clientset.KubefipV1().FloatingIPRanges().List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			log.Errorf("(checkNewNamespace) cannot get a list of fipranges: %s", err.Error())
+		return false, fmt.Errorf("(isRancherClusterNamespace) error while fetching the management cluster object [%s]: %s", nsName, err.Error())
+	}
 
-			return
-		}
+	return true, nil
+}
 
-		for _, fiprange := range fipRangeList.Items {
-			if fiprange.ObjectMeta.Annotations["harvesterClusterName"] == cluster.HarvesterClusterName {
-				log.Debugf("(checkNewNamespace) fiprange [%s] has a harvesterClusterName annotation match for cluster [%s]",
-					fiprange.ObjectMeta.Name, fiprange.ObjectMeta.Annotations["harvesterClusterName"])
+func checkNewNamespace(ns *corev1.Namespace, k8s_clientset *kubernetes.Clientset) {
+	log.Debugf("(checkNewNamespace) checking if the new namespace is a new cluster object")
 
-				// if a harvester network name is found, try to match it with a annotation in the the fiprange
-				if harvesterNetworkName != "" {
-					if fiprange.ObjectMeta.Annotations["harvesterNetworkName"] == harvesterNetworkName {
-						log.Debugf("(checkNewNamespace) fiprange [%s] has a harvesterNetworkName annotation match with network [%s]",
-							fiprange.ObjectMeta.Name, fiprange.ObjectMeta.Annotations["harvesterNetworkName"])
+	if _, err := getClusterSourceByName(ClusterSourceRancher); err != nil {
+		log.Debugf("(checkNewNamespace) skipping namespace [%s]: %s", ns.Name, err.Error())
 
-						fipRangeName = fiprange.ObjectMeta.Name
+		return
+	}
 
-						break
-					} else {
-						log.Debugf("(checkNewNamespace) fiprange [%s] has no harvesterNetworkName annotation match with network [%s]",
-							fiprange.ObjectMeta.Name, fiprange.ObjectMeta.Annotations["harvesterNetworkName"])
-					}
-				} else {
-					log.Debugf("(checkNewNamespace) harvesterNetworkName is empty")
+	isClusterNamespace, err := isRancherClusterNamespace(ns.Name, k8s_clientset)
+	if err != nil {
+		// the namespace is resolved by the reconciler, it gives up when there is no provisioning cluster for it
+		log.Errorf("(checkNewNamespace) %s", err.Error())
+	} else if !isClusterNamespace {
+		log.Debugf("(checkNewNamespace) new namespace [%s] is not a guest cluster", ns.Name)
 
-					fipRangeName = fiprange.ObjectMeta.Name
+		return
+	}
 
-					break
-				}
+	// guest cluster namespace found
+	log.Debugf("(checkNewNamespace) new cluster namespace [%s] detected", ns.Name)
 
-				// register the first fiprange hit so we can return it if there is no harvester network match found due a missing annotation
-				if fipRangeName == "" {
-					fipRangeName = fiprange.ObjectMeta.Name
+	// usually it takes some seconds before the provisioning cluster and the harvester objects are complete, the
+	// reconciler requeues the namespace until they are
+	enqueueClusterNamespace(ns.Name)
+}
 
-					log.Debugf("(checkNewNamespace) registered the first fiprange hit: [%s]", fipRangeName)
-				}
-			}
-		}
+// resolveClusterNamespace creates the fip of the cluster of a new cluster namespace, it returns false when the
+// cluster is not complete yet and the namespace should be requeued. When final is true the fip is created with the
+// objects which are found so far.
+func resolveClusterNamespace(nsName string, final bool, kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset,
+	kubefipConfig *config.KubefipConfigStruct) bool {
+	// get the cloud credential name to determine the fiprange
+	cluster, err := getRancherClusterByNamespace(nsName, k8s_clientset)
+	if err != nil {
+		log.Errorf("(resolveClusterNamespace) cannot get cluster object for cluster namespace [%s]: %s", nsName, err.Error())
+
+		return final
+	}
+
+	if cluster.ClusterName == "" {
+		log.Debugf("(resolveClusterNamespace) namespace [%s] has no provisioning cluster yet", nsName)
+
+		return final
+	}
+
+	if cluster.MachineConfigRefName != "" && cluster.HarvesterNetworkName == "" && !final {
+		log.Debugf("(resolveClusterNamespace) harvesterconfig [%s] of cluster [%s] has no network yet",
+			cluster.MachineConfigRefName, cluster.ClusterName)
 
-		if fipRangeName == "" {
-			log.Errorf("(checkNewNamespace) no fiprange match found for clustername [%s]", cluster.ClusterName)
+		return false
+	}
+
+	createFipForCluster(cluster, kubefip_clientset, kubefipConfig)
+
+	return true
+}
+
+func createFipForCluster(cluster Cluster, kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+	// if the clustername is empty we have no match
+	if cluster.ClusterName == "" {
+		log.Debugf("(createFipForCluster) namespace [%s] does not exists as a cluster object", cluster.Namespace)
+
+		return
+	}
+
+	log.Debugf("(createFipForCluster) harvesterClusterName [%s] and cloudCredentialSecretName [%s] and clusterName [%s] and machineConfigRefName [%s] and project [%s] found for namespace [%s]",
+		cluster.HarvesterClusterName, cluster.CloudCredentialSecretName, cluster.ClusterName, cluster.MachineConfigRefName, cluster.Project, cluster.Namespace)
+
+	log.Debugf("(createFipForCluster) harvesterNetworkName [%s]", cluster.HarvesterNetworkName)
+
+	overrides := getClusterOverrides(cluster)
+	if !overrides.Allocate {
+		log.Infof("(createFipForCluster) cluster [%s] has annotation [%s] set to false, not creating a fip",
+			cluster.ClusterName, AnnotationAllocate)
+
+		return
+	}
+
+	// check if there is already a fip object for the cluster in the namespace
+	fipList, err := kubefip_clientset.KubefipV2().FloatingIPs(cluster.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("(createFipForCluster) cannot get a list of fips in namespace [%s]: %s", cluster.Namespace, err.Error())
+
+		return
+	}
+
+	for _, fip := range fipList.Items {
+		if fip.Spec.ClusterName == cluster.ClusterName {
+			log.Errorf("(createFipForCluster) namespace [%s] already has fip [%s] registered for cluster [%s]",
+				cluster.Namespace, fip.ObjectMeta.Name, cluster.ClusterName)
 
 			return
 		}
+	}
+
+	// get the fipranges and check if the cluster has a fiprange, return a fiprange
+	fipRangeList, err := kubefip_clientset.KubefipV2().FloatingIPRanges().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("(createFipForCluster) cannot get a list of fipranges: %s", err.Error())
 
-		log.Debugf("(checkNewNamespace) clusterName [%s] / fipRangeName [%s]", cluster.ClusterName, fipRangeName)
+		return
+	}
 
-		// create a new fip object
-		fip := KubefipV1.FloatingIP{}
-		fip.ObjectMeta.Name = fmt.Sprintf("%s-kubevip", cluster.ClusterName)
-		fip.ObjectMeta.Namespace = ns.Name
+	// a reservation made by the network team for this cluster wins from the cluster annotations
+	fipReservation, reservationReason := getFipReservationForCluster(cluster)
+	if fipReservation.ObjectMeta.Name != "" {
+		overrides.FipRange = fipReservation.Spec.FipRange
+		overrides.IPAddress = fipReservation.Spec.IPAddress
+	}
 
-		annotations := make(map[string]string)
-		annotations["clustername"] = cluster.ClusterName
-		annotations["fiprange"] = fipRangeName
-		annotations["updateConfigMap"] = "true"
+	// pick the interface of a multi-nic machinepool which network has a matching fiprange
+	cluster = selectClusterNetwork(cluster, fipRangeList, overrides.FipRange)
 
-		fip.ObjectMeta.Annotations = annotations
+	var fipRangeName, reason string
+	if fipReservation.ObjectMeta.Name != "" {
+		fipRangeName = fipReservation.Spec.FipRange
+		reason = reservationReason
+	} else if overrides.FipRange != "" {
+		if fipRangeExists(fipRangeList, overrides.FipRange) {
+			fipRangeName = overrides.FipRange
+			reason = "cluster annotation " + AnnotationFipRange
+		} else {
+			log.Errorf("(createFipForCluster) requested fiprange [%s] for cluster [%s] cannot be found", overrides.FipRange, cluster.ClusterName)
 
-		fipCreateObj, err := kubefip_clientset.KubefipV1().FloatingIPs(ns.Name).Create(context.TODO(), &fip, metav1.CreateOptions{})
-		if err != nil {
-			log.Errorf("(checkNewNamespace) error creating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
 			return
 		}
-
-		log.Infof("(checkNewNamespace) successfully created new fip object [%s/%s] for cluster [%s]",
-			fipCreateObj.ObjectMeta.Namespace, fipCreateObj.ObjectMeta.Name, cluster.ClusterName)
 	} else {
-		log.Debugf("(checkNewNamespace) new namespace [%s] is not a guest cluster", ns.Name)
+		fipRangeName, reason = getFipRangeForCluster(cluster, fipRangeList, kubefipConfig)
+	}
+
+	if fipRangeName == "" {
+		log.Errorf("(createFipForCluster) no fiprange match found for clustername [%s]", cluster.ClusterName)
+
+		return
+	}
+
+	log.Infof("(createFipForCluster) selected fiprange [%s] for cluster [%s]: %s", fipRangeName, cluster.ClusterName, reason)
+
+	log.Debugf("(createFipForCluster) clusterName [%s] / fipRangeName [%s]", cluster.ClusterName, fipRangeName)
+
+	// create a new fip object
+	fip := KubefipV2.FloatingIP{}
+	fip.ObjectMeta.Name = fmt.Sprintf("%s-kubevip", cluster.ClusterName)
+	fip.ObjectMeta.Namespace = cluster.Namespace
+
+	fip.Spec.ClusterName = cluster.ClusterName
+	fip.Spec.FipRange = fipRangeName
+	fip.Spec.UpdateConfigMap = true
+
+	annotations := make(map[string]string)
+	annotations["clustersource"] = cluster.Source
+	annotations["fiprangeReason"] = reason
+	if cluster.HarvesterNetworkName != "" {
+		annotations["harvesterNetworkName"] = cluster.HarvesterNetworkName
+	}
+	// the vipInterface is only set when it's detected in the guest os, until then the vip_interface of the
+	// kubevipChartValues applies
+	if len(cluster.HarvesterNetworkNames) > 1 {
+		annotations["harvesterNetworkInterface"] = strconv.Itoa(cluster.HarvesterNetworkInterface)
+	}
+
+	fip.ObjectMeta.Annotations = annotations
+
+	// a static ip address requested by the cluster owner, otherwise the fip event watcher allocates a new one
+	fip.Spec.IPAddress = overrides.IPAddress
+
+	// the fip add event takes the reserved ip address over from the reservation, the fip is registered first so only
+	// this fip is accepted as the owner of the reservation
+	if fipReservation.ObjectMeta.Name != "" {
+		fip.ObjectMeta.Annotations["fipreservation"] = fipReservation.ObjectMeta.Name
+		kubefip.SetPendingFipReservation(fipReservation.ObjectMeta.Name, &fip)
+	}
+
+	fipCreateObj, err := kubefip_clientset.KubefipV2().FloatingIPs(cluster.Namespace).Create(context.TODO(), &fip, metav1.CreateOptions{})
+	if err != nil {
+		log.Errorf("(createFipForCluster) error creating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+		if fipReservation.ObjectMeta.Name != "" {
+			kubefip.RemovePendingFipReservation(fipReservation.ObjectMeta.Name)
+		}
+
+		return
+	}
+
+	log.Infof("(createFipForCluster) successfully created new fip object [%s/%s] for cluster [%s]",
+		fipCreateObj.ObjectMeta.Namespace, fipCreateObj.ObjectMeta.Name, cluster.ClusterName)
+
+	// the reservation is only bound when the fip exists, so a failed create leaves the reservation active
+	if fipReservation.ObjectMeta.Name != "" {
+		if err := bindFipReservation(fipReservation, *fipCreateObj, kubefip_clientset); err != nil {
+			log.Errorf("(createFipForCluster) error binding fipreservation [%s] to fip [%s/%s]: %s", fipReservation.ObjectMeta.Name,
+				fipCreateObj.ObjectMeta.Namespace, fipCreateObj.ObjectMeta.Name, err.Error())
+		}
 	}
 }
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHarvesterNetworkInterfaces(t *testing.T) {
+	harvesterConfigs := HarvesterConfigsStruct{Items: []HarvesterConfigStruct{
+		{
+			Metadata:    MetadataStruct{Name: "pool-networkname"},
+			NetworkName: "default/vlan10",
+			VmNamespace: "ns1",
+		},
+		{
+			Metadata: MetadataStruct{Name: "pool-networkinfo"},
+			NetworkInfo: `{"interfaces":[{"networkName":"default/vlan10","macAddress":"aa:bb:cc:dd:ee:01"},` +
+				`{"networkName":"","macAddress":"aa:bb:cc:dd:ee:02"},{"networkName":"default/vlan20"}]}`,
+			VmNamespace: "ns2",
+		},
+		{
+			Metadata:    MetadataStruct{Name: "pool-invalid-networkname"},
+			NetworkName: "vlan10",
+			VmNamespace: "ns3",
+		},
+		{
+			Metadata:    MetadataStruct{Name: "pool-invalid-networkinfo"},
+			NetworkInfo: "not json",
+			VmNamespace: "ns4",
+		},
+	}}
+
+	tests := []struct {
+		name                 string
+		machineConfigRefName string
+		wantInterfaces       []HarvesterNetworkInfoInterfacesStruct
+		wantNamespace        string
+	}{
+		{
+			name:                 "networkName",
+			machineConfigRefName: "pool-networkname",
+			wantInterfaces:       []HarvesterNetworkInfoInterfacesStruct{{NetworkName: "vlan10"}},
+			wantNamespace:        "ns1",
+		},
+		{
+			name:                 "networkInfo keeps the interface order",
+			machineConfigRefName: "pool-networkinfo",
+			wantInterfaces: []HarvesterNetworkInfoInterfacesStruct{
+				{NetworkName: "vlan10", MacAddress: "aa:bb:cc:dd:ee:01"},
+				{NetworkName: "", MacAddress: "aa:bb:cc:dd:ee:02"},
+				{NetworkName: "vlan20"},
+			},
+			wantNamespace: "ns2",
+		},
+		{
+			name:                 "networkName without namespace",
+			machineConfigRefName: "pool-invalid-networkname",
+			wantNamespace:        "ns3",
+		},
+		{
+			name:                 "invalid networkInfo",
+			machineConfigRefName: "pool-invalid-networkinfo",
+			wantNamespace:        "ns4",
+		},
+		{
+			name:                 "unknown machinepool",
+			machineConfigRefName: "pool-unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		interfaces, namespace := getHarvesterNetworkInterfaces(tt.machineConfigRefName, &harvesterConfigs)
+		if !reflect.DeepEqual(interfaces, tt.wantInterfaces) {
+			t.Errorf("%s: expected interfaces [%+v], got [%+v]", tt.name, tt.wantInterfaces, interfaces)
+		}
+		if namespace != tt.wantNamespace {
+			t.Errorf("%s: expected vm namespace [%s], got [%s]", tt.name, tt.wantNamespace, namespace)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	ClusterSourceRancher = "rancher"
+	ClusterSourceCapi    = "capi"
+)
+
+// The ClusterSource interface hides where the guest clusters come from (Rancher provisioning, Cluster API, ..).
+// Everything after the cluster lookup (kubeconfig, kube-vip installation, configmap management) is source independent.
+type ClusterSource interface {
+	// Name returns the source name, which is stored in the clustersource annotation of the fip
+	Name() string
+
+	// DiscoverClusters returns all the clusters known by the source
+	DiscoverClusters() ([]Cluster, error)
+
+	// GetCluster resolves the cluster, location and network of a fip, it returns an error wrapping errClusterNotFound if the cluster is gone
+	GetCluster(fip KubefipV2.FloatingIP) (Cluster, error)
+
+	// GetGuestClient returns the cached client of the guest cluster, it's renewed when the kubeconfig secret changes
+	GetGuestClient(cluster Cluster) (*guestClient, error)
+}
+
+var (
+	// GetCluster wraps this error when the cluster of the fip is deleted
+	errClusterNotFound = errors.New("cannot be found in the cluster objects")
+
+	// the cluster sources are replaced by the configmap events while the guest cluster workers use them
+	clusterSources      []ClusterSource
+	clusterSourcesMutex sync.RWMutex
+
+	// keeps track of the clusters which are seen by the syncClusterSources function
+	knownClusters map[string]bool
+)
+
+func initClusterSources(k8s_clientset *kubernetes.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+	var newClusterSources []ClusterSource
+
+	for _, sourceName := range kubefipConfig.ClusterSources {
+		switch sourceName {
+		case ClusterSourceRancher:
+			newClusterSources = append(newClusterSources, newRancherClusterSource(k8s_clientset))
+		case ClusterSourceCapi:
+			newClusterSources = append(newClusterSources, newCapiClusterSource(k8s_clientset))
+		default:
+			log.Errorf("(initClusterSources) unknown cluster source [%s], skipping it", sourceName)
+
+			continue
+		}
+
+		log.Infof("(initClusterSources) cluster source [%s] enabled", sourceName)
+	}
+
+	clusterSourcesMutex.Lock()
+	clusterSources = newClusterSources
+	clusterSourcesMutex.Unlock()
+}
+
+// getClusterSources returns a copy of the enabled cluster sources
+func getClusterSources() []ClusterSource {
+	clusterSourcesMutex.RLock()
+	defer clusterSourcesMutex.RUnlock()
+
+	return append([]ClusterSource(nil), clusterSources...)
+}
+
+func getClusterSourceByName(sourceName string) (ClusterSource, error) {
+	for _, source := range getClusterSources() {
+		if source.Name() == sourceName {
+			return source, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cluster source [%s] is not enabled", sourceName)
+}
+
+// getClusterSource returns the source of the fip, fips created before the cluster sources existed are Rancher fips
+func getClusterSource(fip KubefipV2.FloatingIP) (ClusterSource, error) {
+	sourceName := fip.ObjectMeta.Annotations["clustersource"]
+	if sourceName == "" {
+		sourceName = ClusterSourceRancher
+	}
+
+	return getClusterSourceByName(sourceName)
+}
+
+func getKnownClusterKey(cluster Cluster) string {
+	return strings.Join([]string{cluster.Source, cluster.Namespace, cluster.ClusterName}, "/")
+}
+
+// syncClusterSources creates fips for the clusters which are new since the last sync. The first sync only registers
+// the existing clusters, just like the event watchers which skip all the events during startup.
+func syncClusterSources(kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+	var newClusters []Cluster
+
+	discoveredClusters := make(map[string]bool)
+
+	for _, source := range getClusterSources() {
+		// rancher clusters are detected by the namespace events
+		if source.Name() == ClusterSourceRancher {
+			continue
+		}
+
+		clusters, err := source.DiscoverClusters()
+		if err != nil {
+			// don't touch the known clusters, otherwise all clusters of this source are new in the next sync
+			log.Errorf("(syncClusterSources) error discovering clusters from source [%s]: %s", source.Name(), err.Error())
+
+			return
+		}
+
+		for _, cluster := range clusters {
+			key := getKnownClusterKey(cluster)
+			discoveredClusters[key] = true
+
+			if knownClusters != nil && !knownClusters[key] {
+				log.Infof("(syncClusterSources) new cluster [%s] discovered by source [%s]", key, source.Name())
+
+				newClusters = append(newClusters, cluster)
+			}
+		}
+	}
+
+	if knownClusters == nil {
+		log.Debugf("(syncClusterSources) registered %d existing clusters", len(discoveredClusters))
+	}
+
+	// deleted clusters are forgotten, so they are detected again when a cluster with the same name is created
+	knownClusters = discoveredClusters
+
+	for _, cluster := range newClusters {
+		createFipForCluster(cluster, kubefip_clientset, kubefipConfig)
+	}
+}
@@ -1,48 +1,66 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"sync/atomic"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
 	log "github.com/sirupsen/logrus"
 
 	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	"github.com/joeyloman/kube-fip-operator/pkg/config"
 	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
 	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
-func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+// the rancher provisioning clusters are not in the kubernetes clientset, they are watched with the dynamic client
+var rancherClusterResource = schema.GroupVersionResource{Group: "provisioning.cattle.io", Version: "v1", Resource: "clusters"}
+
+func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset, dynamic_clientset dynamic.Interface,
+	kubefipConfig *config.KubefipConfigStruct) {
 	var watchEventTimeout int = 30 // in seconds (skip all events for the first 30 secs)
 
-	log.Infof("(watchEvents) start watching the floatingipaddress, floatingiprange, namespace and configmap events ..")
+	log.Infof("(watchEvents) start watching the floatingipaddress, floatingiprange, floatingipreservation, namespace, configmap and rancher cluster events ..")
 
 	// toggle watchEventsActivated after 10 secs
-	var watchEventsActivated bool = false
-	time.AfterFunc(time.Duration(watchEventTimeout)*time.Second, func() { watchEventsActivated = true })
+	var watchEventsActivated atomic.Bool
+	time.AfterFunc(time.Duration(watchEventTimeout)*time.Second, func() { watchEventsActivated.Store(true) })
 
 	// do the eventwatch stuff for fips
-	watchlistFips := cache.NewListWatchFromClient(kubefip_clientset.KubefipV1().RESTClient(), "floatingips", corev1.NamespaceAll,
+	watchlistFips := cache.NewListWatchFromClient(kubefip_clientset.KubefipV2().RESTClient(), "floatingips", corev1.NamespaceAll,
 		fields.Everything())
 
 	_, controllerFips := cache.NewInformer(
 		watchlistFips,
-		&KubefipV1.FloatingIP{},
+		&KubefipV2.FloatingIP{},
 		0,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				log.Debugf("(watchFipEvents) entering the eventwatch AddFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					// allocate the new Fip
-					if err := kubefip.AllocateFip(obj.(*KubefipV1.FloatingIP), kubefip_clientset); err != nil {
+					if err := kubefip.AllocateFip(obj.(*KubefipV2.FloatingIP), kubefip_clientset); err != nil {
 						log.Errorf("(watchFipEvents) error allocating fip: %s", err.Error())
+					} else if obj.(*KubefipV2.FloatingIP).Spec.IPAddress != "" {
+						// a fip without an ip address is updated by AllocateFip and enqueued by the UpdateFunc
+						enqueueGuestCluster(obj.(*KubefipV2.FloatingIP), "fip added")
 					}
 				} else {
 					log.Debugf("(watchFipEvents) not activated yet, object action not executed")
@@ -51,21 +69,27 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			DeleteFunc: func(obj interface{}) {
 				log.Debugf("(watchFipEvents) entering the eventwatch DeleteFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					// remove the Fip
-					if err := kubefip.RemoveFip(obj.(*KubefipV1.FloatingIP)); err != nil {
+					if err := kubefip.RemoveFip(obj.(*KubefipV2.FloatingIP)); err != nil {
 						log.Errorf("(watchFipEvents) error removing fip: %s", err.Error())
 					}
 
 					// get the harvester clustername from the FipRange object (because the cluster and related objects are already gone from here)
-					harvesterClusterName, err := getHarvesterClusterNameFromFipRange(obj.(*KubefipV1.FloatingIP), kubefip_clientset)
+					harvesterClusterName, err := getHarvesterClusterNameFromFipRange(obj.(*KubefipV2.FloatingIP), kubefip_clientset)
 					if err != nil {
 						log.Errorf("(watchFipEvents) error cannot get harvester clustername for fip: [%s]: %s",
-							obj.(*KubefipV1.FloatingIP).ObjectMeta.Name, err.Error())
+							obj.(*KubefipV2.FloatingIP).ObjectMeta.Name, err.Error())
 					}
 
 					// add the cluster name and harvester cluster name to the metrics cleanup queue
-					metrics.AddClusterToMetricsCleanupQueue(obj.(*KubefipV1.FloatingIP).ObjectMeta.Annotations["clustername"], harvesterClusterName)
+					metrics.AddClusterToMetricsCleanupQueue(obj.(*KubefipV2.FloatingIP).Spec.ClusterName, harvesterClusterName)
+
+					// remove the last guest cycle result of the cluster from the ipam inspection api
+					status.RemoveGuestCycleResult(obj.(*KubefipV2.FloatingIP).ObjectMeta.Namespace, obj.(*KubefipV2.FloatingIP).Spec.ClusterName)
+
+					// remove the cached client of the guest cluster
+					removeGuestClientOfFip(obj.(*KubefipV2.FloatingIP))
 				} else {
 					log.Debugf("(watchFipEvents) not activated yet, object action not executed")
 				}
@@ -73,11 +97,19 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				log.Debugf("(watchFipEvents) entering the eventwatch UpdateFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
+					oldFip := oldObj.(*KubefipV2.FloatingIP)
+					newFip := newObj.(*KubefipV2.FloatingIP)
+
 					// update the Fip
-					if err := kubefip.UpdateFip(oldObj.(*KubefipV1.FloatingIP), newObj.(*KubefipV1.FloatingIP), kubefip_clientset); err != nil {
+					if err := kubefip.UpdateFip(oldFip, newFip, kubefip_clientset); err != nil {
 						log.Errorf("(watchFipEvents) error removing fip: %s", err.Error())
 					}
+
+					// push the allocated or changed ip address to the guest cluster right away
+					if newFip.Spec.IPAddress != "" && (oldFip.Spec.IPAddress != newFip.Spec.IPAddress || oldFip.Spec.FipRange != newFip.Spec.FipRange) {
+						enqueueGuestCluster(newFip, fmt.Sprintf("ip address changed from [%s] to [%s]", oldFip.Spec.IPAddress, newFip.Spec.IPAddress))
+					}
 				} else {
 					log.Debugf("(watchFipEvents) not activated yet, object action not executed")
 				}
@@ -86,20 +118,20 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 	)
 
 	// do the eventwatch stuff for fipranges
-	watchlistFipRanges := cache.NewListWatchFromClient(kubefip_clientset.KubefipV1().RESTClient(), "floatingipranges", corev1.NamespaceAll,
+	watchlistFipRanges := cache.NewListWatchFromClient(kubefip_clientset.KubefipV2().RESTClient(), "floatingipranges", corev1.NamespaceAll,
 		fields.Everything())
 
 	_, controllerFipRanges := cache.NewInformer(
 		watchlistFipRanges,
-		&KubefipV1.FloatingIPRange{},
+		&KubefipV2.FloatingIPRange{},
 		0,
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				log.Debugf("(watchFipRangeEvents) entering the eventwatch AddFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					// allocate the new FipRange
-					if err := kubefip.AllocateFipRange(obj.(*KubefipV1.FloatingIPRange)); err != nil {
+					if err := kubefip.AllocateFipRange(obj.(*KubefipV2.FloatingIPRange)); err != nil {
 						log.Errorf("(watchFipRangeEvents) error allocating fiprange: %s", err.Error())
 					}
 				} else {
@@ -109,9 +141,9 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			DeleteFunc: func(obj interface{}) {
 				log.Debugf("(watchFipRangeEvents) entering the eventwatch DeleteFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					// remove the Fip
-					if err := kubefip.RemoveFipRange(obj.(*KubefipV1.FloatingIPRange)); err != nil {
+					if err := kubefip.RemoveFipRange(obj.(*KubefipV2.FloatingIPRange)); err != nil {
 						log.Errorf("(watchFipRangeEvents) error removing fiprange: %s", err.Error())
 					}
 				} else {
@@ -123,9 +155,9 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 
 				// log.Debugf("(watchFipRangeEvents) entering the eventwatch UpdateFunc ..")
 
-				// if watchEventsActivated {
+				// if watchEventsActivated.Load() {
 				// 	// update the Fip
-				// 	if err := kubefip.UpdateFipRange(oldObj.(*KubefipV1.FloatingIPRange), newObj.(*KubefipV1.FloatingIPRange)); err != nil {
+				// 	if err := kubefip.UpdateFipRange(oldObj.(*KubefipV2.FloatingIPRange), newObj.(*KubefipV2.FloatingIPRange)); err != nil {
 				// 		log.Errorf("(watchFipRangeEvents) error removing fiprange: %s", err.Error())
 				// 	}
 				// } else {
@@ -135,6 +167,95 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 		},
 	)
 
+	// do the eventwatch stuff for fipreservations
+	watchlistFipReservations := cache.NewListWatchFromClient(kubefip_clientset.KubefipV1().RESTClient(), "floatingipreservations", corev1.NamespaceAll,
+		fields.Everything())
+
+	_, controllerFipReservations := cache.NewInformer(
+		watchlistFipReservations,
+		&KubefipV1.FloatingIPReservation{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				log.Debugf("(watchFipReservationEvents) entering the eventwatch AddFunc ..")
+
+				if watchEventsActivated.Load() {
+					// hold the reserved ip address
+					if err := kubefip.AllocateFipReservation(obj.(*KubefipV1.FloatingIPReservation)); err != nil {
+						log.Errorf("(watchFipReservationEvents) error allocating fipreservation: %s", err.Error())
+					}
+				} else {
+					log.Debugf("(watchFipReservationEvents) not activated yet, object action not executed")
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				log.Debugf("(watchFipReservationEvents) entering the eventwatch DeleteFunc ..")
+
+				if watchEventsActivated.Load() {
+					// release the reserved ip address
+					if err := kubefip.RemoveFipReservation(obj.(*KubefipV1.FloatingIPReservation)); err != nil {
+						log.Errorf("(watchFipReservationEvents) error removing fipreservation: %s", err.Error())
+					}
+				} else {
+					log.Debugf("(watchFipReservationEvents) not activated yet, object action not executed")
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				log.Debugf("(watchFipReservationEvents) entering the eventwatch UpdateFunc ..")
+
+				if watchEventsActivated.Load() {
+					if err := kubefip.UpdateFipReservation(oldObj.(*KubefipV1.FloatingIPReservation), newObj.(*KubefipV1.FloatingIPReservation)); err != nil {
+						log.Errorf("(watchFipReservationEvents) error updating fipreservation: %s", err.Error())
+					}
+				} else {
+					log.Debugf("(watchFipReservationEvents) not activated yet, object action not executed")
+				}
+			},
+		},
+	)
+
+	// do the eventwatch stuff for kubevipprofiles, the profiles are stored by name so the events which are already
+	// gathered at startup are applied again without effect
+	watchlistKubeVipProfiles := cache.NewListWatchFromClient(kubefip_clientset.KubefipV1().RESTClient(), "kubevipprofiles", corev1.NamespaceAll,
+		fields.Everything())
+
+	_, controllerKubeVipProfiles := cache.NewInformer(
+		watchlistKubeVipProfiles,
+		&KubefipV1.KubeVipProfile{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				log.Debugf("(watchKubeVipProfileEvents) entering the eventwatch AddFunc ..")
+
+				storeKubeVipProfile(obj.(*KubefipV1.KubeVipProfile))
+			},
+			DeleteFunc: func(obj interface{}) {
+				log.Debugf("(watchKubeVipProfileEvents) entering the eventwatch DeleteFunc ..")
+
+				profile, ok := obj.(*KubefipV1.KubeVipProfile)
+				if !ok {
+					tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+					if !ok {
+						return
+					}
+
+					if profile, ok = tombstone.Obj.(*KubefipV1.KubeVipProfile); !ok {
+						return
+					}
+				}
+
+				log.Infof("(watchKubeVipProfileEvents) kubevipprofile [%s] removed", profile.ObjectMeta.Name)
+
+				removeKubeVipProfile(profile)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				log.Debugf("(watchKubeVipProfileEvents) entering the eventwatch UpdateFunc ..")
+
+				storeKubeVipProfile(newObj.(*KubefipV1.KubeVipProfile))
+			},
+		},
+	)
+
 	// do the eventwatch stuff for namespaces so we can detect new clusters
 	watchlistNamespaces := cache.NewListWatchFromClient(k8s_clientset.CoreV1().RESTClient(), "namespaces", corev1.NamespaceAll,
 		fields.Everything())
@@ -147,9 +268,9 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			AddFunc: func(obj interface{}) {
 				log.Debugf("(watchNamespaceEvents) Entering the eventwatch AddFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					// check if the new namespace is a cluster
-					checkNewNamespace(obj.(*corev1.Namespace), kubefip_clientset, k8s_clientset)
+					checkNewNamespace(obj.(*corev1.Namespace), k8s_clientset)
 				} else {
 					log.Debugf("(watchNamespaceEvents) not activated yet, object action not executed")
 				}
@@ -168,7 +289,7 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			AddFunc: func(obj interface{}) {
 				log.Debugf("(watchConfigmapEvents) entering the eventwatch AddFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					if obj.(*corev1.ConfigMap).ObjectMeta.Name == "kube-fip-config" {
 						log.Debugf("(watchConfigmapEvents) new kube-fip-config configmap found")
 
@@ -176,13 +297,16 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 						oldOperateGuestClusterInterval := kubefipConfig.OperateGuestClusterInterval
 
 						// parse the new configmap
-						*kubefipConfig = config.ParseKubfipConfigMap(obj.(*corev1.ConfigMap))
+						setKubefipConfig(kubefipConfig, config.ParseKubfipConfigMap(obj.(*corev1.ConfigMap)))
 
 						// update the loglevel
 						updateLoglevel(kubefipConfig)
 
+						// (re)initialize the cluster sources
+						initClusterSources(k8s_clientset, kubefipConfig)
+
 						// restart the operateTicker when the interval has changed
-						restartManageKubevip(k8s_clientset, kubefipConfig, oldOperateGuestClusterInterval)
+						restartManageKubevip(kubefip_clientset, kubefipConfig, oldOperateGuestClusterInterval)
 					}
 				} else {
 					log.Debugf("(watchConfigmapEvents) not activated yet, object action not executed")
@@ -191,7 +315,7 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			DeleteFunc: func(obj interface{}) {
 				log.Debugf("(watchConfigmapEvents) entering the eventwatch DeleteFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					if obj.(*corev1.ConfigMap).ObjectMeta.Name == "kube-fip-config" {
 						log.Debugf("(watchConfigmapEvents) kube-fip-config configmap deleted")
 
@@ -199,13 +323,16 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 						oldOperateGuestClusterInterval := kubefipConfig.OperateGuestClusterInterval
 
 						// parse the new configmap
-						*kubefipConfig = config.ParseKubfipConfigMap(nil)
+						setKubefipConfig(kubefipConfig, config.ParseKubfipConfigMap(nil))
 
 						// update the loglevel
 						updateLoglevel(kubefipConfig)
 
+						// (re)initialize the cluster sources
+						initClusterSources(k8s_clientset, kubefipConfig)
+
 						// restart the operateTicker when the interval has changed
-						restartManageKubevip(k8s_clientset, kubefipConfig, oldOperateGuestClusterInterval)
+						restartManageKubevip(kubefip_clientset, kubefipConfig, oldOperateGuestClusterInterval)
 					}
 				} else {
 					log.Debugf("(watchConfigmapEvents) not activated yet, object action not executed")
@@ -214,7 +341,7 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				log.Debugf("(watchConfigmapEvents) entering the eventwatch UpdateFunc ..")
 
-				if watchEventsActivated {
+				if watchEventsActivated.Load() {
 					if newObj.(*corev1.ConfigMap).ObjectMeta.Name == "kube-fip-config" {
 						log.Debugf("(watchConfigmapEvents) kube-fip-config configmap updated")
 
@@ -222,13 +349,16 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 						oldOperateGuestClusterInterval := kubefipConfig.OperateGuestClusterInterval
 
 						// parse the new configmap
-						*kubefipConfig = config.ParseKubfipConfigMap(newObj.(*corev1.ConfigMap))
+						setKubefipConfig(kubefipConfig, config.ParseKubfipConfigMap(newObj.(*corev1.ConfigMap)))
 
 						// update the loglevel
 						updateLoglevel(kubefipConfig)
 
+						// (re)initialize the cluster sources
+						initClusterSources(k8s_clientset, kubefipConfig)
+
 						// restart the operateTicker when the interval has changed
-						restartManageKubevip(k8s_clientset, kubefipConfig, oldOperateGuestClusterInterval)
+						restartManageKubevip(kubefip_clientset, kubefipConfig, oldOperateGuestClusterInterval)
 					}
 				} else {
 					log.Debugf("(watchConfigmapEvents) not activated yet, object action not executed")
@@ -239,8 +369,58 @@ func watchEvents(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *k
 
 	stop := make(chan struct{})
 	defer close(stop)
+
+	// do the eventwatch stuff for rancher clusters so a changed kube-vip label is applied right away
+	if _, err := getClusterSourceByName(ClusterSourceRancher); err == nil {
+		watchlistRancherClusters := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return dynamic_clientset.Resource(rancherClusterResource).Namespace("fleet-default").List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return dynamic_clientset.Resource(rancherClusterResource).Namespace("fleet-default").Watch(context.TODO(), options)
+			},
+		}
+
+		_, controllerRancherClusters := cache.NewInformer(
+			watchlistRancherClusters,
+			&unstructured.Unstructured{},
+			0,
+			cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(oldObj, newObj interface{}) {
+					if !watchEventsActivated.Load() || getKubefipConfig(kubefipConfig).KubevipGuestInstall != "clusterlabel" {
+						return
+					}
+
+					oldLabel := oldObj.(*unstructured.Unstructured).GetLabels()["kube-vip"]
+					newLabel := newObj.(*unstructured.Unstructured).GetLabels()["kube-vip"]
+					if oldLabel == newLabel {
+						return
+					}
+
+					// the fips of a rancher cluster are in the cluster namespace, which is the status clustername
+					nsName, _, _ := unstructured.NestedString(newObj.(*unstructured.Unstructured).Object, "status", "clusterName")
+					if nsName == "" {
+						return
+					}
+
+					log.Debugf("(watchRancherClusterEvents) kube-vip label of cluster [%s] changed from [%s] to [%s]",
+						newObj.(*unstructured.Unstructured).GetName(), oldLabel, newLabel)
+
+					enqueueGuestClustersInNamespace(nsName, fmt.Sprintf("kube-vip label changed from [%s] to [%s]", oldLabel, newLabel))
+				},
+			},
+		)
+
+		go controllerRancherClusters.Run(stop)
+
+		// evict the cached guest clients when their kubeconfig secret changes
+		go watchKubeconfigSecrets(k8s_clientset, "fleet-default", stop)
+	}
+
 	go controllerFips.Run(stop)
 	go controllerFipRanges.Run(stop)
+	go controllerFipReservations.Run(stop)
+	go controllerKubeVipProfiles.Run(stop)
 	go controllerNamespaces.Run(stop)
 	go controllerConfigmaps.Run(stop)
 
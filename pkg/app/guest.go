@@ -2,28 +2,34 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/apps/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
 
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	"github.com/joeyloman/kube-fip-operator/pkg/config"
 	"github.com/joeyloman/kube-fip-operator/pkg/configmap"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
 	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
 
 	helmclient "github.com/mittwald/go-helm-client"
 	"github.com/mittwald/go-helm-client/values"
 	"helm.sh/helm/v3/pkg/repo"
 
 	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
 var (
@@ -32,11 +38,21 @@ var (
 
 	updateMetrics     bool = true
 	dontUpdateMetrics bool = false
+
+	// the fips of the clusters which are operated right now, by a worker of the cycle, the reconciler or a timed out
+	// worker
+	guestClustersInProgress      = make(map[string]bool)
+	guestClustersInProgressMutex sync.Mutex
+
+	// the workers share the helm repository cache
+	helmRepoMutex sync.Mutex
+
+	errGuestClusterInProgress = errors.New("the operations of the cluster are still running")
 )
 
 // The patchHarvesterKubeVipDaemonset and checkForHarvesterKubeVipDaemonset functions disables the Harvester cloud provider
 // based kube-vip deployment because this interferes with our deployment.
-func patchHarvesterKubeVipDaemonset(clientset *kubernetes.Clientset, harvesterKubeVipDaemonSet *v1.DaemonSet, clusterName string, kubevipDsNamespace string, nodeSelectorName string) (err error) {
+func patchHarvesterKubeVipDaemonset(guestClient *guestClient, harvesterKubeVipDaemonSet *v1.DaemonSet, clusterName string, kubevipDsNamespace string, nodeSelectorName string) (err error) {
 	log.Infof("(patchHarvesterKubeVipDaemonset) patching Harvester DaemonSet for kube-vip in cluster [%s]", clusterName)
 
 	newNodeSelector := make(map[string]string)
@@ -45,7 +61,15 @@ func patchHarvesterKubeVipDaemonset(clientset *kubernetes.Clientset, harvesterKu
 	newharvesterKubeVipDaemonSet := harvesterKubeVipDaemonSet.DeepCopy()
 	newharvesterKubeVipDaemonSet.Spec.Template.Spec.NodeSelector = newNodeSelector
 
-	if _, err := clientset.AppsV1().DaemonSets(kubevipDsNamespace).Update(context.TODO(), newharvesterKubeVipDaemonSet, metav1.UpdateOptions{}); err != nil {
+	// record the nodeSelector from before the patch, so it can be restored when the cluster opts out of kube-vip
+	if originalNodeSelector, err := json.Marshal(harvesterKubeVipDaemonSet.Spec.Template.Spec.NodeSelector); err == nil {
+		if newharvesterKubeVipDaemonSet.ObjectMeta.Annotations == nil {
+			newharvesterKubeVipDaemonSet.ObjectMeta.Annotations = make(map[string]string)
+		}
+		newharvesterKubeVipDaemonSet.ObjectMeta.Annotations[AnnotationOriginalNodeSelector] = string(originalNodeSelector)
+	}
+
+	if _, err := guestClient.clientset.AppsV1().DaemonSets(kubevipDsNamespace).Update(guestClient.ctx, newharvesterKubeVipDaemonSet, metav1.UpdateOptions{}); err != nil {
 		return fmt.Errorf("(patchHarvesterKubeVipDaemonset) error while updating kube-vip DaemonSet in cluster [%s]: %s",
 			clusterName, err.Error())
 	}
@@ -55,38 +79,26 @@ func patchHarvesterKubeVipDaemonset(clientset *kubernetes.Clientset, harvesterKu
 	return
 }
 
-func checkForHarvesterKubeVipDaemonset(kubeconfig []byte, fip KubefipV1.FloatingIP) {
+func checkForHarvesterKubeVipDaemonset(guestClient *guestClient, fip KubefipV2.FloatingIP) {
 	var kubevipDsMapName string = "kube-vip"
 	var kubevipDsNamespace string = "kube-system"
 	var nodeSelectorName string = "node-role.kubernetes.io/harvester-kube-vip-disabled"
 
 	log.Debugf("(checkForHarvesterKubeVipDaemonset) start connection to guest cluster [%s]",
-		fip.ObjectMeta.Annotations["clustername"])
-
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
-	if err != nil {
-		log.Errorf("(checkForHarvesterKubeVipDaemonset) error while getting restconfig from kubeconfig: %s", err.Error())
-
-		return
-	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Errorf("(checkForHarvesterKubeVipDaemonset) error while creating K8S clientset: %s", err.Error())
+		fip.Spec.ClusterName)
 
-		return
-	}
+	clientset := guestClient.clientset
 
-	harvesterKubeVipDaemonSet, err := clientset.AppsV1().DaemonSets(kubevipDsNamespace).Get(context.TODO(), kubevipDsMapName, metav1.GetOptions{})
+	harvesterKubeVipDaemonSet, err := clientset.AppsV1().DaemonSets(kubevipDsNamespace).Get(guestClient.ctx, kubevipDsMapName, metav1.GetOptions{})
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			log.Debugf("(checkForHarvesterKubeVipDaemonset) DaemonSet [%s/%s] not found in guest cluster [%s], skipping update..",
-				kubevipDsNamespace, kubevipDsMapName, fip.ObjectMeta.Annotations["clustername"])
+				kubevipDsNamespace, kubevipDsMapName, fip.Spec.ClusterName)
 
 			return
 		} else {
 			log.Errorf("(checkForHarvesterKubeVipDaemonset) error while getting daemonset [%s/%s] not found in guest cluster [%s]: %s",
-				kubevipDsNamespace, kubevipDsMapName, fip.ObjectMeta.Annotations["clustername"], err.Error())
+				kubevipDsNamespace, kubevipDsMapName, fip.Spec.ClusterName, err.Error())
 
 			return
 		}
@@ -94,22 +106,22 @@ func checkForHarvesterKubeVipDaemonset(kubeconfig []byte, fip KubefipV1.Floating
 
 	if harvesterKubeVipDaemonSet.ObjectMeta.Annotations["meta.helm.sh/release-name"] == "harvester-cloud-provider" {
 		log.Debugf("(checkForHarvesterKubeVipDaemonset) harvester-cloud-provider found in meta.helm.sh/release-name in cluster [%s]",
-			fip.ObjectMeta.Annotations["clustername"])
+			fip.Spec.ClusterName)
 
 		for k, v := range harvesterKubeVipDaemonSet.Spec.Template.Spec.NodeSelector {
 			log.Debugf("(checkForHarvesterKubeVipDaemonset) DaemonSet NodeSelector found in cluster [%s]: k=%s / v=%s",
-				fip.ObjectMeta.Annotations["clustername"], k, v)
+				fip.Spec.ClusterName, k, v)
 
 			if k == nodeSelectorName {
 				log.Debugf("(checkForHarvesterKubeVipDaemonset) DaemonSet NodeSelector [%s] already found in guest cluster [%s]",
-					nodeSelectorName, fip.ObjectMeta.Annotations["clustername"])
+					nodeSelectorName, fip.Spec.ClusterName)
 
 				return
 			}
 		}
 
 		// nodeSelector not found, patch the Harvester DaemonSet
-		if err := patchHarvesterKubeVipDaemonset(clientset, harvesterKubeVipDaemonSet, fip.ObjectMeta.Annotations["clustername"], kubevipDsNamespace, nodeSelectorName); err != nil {
+		if err := patchHarvesterKubeVipDaemonset(guestClient, harvesterKubeVipDaemonSet, fip.Spec.ClusterName, kubevipDsNamespace, nodeSelectorName); err != nil {
 			log.Errorf("%s", err.Error())
 		}
 
@@ -118,17 +130,12 @@ func checkForHarvesterKubeVipDaemonset(kubeconfig []byte, fip KubefipV1.Floating
 
 	// Harvester DaemonSet not found
 	log.Debugf("(checkForHarvesterKubeVipDaemonset) No Harvester DaemonSet found in guest cluster [%s]",
-		fip.ObjectMeta.Annotations["clustername"])
+		fip.Spec.ClusterName)
 }
 
-func installKubevipCloudproviderInGuestCluster(kubeconfig []byte, kubefipConfig *config.KubefipConfigStruct, fip KubefipV1.FloatingIP) (bool, error) {
-	var err error
+func installKubevipCloudproviderInGuestCluster(guestClient *guestClient, kubefipConfig *config.KubefipConfigStruct, fip KubefipV2.FloatingIP, charts kubevipCharts, allowUpgrade bool) (bool, *status.HelmReleaseDrift, error) {
 	var chartName string
-
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
-	if err != nil {
-		return updateMetrics, err
-	}
+	var chartRepo *repo.Entry
 
 	opt := &helmclient.RestConfClientOptions{
 		Options: &helmclient.Options{
@@ -138,48 +145,28 @@ func installKubevipCloudproviderInGuestCluster(kubeconfig []byte, kubefipConfig
 			Debug:            true,
 			Linting:          true,
 		},
-		RestConfig: config,
+		RestConfig: rest.CopyConfig(guestClient.restConfig),
 	}
 
 	helmClient, err := helmclient.NewClientFromRestConf(opt)
 	if err != nil {
-		return updateMetrics, err
-	}
-
-	kubevipCloudproviderReleaseCheck, err := helmClient.GetRelease(kubefipConfig.KubevipCloudProviderReleaseName)
-	if err != nil {
-		if err.Error() == "release: not found" {
-			// now we can install it
-			log.Infof("(installKubevipCloudproviderInGuestCluster) %s release not found in guest cluster [%s], trying to install it",
-				kubefipConfig.KubevipCloudProviderReleaseName, fip.ObjectMeta.Annotations["clustername"])
-		} else {
-			return updateMetrics, err
-		}
+		return updateMetrics, nil, err
 	}
 
-	if kubevipCloudproviderReleaseCheck != nil {
-		log.Debugf("(installKubevipCloudproviderInGuestCluster) helm chart already found in guest cluster [%s]: %s",
-			fip.ObjectMeta.Annotations["clustername"], kubevipCloudproviderReleaseCheck.Name)
-
-		// if not in update mode, don't update kube-vip
-		if !kubefipConfig.KubevipUpdate {
-			return dontUpdateMetrics, err
-		}
-	}
-
-	if kubefipConfig.KubevipCloudProviderChartRef == "" {
-		chartRepo := repo.Entry{
+	if charts.KubevipCloudProviderChartRef == "" {
+		chartRepo = &repo.Entry{
 			Name: "kube-vip",
 			URL:  kubefipConfig.KubevipChartRepoUrl,
 		}
 
-		if err := helmClient.AddOrUpdateChartRepo(chartRepo); err != nil {
-			return updateMetrics, err
-		}
-
 		chartName = "kube-vip/kube-vip-cloud-provider"
 	} else {
-		chartName = kubefipConfig.KubevipCloudProviderChartRef
+		chartName = charts.KubevipCloudProviderChartRef
+	}
+
+	valuesYaml, err := renderChartValues(kubefipConfig.KubevipCloudProviderReleaseName, charts.KubevipCloudProviderChartValues, charts.ValuesData)
+	if err != nil {
+		return updateMetrics, nil, err
 	}
 
 	vOpts := values.Options{}
@@ -188,35 +175,34 @@ func installKubevipCloudproviderInGuestCluster(kubeconfig []byte, kubefipConfig
 	chartSpecKubevipCloudprovider := helmclient.ChartSpec{
 		ReleaseName:     kubefipConfig.KubevipCloudProviderReleaseName,
 		ChartName:       chartName,
-		Version:         kubefipConfig.KubevipCloudProviderChartVersion,
+		Version:         charts.KubevipCloudProviderChartVersion,
 		Namespace:       kubefipConfig.KubevipNamespace,
-		ValuesYaml:      kubefipConfig.KubevipCloudProviderChartValues,
+		ValuesYaml:      valuesYaml,
 		ValuesOptions:   vOpts,
 		CreateNamespace: true,
 		Wait:            false,
 	}
 
-	kubevipCloudproviderRelease, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpecKubevipCloudprovider, nil)
+	metricUpdate, drift, err := reconcileHelmRelease(guestClient.ctx, helmClient, chartRepo, &chartSpecKubevipCloudprovider, fip, allowUpgrade)
 	if err != nil {
-		return updateMetrics, err
+		return metricUpdate, drift, err
 	}
 
-	log.Debugf("(installKubevipCloudproviderInGuestCluster) returned %s helm release manifest: %s",
-		kubefipConfig.KubevipCloudProviderReleaseName, kubevipCloudproviderRelease.Manifest)
-
-	log.Infof("(installKubevipCloudproviderInGuestCluster) kube-vip-cloud-provider helm chart installed successfully in guest cluster [%s]",
-		fip.ObjectMeta.Annotations["clustername"])
+	if metricUpdate {
+		log.Infof("(installKubevipCloudproviderInGuestCluster) kube-vip-cloud-provider helm chart installed successfully in guest cluster [%s]",
+			fip.Spec.ClusterName)
+	}
 
-	return updateMetrics, err
+	return metricUpdate, drift, nil
 }
 
-func installKubevipInGuestCluster(kubeconfig []byte, kubefipConfig *config.KubefipConfigStruct, fip KubefipV1.FloatingIP) (bool, error) {
-	var err error
+func installKubevipInGuestCluster(guestClient *guestClient, kubefipConfig *config.KubefipConfigStruct, fip KubefipV2.FloatingIP, charts kubevipCharts, pinnedChartVersion string, allowUpgrade bool) (bool, *status.HelmReleaseDrift, error) {
 	var chartName string
+	var chartRepo *repo.Entry
 
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
-	if err != nil {
-		return updateMetrics, err
+	chartVersion := charts.KubevipChartVersion
+	if pinnedChartVersion != "" {
+		chartVersion = pinnedChartVersion
 	}
 
 	opt := &helmclient.RestConfClientOptions{
@@ -227,173 +213,159 @@ func installKubevipInGuestCluster(kubeconfig []byte, kubefipConfig *config.Kubef
 			Debug:            true,
 			Linting:          true,
 		},
-		RestConfig: config,
+		RestConfig: rest.CopyConfig(guestClient.restConfig),
 	}
 
 	helmClient, err := helmclient.NewClientFromRestConf(opt)
 	if err != nil {
-		return updateMetrics, err
-	}
-
-	kubevipReleaseCheck, err := helmClient.GetRelease(kubefipConfig.KubevipReleaseName)
-	if err != nil {
-		if err.Error() == "release: not found" {
-			// now we can install it
-			log.Infof("(installKubevipInGuestCluster) %s release not found in guest cluster [%s], trying to install it",
-				kubefipConfig.KubevipReleaseName, fip.ObjectMeta.Annotations["clustername"])
-		} else {
-			return updateMetrics, err
-		}
+		return updateMetrics, nil, err
 	}
 
-	if kubevipReleaseCheck != nil {
-		log.Debugf("(installKubevipInGuestCluster) helm chart already found in guest cluster [%s]: %s",
-			fip.ObjectMeta.Annotations["clustername"], kubevipReleaseCheck.Name)
-
-		// if not in update mode, don't update kube-vip
-		if !kubefipConfig.KubevipUpdate {
-			return dontUpdateMetrics, err
-		}
-	}
-
-	if kubefipConfig.KubevipChartRef == "" {
-		chartRepo := repo.Entry{
+	if charts.KubevipChartRef == "" {
+		chartRepo = &repo.Entry{
 			Name: "kube-vip",
 			URL:  kubefipConfig.KubevipChartRepoUrl,
 		}
 
-		if err := helmClient.AddOrUpdateChartRepo(chartRepo); err != nil {
-			return updateMetrics, err
-		}
-
 		chartName = "kube-vip/kube-vip"
 	} else {
-		chartName = kubefipConfig.KubevipChartRef
+		chartName = charts.KubevipChartRef
+	}
+
+	valuesYaml, err := renderChartValues(kubefipConfig.KubevipReleaseName, charts.KubevipChartValues, charts.ValuesData)
+	if err != nil {
+		return updateMetrics, nil, err
 	}
 
 	vOpts := values.Options{}
 	vOpts.Values = append(vOpts.Values, fmt.Sprintf("nameOverride=%s", kubefipConfig.KubevipReleaseName))
 
+	// the (detected) interface of the network with the fip, this overrides the vip_interface of the kubevipChartValues
+	if fip.ObjectMeta.Annotations["vipInterface"] != "" {
+		vOpts.Values = append(vOpts.Values, fmt.Sprintf("config.vip_interface=%s", fip.ObjectMeta.Annotations["vipInterface"]))
+	}
+
 	chartSpecKubevip := helmclient.ChartSpec{
 		ReleaseName:     kubefipConfig.KubevipReleaseName,
 		ChartName:       chartName,
-		Version:         kubefipConfig.KubevipChartVersion,
+		Version:         chartVersion,
 		Namespace:       kubefipConfig.KubevipNamespace,
-		ValuesYaml:      kubefipConfig.KubevipChartValues,
+		ValuesYaml:      valuesYaml,
 		ValuesOptions:   vOpts,
 		CreateNamespace: true,
 		Wait:            false,
 	}
 
-	kubevipRelease, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpecKubevip, nil)
+	metricUpdate, drift, err := reconcileHelmRelease(guestClient.ctx, helmClient, chartRepo, &chartSpecKubevip, fip, allowUpgrade)
 	if err != nil {
-		return updateMetrics, err
+		return metricUpdate, drift, err
 	}
 
-	log.Debugf("(installKubevipInGuestCluster) returned %s helm release manifest: %s",
-		kubefipConfig.KubevipReleaseName, kubevipRelease.Manifest)
+	if metricUpdate {
+		log.Infof("(installKubevipInGuestCluster) kube-vip helm chart installed successfully in guest cluster [%s]",
+			fip.Spec.ClusterName)
+	}
 
-	log.Infof("(installKubevipInGuestCluster) kube-vip helm chart installed successfully in guest cluster [%s]",
-		fip.ObjectMeta.Annotations["clustername"])
+	return metricUpdate, drift, nil
+}
 
-	return updateMetrics, err
+// isRetryableApplyError returns true for the errors of a configmap operation which are worth a retry, a conflict is
+// retried with the current configmap
+func isRetryableApplyError(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err)
 }
 
-func createOrUpdateKubevipConfigmapInGuestCluster(kubeconfig []byte, kubefipConfig *config.KubefipConfigStruct, fip KubefipV1.FloatingIP) (bool, error) {
+// isKubevipConfigmapApplied checks if the cidr-global key of the configmap is the fip and is applied by the operator
+func isKubevipConfigmapApplied(cm *corev1.ConfigMap, fip *KubefipV2.FloatingIP) bool {
+	if cm.Data[configmap.KeyCidrGlobal] != configmap.KubevipCidr(fip) {
+		return false
+	}
+
+	for _, managedFields := range cm.ObjectMeta.ManagedFields {
+		if managedFields.Manager == configmap.FieldManager && managedFields.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createOrUpdateKubevipConfigmapInGuestCluster manages the cidr-global key of the kubevip configmap with a server-side
+// apply. The kube-fip field manager only owns this key, so the cidr-<namespace> and range-<namespace> keys of the guest
+// admins are preserved. An existing configmap is only changed when updateConfigMap is set on the fip, a configmap which
+// is created by someone else in the meantime is a conflict which is retried with the existing configmap.
+func createOrUpdateKubevipConfigmapInGuestCluster(guestClient *guestClient, kubefipConfig *config.KubefipConfigStruct, fip KubefipV2.FloatingIP) (bool, error) {
 	var kubevipConfigMapName string = "kubevip"
 	var kubevipConfigMapNamespace string = "kube-system"
-	var configMapExists bool = false
-	var err error
+	var metricUpdate bool
 
 	log.Debugf("(createKubevipConfigmapInGuestCluster) start connection to guest cluster")
 
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
-	if err != nil {
-		return updateMetrics, err
-	}
+	clientset := guestClient.clientset
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return updateMetrics, err
-	}
+	err := retry.OnError(retry.DefaultBackoff, isRetryableApplyError, func() error {
+		metricUpdate = dontUpdateMetrics
 
-	// list the configmaps in kube-system
-	cmList, err := clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		return updateMetrics, err
-	}
+		configMapExists := true
+		cm, err := clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Get(guestClient.ctx, kubevipConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			configMapExists = false
+		}
 
-	// check if the kubevip configmap already exists
-	for _, cm := range cmList.Items {
-		if cm.Name == kubevipConfigMapName {
+		if configMapExists {
 			log.Debugf("(createKubevipConfigmapInGuestCluster) configmap [%s/%s] already exists in guest cluster [%s]",
-				kubevipConfigMapNamespace, kubevipConfigMapName, fip.ObjectMeta.Annotations["clustername"])
+				kubevipConfigMapNamespace, kubevipConfigMapName, fip.Spec.ClusterName)
 
-			configMapExists = true
-			break
+			if !fip.Spec.UpdateConfigMap || isKubevipConfigmapApplied(cm, &fip) {
+				return nil
+			}
+
+			// an unchanged cidr-global is only taken over by the field manager
+			metricUpdate = cm.Data[configmap.KeyCidrGlobal] != configmap.KubevipCidr(&fip)
+		} else {
+			metricUpdate = updateMetrics
 		}
-	}
 
-	if !configMapExists {
-		// generating the new configmap
+		// generating the keys of the configmap which are owned by the operator
 		newConfigMap := configmap.NewKubevipConfigmap(&fip, kubevipConfigMapName, kubevipConfigMapNamespace)
 
-		// creating the new configmap
-		cmCreateObj, err := clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Create(context.TODO(), &newConfigMap, metav1.CreateOptions{})
-		if err != nil {
-			errMsg := fmt.Sprintf("error creating kubevip configmap [%s/%s] in guest cluster [%s]: %s",
-				kubevipConfigMapNamespace, kubevipConfigMapName, fip.ObjectMeta.Annotations["clustername"], err.Error())
-			return updateMetrics, errors.New(errMsg)
-		}
-		log.Tracef("(createKubevipConfigmapInGuestCluster) configmap obj created: [%s]", cmCreateObj)
-
-		log.Infof("(createKubevipConfigmapInGuestCluster) successfully created configmap [%s/%s] in guest cluster [%s]",
-			kubevipConfigMapNamespace, kubevipConfigMapName, fip.ObjectMeta.Annotations["clustername"])
-	} else {
-		forceUpdate, err := strconv.ParseBool(fip.ObjectMeta.Annotations["updateConfigMap"])
+		// the cidr-global of an existing configmap is forced, a new configmap is created without force so a
+		// configmap which is created in the meantime results in a conflict
+		cmApplyObj, err := clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Apply(guestClient.ctx, newConfigMap,
+			metav1.ApplyOptions{FieldManager: configmap.FieldManager, Force: configMapExists})
 		if err != nil {
-			log.Debugf("(createKubevipConfigmapInGuestCluster) forceUpdate annotation error: %s", err)
+			return err
 		}
+		log.Tracef("(createKubevipConfigmapInGuestCluster) configmap obj applied: [%s]", cmApplyObj)
 
-		if forceUpdate {
-			// generating the new configmap
-			newConfigMap := configmap.NewKubevipConfigmap(&fip, kubevipConfigMapName, kubevipConfigMapNamespace)
-
-			// updating the existing configmap
-			cmUpdateObj, err := clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Update(context.TODO(), &newConfigMap, metav1.UpdateOptions{})
-			if err != nil {
-				errMsg := fmt.Sprintf("error updating kubevip configmap [%s/%s] in guest cluster [%s]: %s", kubevipConfigMapNamespace,
-					kubevipConfigMapName, fip.ObjectMeta.Annotations["clustername"], err.Error())
-				return updateMetrics, errors.New(errMsg)
-			}
-			log.Tracef("(createKubevipConfigmapInGuestCluster) configmap obj updated: [%s]", cmUpdateObj)
-
+		if configMapExists {
 			log.Debugf("(createKubevipConfigmapInGuestCluster) successfully updated configmap [%s/%s] in guest cluster [%s]",
-				kubevipConfigMapNamespace, kubevipConfigMapName, fip.ObjectMeta.Annotations["clustername"])
+				kubevipConfigMapNamespace, kubevipConfigMapName, fip.Spec.ClusterName)
 		} else {
-			return dontUpdateMetrics, err
+			log.Infof("(createKubevipConfigmapInGuestCluster) successfully created configmap [%s/%s] in guest cluster [%s]",
+				kubevipConfigMapNamespace, kubevipConfigMapName, fip.Spec.ClusterName)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return updateMetrics, fmt.Errorf("error applying kubevip configmap [%s/%s] in guest cluster [%s]: %s",
+			kubevipConfigMapNamespace, kubevipConfigMapName, fip.Spec.ClusterName, err.Error())
 	}
 
-	return updateMetrics, err
+	return metricUpdate, nil
 }
 
-func testGuestClusterConnection(kubeconfig []byte) error {
+func testGuestClusterConnection(guestClient *guestClient) error {
 	var err error
 
 	log.Debugf("(testGuestClusterConnection) start checking the connection to the guest cluster")
 
-	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
-	if err != nil {
-		return err
-	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-
-	_, err = clientset.CoreV1().Pods("kube-system").List(context.TODO(), metav1.ListOptions{})
+	_, err = guestClient.clientset.CoreV1().Pods("kube-system").List(guestClient.ctx, metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
@@ -401,168 +373,317 @@ func testGuestClusterConnection(kubeconfig []byte) error {
 	return nil
 }
 
-func getGuestClusterKubeconfig(clientset *kubernetes.Clientset, fip KubefipV1.FloatingIP) ([]byte, error) {
-	var err error
-	var kubeconfig []byte
+// operateGuestCluster runs the guest cluster operations for the cluster of a fip, it returns the fip when its cluster
+// is deleted
+func operateGuestCluster(ctx context.Context, fip KubefipV2.FloatingIP, kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) orphanedFip {
+	var kubevipGuestInstallLabel bool
+	var orphaned orphanedFip
 
-	log.Debugf("(getGuestClusterKubeconfig) retrieving guest cluster kubeconfig")
+	log.Debugf("(operateGuestCluster) checking fip name [%s] in clusternamespace [%s]",
+		fip.ObjectMeta.Name, fip.ObjectMeta.Namespace)
 
-	if fip.ObjectMeta.Annotations["clustername"] == "" {
-		errMsg := fmt.Sprintf("(getGuestClusterKubeconfig) clustername annotation not set in fip [%s/%s]",
-			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
-		return kubeconfig, errors.New(errMsg)
+	// the result of this cycle for the ipam inspection api
+	result := status.GuestCycleResult{
+		ClusterName: fip.Spec.ClusterName,
+		Namespace:   fip.ObjectMeta.Namespace,
+		Fip:         fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name),
+		IPAddress:   fip.Spec.IPAddress,
 	}
 
-	kubeconfigSecretName := fmt.Sprintf("%s-kubeconfig", fip.ObjectMeta.Annotations["clustername"])
-	kubeconfigSecretObj, err := clientset.CoreV1().Secrets("fleet-default").Get(context.TODO(), kubeconfigSecretName, metav1.GetOptions{})
+	source, err := getClusterSource(fip)
 	if err != nil {
-		return kubeconfig, err
+		log.Errorf("(operateGuestCluster) skipping fip [%s/%s]: %s", fip.ObjectMeta.Namespace,
+			fip.ObjectMeta.Name, err.Error())
+
+		result.Status = status.ClusterStatusSkipped
+		result.Message = err.Error()
+		status.SetGuestCycleResult(result)
+
+		return orphaned
 	}
 
-	log.Tracef("(getGuestClusterKubeconfig) got secret object: [%+v]", kubeconfigSecretObj)
+	// check if the floatingip object is still a part of the cluster object and get all cluster variables, otherwise skip the rest
+	if cluster, err := source.GetCluster(fip); err != nil {
+		log.Errorf("%s", err.Error())
 
-	kubeconfig = kubeconfigSecretObj.Data["value"]
+		result.Status = status.ClusterStatusNotFound
+		result.Message = err.Error()
 
-	return kubeconfig, err
-}
+		// the fip of a deleted cluster is released after the orphanedFipGracePeriod
+		if errors.Is(err, errClusterNotFound) {
+			removeGuestClientOfFip(&fip)
 
-func operateGuestClusters(clientset *kubernetes.Clientset, kubefipConfig *config.KubefipConfigStruct) {
-	var kubevipGuestInstallLabel bool
+			orphaned = handleOrphanedFip(fip, kubefipConfig, kubefip_clientset)
+		}
+	} else {
+		clearOrphanedFip(fip, kubefip_clientset)
 
-	log.Debugf("(operateGuestClusters) start operating guest clusters")
+		result.HarvesterClusterName = cluster.HarvesterClusterName
 
-	metrics.InOperationMode = true
+		overrides := getClusterOverrides(cluster)
+		if !overrides.Allocate {
+			log.Warnf("(operateGuestCluster) cluster [%s] has annotation [%s] set to false, skipping the existing fip [%s/%s]",
+				cluster.ClusterName, AnnotationAllocate, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
 
-	// create a copy of the AllFips list so we are not run into issues when a fip is removed during cluster operations
-	allFipsCopy := kubefip.AllFips
+			result.Status = status.ClusterStatusSkipped
+			result.Message = fmt.Sprintf("annotation [%s] is set to false", AnnotationAllocate)
+			status.SetGuestCycleResult(result)
 
-	for i := 0; i < len(allFipsCopy); i++ {
-		log.Debugf("(operateGuestClusters) checking fip name [%s] in clusternamespace [%s]",
-			allFipsCopy[i].ObjectMeta.Name, allFipsCopy[i].ObjectMeta.Namespace)
+			return orphaned
+		}
 
-		// check if the floatingip object is still a part of the cluster object, otherwise skip the rest
-		if err := checkClusterStatus(clientset, allFipsCopy[i]); err != nil {
-			log.Errorf("%s", err.Error())
+		// move the fip when the cluster owner requested another fiprange or ipaddress
+		reconcileClusterOverrides(fip, overrides, kubefip_clientset)
+
+		// get the cached guest cluster client and test the connection to the guest cluster
+		guestClient, err := source.GetGuestClient(cluster)
+		if err != nil {
+			log.Errorf("(operateGuestCluster) error in fetching kubeconfig: %s", err.Error())
 		} else {
-			// get the guest cluster kubeconfig
-			kubeconfig, err := getGuestClusterKubeconfig(clientset, allFipsCopy[i])
-			if err != nil {
-				log.Errorf("(operateGuestClusters) error in fetching kubeconfig: %s", err.Error())
-			}
+			// the requests to the guest cluster are cancelled when the guestClusterTimeout expires
+			guestClient = guestClient.withContext(ctx)
 
-			// get all cluster variables
-			cluster, err := getClusterVariables(allFipsCopy[i].ObjectMeta.Namespace, clientset)
-			if err != nil {
-				log.Errorf("(operateGuestClusters) error cannot get cluster object for cluster namespace [%s] to determine clusterlabel: %s",
-					allFipsCopy[i].ObjectMeta.Namespace, err.Error())
-			}
+			err = testGuestClusterConnection(guestClient)
+		}
 
-			// test the connection to the guest cluster
-			if err := testGuestClusterConnection(kubeconfig); err != nil {
-				// if the error contains "Forbidden" the cluster is in deploy state
-				if strings.Contains(err.Error(), "Forbidden") {
-					log.Debugf("(operateGuestClusters) guest cluster [%s] is still in deploy state: %s",
-						allFipsCopy[i].ObjectMeta.Annotations["clustername"], err.Error())
-				} else {
-					log.Warningf("(operateGuestClusters) cannot connect to guest cluster [%s]: %s",
-						allFipsCopy[i].ObjectMeta.Annotations["clustername"], err.Error())
-
-					metrics.SetGuestClusterStatus(allFipsCopy[i].ObjectMeta.Annotations["clustername"], cluster.HarvesterClusterName,
-						metrics.StatusDown)
-
-					metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-						cluster.HarvesterClusterName, metrics.EventApiConnection, metrics.StatusError)
-				}
+		if err != nil {
+			// if the error contains "Forbidden" the cluster is in deploy state
+			if strings.Contains(err.Error(), "Forbidden") {
+				log.Debugf("(operateGuestCluster) guest cluster [%s] is still in deploy state: %s",
+					fip.Spec.ClusterName, err.Error())
+
+				result.Status = status.ClusterStatusDeploying
+				result.Message = err.Error()
 			} else {
-				metrics.SetGuestClusterStatus(allFipsCopy[i].ObjectMeta.Annotations["clustername"], cluster.HarvesterClusterName,
-					metrics.StatusUp)
-
-				metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-					cluster.HarvesterClusterName, metrics.EventApiConnection, metrics.StatusSuccess)
-
-				// patch the Harvester cloud provider Kube-Vip daemonset
-				checkForHarvesterKubeVipDaemonset(kubeconfig, allFipsCopy[i])
-
-				// determine the kube-vip installation type
-				kubevipGuestInstallLabel = false
-				if kubefipConfig.KubevipGuestInstall == "clusterlabel" {
-					// check if the cluster label is set
-					if cluster.Labels["kube-vip"] != "" {
-						kubeVipLabel, err := strconv.ParseBool(cluster.Labels["kube-vip"])
-						if err != nil {
-							log.Errorf("(operateGuestClusters) error parsing kube-vip label: %s", err)
-						} else {
-							kubevipGuestInstallLabel = kubeVipLabel
-						}
-					}
-				}
+				log.Warningf("(operateGuestCluster) cannot connect to guest cluster [%s]: %s",
+					fip.Spec.ClusterName, err.Error())
 
-				log.Debugf("(operateGuestClusters) kubevipGuestInstallLabel: [%+v]", kubevipGuestInstallLabel)
+				metrics.SetGuestClusterStatus(fip.Spec.ClusterName, cluster.HarvesterClusterName,
+					metrics.StatusDown)
 
-				// try to install kube-vip and the kube-vip-cloud-provider
-				if kubefipConfig.KubevipGuestInstall == "enabled" || kubevipGuestInstallLabel {
-					if metricUpdate, err := installKubevipInGuestCluster(kubeconfig, kubefipConfig, allFipsCopy[i]); err != nil {
-						log.Errorf("(operateGuestClusters) error while managing the kube-vip installation in guest cluster [%s]: %s",
-							allFipsCopy[i].ObjectMeta.Annotations["clustername"], err.Error())
+				metrics.IncrementGuestClusterEventsMetric(fip.Spec.ClusterName,
+					cluster.HarvesterClusterName, metrics.EventApiConnection, metrics.StatusError)
 
-						metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-							cluster.HarvesterClusterName, metrics.EventKubevipInstall, metrics.StatusError)
+				result.Status = status.ClusterStatusDown
+				result.AddEvent(metrics.EventApiConnection, metrics.StatusError, err)
+			}
+		} else {
+			metrics.SetGuestClusterStatus(fip.Spec.ClusterName, cluster.HarvesterClusterName,
+				metrics.StatusUp)
+
+			metrics.IncrementGuestClusterEventsMetric(fip.Spec.ClusterName,
+				cluster.HarvesterClusterName, metrics.EventApiConnection, metrics.StatusSuccess)
+
+			result.Status = status.ClusterStatusUp
+			result.AddEvent(metrics.EventApiConnection, metrics.StatusSuccess, nil)
+
+			// determine the kube-vip installation type
+			kubevipGuestInstallLabel = false
+			if kubefipConfig.KubevipGuestInstall == "clusterlabel" {
+				// check if the cluster label is set
+				if cluster.Labels["kube-vip"] != "" {
+					kubeVipLabel, err := strconv.ParseBool(cluster.Labels["kube-vip"])
+					if err != nil {
+						log.Errorf("(operateGuestCluster) error parsing kube-vip label: %s", err)
 					} else {
-						if metricUpdate {
-							metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-								cluster.HarvesterClusterName, metrics.EventKubevipInstall, metrics.StatusSuccess)
-						}
+						kubevipGuestInstallLabel = kubeVipLabel
 					}
+				}
+			}
 
-					if metricUpdate, err := installKubevipCloudproviderInGuestCluster(kubeconfig, kubefipConfig, allFipsCopy[i]); err != nil {
-						log.Errorf("(operateGuestClusters) error while managing the kube-vip-cloud-provider installation in guest cluster [%s]: %s",
-							allFipsCopy[i].ObjectMeta.Annotations["clustername"], err.Error())
+			log.Debugf("(operateGuestCluster) kubevipGuestInstallLabel: [%+v]", kubevipGuestInstallLabel)
 
-						metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-							cluster.HarvesterClusterName, metrics.EventKubevipCloudproviderInstall, metrics.StatusError)
-					} else {
-						if metricUpdate {
-							metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-								cluster.HarvesterClusterName, metrics.EventKubevipCloudproviderInstall, metrics.StatusSuccess)
-						}
-					}
-				}
+			kubevipGuestInstall := kubefipConfig.KubevipGuestInstall == "enabled" || kubevipGuestInstallLabel
+			if overrides.Kubevip != nil {
+				// the cluster annotation wins from the kubevipGuestInstall option and the kube-vip label
+				kubevipGuestInstall = *overrides.Kubevip
+			}
 
-				// try to manage the kubevip configmap in kube-system
-				if metricUpdate, err := createOrUpdateKubevipConfigmapInGuestCluster(kubeconfig, kubefipConfig, allFipsCopy[i]); err != nil {
-					log.Errorf("(operateGuestClusters) error while managing the kube-vip config in guest cluster [%s]: %s",
-						allFipsCopy[i].ObjectMeta.Annotations["clustername"], err.Error())
-
-					metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-						cluster.HarvesterClusterName, metrics.EventConfigmapManagement, metrics.StatusError)
-				} else {
-					if metricUpdate {
-						metrics.IncrementGuestClusterEventsMetric(allFipsCopy[i].ObjectMeta.Annotations["clustername"],
-							cluster.HarvesterClusterName, metrics.EventConfigmapManagement, metrics.StatusSuccess)
-					}
-				}
+			// the lb backend of the cluster, kube-vip unless the lbBackend option or the cluster annotation selects another one
+			backend, err := getLBBackend(overrides, kubefipConfig)
+			if err != nil {
+				log.Errorf("(operateGuestCluster) skipping the lb backend of guest cluster [%s]: %s", fip.Spec.ClusterName, err.Error())
+
+				result.Message = err.Error()
+				status.SetGuestCycleResult(result)
+
+				return orphaned
+			}
+
+			op := &lbOperation{
+				guestClient:       guestClient,
+				kubefipConfig:     kubefipConfig,
+				kubefip_clientset: kubefip_clientset,
+				cluster:           cluster,
+				fip:               fip,
+				overrides:         overrides,
+				result:            &result,
+				install:           kubevipGuestInstall,
 			}
+
+			// the fip with the detected guest interface of the fip network for the helm values and the pools
+			op.installFip = getKubevipFip(source, cluster, fip, guestClient, kubefip_clientset)
+
+			operateLBBackend(op, backend)
 		}
 	}
 
-	log.Debugf("(operateGuestClusters) end operating guest clusters")
+	status.SetGuestCycleResult(result)
+
+	return orphaned
+}
+
+// operateGuestClusterWithTimeout runs the guest cluster operations and stops waiting for them after the
+// guestClusterTimeout. The requests to the guest cluster are cancelled at the timeout, so the remaining operations of a
+// timed out cluster fail fast in the background. The cluster is skipped in the next cycles until they are finished, the
+// errGuestClusterInProgress error is returned when the cluster is already operated.
+func operateGuestClusterWithTimeout(fip KubefipV2.FloatingIP, kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) (orphanedFip, error) {
+	fipKey := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	guestClustersInProgressMutex.Lock()
+	if guestClustersInProgress[fipKey] {
+		guestClustersInProgressMutex.Unlock()
+
+		return orphanedFip{}, errGuestClusterInProgress
+	}
+	guestClustersInProgress[fipKey] = true
+	guestClustersInProgressMutex.Unlock()
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if kubefipConfig.GuestClusterTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(kubefipConfig.GuestClusterTimeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	// the operations can outlive the timeout, so the context is canceled by the goroutine when they are finished
+	done := make(chan orphanedFip, 1)
+	go func() {
+		defer cancel()
+
+		done <- operateGuestCluster(ctx, fip, kubefip_clientset, kubefipConfig)
 
-	if kubefipConfig.TraceIpamData {
-		log.Infof("(IPAM DATA) dumping stored fip and prefix data")
-		for i := 0; i < len(allFipsCopy); i++ {
-			log.Infof("(IPAM DATA) stored fip name [%s/%s] and ipaddress [%s]",
-				allFipsCopy[i].ObjectMeta.Namespace, allFipsCopy[i].ObjectMeta.Name, allFipsCopy[i].Spec.IPAddress)
+		guestClustersInProgressMutex.Lock()
+		delete(guestClustersInProgress, fipKey)
+		guestClustersInProgressMutex.Unlock()
+	}()
+
+	if kubefipConfig.GuestClusterTimeout <= 0 {
+		return <-done, nil
+	}
+
+	select {
+	case o := <-done:
+		return o, nil
+	case <-ctx.Done():
+		// the operations finished at the deadline
+		select {
+		case o := <-done:
+			return o, nil
+		default:
 		}
 
-		for i := 0; i < len(kubefip.AllFipRanges); i++ {
-			kubefip.IPAM.Usage(kubefip.AllFipRanges[i].Name)
+		log.Errorf("(operateGuestClusterWithTimeout) the operations for cluster [%s] of fip [%s] did not finish within %d seconds",
+			fip.Spec.ClusterName, fipKey, kubefipConfig.GuestClusterTimeout)
+
+		var harvesterClusterName string
+		if fipRange, err := kubefip.GetFipRange(fip.Spec.FipRange); err == nil {
+			harvesterClusterName = fipRange.Spec.HarvesterClusterName
 		}
+
+		metrics.IncrementGuestClusterEventsMetric(fip.Spec.ClusterName, harvesterClusterName, metrics.EventOperateTimeout, metrics.StatusError)
+
+		status.SetGuestCycleResult(status.GuestCycleResult{
+			ClusterName:          fip.Spec.ClusterName,
+			Namespace:            fip.ObjectMeta.Namespace,
+			HarvesterClusterName: harvesterClusterName,
+			Fip:                  fipKey,
+			IPAddress:            fip.Spec.IPAddress,
+			Status:               status.ClusterStatusTimeout,
+			Message:              fmt.Sprintf("the operations did not finish within %d seconds", kubefipConfig.GuestClusterTimeout),
+		})
+
+		return orphanedFip{}, nil
+	}
+}
+
+func operateGuestClusters(kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+	var newOrphanedFips []orphanedFip
+
+	log.Debugf("(operateGuestClusters) start operating guest clusters")
+
+	// the whole cycle uses the same config, also when the configmap changes in the meantime
+	kubefipConfig = getKubefipConfig(kubefipConfig)
+
+	metrics.SetInOperationMode(true)
+	cycleStart := time.Now()
+
+	// remove the reservations which are expired before their cluster showed up
+	expireFipReservations(kubefip_clientset)
+
+	// create fips for the clusters of the sources without event watchers
+	syncClusterSources(kubefip_clientset, kubefipConfig)
+
+	// create a copy of the fips list so we are not run into issues when a fip is removed during cluster operations
+	allFipsCopy := kubefip.GetAllFips()
+
+	// the clusters are operated by a pool of workers, so a slow or unreachable cluster doesn't stall the others
+	workers := max(kubefipConfig.GuestClusterWorkers, 1)
+	fipQueue := make(chan KubefipV2.FloatingIP)
+	newOrphanedFipsMutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for fip := range fipQueue {
+				o, err := operateGuestClusterWithTimeout(fip, kubefip_clientset, kubefipConfig)
+				if err != nil {
+					log.Warnf("(operateGuestClusters) skipping cluster [%s] of fip [%s/%s]: %s",
+						fip.Spec.ClusterName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+					continue
+				}
+
+				if o.Name != "" {
+					newOrphanedFipsMutex.Lock()
+					newOrphanedFips = append(newOrphanedFips, o)
+					newOrphanedFipsMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < len(allFipsCopy); i++ {
+		fipQueue <- allFipsCopy[i]
 	}
+	close(fipQueue)
+
+	wg.Wait()
+
+	reportOrphanedFips(newOrphanedFips, kubefipConfig)
+
+	// move the kube-vip rollout to the next wave or halt it
+	progressRollout(kubefipConfig, cycleStart)
+
+	cycleDuration := time.Since(cycleStart)
+	metrics.SetGuestClusterCycleDuration(cycleDuration.Seconds())
+
+	if cycleDuration > time.Duration(kubefipConfig.OperateGuestClusterInterval)*time.Second {
+		log.Warnf("(operateGuestClusters) the guest cluster operations took %s, which is longer than the operateGuestClusterInterval of %d seconds",
+			cycleDuration.Round(time.Second), kubefipConfig.OperateGuestClusterInterval)
+	}
+
+	log.Debugf("(operateGuestClusters) end operating %d guest clusters in %s", len(allFipsCopy), cycleDuration.Round(time.Millisecond))
 
-	metrics.InOperationMode = false
+	metrics.SetInOperationMode(false)
 }
 
-func startManageKubevip(clientset *kubernetes.Clientset, kubefipConfig *config.KubefipConfigStruct) {
+func startManageKubevip(kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct) {
 	log.Infof("(startManageKubevip) start managing the kubevip configs on the guest clusters")
 
 	// this implemention makes sure that the ticker stops and starts again to prevent race conditions
@@ -572,7 +693,7 @@ func startManageKubevip(clientset *kubernetes.Clientset, kubefipConfig *config.K
 		for {
 			select {
 			case <-operateTicker.C:
-				operateGuestClusters(clientset, kubefipConfig)
+				operateGuestClusters(kubefip_clientset, kubefipConfig)
 			case <-quitOperation:
 				operateTicker.Stop()
 				return
@@ -595,7 +716,7 @@ func startManageKubevip(clientset *kubernetes.Clientset, kubefipConfig *config.K
 	}()
 }
 
-func restartManageKubevip(clientset *kubernetes.Clientset, kubefipConfig *config.KubefipConfigStruct, oldOperateGuestClusterInterval int) {
+func restartManageKubevip(kubefip_clientset *kubefipclientset.Clientset, kubefipConfig *config.KubefipConfigStruct, oldOperateGuestClusterInterval int) {
 	log.Infof("(restartManageKubevip) restart managing the kubevip configs on the guest clusters")
 
 	if oldOperateGuestClusterInterval == kubefipConfig.OperateGuestClusterInterval {
@@ -612,5 +733,5 @@ func restartManageKubevip(clientset *kubernetes.Clientset, kubefipConfig *config
 	metricsCleanupTicker.Stop()
 
 	// start the ticker again
-	startManageKubevip(clientset, kubefipConfig)
+	startManageKubevip(kubefip_clientset, kubefipConfig)
 }
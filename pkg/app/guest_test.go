@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRaceTestFip(i int) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("race-%d-kubevip", i),
+			Namespace: "race-test",
+			// an unknown source skips the guest cluster operations, so no clients are needed
+			Annotations: map[string]string{"clustersource": "race-test"},
+		},
+		Spec: KubefipV2.FloatingIPSpec{
+			ClusterName: fmt.Sprintf("race-%d", i),
+			FipRange:    "race-test",
+			IPAddress:   fmt.Sprintf("192.168.100.%d", i+1),
+		},
+	}
+}
+
+// TestOperateGuestClustersRace runs the guest cluster cycle while the informers and the configmap events change the
+// shared state, run it with `go test -race`
+func TestOperateGuestClustersRace(t *testing.T) {
+	metrics.AppMetrics = metrics.NewMetrics(prometheus.NewRegistry())
+
+	// every skipped cluster is logged
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logOutput) })
+
+	kubefipConfig := config.ParseKubfipConfigMap(nil)
+	kubefipConfig.ClusterSources = nil
+	kubefipConfig.GuestClusterWorkers = 4
+	kubefipConfig.GuestClusterTimeout = 10
+
+	fips := make([]KubefipV2.FloatingIP, 20)
+	for i := range fips {
+		fips[i] = newRaceTestFip(i)
+		if err := kubefip.UpdateAllFips(&fips[i]); err != nil {
+			t.Fatalf("error adding fip: %s", err.Error())
+		}
+	}
+	t.Cleanup(func() {
+		for i := range fips {
+			_ = kubefip.RemoveFipFromAllFips(&fips[i])
+		}
+	})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// the configmap events
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		newKubefipConfig := kubefipConfig
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			newKubefipConfig.GuestClusterWorkers = 1 + i%4
+			setKubefipConfig(&kubefipConfig, newKubefipConfig)
+			initClusterSources(nil, &kubefipConfig)
+		}
+	}()
+
+	// the fip events
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		fip := newRaceTestFip(len(fips))
+		for {
+			select {
+			case <-done:
+				_ = kubefip.RemoveFipFromAllFips(&fip)
+				return
+			default:
+			}
+
+			_ = kubefip.UpdateAllFips(&fip)
+			_ = kubefip.RemoveFipFromAllFips(&fip)
+		}
+	}()
+
+	// the metrics cleanup ticker
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			metrics.AddClusterToMetricsCleanupQueue("race-0", "race-test")
+			metrics.CleanupMetrics()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		operateGuestClusters(nil, &kubefipConfig)
+	}
+
+	close(done)
+	wg.Wait()
+
+	results := make(map[string]status.GuestCycleResult)
+	for _, result := range status.GetGuestCycleResults() {
+		results[result.ClusterName] = result
+	}
+
+	for i := range fips {
+		result, found := results[fips[i].Spec.ClusterName]
+		if !found || result.Status != status.ClusterStatusSkipped {
+			t.Errorf("expected the cluster of fip [%s] to be skipped, got [%+v]", fips[i].ObjectMeta.Name, result)
+		}
+	}
+}
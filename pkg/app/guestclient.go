@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// guestClient holds the clients of a guest cluster, it's shared by all the guest cluster operations until the
+// kubeconfig secret changes. The requests to the guest cluster use the ctx, see withContext.
+type guestClient struct {
+	ctx             context.Context
+	restConfig      *rest.Config
+	clientset       *kubernetes.Clientset
+	dynamicClient   dynamic.Interface
+	secretNamespace string
+	secretName      string
+	resourceVersion string
+}
+
+var (
+	// the guest clients by <cluster namespace>/<cluster name>
+	guestClients = make(map[string]*guestClient)
+
+	// the namespaces whose kubeconfig secrets are watched, the clients of these secrets are evicted by the secret
+	// informer so they are used without reading the secret, see watchKubeconfigSecrets
+	watchedKubeconfigSecretNamespaces = make(map[string]bool)
+
+	// guards guestClients and watchedKubeconfigSecretNamespaces
+	guestClientsMutex sync.Mutex
+)
+
+func getGuestClientKey(namespace string, clusterName string) string {
+	return fmt.Sprintf("%s/%s", namespace, clusterName)
+}
+
+// getGuestClient returns the cached client of the guest cluster, a new client is created when the <cluster>-kubeconfig
+// secret in the secretNamespace is changed since the client was created. The secret is only read for a new client
+// when the secretNamespace is watched, otherwise its resourceVersion is compared on every call.
+func getGuestClient(clientset *kubernetes.Clientset, cluster Cluster, secretNamespace string) (*guestClient, error) {
+	log.Debugf("(getGuestClient) retrieving guest cluster kubeconfig")
+
+	if cluster.ClusterName == "" {
+		return nil, errors.New("(getGuestClient) clustername not set")
+	}
+
+	key := getGuestClientKey(cluster.Namespace, cluster.ClusterName)
+
+	guestClientsMutex.Lock()
+	if c, ok := guestClients[key]; ok && watchedKubeconfigSecretNamespaces[secretNamespace] {
+		guestClientsMutex.Unlock()
+
+		return c, nil
+	}
+	guestClientsMutex.Unlock()
+
+	kubeconfigSecretName := fmt.Sprintf("%s-kubeconfig", cluster.ClusterName)
+	kubeconfigSecretObj, err := clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), kubeconfigSecretName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			removeGuestClient(cluster.Namespace, cluster.ClusterName)
+		}
+
+		return nil, err
+	}
+
+	guestClientsMutex.Lock()
+	defer guestClientsMutex.Unlock()
+
+	if c, ok := guestClients[key]; ok && c.resourceVersion == kubeconfigSecretObj.ObjectMeta.ResourceVersion {
+		return c, nil
+	}
+
+	log.Debugf("(getGuestClient) creating a new client for guest cluster [%s] with kubeconfig secret [%s/%s] version [%s]",
+		key, secretNamespace, kubeconfigSecretName, kubeconfigSecretObj.ObjectMeta.ResourceVersion)
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigSecretObj.Data["value"])
+	if err != nil {
+		return nil, err
+	}
+
+	guestClientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	guestDynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &guestClient{
+		ctx:             context.Background(),
+		restConfig:      config,
+		clientset:       guestClientset,
+		dynamicClient:   guestDynamicClient,
+		secretNamespace: secretNamespace,
+		secretName:      kubeconfigSecretName,
+		resourceVersion: kubeconfigSecretObj.ObjectMeta.ResourceVersion,
+	}
+	guestClients[key] = c
+
+	return c, nil
+}
+
+// withContext returns a copy of the client whose requests are cancelled with the ctx, the helm clients which are created
+// from the copy get the time left until the deadline of the ctx as their request timeout
+func (c *guestClient) withContext(ctx context.Context) *guestClient {
+	guestClientCopy := *c
+	guestClientCopy.ctx = ctx
+
+	if deadline, ok := ctx.Deadline(); ok {
+		guestClientCopy.restConfig = rest.CopyConfig(c.restConfig)
+		guestClientCopy.restConfig.Timeout = time.Until(deadline)
+	}
+
+	return &guestClientCopy
+}
+
+// removeGuestClient evicts the client of a guest cluster from the cache
+func removeGuestClient(namespace string, clusterName string) {
+	key := getGuestClientKey(namespace, clusterName)
+
+	guestClientsMutex.Lock()
+	defer guestClientsMutex.Unlock()
+
+	if _, ok := guestClients[key]; ok {
+		log.Debugf("(removeGuestClient) removing the client of guest cluster [%s]", key)
+
+		delete(guestClients, key)
+	}
+}
+
+// removeGuestClientOfFip evicts the client of the guest cluster of the fip, the fip lives in the cluster namespace
+func removeGuestClientOfFip(fip *KubefipV2.FloatingIP) {
+	removeGuestClient(fip.ObjectMeta.Namespace, fip.Spec.ClusterName)
+}
+
+// removeGuestClientsOfSecret evicts the clients which are created from another version of the kubeconfig secret, all
+// clients of the secret are evicted when the resourceVersion is empty
+func removeGuestClientsOfSecret(secretNamespace string, secretName string, resourceVersion string) {
+	guestClientsMutex.Lock()
+	defer guestClientsMutex.Unlock()
+
+	for key, c := range guestClients {
+		if c.secretNamespace != secretNamespace || c.secretName != secretName || c.resourceVersion == resourceVersion {
+			continue
+		}
+
+		log.Debugf("(removeGuestClientsOfSecret) removing the client of guest cluster [%s], kubeconfig secret [%s/%s] changed",
+			key, secretNamespace, secretName)
+
+		delete(guestClients, key)
+	}
+}
+
+// onKubeconfigSecretEvent evicts the guest clients of a changed or removed kubeconfig secret
+func onKubeconfigSecretEvent(obj interface{}, removed bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || !strings.HasSuffix(secret.ObjectMeta.Name, "-kubeconfig") {
+		return
+	}
+
+	resourceVersion := secret.ObjectMeta.ResourceVersion
+	if removed {
+		resourceVersion = ""
+	}
+
+	removeGuestClientsOfSecret(secret.ObjectMeta.Namespace, secret.ObjectMeta.Name, resourceVersion)
+}
+
+// watchKubeconfigSecrets watches the kubeconfig secrets in the secretNamespace and evicts the guest clients when their
+// secret changes, the cached clients of the namespace are used without reading the secret once the informer is synced
+func watchKubeconfigSecrets(k8s_clientset *kubernetes.Clientset, secretNamespace string, stop <-chan struct{}) {
+	_, controllerSecrets := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: cache.NewListWatchFromClient(k8s_clientset.CoreV1().RESTClient(), "secrets", secretNamespace, fields.Everything()),
+		ObjectType:    &corev1.Secret{},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				onKubeconfigSecretEvent(obj, false)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				onKubeconfigSecretEvent(newObj, false)
+			},
+			DeleteFunc: func(obj interface{}) {
+				onKubeconfigSecretEvent(obj, true)
+			},
+		},
+		// only the name and the resourceVersion are needed, the kubeconfig is read when a new client is created
+		Transform: func(obj interface{}) (interface{}, error) {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				secret.Data = nil
+				secret.StringData = nil
+			}
+
+			return obj, nil
+		},
+	})
+
+	go controllerSecrets.Run(stop)
+
+	if !cache.WaitForCacheSync(stop, controllerSecrets.HasSynced) {
+		return
+	}
+
+	log.Infof("(watchKubeconfigSecrets) watching the kubeconfig secrets in namespace [%s]", secretNamespace)
+
+	guestClientsMutex.Lock()
+	watchedKubeconfigSecretNamespaces[secretNamespace] = true
+	guestClientsMutex.Unlock()
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// setTestGuestClients replaces the cached guest clients and the watched namespaces for the duration of the test
+func setTestGuestClients(t *testing.T, clients map[string]*guestClient, watchedNamespaces map[string]bool) {
+	t.Helper()
+
+	guestClientsMutex.Lock()
+	oldClients, oldWatchedNamespaces := guestClients, watchedKubeconfigSecretNamespaces
+	guestClients, watchedKubeconfigSecretNamespaces = clients, watchedNamespaces
+	guestClientsMutex.Unlock()
+
+	t.Cleanup(func() {
+		guestClientsMutex.Lock()
+		guestClients, watchedKubeconfigSecretNamespaces = oldClients, oldWatchedNamespaces
+		guestClientsMutex.Unlock()
+	})
+}
+
+func getGuestClientKeys() []string {
+	guestClientsMutex.Lock()
+	defer guestClientsMutex.Unlock()
+
+	keys := []string{}
+	for key := range guestClients {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func newTestKubeconfigSecret(namespace string, name string, resourceVersion string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, ResourceVersion: resourceVersion}}
+}
+
+func TestOnKubeconfigSecretEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      interface{}
+		removed  bool
+		wantKeys []string
+	}{
+		{
+			name:     "secret of another version",
+			obj:      newTestKubeconfigSecret("fleet-default", "cluster1-kubeconfig", "2"),
+			wantKeys: []string{"c-2/cluster2", "c-3/cluster1"},
+		},
+		{
+			name:     "secret of the same version",
+			obj:      newTestKubeconfigSecret("fleet-default", "cluster1-kubeconfig", "1"),
+			wantKeys: []string{"c-1/cluster1", "c-2/cluster2", "c-3/cluster1"},
+		},
+		{
+			name:     "removed secret",
+			obj:      newTestKubeconfigSecret("fleet-default", "cluster2-kubeconfig", "5"),
+			removed:  true,
+			wantKeys: []string{"c-1/cluster1", "c-3/cluster1"},
+		},
+		{
+			name:     "removed secret of a tombstone",
+			obj:      cache.DeletedFinalStateUnknown{Obj: newTestKubeconfigSecret("fleet-default", "cluster2-kubeconfig", "5")},
+			removed:  true,
+			wantKeys: []string{"c-1/cluster1", "c-3/cluster1"},
+		},
+		{
+			name:     "secret in another namespace",
+			obj:      newTestKubeconfigSecret("other", "cluster1-kubeconfig", "2"),
+			wantKeys: []string{"c-1/cluster1", "c-2/cluster2", "c-3/cluster1"},
+		},
+		{
+			name:     "secret which is not a kubeconfig",
+			obj:      newTestKubeconfigSecret("fleet-default", "cluster2", "2"),
+			removed:  true,
+			wantKeys: []string{"c-1/cluster1", "c-2/cluster2", "c-3/cluster1"},
+		},
+		{
+			name:     "other object",
+			obj:      &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "cluster1-kubeconfig"}},
+			removed:  true,
+			wantKeys: []string{"c-1/cluster1", "c-2/cluster2", "c-3/cluster1"},
+		},
+	}
+
+	for _, tt := range tests {
+		setTestGuestClients(t, map[string]*guestClient{
+			"c-1/cluster1": {secretNamespace: "fleet-default", secretName: "cluster1-kubeconfig", resourceVersion: "1"},
+			"c-2/cluster2": {secretNamespace: "fleet-default", secretName: "cluster2-kubeconfig", resourceVersion: "5"},
+			// the same cluster name with a kubeconfig secret in another namespace
+			"c-3/cluster1": {secretNamespace: "other", secretName: "cluster1-kubeconfig", resourceVersion: "2"},
+		}, map[string]bool{})
+
+		onKubeconfigSecretEvent(tt.obj, tt.removed)
+
+		if keys := getGuestClientKeys(); !reflect.DeepEqual(keys, tt.wantKeys) {
+			t.Errorf("%s: expected guest clients %v, got %v", tt.name, tt.wantKeys, keys)
+		}
+	}
+}
+
+func TestGetGuestClientOfWatchedNamespace(t *testing.T) {
+	cached := &guestClient{secretNamespace: "fleet-default", secretName: "cluster1-kubeconfig", resourceVersion: "1"}
+	setTestGuestClients(t, map[string]*guestClient{"c-1/cluster1": cached}, map[string]bool{"fleet-default": true})
+
+	// the secret isn't read for the cached client, so no clientset is needed
+	c, err := getGuestClient(nil, Cluster{Namespace: "c-1", ClusterName: "cluster1"}, "fleet-default")
+	if err != nil || c != cached {
+		t.Errorf("expected the cached client, got [%+v] and error [%v]", c, err)
+	}
+
+	if _, err := getGuestClient(nil, Cluster{Namespace: "c-1"}, "fleet-default"); err == nil {
+		t.Errorf("expected an error for a cluster without a clustername")
+	}
+
+	removeGuestClient("c-1", "cluster1")
+	if keys := getGuestClientKeys(); len(keys) != 0 {
+		t.Errorf("expected no guest clients after the remove, got %v", keys)
+	}
+}
+
+func TestGuestClientWithContext(t *testing.T) {
+	c := &guestClient{ctx: context.Background(), restConfig: &rest.Config{Host: "https://guest"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	withTimeout := c.withContext(ctx)
+	if withTimeout.ctx != ctx || withTimeout.restConfig.Timeout <= 0 || withTimeout.restConfig.Timeout > time.Minute {
+		t.Errorf("expected the ctx and a request timeout of at most a minute, got timeout [%s]", withTimeout.restConfig.Timeout)
+	}
+	if c.restConfig.Timeout != 0 || c.ctx != context.Background() {
+		t.Errorf("expected the cached client to be unchanged, got timeout [%s]", c.restConfig.Timeout)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	if withCancel := c.withContext(ctx); withCancel.ctx != ctx || withCancel.restConfig != c.restConfig {
+		t.Errorf("expected the ctx and the rest config of the cached client")
+	}
+}
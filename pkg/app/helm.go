@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	log "github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/client-go/rest"
+)
+
+// the desired chart versions are cached, so the repository index is not downloaded for every guest cluster
+const resolvedChartTTL = 5 * time.Minute
+
+type resolvedChart struct {
+	name     string
+	version  string
+	resolved time.Time
+}
+
+// the resolved charts by <repository url>|<chart name>@<chart version>, guarded by the helmRepoMutex
+var resolvedCharts = make(map[string]resolvedChart)
+
+func (c resolvedChart) String() string {
+	return fmt.Sprintf("%s-%s", c.name, c.version)
+}
+
+// resolveChart returns the name and version of the chart which is installed for the chart name and version, an empty
+// version resolves to the latest version in the repository
+func resolveChart(helmClient helmclient.Client, chartRepo *repo.Entry, chartName string, chartVersion string) (resolvedChart, error) {
+	var repoUrl string
+	if chartRepo != nil {
+		repoUrl = chartRepo.URL
+	}
+	key := fmt.Sprintf("%s|%s@%s", repoUrl, chartName, chartVersion)
+
+	helmRepoMutex.Lock()
+	defer helmRepoMutex.Unlock()
+
+	if c, ok := resolvedCharts[key]; ok && time.Since(c.resolved) < resolvedChartTTL {
+		return c, nil
+	}
+
+	if chartRepo != nil {
+		if err := helmClient.AddOrUpdateChartRepo(*chartRepo); err != nil {
+			return resolvedChart{}, err
+		}
+	}
+
+	helmChart, _, err := helmClient.GetChart(chartName, &action.ChartPathOptions{Version: chartVersion})
+	if err != nil {
+		return resolvedChart{}, err
+	}
+
+	c := resolvedChart{
+		name:     helmChart.Metadata.Name,
+		version:  helmChart.Metadata.Version,
+		resolved: time.Now(),
+	}
+	resolvedCharts[key] = c
+
+	log.Debugf("(resolveChart) chart [%s] version [%s] resolved to [%s]", chartName, chartVersion, c.String())
+
+	return c, nil
+}
+
+// normalizeValues converts the values to their json types, so the values parsed from yaml and the values stored in the
+// release can be compared
+func normalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	normalized := map[string]interface{}{}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return normalized, err
+	}
+
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return normalized, err
+	}
+
+	return normalized, nil
+}
+
+// diffValues returns the sorted paths of the values which are added, removed or changed
+func diffValues(deployed map[string]interface{}, desired map[string]interface{}, prefix string) []string {
+	var diff []string
+
+	keys := map[string]bool{}
+	for k := range deployed {
+		keys[k] = true
+	}
+	for k := range desired {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		deployedValue, inDeployed := deployed[k]
+		desiredValue, inDesired := desired[k]
+
+		deployedMap, deployedIsMap := deployedValue.(map[string]interface{})
+		desiredMap, desiredIsMap := desiredValue.(map[string]interface{})
+
+		switch {
+		case !inDeployed:
+			diff = append(diff, fmt.Sprintf("%s: added", path))
+		case !inDesired:
+			diff = append(diff, fmt.Sprintf("%s: removed", path))
+		case deployedIsMap && desiredIsMap:
+			diff = append(diff, diffValues(deployedMap, desiredMap, path)...)
+		case !reflect.DeepEqual(deployedValue, desiredValue):
+			diff = append(diff, fmt.Sprintf("%s: changed", path))
+		}
+	}
+
+	sort.Strings(diff)
+
+	return diff
+}
+
+// getHelmReleaseDrift compares the deployed release with the desired chart and the values of the chart spec
+func getHelmReleaseDrift(helmClient helmclient.Client, spec *helmclient.ChartSpec, deployed *release.Release, desired resolvedChart) (*status.HelmReleaseDrift, error) {
+	drift := &status.HelmReleaseDrift{
+		Release:      spec.ReleaseName,
+		DesiredChart: desired.String(),
+	}
+
+	if deployed.Chart != nil && deployed.Chart.Metadata != nil {
+		drift.DeployedChart = fmt.Sprintf("%s-%s", deployed.Chart.Metadata.Name, deployed.Chart.Metadata.Version)
+	}
+
+	desiredValues, err := spec.GetValuesMap(helmClient.GetProviders())
+	if err != nil {
+		return drift, err
+	}
+
+	normalizedDesiredValues, err := normalizeValues(desiredValues)
+	if err != nil {
+		return drift, err
+	}
+
+	normalizedDeployedValues, err := normalizeValues(deployed.Config)
+	if err != nil {
+		return drift, err
+	}
+
+	drift.ValuesDiff = diffValues(normalizedDeployedValues, normalizedDesiredValues, "")
+
+	return drift, nil
+}
+
+func hasVersionDrift(drift *status.HelmReleaseDrift) bool {
+	return drift.DeployedChart != drift.DesiredChart
+}
+
+func hasValuesDrift(drift *status.HelmReleaseDrift) bool {
+	return len(drift.ValuesDiff) > 0
+}
+
+// reconcileHelmRelease installs the chart of the spec when the release is missing and upgrades the release when its
+// chart, version or values drifted and allowUpgrade is set. The drift is returned when a deployed release is found.
+func reconcileHelmRelease(ctx context.Context, helmClient helmclient.Client, chartRepo *repo.Entry, spec *helmclient.ChartSpec, fip KubefipV2.FloatingIP, allowUpgrade bool) (bool, *status.HelmReleaseDrift, error) {
+	var drift *status.HelmReleaseDrift
+
+	deployed, err := helmClient.GetRelease(spec.ReleaseName)
+	if err != nil {
+		if err.Error() == "release: not found" {
+			// now we can install it
+			log.Infof("(reconcileHelmRelease) %s release not found in guest cluster [%s], trying to install it",
+				spec.ReleaseName, fip.Spec.ClusterName)
+		} else {
+			return updateMetrics, drift, err
+		}
+	}
+
+	desired, err := resolveChart(helmClient, chartRepo, spec.ChartName, spec.Version)
+	if err != nil {
+		return updateMetrics, drift, err
+	}
+
+	if deployed != nil {
+		drift, err = getHelmReleaseDrift(helmClient, spec, deployed, desired)
+		if err != nil {
+			return updateMetrics, drift, err
+		}
+
+		if !hasVersionDrift(drift) && !hasValuesDrift(drift) {
+			log.Debugf("(reconcileHelmRelease) %s release [%s] in guest cluster [%s] is in sync",
+				spec.ReleaseName, drift.DeployedChart, fip.Spec.ClusterName)
+
+			return dontUpdateMetrics, drift, nil
+		}
+
+		if !allowUpgrade {
+			log.Infof("(reconcileHelmRelease) %s release in guest cluster [%s] drifted from [%s] to [%s], the upgrade waits for its rollout wave",
+				spec.ReleaseName, fip.Spec.ClusterName, drift.DeployedChart, drift.DesiredChart)
+
+			return dontUpdateMetrics, drift, nil
+		}
+
+		log.Infof("(reconcileHelmRelease) upgrading %s release in guest cluster [%s] from [%s] to [%s], changed values: [%s]",
+			spec.ReleaseName, fip.Spec.ClusterName, drift.DeployedChart, drift.DesiredChart, strings.Join(drift.ValuesDiff, ", "))
+	}
+
+	// install the resolved version, so a new chart in the repository doesn't end up in a release which is not reported
+	spec.Version = desired.version
+
+	helmRelease, err := helmClient.InstallOrUpgradeChart(ctx, spec, nil)
+	if err != nil {
+		return updateMetrics, drift, err
+	}
+
+	if drift != nil {
+		drift.Upgraded = true
+	}
+
+	log.Debugf("(reconcileHelmRelease) returned %s helm release manifest: %s", spec.ReleaseName, helmRelease.Manifest)
+
+	return updateMetrics, drift, nil
+}
+
+// newGuestHelmClient returns a helm client for the releases in the namespace of the guest cluster
+func newGuestHelmClient(guestClient *guestClient, namespace string) (helmclient.Client, error) {
+	opt := &helmclient.RestConfClientOptions{
+		Options: &helmclient.Options{
+			Namespace:        namespace,
+			RepositoryCache:  "/tmp/.helmcache-kube-vip",
+			RepositoryConfig: "/tmp/.helmrepo-kube-vip",
+			Debug:            true,
+		},
+		RestConfig: rest.CopyConfig(guestClient.restConfig),
+	}
+
+	return helmclient.NewClientFromRestConf(opt)
+}
+
+// recordHelmDrift updates the drift metric of the release and adds a drifted release to the cycle result
+func recordHelmDrift(result *status.GuestCycleResult, clusterName string, harvesterClusterName string, drift *status.HelmReleaseDrift) {
+	if drift == nil {
+		return
+	}
+
+	versionDrift := hasVersionDrift(drift)
+	valuesDrift := hasValuesDrift(drift)
+
+	// an upgraded release is converged
+	metrics.SetGuestClusterHelmDrift(clusterName, harvesterClusterName, drift.Release,
+		versionDrift && !drift.Upgraded, valuesDrift && !drift.Upgraded)
+
+	if versionDrift || valuesDrift {
+		result.HelmDrift = append(result.HelmDrift, *drift)
+	}
+}
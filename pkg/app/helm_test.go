@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNormalizeValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "numbers",
+			values: map[string]interface{}{"int": 1, "int64": int64(2), "float": 1.5},
+			want:   map[string]interface{}{"int": float64(1), "int64": float64(2), "float": 1.5},
+		},
+		{
+			name: "nested maps and lists",
+			values: map[string]interface{}{
+				"config": map[string]interface{}{"vip_arp": true, "ports": []int{80, 443}},
+				"env":    []map[string]string{{"name": "vip_interface"}},
+			},
+			want: map[string]interface{}{
+				"config": map[string]interface{}{"vip_arp": true, "ports": []interface{}{float64(80), float64(443)}},
+				"env":    []interface{}{map[string]interface{}{"name": "vip_interface"}},
+			},
+		},
+		{
+			// a release without values has a nil config, which diffValues handles as an empty map
+			name: "nil values",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeValues(tt.values)
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected values [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := normalizeValues(map[string]interface{}{"func": func() {}}); err == nil {
+		t.Errorf("expected an error for values which can't be encoded")
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		deployed map[string]interface{}
+		desired  map[string]interface{}
+		want     []string
+	}{
+		{
+			name:     "same values",
+			deployed: map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": true}},
+			desired:  map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": true}},
+		},
+		{
+			name:     "added, removed and changed values",
+			deployed: map[string]interface{}{"b": "1", "c": "1"},
+			desired:  map[string]interface{}{"a": "1", "c": "2"},
+			want:     []string{"a: added", "b: removed", "c: changed"},
+		},
+		{
+			name: "nested values",
+			deployed: map[string]interface{}{"config": map[string]interface{}{
+				"vip_interface": "eth0", "vip_arp": true, "nested": map[string]interface{}{"x": "1"}}},
+			desired: map[string]interface{}{"config": map[string]interface{}{
+				"vip_interface": "enp2s0", "vip_arp": true, "nested": map[string]interface{}{"y": "1"}}},
+			want: []string{"config.nested.x: removed", "config.nested.y: added", "config.vip_interface: changed"},
+		},
+		{
+			name:     "map replaced by a value",
+			deployed: map[string]interface{}{"config": map[string]interface{}{"a": "1"}},
+			desired:  map[string]interface{}{"config": "a"},
+			want:     []string{"config: changed"},
+		},
+		{
+			name:     "changed list",
+			deployed: map[string]interface{}{"list": []interface{}{"a", "b"}},
+			desired:  map[string]interface{}{"list": []interface{}{"b", "a"}},
+			want:     []string{"list: changed"},
+		},
+		{
+			name:    "no deployed values",
+			desired: map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+			want:    []string{"a: added"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := diffValues(tt.deployed, tt.desired, ""); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected diff %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// testHelmClient is a helm client with a deployed release and a chart repository with one chart version
+type testHelmClient struct {
+	helmclient.Client
+	deployed       *release.Release
+	chartVersion   string
+	installedSpecs []helmclient.ChartSpec
+}
+
+func (c *testHelmClient) GetRelease(name string) (*release.Release, error) {
+	if c.deployed == nil {
+		return nil, errors.New("release: not found")
+	}
+
+	return c.deployed, nil
+}
+
+func (c *testHelmClient) GetChart(chartName string, chartPathOptions *action.ChartPathOptions) (*chart.Chart, string, error) {
+	return &chart.Chart{Metadata: &chart.Metadata{Name: chartName, Version: c.chartVersion}}, "", nil
+}
+
+func (c *testHelmClient) GetProviders() getter.Providers {
+	return getter.Providers{}
+}
+
+func (c *testHelmClient) InstallOrUpgradeChart(ctx context.Context, spec *helmclient.ChartSpec, opts *helmclient.GenericHelmOptions) (*release.Release, error) {
+	c.installedSpecs = append(c.installedSpecs, *spec)
+
+	return &release.Release{Name: spec.ReleaseName}, nil
+}
+
+func newTestRelease(version string, values map[string]interface{}) *release.Release {
+	return &release.Release{
+		Name:   "kube-vip",
+		Chart:  &chart.Chart{Metadata: &chart.Metadata{Name: "kube-vip", Version: version}},
+		Config: values,
+	}
+}
+
+func TestReconcileHelmRelease(t *testing.T) {
+	helmRepoMutex.Lock()
+	oldResolvedCharts := resolvedCharts
+	helmRepoMutex.Unlock()
+	t.Cleanup(func() {
+		helmRepoMutex.Lock()
+		resolvedCharts = oldResolvedCharts
+		helmRepoMutex.Unlock()
+	})
+
+	valuesYaml := "config:\n  vip_interface: enp1s0\nreplicas: 1\n"
+	values := map[string]interface{}{"config": map[string]interface{}{"vip_interface": "enp1s0"}, "replicas": int64(1)}
+
+	tests := []struct {
+		name         string
+		deployed     *release.Release
+		allowUpgrade bool
+		wantInstall  bool
+		wantDrift    bool
+		wantUpgraded bool
+		wantDiff     []string
+	}{
+		{name: "missing release", wantInstall: true},
+		{name: "release in sync", deployed: newTestRelease("0.6.4", values), allowUpgrade: true, wantDrift: true},
+		{name: "version drift", deployed: newTestRelease("0.6.3", values), allowUpgrade: true, wantInstall: true,
+			wantDrift: true, wantUpgraded: true},
+		{name: "values drift", deployed: newTestRelease("0.6.4", map[string]interface{}{"replicas": 2}), allowUpgrade: true,
+			wantInstall: true, wantDrift: true, wantUpgraded: true, wantDiff: []string{"config: added", "replicas: changed"}},
+		{name: "drift which waits for the rollout wave", deployed: newTestRelease("0.6.3", values), wantDrift: true},
+	}
+
+	for _, tt := range tests {
+		helmRepoMutex.Lock()
+		resolvedCharts = make(map[string]resolvedChart)
+		helmRepoMutex.Unlock()
+
+		helmClient := &testHelmClient{deployed: tt.deployed, chartVersion: "0.6.4"}
+		spec := &helmclient.ChartSpec{ReleaseName: "kube-vip", ChartName: "kube-vip", ValuesYaml: valuesYaml}
+		fip := KubefipV2.FloatingIP{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip"}}
+
+		_, drift, err := reconcileHelmRelease(context.Background(), helmClient, nil, spec, fip, tt.allowUpgrade)
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+
+			continue
+		}
+
+		if installed := len(helmClient.installedSpecs) > 0; installed != tt.wantInstall {
+			t.Errorf("%s: expected install or upgrade [%t], got [%t]", tt.name, tt.wantInstall, installed)
+		} else if installed && helmClient.installedSpecs[0].Version != "0.6.4" {
+			t.Errorf("%s: expected the resolved version [0.6.4] to be installed, got [%s]", tt.name, helmClient.installedSpecs[0].Version)
+		}
+
+		if (drift != nil) != tt.wantDrift {
+			t.Errorf("%s: expected drift [%t], got [%+v]", tt.name, tt.wantDrift, drift)
+
+			continue
+		}
+
+		if drift == nil {
+			continue
+		}
+
+		if drift.DesiredChart != "kube-vip-0.6.4" || drift.DeployedChart != "kube-vip-"+tt.deployed.Chart.Metadata.Version {
+			t.Errorf("%s: expected the deployed and desired chart, got [%+v]", tt.name, drift)
+		}
+
+		if drift.Upgraded != tt.wantUpgraded || !reflect.DeepEqual(drift.ValuesDiff, tt.wantDiff) {
+			t.Errorf("%s: expected upgraded [%t] with values diff %v, got [%t] with %v", tt.name, tt.wantUpgraded, tt.wantDiff,
+				drift.Upgraded, drift.ValuesDiff)
+		}
+	}
+}
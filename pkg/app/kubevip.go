@@ -0,0 +1,112 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// kubevipBackend is the default lb backend, kube-vip and the kube-vip-cloud-provider are installed with helm and the
+// fip is handed out by the cidr-global of the kubevip configmap
+type kubevipBackend struct{}
+
+func (b kubevipBackend) Name() string {
+	return LBBackendKubevip
+}
+
+// Install installs or upgrades the kube-vip releases, the drifted releases are upgraded when the rollout reached the
+// wave of the cluster
+func (b kubevipBackend) Install(op *lbOperation) bool {
+	rolloutUpdate := rolloutClusterUpdate{inSync: true}
+	gate := getRolloutGate(op.fip, op.cluster, op.kubefipConfig)
+
+	if gate.rollback {
+		rollbackKubevipReleases(op.guestClient, op.kubefipConfig, op.fip, gate.upgraded)
+
+		return false
+	}
+
+	// the charts of the most specific kubevipprofile of the cluster, or the kube-fip-config charts
+	charts := getKubevipCharts(op.cluster, op.kubefipConfig)
+	charts.ValuesData = getChartValuesData(op.cluster, op.installFip)
+	op.result.KubevipProfile = charts.Profile
+
+	installed := false
+
+	metricUpdate, drift, err := installKubevipInGuestCluster(op.guestClient, op.kubefipConfig, op.installFip, charts, op.overrides.KubevipChartVersion, gate.allowUpgrade)
+	recordHelmDrift(op.result, op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName, drift)
+	trackRolloutRelease(&rolloutUpdate, drift, err)
+
+	if op.recordEvent(metrics.EventKubevipInstall, metricUpdate, err) {
+		installed = true
+	}
+
+	metricUpdate, drift, err = installKubevipCloudproviderInGuestCluster(op.guestClient, op.kubefipConfig, op.fip, charts, gate.allowUpgrade)
+	recordHelmDrift(op.result, op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName, drift)
+	trackRolloutRelease(&rolloutUpdate, drift, err)
+
+	if op.recordEvent(metrics.EventKubevipCloudproviderInstall, metricUpdate, err) {
+		installed = true
+	}
+
+	recordRolloutCluster(op.fip, op.guestClient, op.kubefipConfig, rolloutUpdate)
+
+	return installed
+}
+
+// ConfigurePool manages the kubevip configmap in kube-system
+func (b kubevipBackend) ConfigurePool(op *lbOperation) error {
+	metricUpdate, err := createOrUpdateKubevipConfigmapInGuestCluster(op.guestClient, op.kubefipConfig, op.fip)
+	op.recordEvent(metrics.EventConfigmapManagement, metricUpdate, err)
+
+	return err
+}
+
+// Verify checks the kube-vip pods, a kube-vip which isn't installed by the operator can have other labels and isn't
+// checked
+func (b kubevipBackend) Verify(op *lbOperation) (string, error) {
+	if !op.install {
+		return "kube-vip is not installed by the operator", nil
+	}
+
+	ready, err := checkPodsReady(op.guestClient, op.kubefipConfig.KubevipNamespace,
+		fmt.Sprintf("app.kubernetes.io/name=%s", op.kubefipConfig.KubevipReleaseName))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d kube-vip pods ready", ready), nil
+}
+
+// Uninstall removes the kube-vip releases and the kubevip configmap
+func (b kubevipBackend) Uninstall(op *lbOperation, releases bool) error {
+	var failed []string
+
+	if releases {
+		if !op.recordEvent(metrics.EventKubevipCloudproviderUninstall, updateMetrics,
+			uninstallHelmRelease(op.guestClient, op.kubefipConfig.KubevipNamespace, op.kubefipConfig.KubevipCloudProviderReleaseName)) {
+			failed = append(failed, metrics.EventKubevipCloudproviderUninstall)
+		}
+
+		if !op.recordEvent(metrics.EventKubevipUninstall, updateMetrics,
+			uninstallHelmRelease(op.guestClient, op.kubefipConfig.KubevipNamespace, op.kubefipConfig.KubevipReleaseName)) {
+			failed = append(failed, metrics.EventKubevipUninstall)
+		}
+	}
+
+	message, err := removeKubevipConfigmapInGuestCluster(op.guestClient, op.fip)
+	if op.recordEvent(metrics.EventConfigmapRemoval, updateMetrics, err) {
+		log.Infof("(kubevipBackend.Uninstall) guest cluster [%s]: %s", op.fip.Spec.ClusterName, message)
+	} else {
+		failed = append(failed, metrics.EventConfigmapRemoval)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/lbpool"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	LBBackendKubevip = "kube-vip"
+	LBBackendMetallb = "metallb"
+	LBBackendCilium  = "cilium"
+
+	// the fip annotation with the backend which is configured in the guest cluster, fips from before the backends
+	// existed are kube-vip fips
+	AnnotationFipLBBackend = "lbBackend"
+)
+
+// The LBBackend interface hides which load balancer hands out the fip to the loadbalancer services of the guest
+// cluster. The backend is installed when the cluster asks for the guest installation (kubevipGuestInstall, the
+// kube-vip label or the kubevip annotation), the pool with the fip is configured on every cluster.
+type LBBackend interface {
+	// Name returns the backend name, which is stored in the lbBackend annotation of the fip
+	Name() string
+
+	// Install installs or upgrades the releases of the backend, it returns true when the releases are installed
+	Install(op *lbOperation) bool
+
+	// ConfigurePool hands the fip to the backend
+	ConfigurePool(op *lbOperation) error
+
+	// Verify checks if the backend is ready to serve the fip, it returns a short description of the checked state
+	Verify(op *lbOperation) (string, error)
+
+	// Uninstall removes the pool of the fip, the releases are only removed when they are installed by the operator
+	Uninstall(op *lbOperation, releases bool) error
+}
+
+// lbOperation holds everything the backends need for the operations of one guest cluster cycle
+type lbOperation struct {
+	guestClient       *guestClient
+	kubefipConfig     *config.KubefipConfigStruct
+	kubefip_clientset *kubefipclientset.Clientset
+	cluster           Cluster
+	fip               KubefipV2.FloatingIP
+	overrides         clusterOverrides
+	result            *status.GuestCycleResult
+
+	// the fip with the detected guest interface of the fip network for the chart values
+	installFip KubefipV2.FloatingIP
+
+	// true when the cluster asks for the guest installation of the backend
+	install bool
+}
+
+var lbBackends = map[string]LBBackend{
+	LBBackendKubevip: kubevipBackend{},
+	LBBackendMetallb: metallbBackend{},
+	LBBackendCilium:  ciliumBackend{},
+}
+
+func getLBBackendByName(backendName string) (LBBackend, error) {
+	backend, ok := lbBackends[backendName]
+	if !ok {
+		return nil, fmt.Errorf("unknown lb backend [%s]", backendName)
+	}
+
+	return backend, nil
+}
+
+// getLBBackend returns the backend of the cluster, the cluster annotation wins from the lbBackend option
+func getLBBackend(overrides clusterOverrides, kubefipConfig *config.KubefipConfigStruct) (LBBackend, error) {
+	if overrides.LBBackend != "" {
+		return getLBBackendByName(overrides.LBBackend)
+	}
+
+	return getLBBackendByName(kubefipConfig.LBBackend)
+}
+
+// getPreviousLBBackendName returns the backend which is configured in the guest cluster by the previous cycles
+func getPreviousLBBackendName(fip KubefipV2.FloatingIP) string {
+	if backendName := fip.ObjectMeta.Annotations[AnnotationFipLBBackend]; backendName != "" {
+		return backendName
+	}
+
+	return LBBackendKubevip
+}
+
+// recordEvent adds the result of a backend operation to the cycle result and the events metric, a success is only
+// counted when metricUpdate is true. It returns false when the operation failed.
+func (op *lbOperation) recordEvent(event string, metricUpdate bool, err error) bool {
+	if err != nil {
+		log.Errorf("(recordEvent) %s failed in guest cluster [%s]: %s", event, op.fip.Spec.ClusterName, err.Error())
+
+		metrics.IncrementGuestClusterEventsMetric(op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName, event, metrics.StatusError)
+		op.result.AddEvent(event, metrics.StatusError, err)
+
+		return false
+	}
+
+	op.result.AddEvent(event, metrics.StatusSuccess, nil)
+
+	if metricUpdate {
+		metrics.IncrementGuestClusterEventsMetric(op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName, event, metrics.StatusSuccess)
+	}
+
+	return true
+}
+
+// operateLBBackend runs the backend operations of the guest cluster. A cluster which moves to another backend gets the
+// previous backend removed first, so two backends never hand out the fip at the same time. A cluster which opted out of
+// the guest installation gets the backend removed, after the uninstall the guest cluster is left alone until it opts
+// in again.
+func operateLBBackend(op *lbOperation, backend LBBackend) {
+	op.result.LBBackend = backend.Name()
+
+	state := getLBState(op.fip)
+
+	if previousName := getPreviousLBBackendName(op.fip); previousName != backend.Name() {
+		previous, err := getLBBackendByName(previousName)
+		if err != nil {
+			log.Errorf("(operateLBBackend) cannot remove the previous lb backend of guest cluster [%s]: %s", op.fip.Spec.ClusterName, err.Error())
+
+			op.result.Message = err.Error()
+
+			return
+		}
+
+		if !switchLBBackend(op, previous, backend, state == LBStateInstalled) {
+			return
+		}
+
+		state = ""
+	}
+
+	if !op.install && state == LBStateInstalled {
+		uninstallLBBackend(op, backend)
+
+		return
+	}
+
+	if !op.install && state == LBStateUninstalled {
+		log.Debugf("(operateLBBackend) %s is uninstalled from guest cluster [%s], skipping the %s config",
+			backend.Name(), op.fip.Spec.ClusterName, backend.Name())
+
+		return
+	}
+
+	// patch the Harvester cloud provider Kube-Vip daemonset, it interferes with every backend
+	checkForHarvesterKubeVipDaemonset(op.guestClient, op.fip)
+
+	if op.install && backend.Install(op) {
+		// the releases are removed when the cluster opts out later
+		markLBInstalled(op.fip, op.kubefip_clientset)
+	}
+
+	if err := backend.ConfigurePool(op); err != nil {
+		return
+	}
+
+	message, err := backend.Verify(op)
+	if op.recordEvent(metrics.EventLoadbalancerVerify, dontUpdateMetrics, err) {
+		log.Debugf("(operateLBBackend) %s in guest cluster [%s]: %s", backend.Name(), op.fip.Spec.ClusterName, message)
+	}
+}
+
+// switchLBBackend removes the previous backend and records the new backend on the fip, it returns false when the
+// previous backend isn't removed completely, the removal is retried in the next cycle
+func switchLBBackend(op *lbOperation, previous LBBackend, backend LBBackend, releases bool) bool {
+	log.Infof("(switchLBBackend) guest cluster [%s] moves from lb backend [%s] to [%s], removing [%s]",
+		op.fip.Spec.ClusterName, previous.Name(), backend.Name(), previous.Name())
+
+	if err := previous.Uninstall(op, releases); err != nil {
+		op.result.Message = fmt.Sprintf("removing the previous lb backend [%s] failed at [%s], retrying in the next cycle", previous.Name(), err.Error())
+
+		return false
+	}
+
+	// the releases are gone, so there is no drift to report anymore
+	metrics.RemoveGuestClusterHelmDrift(op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName)
+
+	if err := updateFipAnnotations(op.fip, map[string]string{
+		AnnotationFipLBBackend: backend.Name(),
+		AnnotationLBState:      "",
+		AnnotationKubevipState: "",
+	}, op.kubefip_clientset); err != nil {
+		log.Errorf("(switchLBBackend) error while updating fip [%s/%s]: %s", op.fip.ObjectMeta.Namespace, op.fip.ObjectMeta.Name, err.Error())
+
+		return false
+	}
+
+	log.Infof("(switchLBBackend) lb backend [%s] is removed from guest cluster [%s]", previous.Name(), op.fip.Spec.ClusterName)
+
+	return true
+}
+
+// errGuestObjectNotManaged is returned when an object with the name of an operator object exists in the guest cluster
+// but is not created by the operator
+var errGuestObjectNotManaged = errors.New("object is not managed by the operator")
+
+// applyGuestObject creates the object in the guest cluster or updates the spec fields of the object when they differ,
+// the spec fields which are not set by the operator (like the defaults of the api server) are left alone. An existing
+// object which is not created by the operator is reported as a conflict and kept as is. It returns true when the object
+// is created or updated.
+func applyGuestObject(guestClient *guestClient, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (bool, error) {
+	client := guestClient.dynamicClient.Resource(gvr).Namespace(obj.GetNamespace())
+
+	existing, err := client.Get(guestClient.ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return updateMetrics, err
+		}
+
+		if _, err := client.Create(guestClient.ctx, obj, metav1.CreateOptions{}); err != nil {
+			return updateMetrics, fmt.Errorf("error creating %s [%s]: %s", obj.GetKind(), obj.GetName(), err.Error())
+		}
+
+		log.Infof("(applyGuestObject) successfully created %s [%s]", obj.GetKind(), obj.GetName())
+
+		return updateMetrics, nil
+	}
+
+	if existing.GetLabels()[lbpool.LabelManagedBy] != lbpool.ManagedBy {
+		return updateMetrics, fmt.Errorf("%w: %s [%s] exists without the label [%s=%s]", errGuestObjectNotManaged,
+			obj.GetKind(), obj.GetName(), lbpool.LabelManagedBy, lbpool.ManagedBy)
+	}
+
+	desiredSpec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	existingSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	if existingSpec == nil {
+		existingSpec = map[string]interface{}{}
+	}
+
+	changed := false
+	for k, v := range desiredSpec {
+		if !reflect.DeepEqual(existingSpec[k], v) {
+			existingSpec[k] = v
+			changed = true
+		}
+	}
+
+	if !changed {
+		return dontUpdateMetrics, nil
+	}
+
+	newObj := existing.DeepCopy()
+	if err := unstructured.SetNestedMap(newObj.Object, existingSpec, "spec"); err != nil {
+		return updateMetrics, err
+	}
+
+	if _, err := client.Update(guestClient.ctx, newObj, metav1.UpdateOptions{}); err != nil {
+		return updateMetrics, fmt.Errorf("error updating %s [%s]: %s", obj.GetKind(), obj.GetName(), err.Error())
+	}
+
+	log.Infof("(applyGuestObject) successfully updated %s [%s]", obj.GetKind(), obj.GetName())
+
+	return updateMetrics, nil
+}
+
+// deleteGuestObject removes the object from the guest cluster when it's created by the operator, a missing object or
+// a missing resource type (the backend is not installed) is no error
+func deleteGuestObject(guestClient *guestClient, gvr schema.GroupVersionResource, namespace string, name string) (string, error) {
+	client := guestClient.dynamicClient.Resource(gvr).Namespace(namespace)
+
+	existing, err := client.Get(guestClient.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("%s [%s] not found", gvr.Resource, name), nil
+		}
+
+		return "", err
+	}
+
+	if existing.GetLabels()[lbpool.LabelManagedBy] != lbpool.ManagedBy {
+		return fmt.Sprintf("%s [%s] kept, it's not created by the operator", gvr.Resource, name), nil
+	}
+
+	if err := client.Delete(guestClient.ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s [%s] removed", gvr.Resource, name), nil
+}
+
+// checkPodsReady checks if all the pods of the label selector are ready, it returns the number of ready pods
+func checkPodsReady(guestClient *guestClient, namespace string, labelSelector string) (int, error) {
+	pods, err := guestClient.clientset.CoreV1().Pods(namespace).List(guestClient.ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(pods.Items) == 0 {
+		return 0, fmt.Errorf("no pods with labels [%s] found in namespace [%s]", labelSelector, namespace)
+	}
+
+	for _, pod := range pods.Items {
+		ready := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+
+		if !ready {
+			return 0, fmt.Errorf("pod [%s/%s] is not ready", pod.ObjectMeta.Namespace, pod.ObjectMeta.Name)
+		}
+	}
+
+	return len(pods.Items), nil
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/lbpool"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testMetallbPoolPath = "/apis/metallb.io/v1beta1/namespaces/metallb-system/ipaddresspools/" + lbpool.PoolName
+
+var testMetallbPoolGVR = schema.GroupVersionResource{Group: "metallb.io", Version: "v1beta1", Resource: "ipaddresspools"}
+
+// newTestMetallbPool returns the pool object of the testGuestServer with the labels and spec
+func newTestMetallbPool(labels map[string]interface{}, spec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "IPAddressPool",
+		"metadata":   map[string]interface{}{"name": lbpool.PoolName, "namespace": "metallb-system", "labels": labels},
+		"spec":       spec,
+	}
+}
+
+func TestApplyGuestObject(t *testing.T) {
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logOutput) })
+
+	fip := KubefipV2.FloatingIP{Spec: KubefipV2.FloatingIPSpec{IPAddress: "192.168.10.10"}}
+	managed := map[string]interface{}{lbpool.LabelManagedBy: lbpool.ManagedBy}
+
+	tests := []struct {
+		name        string
+		existing    map[string]interface{}
+		wantUpdated bool
+		wantErr     error
+		wantSpec    map[string]interface{}
+	}{
+		{
+			name:        "object created",
+			wantUpdated: true,
+			wantSpec:    map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true},
+		},
+		{
+			name: "object in sync",
+			existing: newTestMetallbPool(managed,
+				map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true}),
+			wantSpec: map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true},
+		},
+		{
+			name: "object in sync with defaults of the api server",
+			existing: newTestMetallbPool(managed,
+				map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true, "avoidBuggyIPs": false}),
+			wantSpec: map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true, "avoidBuggyIPs": false},
+		},
+		{
+			name: "object with another fip",
+			existing: newTestMetallbPool(managed,
+				map[string]interface{}{"addresses": []interface{}{"192.168.10.11/32"}, "autoAssign": true, "avoidBuggyIPs": false}),
+			wantUpdated: true,
+			wantSpec:    map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true, "avoidBuggyIPs": false},
+		},
+		{
+			name:        "object without spec",
+			existing:    newTestMetallbPool(managed, nil),
+			wantUpdated: true,
+			wantSpec:    map[string]interface{}{"addresses": []interface{}{"192.168.10.10/32"}, "autoAssign": true},
+		},
+		{
+			name: "object which is not created by the operator",
+			existing: newTestMetallbPool(map[string]interface{}{lbpool.LabelManagedBy: "helm"},
+				map[string]interface{}{"addresses": []interface{}{"10.0.0.0/24"}}),
+			wantUpdated: true,
+			wantErr:     errGuestObjectNotManaged,
+			wantSpec:    map[string]interface{}{"addresses": []interface{}{"10.0.0.0/24"}},
+		},
+		{
+			name:        "object without labels",
+			existing:    newTestMetallbPool(nil, map[string]interface{}{"addresses": []interface{}{"10.0.0.0/24"}}),
+			wantUpdated: true,
+			wantErr:     errGuestObjectNotManaged,
+			wantSpec:    map[string]interface{}{"addresses": []interface{}{"10.0.0.0/24"}},
+		},
+	}
+
+	for _, tt := range tests {
+		s := &testGuestServer{objects: map[string][]byte{}}
+		if tt.existing != nil {
+			s.setObject(testMetallbPoolPath, tt.existing)
+		}
+
+		updated, err := applyGuestObject(newTestGuestClient(t, s), testMetallbPoolGVR, lbpool.NewMetallbIPAddressPool(&fip, "metallb-system"))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: expected error [%v], got [%v]", tt.name, tt.wantErr, err)
+		}
+
+		if updated != tt.wantUpdated {
+			t.Errorf("%s: expected updated [%t], got [%t]", tt.name, tt.wantUpdated, updated)
+		}
+
+		obj := s.getObject(testMetallbPoolPath)
+		if obj == nil {
+			t.Errorf("%s: expected the object to exist", tt.name)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(obj["spec"], tt.wantSpec) {
+			t.Errorf("%s: expected spec %v, got %v", tt.name, tt.wantSpec, obj["spec"])
+		}
+	}
+}
+
+func TestDeleteGuestObject(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    map[string]interface{}
+		wantMessage string
+		wantRemoved bool
+	}{
+		{
+			name:        "object not found",
+			wantMessage: "ipaddresspools [kube-fip] not found",
+			wantRemoved: true,
+		},
+		{
+			name:        "object created by the operator",
+			existing:    newTestMetallbPool(map[string]interface{}{lbpool.LabelManagedBy: lbpool.ManagedBy}, nil),
+			wantMessage: "ipaddresspools [kube-fip] removed",
+			wantRemoved: true,
+		},
+		{
+			name:        "object which is not created by the operator",
+			existing:    newTestMetallbPool(map[string]interface{}{"app": "metallb"}, nil),
+			wantMessage: "ipaddresspools [kube-fip] kept, it's not created by the operator",
+		},
+	}
+
+	for _, tt := range tests {
+		s := &testGuestServer{objects: map[string][]byte{}}
+		if tt.existing != nil {
+			s.setObject(testMetallbPoolPath, tt.existing)
+		}
+
+		message, err := deleteGuestObject(newTestGuestClient(t, s), testMetallbPoolGVR, "metallb-system", lbpool.PoolName)
+		if err != nil || message != tt.wantMessage {
+			t.Errorf("%s: expected message [%s], got [%s] with error [%v]", tt.name, tt.wantMessage, message, err)
+		}
+
+		if removed := s.getObject(testMetallbPoolPath) == nil; removed != tt.wantRemoved {
+			t.Errorf("%s: expected removed [%t], got [%t]", tt.name, tt.wantRemoved, removed)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joeyloman/kube-fip-operator/pkg/lbpool"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	log "github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// metallbBackend installs MetalLB with helm and hands out the fip with an IPAddressPool and a L2Advertisement
+type metallbBackend struct{}
+
+func (b metallbBackend) Name() string {
+	return LBBackendMetallb
+}
+
+// Install installs or upgrades the metallb release, the release follows the chart options without a rollout
+func (b metallbBackend) Install(op *lbOperation) bool {
+	metricUpdate, err := installMetallbInGuestCluster(op)
+
+	return op.recordEvent(metrics.EventMetallbInstall, metricUpdate, err)
+}
+
+func installMetallbInGuestCluster(op *lbOperation) (bool, error) {
+	var chartName string
+	var chartRepo *repo.Entry
+
+	kubefipConfig := op.kubefipConfig
+
+	helmClient, err := newGuestHelmClient(op.guestClient, kubefipConfig.MetallbNamespace)
+	if err != nil {
+		return updateMetrics, err
+	}
+
+	if kubefipConfig.MetallbChartRef == "" {
+		chartRepo = &repo.Entry{
+			Name: "metallb",
+			URL:  kubefipConfig.MetallbChartRepoUrl,
+		}
+
+		chartName = "metallb/metallb"
+	} else {
+		chartName = kubefipConfig.MetallbChartRef
+	}
+
+	valuesYaml, err := renderChartValues(kubefipConfig.MetallbReleaseName, kubefipConfig.MetallbChartValues, getChartValuesData(op.cluster, op.installFip))
+	if err != nil {
+		return updateMetrics, err
+	}
+
+	chartSpecMetallb := helmclient.ChartSpec{
+		ReleaseName:     kubefipConfig.MetallbReleaseName,
+		ChartName:       chartName,
+		Version:         kubefipConfig.MetallbChartVersion,
+		Namespace:       kubefipConfig.MetallbNamespace,
+		ValuesYaml:      valuesYaml,
+		CreateNamespace: true,
+		Wait:            false,
+	}
+
+	metricUpdate, drift, err := reconcileHelmRelease(op.guestClient.ctx, helmClient, chartRepo, &chartSpecMetallb, op.fip, true)
+	recordHelmDrift(op.result, op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName, drift)
+	if err != nil {
+		return metricUpdate, err
+	}
+
+	if metricUpdate {
+		log.Infof("(installMetallbInGuestCluster) metallb helm chart installed successfully in guest cluster [%s]",
+			op.fip.Spec.ClusterName)
+	}
+
+	return metricUpdate, nil
+}
+
+// ConfigurePool manages the IPAddressPool and the L2Advertisement of the fip in the metallbNamespace
+func (b metallbBackend) ConfigurePool(op *lbOperation) error {
+	poolUpdate, err := applyGuestObject(op.guestClient, lbpool.MetallbIPAddressPoolResource,
+		lbpool.NewMetallbIPAddressPool(&op.installFip, op.kubefipConfig.MetallbNamespace))
+	if err != nil {
+		err = fmt.Errorf("error managing the metallb pool in namespace [%s], is metallb installed? %s", op.kubefipConfig.MetallbNamespace, err.Error())
+		op.recordEvent(metrics.EventPoolManagement, updateMetrics, err)
+
+		return err
+	}
+
+	advertisementUpdate, err := applyGuestObject(op.guestClient, lbpool.MetallbL2AdvertisementResource,
+		lbpool.NewMetallbL2Advertisement(&op.installFip, op.kubefipConfig.MetallbNamespace))
+	op.recordEvent(metrics.EventPoolManagement, poolUpdate || advertisementUpdate, err)
+
+	return err
+}
+
+// Verify checks the metallb controller and speaker pods
+func (b metallbBackend) Verify(op *lbOperation) (string, error) {
+	ready, err := checkPodsReady(op.guestClient, op.kubefipConfig.MetallbNamespace, "app.kubernetes.io/name=metallb")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d metallb pods ready", ready), nil
+}
+
+// Uninstall removes the L2Advertisement and the IPAddressPool of the fip and the metallb release, the pool is removed
+// first because the release removes the metallb crds
+func (b metallbBackend) Uninstall(op *lbOperation, releases bool) error {
+	var failed []string
+
+	var messages []string
+	message, err := deleteGuestObject(op.guestClient, lbpool.MetallbL2AdvertisementResource, op.kubefipConfig.MetallbNamespace, lbpool.PoolName)
+	if err == nil {
+		messages = append(messages, message)
+
+		message, err = deleteGuestObject(op.guestClient, lbpool.MetallbIPAddressPoolResource, op.kubefipConfig.MetallbNamespace, lbpool.PoolName)
+		messages = append(messages, message)
+	}
+
+	if op.recordEvent(metrics.EventPoolRemoval, updateMetrics, err) {
+		log.Infof("(metallbBackend.Uninstall) guest cluster [%s]: %s", op.fip.Spec.ClusterName, strings.Join(messages, ", "))
+	} else {
+		failed = append(failed, metrics.EventPoolRemoval)
+	}
+
+	if releases && len(failed) == 0 {
+		if !op.recordEvent(metrics.EventMetallbUninstall, updateMetrics,
+			uninstallHelmRelease(op.guestClient, op.kubefipConfig.MetallbNamespace, op.kubefipConfig.MetallbReleaseName)) {
+			failed = append(failed, metrics.EventMetallbUninstall)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the fip annotation which contains the time (RFC3339) when the cluster of the fip is found to be deleted
+const AnnotationOrphanedSince = "orphanedSince"
+
+type orphanedFip struct {
+	Namespace     string
+	Name          string
+	ClusterName   string
+	IPAddress     string
+	FipRange      string
+	OrphanedSince time.Time
+}
+
+// the orphaned fips of the last operateGuestClusters run
+var orphanedFips []orphanedFip
+
+func updateFipOrphanedSince(fip KubefipV2.FloatingIP, orphanedSince string, kubefip_clientset kubefipclientset.Interface) error {
+	fipObj, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	newFip := fipObj.DeepCopy()
+	if newFip.ObjectMeta.Annotations == nil {
+		newFip.ObjectMeta.Annotations = make(map[string]string)
+	}
+
+	if orphanedSince == "" {
+		delete(newFip.ObjectMeta.Annotations, AnnotationOrphanedSince)
+	} else {
+		newFip.ObjectMeta.Annotations[AnnotationOrphanedSince] = orphanedSince
+	}
+
+	_, err = kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), newFip, metav1.UpdateOptions{})
+
+	return err
+}
+
+// handleOrphanedFip marks the fip of a deleted cluster as orphaned and deletes it after the orphanedFipGracePeriod,
+// the fip delete event releases the ip address. It returns the orphaned fip for the report.
+func handleOrphanedFip(fip KubefipV2.FloatingIP, kubefipConfig *config.KubefipConfigStruct, kubefip_clientset kubefipclientset.Interface) orphanedFip {
+	o := orphanedFip{
+		Namespace:     fip.ObjectMeta.Namespace,
+		Name:          fip.ObjectMeta.Name,
+		ClusterName:   fip.Spec.ClusterName,
+		IPAddress:     fip.Spec.IPAddress,
+		FipRange:      fip.Spec.FipRange,
+		OrphanedSince: time.Now(),
+	}
+
+	if fip.ObjectMeta.Annotations[AnnotationOrphanedSince] == "" {
+		log.Warnf("(handleOrphanedFip) cluster [%s] of fip [%s/%s] is deleted, marking the fip as orphaned",
+			o.ClusterName, o.Namespace, o.Name)
+
+		if err := updateFipOrphanedSince(fip, o.OrphanedSince.Format(time.RFC3339), kubefip_clientset); err != nil {
+			log.Errorf("(handleOrphanedFip) error while marking fip [%s/%s] as orphaned: %s", o.Namespace, o.Name, err.Error())
+		}
+	} else {
+		orphanedSince, err := time.Parse(time.RFC3339, fip.ObjectMeta.Annotations[AnnotationOrphanedSince])
+		if err != nil {
+			log.Errorf("(handleOrphanedFip) error parsing the %s annotation of fip [%s/%s]: %s", AnnotationOrphanedSince,
+				o.Namespace, o.Name, err.Error())
+		} else {
+			o.OrphanedSince = orphanedSince
+		}
+	}
+
+	metrics.SetOrphanedFip(o.Namespace, o.Name, o.ClusterName, o.IPAddress, float64(o.OrphanedSince.Unix()))
+
+	if kubefipConfig.OrphanedFipGracePeriod <= 0 {
+		return o
+	}
+
+	if time.Since(o.OrphanedSince) < time.Duration(kubefipConfig.OrphanedFipGracePeriod)*time.Second {
+		log.Debugf("(handleOrphanedFip) fip [%s/%s] is orphaned since [%s], waiting for the grace period of %d seconds",
+			o.Namespace, o.Name, o.OrphanedSince.Format(time.RFC3339), kubefipConfig.OrphanedFipGracePeriod)
+
+		return o
+	}
+
+	log.Infof("(handleOrphanedFip) deleting fip [%s/%s] with ip [%s] of deleted cluster [%s], orphaned since [%s]",
+		o.Namespace, o.Name, o.IPAddress, o.ClusterName, o.OrphanedSince.Format(time.RFC3339))
+
+	if err := kubefip_clientset.KubefipV2().FloatingIPs(o.Namespace).Delete(context.TODO(), o.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		log.Errorf("(handleOrphanedFip) error while deleting fip [%s/%s]: %s", o.Namespace, o.Name, err.Error())
+
+		return o
+	}
+
+	metrics.RemoveOrphanedFip(o.Namespace, o.Name, o.ClusterName, o.IPAddress)
+
+	return orphanedFip{}
+}
+
+// clearOrphanedFip removes the orphaned mark when the cluster is back, for example after a temporary api problem
+func clearOrphanedFip(fip KubefipV2.FloatingIP, kubefip_clientset kubefipclientset.Interface) {
+	if fip.ObjectMeta.Annotations[AnnotationOrphanedSince] == "" {
+		return
+	}
+
+	log.Infof("(clearOrphanedFip) cluster [%s] of fip [%s/%s] exists again, removing the orphaned mark",
+		fip.Spec.ClusterName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	if err := updateFipOrphanedSince(fip, "", kubefip_clientset); err != nil {
+		log.Errorf("(clearOrphanedFip) error while updating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+		return
+	}
+
+	metrics.RemoveOrphanedFip(fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, fip.Spec.ClusterName, fip.Spec.IPAddress)
+}
+
+func reportOrphanedFips(newOrphanedFips []orphanedFip, kubefipConfig *config.KubefipConfigStruct) {
+	orphanedFips = newOrphanedFips
+
+	if len(orphanedFips) == 0 {
+		return
+	}
+
+	log.Infof("(reportOrphanedFips) %d orphaned fips of deleted clusters found", len(orphanedFips))
+	for _, o := range orphanedFips {
+		if kubefipConfig.OrphanedFipGracePeriod > 0 {
+			log.Infof("(reportOrphanedFips) fip [%s/%s] with ip [%s] in fiprange [%s] of cluster [%s] is orphaned since [%s] and will be deleted after [%s]",
+				o.Namespace, o.Name, o.IPAddress, o.FipRange, o.ClusterName, o.OrphanedSince.Format(time.RFC3339),
+				o.OrphanedSince.Add(time.Duration(kubefipConfig.OrphanedFipGracePeriod)*time.Second).Format(time.RFC3339))
+		} else {
+			log.Infof("(reportOrphanedFips) fip [%s/%s] with ip [%s] in fiprange [%s] of cluster [%s] is orphaned since [%s]",
+				o.Namespace, o.Name, o.IPAddress, o.FipRange, o.ClusterName, o.OrphanedSince.Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipfake "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/fake"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestOrphanedFip(orphanedSince string) *KubefipV2.FloatingIP {
+	fip := &KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: "cluster1", FipRange: "range1", IPAddress: "192.168.10.10"},
+	}
+	if orphanedSince != "" {
+		fip.ObjectMeta.Annotations = map[string]string{AnnotationOrphanedSince: orphanedSince}
+	}
+
+	return fip
+}
+
+func TestHandleOrphanedFip(t *testing.T) {
+	metrics.AppMetrics = metrics.NewMetrics(prometheus.NewRegistry())
+
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logOutput) })
+
+	hourAgo := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name              string
+		orphanedSince     string
+		gracePeriod       int
+		wantDeleted       bool
+		wantMarked        bool
+		wantOrphanedSince time.Time
+	}{
+		{name: "newly orphaned", gracePeriod: 3600, wantMarked: true},
+		{name: "newly orphaned without grace period", gracePeriod: 0, wantMarked: true},
+		{name: "within the grace period", orphanedSince: hourAgo.Format(time.RFC3339), gracePeriod: 7200,
+			wantOrphanedSince: hourAgo},
+		{name: "grace period passed", orphanedSince: hourAgo.Format(time.RFC3339), gracePeriod: 1800, wantDeleted: true},
+		{name: "grace period disabled", orphanedSince: hourAgo.Format(time.RFC3339), gracePeriod: 0,
+			wantOrphanedSince: hourAgo},
+		{name: "invalid orphanedSince restarts the grace period", orphanedSince: "yesterday", gracePeriod: 1800},
+	}
+
+	for _, tt := range tests {
+		fip := newTestOrphanedFip(tt.orphanedSince)
+		// NewClientset can't update the fip, the v2 types are missing in the generated apply configurations
+		kubefip_clientset := kubefipfake.NewSimpleClientset(fip)
+
+		kubefipConfig := config.ParseKubfipConfigMap(nil)
+		kubefipConfig.OrphanedFipGracePeriod = tt.gracePeriod
+
+		start := time.Now().Truncate(time.Second)
+		o := handleOrphanedFip(*fip, &kubefipConfig, kubefip_clientset)
+
+		got, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+		if tt.wantDeleted {
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("%s: expected the fip to be deleted, got error [%v]", tt.name, err)
+			}
+			if o != (orphanedFip{}) {
+				t.Errorf("%s: expected no orphaned fip in the report, got [%+v]", tt.name, o)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: expected the fip to exist, got error [%s]", tt.name, err.Error())
+		}
+
+		if o.Namespace != fip.ObjectMeta.Namespace || o.Name != fip.ObjectMeta.Name || o.IPAddress != fip.Spec.IPAddress {
+			t.Errorf("%s: expected the fip in the report, got [%+v]", tt.name, o)
+		}
+
+		if tt.wantOrphanedSince.IsZero() {
+			if o.OrphanedSince.Before(start) {
+				t.Errorf("%s: expected orphaned since now, got [%s]", tt.name, o.OrphanedSince)
+			}
+		} else if !o.OrphanedSince.Equal(tt.wantOrphanedSince) {
+			t.Errorf("%s: expected orphaned since [%s], got [%s]", tt.name, tt.wantOrphanedSince, o.OrphanedSince)
+		}
+
+		wantAnnotation := tt.orphanedSince
+		if tt.wantMarked {
+			wantAnnotation = o.OrphanedSince.Format(time.RFC3339)
+		}
+		if got.ObjectMeta.Annotations[AnnotationOrphanedSince] != wantAnnotation {
+			t.Errorf("%s: expected %s annotation [%s], got [%s]", tt.name, AnnotationOrphanedSince, wantAnnotation,
+				got.ObjectMeta.Annotations[AnnotationOrphanedSince])
+		}
+	}
+}
+
+func TestClearOrphanedFip(t *testing.T) {
+	metrics.AppMetrics = metrics.NewMetrics(prometheus.NewRegistry())
+
+	tests := []struct {
+		name          string
+		orphanedSince string
+	}{
+		{"orphaned fip", time.Now().Format(time.RFC3339)},
+		{"fip which isn't orphaned", ""},
+	}
+
+	for _, tt := range tests {
+		fip := newTestOrphanedFip(tt.orphanedSince)
+		kubefip_clientset := kubefipfake.NewSimpleClientset(fip)
+
+		clearOrphanedFip(*fip, kubefip_clientset)
+
+		got, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("%s: expected the fip to exist, got error [%s]", tt.name, err.Error())
+		}
+
+		if _, ok := got.ObjectMeta.Annotations[AnnotationOrphanedSince]; ok {
+			t.Errorf("%s: expected the %s annotation to be removed, got [%s]", tt.name, AnnotationOrphanedSince,
+				got.ObjectMeta.Annotations[AnnotationOrphanedSince])
+		}
+	}
+}
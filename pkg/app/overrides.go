@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The cluster owners can set these annotations on their cluster object, so they don't need access to the
+// FloatingIP objects in the cluster namespaces.
+const (
+	AnnotationFipRange            = "kubefip.k8s.binbash.org/fiprange"
+	AnnotationIPAddress           = "kubefip.k8s.binbash.org/ipaddress"
+	AnnotationAllocate            = "kubefip.k8s.binbash.org/allocate"
+	AnnotationKubevip             = "kubefip.k8s.binbash.org/kubevip"
+	AnnotationKubevipChartVersion = "kubefip.k8s.binbash.org/kubevip-chart-version"
+	AnnotationLBBackend           = "kubefip.k8s.binbash.org/lb-backend"
+)
+
+type clusterOverrides struct {
+	FipRange            string
+	IPAddress           string
+	Allocate            bool
+	Kubevip             *bool
+	KubevipChartVersion string
+	LBBackend           string
+}
+
+func parseBoolAnnotation(cluster Cluster, annotation string) *bool {
+	value, ok := cluster.Annotations[annotation]
+	if !ok || value == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Errorf("(parseBoolAnnotation) error parsing annotation [%s] of cluster [%s]: %s", annotation, cluster.ClusterName, err)
+
+		return nil
+	}
+
+	return &b
+}
+
+func getClusterOverrides(cluster Cluster) clusterOverrides {
+	overrides := clusterOverrides{
+		FipRange:            cluster.Annotations[AnnotationFipRange],
+		IPAddress:           cluster.Annotations[AnnotationIPAddress],
+		Allocate:            true,
+		Kubevip:             parseBoolAnnotation(cluster, AnnotationKubevip),
+		KubevipChartVersion: cluster.Annotations[AnnotationKubevipChartVersion],
+		LBBackend:           strings.ToLower(cluster.Annotations[AnnotationLBBackend]),
+	}
+
+	if allocate := parseBoolAnnotation(cluster, AnnotationAllocate); allocate != nil {
+		overrides.Allocate = *allocate
+	}
+
+	if overrides.IPAddress != "" && net.ParseIP(overrides.IPAddress) == nil {
+		log.Errorf("(getClusterOverrides) ignoring the invalid ipaddress [%s] in the annotation of cluster [%s]",
+			overrides.IPAddress, cluster.ClusterName)
+
+		overrides.IPAddress = ""
+	}
+
+	log.Debugf("(getClusterOverrides) cluster [%s] overrides: [%+v]", cluster.ClusterName, overrides)
+
+	return overrides
+}
+
+// reconcileClusterOverrides updates the fip when the requested fiprange or ipaddress of the cluster annotations differs,
+// the fip update event releases the old ip address and allocates the new one.
+func reconcileClusterOverrides(fip KubefipV2.FloatingIP, overrides clusterOverrides, kubefip_clientset *kubefipclientset.Clientset) {
+	fipRangeChanged := overrides.FipRange != "" && overrides.FipRange != fip.Spec.FipRange
+	ipAddressChanged := overrides.IPAddress != "" && overrides.IPAddress != fip.Spec.IPAddress
+
+	if !fipRangeChanged && !ipAddressChanged {
+		return
+	}
+
+	fipObj, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("(reconcileClusterOverrides) error while fetching fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+		return
+	}
+
+	newFip := fipObj.DeepCopy()
+
+	if fipRangeChanged {
+		if _, err := kubefip_clientset.KubefipV2().FloatingIPRanges().Get(context.TODO(), overrides.FipRange, metav1.GetOptions{}); err != nil {
+			log.Errorf("(reconcileClusterOverrides) requested fiprange [%s] for cluster [%s] cannot be found: %s",
+				overrides.FipRange, fip.Spec.ClusterName, err.Error())
+
+			return
+		}
+
+		newFip.Spec.FipRange = overrides.FipRange
+		if newFip.ObjectMeta.Annotations == nil {
+			newFip.ObjectMeta.Annotations = make(map[string]string)
+		}
+		newFip.ObjectMeta.Annotations["fiprangeReason"] = "cluster annotation " + AnnotationFipRange
+	}
+
+	// a new ip address is allocated from the (new) range if there is no static ip requested
+	newFip.Spec.IPAddress = overrides.IPAddress
+
+	if _, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), newFip, metav1.UpdateOptions{}); err != nil {
+		log.Errorf("(reconcileClusterOverrides) error while updating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+		return
+	}
+
+	log.Infof("(reconcileClusterOverrides) updated fip [%s/%s] to fiprange [%s] and ipaddress [%s] from the cluster annotations",
+		newFip.ObjectMeta.Namespace, newFip.ObjectMeta.Name, newFip.Spec.FipRange, newFip.Spec.IPAddress)
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestGetClusterOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        clusterOverrides
+	}{
+		{
+			name: "no annotations",
+			want: clusterOverrides{Allocate: true},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				AnnotationFipRange:            "range1",
+				AnnotationIPAddress:           "192.168.10.10",
+				AnnotationAllocate:            "true",
+				AnnotationKubevip:             "false",
+				AnnotationKubevipChartVersion: "0.6.4",
+				AnnotationLBBackend:           "MetalLB",
+			},
+			want: clusterOverrides{FipRange: "range1", IPAddress: "192.168.10.10", Allocate: true, Kubevip: boolPtr(false),
+				KubevipChartVersion: "0.6.4", LBBackend: "metallb"},
+		},
+		{
+			name:        "allocate false",
+			annotations: map[string]string{AnnotationAllocate: "false"},
+			want:        clusterOverrides{Allocate: false},
+		},
+		{
+			name:        "allocate in another bool notation",
+			annotations: map[string]string{AnnotationAllocate: "0", AnnotationKubevip: "T"},
+			want:        clusterOverrides{Allocate: false, Kubevip: boolPtr(true)},
+		},
+		{
+			name:        "invalid bools are ignored",
+			annotations: map[string]string{AnnotationAllocate: "no", AnnotationKubevip: "yes"},
+			want:        clusterOverrides{Allocate: true},
+		},
+		{
+			name:        "empty bools are ignored",
+			annotations: map[string]string{AnnotationAllocate: "", AnnotationKubevip: ""},
+			want:        clusterOverrides{Allocate: true},
+		},
+		{
+			name:        "invalid ipaddress is ignored",
+			annotations: map[string]string{AnnotationFipRange: "range1", AnnotationIPAddress: "192.168.10"},
+			want:        clusterOverrides{FipRange: "range1", Allocate: true},
+		},
+	}
+
+	for _, tt := range tests {
+		got := getClusterOverrides(Cluster{ClusterName: "cluster1", Annotations: tt.annotations})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected overrides [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+	}
+}
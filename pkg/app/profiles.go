@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// kubevipCharts holds the chart options of the kube-vip and kube-vip-cloud-provider releases of a guest cluster
+type kubevipCharts struct {
+	Profile                          string
+	KubevipChartRef                  string
+	KubevipChartVersion              string
+	KubevipChartValues               string
+	KubevipCloudProviderChartRef     string
+	KubevipCloudProviderChartVersion string
+	KubevipCloudProviderChartValues  string
+
+	// the variables of the templated chart values
+	ValuesData chartValuesData
+}
+
+var (
+	allKubeVipProfiles      []KubefipV1.KubeVipProfile
+	allKubeVipProfilesMutex sync.Mutex
+)
+
+func gatherAllKubeVipProfiles(kubefip_clientset *kubefipclientset.Clientset) error {
+	log.Infof("(gatherAllKubeVipProfiles) gathering and storing all kubevipprofiles..")
+
+	profileList, err := kubefip_clientset.KubefipV1().KubeVipProfiles().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(profileList.Items); i++ {
+		log.Infof("(gatherAllKubeVipProfiles) kubevipprofile found: %s", profileList.Items[i].ObjectMeta.Name)
+
+		storeKubeVipProfile(&profileList.Items[i])
+	}
+
+	return nil
+}
+
+// storeKubeVipProfile adds the profile to the allKubeVipProfiles list or replaces the profile with the same name
+func storeKubeVipProfile(profile *KubefipV1.KubeVipProfile) {
+	allKubeVipProfilesMutex.Lock()
+	defer allKubeVipProfilesMutex.Unlock()
+
+	for i := 0; i < len(allKubeVipProfiles); i++ {
+		if allKubeVipProfiles[i].ObjectMeta.Name == profile.ObjectMeta.Name {
+			allKubeVipProfiles[i] = *profile.DeepCopy()
+
+			return
+		}
+	}
+
+	allKubeVipProfiles = append(allKubeVipProfiles, *profile.DeepCopy())
+}
+
+func removeKubeVipProfile(profile *KubefipV1.KubeVipProfile) {
+	allKubeVipProfilesMutex.Lock()
+	defer allKubeVipProfilesMutex.Unlock()
+
+	var profiles []KubefipV1.KubeVipProfile
+	for i := 0; i < len(allKubeVipProfiles); i++ {
+		if allKubeVipProfiles[i].ObjectMeta.Name != profile.ObjectMeta.Name {
+			profiles = append(profiles, allKubeVipProfiles[i])
+		}
+	}
+
+	allKubeVipProfiles = profiles
+}
+
+// getKubeVipProfileSpecificity returns the number of conditions of the profile, the profile with the most conditions
+// is the most specific one
+func getKubeVipProfileSpecificity(profile *KubefipV1.KubeVipProfile) int {
+	specificity := 0
+
+	if profile.Spec.HarvesterClusterName != "" {
+		specificity++
+	}
+
+	if profile.Spec.ClusterSelector != nil {
+		specificity += len(profile.Spec.ClusterSelector.MatchLabels) + len(profile.Spec.ClusterSelector.MatchExpressions)
+	}
+
+	return specificity
+}
+
+// matchKubeVipProfile checks if the clusterSelector and the harvesterClusterName of the profile match the cluster
+func matchKubeVipProfile(cluster Cluster, profile *KubefipV1.KubeVipProfile) (bool, error) {
+	if profile.Spec.ClusterSelector == nil && profile.Spec.HarvesterClusterName == "" {
+		return false, nil
+	}
+
+	if profile.Spec.HarvesterClusterName != "" && profile.Spec.HarvesterClusterName != cluster.HarvesterClusterName {
+		return false, nil
+	}
+
+	if profile.Spec.ClusterSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(profile.Spec.ClusterSelector)
+		if err != nil {
+			return false, err
+		}
+
+		if !selector.Matches(getClusterSelectorLabels(cluster)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// getKubeVipProfile returns the most specific profile which matches the cluster, the profile with the lowest name wins
+// when several profiles are equally specific
+func getKubeVipProfile(cluster Cluster) *KubefipV1.KubeVipProfile {
+	var matched []KubefipV1.KubeVipProfile
+
+	allKubeVipProfilesMutex.Lock()
+	for i := 0; i < len(allKubeVipProfiles); i++ {
+		match, err := matchKubeVipProfile(cluster, &allKubeVipProfiles[i])
+		if err != nil {
+			log.Errorf("(getKubeVipProfile) kubevipprofile [%s] has an invalid clusterSelector: %s",
+				allKubeVipProfiles[i].ObjectMeta.Name, err.Error())
+
+			continue
+		}
+
+		if match {
+			matched = append(matched, *allKubeVipProfiles[i].DeepCopy())
+		}
+	}
+	allKubeVipProfilesMutex.Unlock()
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		si := getKubeVipProfileSpecificity(&matched[i])
+		sj := getKubeVipProfileSpecificity(&matched[j])
+		if si != sj {
+			return si > sj
+		}
+
+		return matched[i].ObjectMeta.Name < matched[j].ObjectMeta.Name
+	})
+
+	if len(matched) > 1 && getKubeVipProfileSpecificity(&matched[0]) == getKubeVipProfileSpecificity(&matched[1]) {
+		log.Warnf("(getKubeVipProfile) kubevipprofiles [%s] and [%s] are equally specific for cluster [%s], using [%s]",
+			matched[0].ObjectMeta.Name, matched[1].ObjectMeta.Name, cluster.ClusterName, matched[0].ObjectMeta.Name)
+	}
+
+	return &matched[0]
+}
+
+// getKubevipCharts returns the chart options of the cluster, the options of the matching profile override the
+// kube-fip-config options
+func getKubevipCharts(cluster Cluster, kubefipConfig *config.KubefipConfigStruct) kubevipCharts {
+	charts := kubevipCharts{
+		KubevipChartRef:                  kubefipConfig.KubevipChartRef,
+		KubevipChartVersion:              kubefipConfig.KubevipChartVersion,
+		KubevipChartValues:               kubefipConfig.KubevipChartValues,
+		KubevipCloudProviderChartRef:     kubefipConfig.KubevipCloudProviderChartRef,
+		KubevipCloudProviderChartVersion: kubefipConfig.KubevipCloudProviderChartVersion,
+		KubevipCloudProviderChartValues:  kubefipConfig.KubevipCloudProviderChartValues,
+	}
+
+	profile := getKubeVipProfile(cluster)
+	if profile == nil {
+		return charts
+	}
+
+	log.Debugf("(getKubevipCharts) using kubevipprofile [%s] for cluster [%s]", profile.ObjectMeta.Name, cluster.ClusterName)
+
+	charts.Profile = profile.ObjectMeta.Name
+
+	if c := profile.Spec.Kubevip; c != nil {
+		if c.ChartRef != "" {
+			charts.KubevipChartRef = c.ChartRef
+		}
+		if c.ChartVersion != "" {
+			charts.KubevipChartVersion = c.ChartVersion
+		}
+		if c.Values != "" {
+			charts.KubevipChartValues = c.Values
+		}
+	}
+
+	if c := profile.Spec.CloudProvider; c != nil {
+		if c.ChartRef != "" {
+			charts.KubevipCloudProviderChartRef = c.ChartRef
+		}
+		if c.ChartVersion != "" {
+			charts.KubevipCloudProviderChartVersion = c.ChartVersion
+		}
+		if c.Values != "" {
+			charts.KubevipCloudProviderChartValues = c.Values
+		}
+	}
+
+	return charts
+}
+
+// getKubeVipProfilesRevision returns the specs of all the profiles, so a changed profile starts a new rollout
+func getKubeVipProfilesRevision() string {
+	allKubeVipProfilesMutex.Lock()
+	defer allKubeVipProfilesMutex.Unlock()
+
+	specs := make(map[string]KubefipV1.KubeVipProfileSpec)
+	for i := 0; i < len(allKubeVipProfiles); i++ {
+		specs[allKubeVipProfiles[i].ObjectMeta.Name] = allKubeVipProfiles[i].Spec
+	}
+
+	// the map keys are sorted by json.Marshal
+	b, err := json.Marshal(specs)
+	if err != nil {
+		return fmt.Sprintf("%d profiles", len(specs))
+	}
+
+	return string(b)
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setTestKubeVipProfiles replaces the kubevipprofiles for the duration of the test
+func setTestKubeVipProfiles(t *testing.T, profiles ...KubefipV1.KubeVipProfile) {
+	t.Helper()
+
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+
+	allKubeVipProfilesMutex.Lock()
+	oldProfiles := allKubeVipProfiles
+	allKubeVipProfiles = nil
+	allKubeVipProfilesMutex.Unlock()
+
+	for i := range profiles {
+		storeKubeVipProfile(&profiles[i])
+	}
+
+	t.Cleanup(func() {
+		allKubeVipProfilesMutex.Lock()
+		allKubeVipProfiles = oldProfiles
+		allKubeVipProfilesMutex.Unlock()
+
+		log.SetOutput(logOutput)
+	})
+}
+
+func newTestKubeVipProfile(name string, harvesterClusterName string, selector *metav1.LabelSelector) KubefipV1.KubeVipProfile {
+	return KubefipV1.KubeVipProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: KubefipV1.KubeVipProfileSpec{
+			HarvesterClusterName: harvesterClusterName,
+			ClusterSelector:      selector,
+		},
+	}
+}
+
+func TestGetKubeVipProfile(t *testing.T) {
+	prod := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	prodWeb := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod", "tier": "web"}}
+	prodRancher := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"env": "prod"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: LabelClusterSource, Operator: metav1.LabelSelectorOpIn, Values: []string{"rancher"}},
+		},
+	}
+	invalid := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Unknown"}},
+	}
+
+	cluster := Cluster{
+		Source:               "rancher",
+		ClusterName:          "cluster1",
+		HarvesterClusterName: "harvester1",
+		Labels:               map[string]string{"env": "prod", "tier": "web"},
+	}
+
+	tests := []struct {
+		name     string
+		profiles []KubefipV1.KubeVipProfile
+		want     string
+	}{
+		{
+			name:     "no profiles",
+			profiles: nil,
+		},
+		{
+			name: "profile without conditions doesn't match",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("empty", "", nil),
+			},
+		},
+		{
+			name: "not matching profiles",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("harvester2", "harvester2", nil),
+				newTestKubeVipProfile("dev", "", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}),
+				newTestKubeVipProfile("harvester1-dev", "harvester1", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}),
+			},
+		},
+		{
+			name: "harvesterClusterName only",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("harvester1", "harvester1", nil),
+			},
+			want: "harvester1",
+		},
+		{
+			name: "more matchLabels win",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("a-prod", "", prod),
+				newTestKubeVipProfile("z-prod-web", "", prodWeb),
+			},
+			want: "z-prod-web",
+		},
+		{
+			name: "matchExpressions count as conditions",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("a-prod", "", prod),
+				newTestKubeVipProfile("z-prod-rancher", "", prodRancher),
+			},
+			want: "z-prod-rancher",
+		},
+		{
+			name: "harvesterClusterName counts as a condition",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("a-prod", "", prod),
+				newTestKubeVipProfile("z-harvester1-prod", "harvester1", prod),
+			},
+			want: "z-harvester1-prod",
+		},
+		{
+			name: "lowest name wins when equally specific",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("z-prod-web", "", prodWeb),
+				newTestKubeVipProfile("m-harvester1-prod", "harvester1", prod),
+				newTestKubeVipProfile("b-prod-rancher", "", prodRancher),
+			},
+			want: "b-prod-rancher",
+		},
+		{
+			name: "invalid clusterSelector is skipped",
+			profiles: []KubefipV1.KubeVipProfile{
+				newTestKubeVipProfile("a-invalid", "harvester1", invalid),
+				newTestKubeVipProfile("z-prod", "", prod),
+			},
+			want: "z-prod",
+		},
+	}
+
+	for _, tt := range tests {
+		setTestKubeVipProfiles(t, tt.profiles...)
+
+		got := ""
+		if profile := getKubeVipProfile(cluster); profile != nil {
+			got = profile.ObjectMeta.Name
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: expected profile [%s], got [%s]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGetKubevipCharts(t *testing.T) {
+	kubefipConfig := &config.KubefipConfigStruct{
+		KubevipChartRef:                  "kube-vip/kube-vip",
+		KubevipChartVersion:              "0.6.0",
+		KubevipChartValues:               "config:\n  address: {{ .IPAddress }}\n",
+		KubevipCloudProviderChartRef:     "kube-vip/kube-vip-cloud-provider",
+		KubevipCloudProviderChartVersion: "0.2.0",
+		KubevipCloudProviderChartValues:  "",
+	}
+
+	defaults := kubevipCharts{
+		KubevipChartRef:                  "kube-vip/kube-vip",
+		KubevipChartVersion:              "0.6.0",
+		KubevipChartValues:               "config:\n  address: {{ .IPAddress }}\n",
+		KubevipCloudProviderChartRef:     "kube-vip/kube-vip-cloud-provider",
+		KubevipCloudProviderChartVersion: "0.2.0",
+	}
+
+	empty := newTestKubeVipProfile("empty", "harvester1", nil)
+
+	kubevipOnly := newTestKubeVipProfile("kubevip-only", "harvester1", nil)
+	kubevipOnly.Spec.Kubevip = &KubefipV1.KubeVipProfileChart{ChartVersion: "0.7.0"}
+
+	both := newTestKubeVipProfile("both", "harvester1", nil)
+	both.Spec.Kubevip = &KubefipV1.KubeVipProfileChart{ChartRef: "oci://registry/kube-vip", ChartVersion: "0.7.0", Values: "env: {}\n"}
+	both.Spec.CloudProvider = &KubefipV1.KubeVipProfileChart{ChartVersion: "0.3.0", Values: "cm: {}\n"}
+
+	tests := []struct {
+		name    string
+		profile *KubefipV1.KubeVipProfile
+		want    kubevipCharts
+	}{
+		{
+			name: "no matching profile",
+			want: defaults,
+		},
+		{
+			name:    "profile without chart options",
+			profile: &empty,
+			want: kubevipCharts{
+				Profile:                          "empty",
+				KubevipChartRef:                  "kube-vip/kube-vip",
+				KubevipChartVersion:              "0.6.0",
+				KubevipChartValues:               "config:\n  address: {{ .IPAddress }}\n",
+				KubevipCloudProviderChartRef:     "kube-vip/kube-vip-cloud-provider",
+				KubevipCloudProviderChartVersion: "0.2.0",
+			},
+		},
+		{
+			name:    "profile which overrides the kube-vip version",
+			profile: &kubevipOnly,
+			want: kubevipCharts{
+				Profile:                          "kubevip-only",
+				KubevipChartRef:                  "kube-vip/kube-vip",
+				KubevipChartVersion:              "0.7.0",
+				KubevipChartValues:               "config:\n  address: {{ .IPAddress }}\n",
+				KubevipCloudProviderChartRef:     "kube-vip/kube-vip-cloud-provider",
+				KubevipCloudProviderChartVersion: "0.2.0",
+			},
+		},
+		{
+			name:    "profile which overrides both charts",
+			profile: &both,
+			want: kubevipCharts{
+				Profile:                          "both",
+				KubevipChartRef:                  "oci://registry/kube-vip",
+				KubevipChartVersion:              "0.7.0",
+				KubevipChartValues:               "env: {}\n",
+				KubevipCloudProviderChartRef:     "kube-vip/kube-vip-cloud-provider",
+				KubevipCloudProviderChartVersion: "0.3.0",
+				KubevipCloudProviderChartValues:  "cm: {}\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.profile != nil {
+			setTestKubeVipProfiles(t, *tt.profile)
+		} else {
+			setTestKubeVipProfiles(t)
+		}
+
+		if got := getKubevipCharts(Cluster{HarvesterClusterName: "harvester1"}, kubefipConfig); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected charts [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestStoreKubeVipProfile(t *testing.T) {
+	setTestKubeVipProfiles(t)
+
+	revision := getKubeVipProfilesRevision()
+
+	profile := newTestKubeVipProfile("profile1", "harvester1", nil)
+	storeKubeVipProfile(&profile)
+
+	stored := getKubeVipProfilesRevision()
+	if stored == revision {
+		t.Errorf("expected the revision to change when a profile is added")
+	}
+
+	// the stored profile is a copy
+	profile.Spec.HarvesterClusterName = "harvester2"
+	if getKubeVipProfilesRevision() != stored {
+		t.Errorf("expected the revision not to change when the added profile object is changed")
+	}
+
+	storeKubeVipProfile(&profile)
+	if got := getKubeVipProfile(Cluster{HarvesterClusterName: "harvester2"}); got == nil || got.ObjectMeta.Name != "profile1" {
+		t.Errorf("expected profile [profile1] to be replaced, got [%+v]", got)
+	}
+
+	allKubeVipProfilesMutex.Lock()
+	count := len(allKubeVipProfiles)
+	allKubeVipProfilesMutex.Unlock()
+	if count != 1 {
+		t.Errorf("expected [1] profile after the replace, got [%d]", count)
+	}
+
+	removeKubeVipProfile(&profile)
+	if got := getKubeVipProfilesRevision(); got != revision {
+		t.Errorf("expected revision [%s] after the remove, got [%s]", revision, got)
+	}
+}
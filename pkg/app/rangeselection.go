@@ -0,0 +1,315 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// the labels which are added to the cluster labels before matching the fiprange clusterSelector
+const (
+	LabelHarvesterClusterName = "kubefip.k8s.binbash.org/harvester-cluster-name"
+	LabelHarvesterNetworkName = "kubefip.k8s.binbash.org/harvester-network-name"
+	LabelClusterName          = "kubefip.k8s.binbash.org/cluster-name"
+	LabelClusterSource        = "kubefip.k8s.binbash.org/cluster-source"
+)
+
+type fipRangeCandidate struct {
+	Name      string
+	Priority  int
+	Weight    int
+	Reason    string
+	Used      int
+	Available int
+	Fallback  bool
+}
+
+// matchClusterRangeRule checks if all the fields of the rule match the cluster
+func matchClusterRangeRule(cluster Cluster, rule config.ClusterRangeRule) bool {
+	if len(rule.MatchLabels) == 0 && len(rule.MatchAnnotations) == 0 && rule.Project == "" {
+		return false
+	}
+
+	for k, v := range rule.MatchLabels {
+		if cluster.Labels[k] != v {
+			return false
+		}
+	}
+
+	for k, v := range rule.MatchAnnotations {
+		if cluster.Annotations[k] != v {
+			return false
+		}
+	}
+
+	if rule.Project != "" && rule.Project != cluster.Project {
+		return false
+	}
+
+	return true
+}
+
+// getClusterSelectorLabels returns the label set which is matched against the fiprange clusterSelector
+func getClusterSelectorLabels(cluster Cluster) labels.Set {
+	clusterLabels := labels.Set{}
+
+	for k, v := range cluster.Labels {
+		clusterLabels[k] = v
+	}
+
+	// only set the fields which have a value, so the Exists and DoesNotExist operators can be used
+	fields := map[string]string{
+		LabelHarvesterClusterName: cluster.HarvesterClusterName,
+		LabelHarvesterNetworkName: cluster.HarvesterNetworkName,
+		LabelClusterName:          cluster.ClusterName,
+		LabelClusterSource:        cluster.Source,
+	}
+	for k, v := range fields {
+		if v != "" {
+			clusterLabels[k] = v
+		}
+	}
+
+	return clusterLabels
+}
+
+// getFipRangeCandidates returns all the fipranges which match the cluster
+func getFipRangeCandidates(cluster Cluster, fipRangeList *KubefipV2.FloatingIPRangeList) []fipRangeCandidate {
+	var candidates []fipRangeCandidate
+	var fallbackCandidates []fipRangeCandidate
+
+	clusterLabels := getClusterSelectorLabels(cluster)
+
+	for _, fiprange := range fipRangeList.Items {
+		if fiprange.Spec.ClusterSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(fiprange.Spec.ClusterSelector)
+			if err != nil {
+				log.Errorf("(getFipRangeCandidates) fiprange [%s] has an invalid clusterSelector: %s", fiprange.ObjectMeta.Name, err.Error())
+
+				continue
+			}
+
+			if selector.Matches(clusterLabels) {
+				candidates = append(candidates, fipRangeCandidate{
+					Name:     fiprange.ObjectMeta.Name,
+					Priority: fiprange.Spec.Priority,
+					Weight:   fiprange.Spec.Weight,
+					Reason:   fmt.Sprintf("clusterSelector [%s] matches", selector.String()),
+				})
+			} else {
+				log.Debugf("(getFipRangeCandidates) fiprange [%s] clusterSelector [%s] does not match cluster [%s]",
+					fiprange.ObjectMeta.Name, selector.String(), cluster.ClusterName)
+			}
+
+			continue
+		}
+
+		// fipranges without a clusterSelector are matched on the harvesterClusterName and harvesterNetworkName annotations
+		if cluster.HarvesterClusterName == "" || fiprange.Spec.HarvesterClusterName != cluster.HarvesterClusterName {
+			continue
+		}
+
+		if cluster.HarvesterNetworkName == "" {
+			candidates = append(candidates, fipRangeCandidate{
+				Name:     fiprange.ObjectMeta.Name,
+				Priority: fiprange.Spec.Priority,
+				Weight:   fiprange.Spec.Weight,
+				Reason:   fmt.Sprintf("harvesterClusterName annotation [%s] matches, the cluster has no network", cluster.HarvesterClusterName),
+			})
+		} else if fiprange.Spec.HarvesterNetworkName == cluster.HarvesterNetworkName {
+			candidates = append(candidates, fipRangeCandidate{
+				Name:     fiprange.ObjectMeta.Name,
+				Priority: fiprange.Spec.Priority,
+				Weight:   fiprange.Spec.Weight,
+				Reason: fmt.Sprintf("harvesterClusterName annotation [%s] and harvesterNetworkName annotation [%s] match",
+					cluster.HarvesterClusterName, cluster.HarvesterNetworkName),
+			})
+		} else {
+			// only used when there is no other match, for example due a missing harvesterNetworkName annotation
+			fallbackCandidates = append(fallbackCandidates, fipRangeCandidate{
+				Name:     fiprange.ObjectMeta.Name,
+				Priority: fiprange.Spec.Priority,
+				Weight:   fiprange.Spec.Weight,
+				Fallback: true,
+				Reason: fmt.Sprintf("harvesterClusterName annotation [%s] matches, but harvesterNetworkName annotation [%s] does not match network [%s]",
+					cluster.HarvesterClusterName, fiprange.Spec.HarvesterNetworkName, cluster.HarvesterNetworkName),
+			})
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = fallbackCandidates
+	}
+
+	return candidates
+}
+
+// selectClusterNetwork selects the interface of a multi-nic machinepool. The first interface which network has a matching
+// fiprange (or the requested fiprange) is selected, otherwise the first interface is kept.
+func selectClusterNetwork(cluster Cluster, fipRangeList *KubefipV2.FloatingIPRangeList, requestedFipRange string) Cluster {
+	if len(cluster.HarvesterNetworkNames) < 2 {
+		return cluster
+	}
+
+	for i, networkName := range cluster.HarvesterNetworkNames {
+		if networkName == "" {
+			continue
+		}
+
+		c := cluster
+		c.HarvesterNetworkName = networkName
+		c.HarvesterNetworkInterface = i
+
+		for _, candidate := range getFipRangeCandidates(c, fipRangeList) {
+			if candidate.Fallback {
+				continue
+			}
+
+			if requestedFipRange == "" || candidate.Name == requestedFipRange {
+				log.Infof("(selectClusterNetwork) selected interface [%d] with network [%s] for cluster [%s]: fiprange [%s] matches",
+					i, networkName, cluster.ClusterName, candidate.Name)
+
+				return c
+			}
+		}
+	}
+
+	log.Warnf("(selectClusterNetwork) no fiprange matches one of the networks [%s] of cluster [%s], using the first interface",
+		strings.Join(cluster.HarvesterNetworkNames, ","), cluster.ClusterName)
+
+	return cluster
+}
+
+// sortFipRangeCandidates orders the candidates by the highest priority first and then by name
+func sortFipRangeCandidates(candidates []fipRangeCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+
+		return candidates[i].Name < candidates[j].Name
+	})
+}
+
+// getFipRangeUtilization returns the used part of the range (0 is empty, 1 is full)
+func getFipRangeUtilization(candidate fipRangeCandidate) float64 {
+	total := candidate.Used + candidate.Available
+	if total == 0 {
+		return 1
+	}
+
+	return float64(candidate.Used) / float64(total)
+}
+
+// getFipRangeWeightedUsage returns the amount of used ips per weight unit, the weighted policy fills the ranges
+// in proportion to their weight by selecting the range with the lowest weighted usage
+func getFipRangeWeightedUsage(candidate fipRangeCandidate) float64 {
+	weight := candidate.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	return float64(candidate.Used) / float64(weight)
+}
+
+// selectFipRangeCandidate selects a candidate with the fipRangeSelectionPolicy. The candidates are sorted on priority
+// and name first, so the policies fall back to the priority order when the candidates are equal.
+func selectFipRangeCandidate(candidates []fipRangeCandidate, policy string) (fipRangeCandidate, string) {
+	var available []fipRangeCandidate
+
+	sortFipRangeCandidates(candidates)
+
+	// skip the ranges without free ip addresses
+	for _, candidate := range candidates {
+		candidate.Used = kubefip.IpamUsed(candidate.Name)
+		candidate.Available = kubefip.IpamAvailable(candidate.Name)
+
+		if candidate.Available == 0 {
+			log.Debugf("(selectFipRangeCandidate) skipping fiprange candidate [%s], there are no free ip addresses", candidate.Name)
+
+			continue
+		}
+
+		available = append(available, candidate)
+	}
+
+	if len(available) == 0 {
+		return fipRangeCandidate{}, ""
+	}
+
+	switch policy {
+	case "leastutilized":
+		sort.SliceStable(available, func(i, j int) bool {
+			return getFipRangeUtilization(available[i]) < getFipRangeUtilization(available[j])
+		})
+
+		return available[0], fmt.Sprintf("leastutilized policy: lowest utilization (%d used, %d available)",
+			available[0].Used, available[0].Available)
+	case "weighted":
+		sort.SliceStable(available, func(i, j int) bool {
+			return getFipRangeWeightedUsage(available[i]) < getFipRangeWeightedUsage(available[j])
+		})
+
+		return available[0], fmt.Sprintf("weighted policy: lowest usage per weight (%d used, weight %d)",
+			available[0].Used, available[0].Weight)
+	default:
+		return available[0], fmt.Sprintf("priority policy: highest priority (%d)", available[0].Priority)
+	}
+}
+
+func fipRangeExists(fipRangeList *KubefipV2.FloatingIPRangeList, fipRangeName string) bool {
+	for _, fipRange := range fipRangeList.Items {
+		if fipRange.ObjectMeta.Name == fipRangeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getFipRangeForCluster returns the fiprange for a new cluster and the reason why it's selected
+func getFipRangeForCluster(cluster Cluster, fipRangeList *KubefipV2.FloatingIPRangeList, kubefipConfig *config.KubefipConfigStruct) (string, string) {
+	// the clusterRangeRules are checked first, they are used for clusters which are not on Harvester (imported, custom, vSphere, ..)
+	for i, rule := range kubefipConfig.ClusterRangeRules {
+		if !matchClusterRangeRule(cluster, rule) {
+			continue
+		}
+
+		// a rule with a removed or misspelled fiprange falls through to the next rules and the candidates
+		if !fipRangeExists(fipRangeList, rule.FipRange) {
+			log.Errorf("(getFipRangeForCluster) fiprange [%s] of clusterRangeRule [%d] for cluster [%s] cannot be found, skipping the rule",
+				rule.FipRange, i, cluster.ClusterName)
+
+			continue
+		}
+
+		return rule.FipRange, fmt.Sprintf("clusterRangeRule [%d] matches", i)
+	}
+
+	candidates := getFipRangeCandidates(cluster, fipRangeList)
+	if len(candidates) == 0 {
+		return "", ""
+	}
+
+	for _, candidate := range candidates {
+		log.Debugf("(getFipRangeForCluster) fiprange candidate [%s] with priority [%d] and weight [%d] for cluster [%s]: %s",
+			candidate.Name, candidate.Priority, candidate.Weight, cluster.ClusterName, candidate.Reason)
+	}
+
+	selected, policyReason := selectFipRangeCandidate(candidates, kubefipConfig.FipRangeSelectionPolicy)
+	if selected.Name == "" {
+		log.Errorf("(getFipRangeForCluster) all %d fiprange candidates for cluster [%s] are full", len(candidates), cluster.ClusterName)
+
+		return "", ""
+	}
+
+	return selected.Name, fmt.Sprintf("%s, %s, %d candidates", selected.Reason, policyReason, len(candidates))
+}
@@ -0,0 +1,512 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type testIpamRange struct {
+	name string
+	size int
+	used int
+}
+
+// setupTestIpam replaces the ipam with one that has a subnet of size addresses for every range, of which used
+// addresses are allocated
+func setupTestIpam(t *testing.T, ranges ...testIpamRange) {
+	t.Helper()
+
+	kubefip.InitIpam()
+	t.Cleanup(kubefip.InitIpam)
+
+	for i, r := range ranges {
+		if err := kubefip.IpamNewSubnet(r.name, fmt.Sprintf("10.0.%d.0/24", i), fmt.Sprintf("10.0.%d.1", i),
+			fmt.Sprintf("10.0.%d.%d", i, r.size)); err != nil {
+			t.Fatalf("error creating the ipam subnet of range [%s]: %s", r.name, err.Error())
+		}
+
+		for j := 0; j < r.used; j++ {
+			if _, err := kubefip.IpamGetIP(r.name, ""); err != nil {
+				t.Fatalf("error allocating an ip address in range [%s]: %s", r.name, err.Error())
+			}
+		}
+	}
+}
+
+func newTestFipRange(name string, harvesterClusterName string, harvesterNetworkName string, selector *metav1.LabelSelector) KubefipV2.FloatingIPRange {
+	return KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: KubefipV2.FloatingIPRangeSpec{
+			HarvesterClusterName: harvesterClusterName,
+			HarvesterNetworkName: harvesterNetworkName,
+			ClusterSelector:      selector,
+		},
+	}
+}
+
+func getCandidateNames(candidates []fipRangeCandidate) []string {
+	names := []string{}
+	for _, candidate := range candidates {
+		names = append(names, candidate.Name)
+	}
+
+	return names
+}
+
+func TestMatchClusterRangeRule(t *testing.T) {
+	cluster := Cluster{
+		ClusterName: "cluster1",
+		Project:     "p-abc",
+		Labels:      map[string]string{"env": "prod", "zone": "a"},
+		Annotations: map[string]string{"team": "infra"},
+	}
+
+	tests := []struct {
+		name string
+		rule config.ClusterRangeRule
+		want bool
+	}{
+		{"empty rule", config.ClusterRangeRule{FipRange: "range1"}, false},
+		{"label matches", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "prod"}}, true},
+		{"all labels match", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "prod", "zone": "a"}}, true},
+		{"label value differs", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "dev"}}, false},
+		{"label missing", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "prod", "rack": "1"}}, false},
+		{"annotation matches", config.ClusterRangeRule{MatchAnnotations: map[string]string{"team": "infra"}}, true},
+		{"annotation differs", config.ClusterRangeRule{MatchAnnotations: map[string]string{"team": "apps"}}, false},
+		{"project matches", config.ClusterRangeRule{Project: "p-abc"}, true},
+		{"project differs", config.ClusterRangeRule{Project: "p-xyz"}, false},
+		{"all fields match", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "prod"},
+			MatchAnnotations: map[string]string{"team": "infra"}, Project: "p-abc"}, true},
+		{"one field differs", config.ClusterRangeRule{MatchLabels: map[string]string{"env": "prod"},
+			MatchAnnotations: map[string]string{"team": "infra"}, Project: "p-xyz"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchClusterRangeRule(cluster, tt.rule); got != tt.want {
+			t.Errorf("%s: expected [%t], got [%t]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGetFipRangeCandidates(t *testing.T) {
+	harvesterCluster := Cluster{
+		Source:               "rancher",
+		ClusterName:          "cluster1",
+		HarvesterClusterName: "harvester1",
+		HarvesterNetworkName: "vlan10",
+		Labels:               map[string]string{"env": "prod"},
+	}
+
+	importedCluster := Cluster{
+		Source:      "rancher",
+		ClusterName: "imported1",
+		Labels:      map[string]string{"env": "prod"},
+	}
+
+	tests := []struct {
+		name      string
+		cluster   Cluster
+		fipRanges []KubefipV2.FloatingIPRange
+		want      []string
+		fallback  bool
+	}{
+		{
+			name:    "harvester cluster and network match",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("vlan10", "harvester1", "vlan10", nil),
+				newTestFipRange("vlan20", "harvester1", "vlan20", nil),
+				newTestFipRange("other", "harvester2", "vlan10", nil),
+			},
+			want: []string{"vlan10"},
+		},
+		{
+			name:    "cluster without a network matches all the ranges of the harvester cluster",
+			cluster: Cluster{ClusterName: "cluster1", HarvesterClusterName: "harvester1"},
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("vlan10", "harvester1", "vlan10", nil),
+				newTestFipRange("vlan20", "harvester1", "vlan20", nil),
+			},
+			want: []string{"vlan10", "vlan20"},
+		},
+		{
+			name:    "network mismatch is a fallback",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("vlan20", "harvester1", "vlan20", nil),
+				newTestFipRange("vlan30", "harvester1", "vlan30", nil),
+			},
+			want:     []string{"vlan20", "vlan30"},
+			fallback: true,
+		},
+		{
+			name:    "fallback is not used when a selector matches",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("vlan20", "harvester1", "vlan20", nil),
+				newTestFipRange("prod", "", "", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}),
+			},
+			want: []string{"prod"},
+		},
+		{
+			name:    "selector doesn't match",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("dev", "", "", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}),
+			},
+			want: []string{},
+		},
+		{
+			name:    "selector on the cluster fields",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("by-network", "", "", &metav1.LabelSelector{MatchLabels: map[string]string{
+					LabelHarvesterClusterName: "harvester1",
+					LabelHarvesterNetworkName: "vlan10",
+				}}),
+				newTestFipRange("by-source", "", "", &metav1.LabelSelector{MatchLabels: map[string]string{LabelClusterSource: "capi"}}),
+			},
+			want: []string{"by-network"},
+		},
+		{
+			name:    "selector on a cluster which is not on harvester",
+			cluster: importedCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("imported", "", "", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: LabelHarvesterClusterName, Operator: metav1.LabelSelectorOpDoesNotExist},
+				}}),
+				newTestFipRange("harvester", "", "", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: LabelHarvesterClusterName, Operator: metav1.LabelSelectorOpExists},
+				}}),
+				newTestFipRange("vlan10", "harvester1", "vlan10", nil),
+			},
+			want: []string{"imported"},
+		},
+		{
+			name:    "invalid selector is skipped",
+			cluster: harvesterCluster,
+			fipRanges: []KubefipV2.FloatingIPRange{
+				newTestFipRange("invalid", "", "", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: "Unknown"},
+				}}),
+				newTestFipRange("vlan10", "harvester1", "vlan10", nil),
+			},
+			want: []string{"vlan10"},
+		},
+	}
+
+	for _, tt := range tests {
+		candidates := getFipRangeCandidates(tt.cluster, &KubefipV2.FloatingIPRangeList{Items: tt.fipRanges})
+
+		if got := getCandidateNames(candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected candidates %v, got %v", tt.name, tt.want, got)
+		}
+
+		for _, candidate := range candidates {
+			if candidate.Fallback != tt.fallback {
+				t.Errorf("%s: expected candidate [%s] fallback [%t], got [%t]", tt.name, candidate.Name, tt.fallback, candidate.Fallback)
+			}
+		}
+	}
+}
+
+func TestSelectFipRangeCandidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		ranges     []testIpamRange
+		candidates []fipRangeCandidate
+		want       string
+	}{
+		{
+			name:       "priority policy selects the highest priority",
+			policy:     "priority",
+			ranges:     []testIpamRange{{"a", 10, 0}, {"b", 10, 9}},
+			candidates: []fipRangeCandidate{{Name: "a", Priority: 1}, {Name: "b", Priority: 5}},
+			want:       "b",
+		},
+		{
+			name:       "priority tie selects the first name",
+			policy:     "priority",
+			ranges:     []testIpamRange{{"a", 10, 9}, {"b", 10, 0}},
+			candidates: []fipRangeCandidate{{Name: "b"}, {Name: "a"}},
+			want:       "a",
+		},
+		{
+			name:       "priority policy skips a full range",
+			policy:     "priority",
+			ranges:     []testIpamRange{{"a", 4, 4}, {"b", 10, 0}},
+			candidates: []fipRangeCandidate{{Name: "a", Priority: 10}, {Name: "b"}},
+			want:       "b",
+		},
+		{
+			name:       "unknown policy uses the priority policy",
+			policy:     "",
+			ranges:     []testIpamRange{{"a", 10, 0}, {"b", 10, 9}},
+			candidates: []fipRangeCandidate{{Name: "a"}, {Name: "b", Priority: 1}},
+			want:       "b",
+		},
+		{
+			name:       "all ranges are full",
+			policy:     "priority",
+			ranges:     []testIpamRange{{"a", 2, 2}, {"b", 3, 3}},
+			candidates: []fipRangeCandidate{{Name: "a"}, {Name: "b"}},
+			want:       "",
+		},
+		{
+			name:       "range without ipam subnet is full",
+			policy:     "priority",
+			ranges:     []testIpamRange{{"b", 3, 0}},
+			candidates: []fipRangeCandidate{{Name: "a", Priority: 1}, {Name: "b"}},
+			want:       "b",
+		},
+		{
+			name:       "leastutilized policy selects the lowest utilization",
+			policy:     "leastutilized",
+			ranges:     []testIpamRange{{"a", 10, 5}, {"b", 4, 1}},
+			candidates: []fipRangeCandidate{{Name: "a", Priority: 1}, {Name: "b"}},
+			want:       "b",
+		},
+		{
+			name:       "leastutilized tie selects the highest priority",
+			policy:     "leastutilized",
+			ranges:     []testIpamRange{{"a", 10, 5}, {"b", 4, 2}},
+			candidates: []fipRangeCandidate{{Name: "a"}, {Name: "b", Priority: 1}},
+			want:       "b",
+		},
+		{
+			name:       "leastutilized policy skips a full range",
+			policy:     "leastutilized",
+			ranges:     []testIpamRange{{"a", 10, 9}, {"b", 4, 4}},
+			candidates: []fipRangeCandidate{{Name: "a"}, {Name: "b"}},
+			want:       "a",
+		},
+		{
+			name:       "weighted policy selects the lowest usage per weight",
+			policy:     "weighted",
+			ranges:     []testIpamRange{{"a", 10, 1}, {"b", 10, 2}},
+			candidates: []fipRangeCandidate{{Name: "a", Weight: 1, Priority: 1}, {Name: "b", Weight: 3}},
+			want:       "b",
+		},
+		{
+			name:       "weighted tie selects the highest priority",
+			policy:     "weighted",
+			ranges:     []testIpamRange{{"a", 10, 1}, {"b", 10, 2}},
+			candidates: []fipRangeCandidate{{Name: "a", Weight: 1}, {Name: "b", Weight: 2, Priority: 1}},
+			want:       "b",
+		},
+		{
+			name:       "weighted policy counts a zero weight as weight 1",
+			policy:     "weighted",
+			ranges:     []testIpamRange{{"a", 10, 1}, {"b", 10, 3}},
+			candidates: []fipRangeCandidate{{Name: "a", Weight: 0}, {Name: "b", Weight: 2}},
+			want:       "a",
+		},
+		{
+			name:       "weighted policy with empty zero weight ranges",
+			policy:     "weighted",
+			ranges:     []testIpamRange{{"a", 10, 0}, {"b", 10, 0}},
+			candidates: []fipRangeCandidate{{Name: "b"}, {Name: "a"}},
+			want:       "a",
+		},
+		{
+			name:       "weighted policy skips a full range",
+			policy:     "weighted",
+			ranges:     []testIpamRange{{"a", 2, 2}, {"b", 10, 8}},
+			candidates: []fipRangeCandidate{{Name: "a", Weight: 10}, {Name: "b", Weight: 1}},
+			want:       "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestIpam(t, tt.ranges...)
+
+			selected, reason := selectFipRangeCandidate(tt.candidates, tt.policy)
+			if selected.Name != tt.want {
+				t.Errorf("expected fiprange [%s], got [%s] (%s)", tt.want, selected.Name, reason)
+			}
+
+			if tt.want != "" && reason == "" {
+				t.Errorf("expected a reason for fiprange [%s]", selected.Name)
+			}
+		})
+	}
+}
+
+func TestGetFipRangeForCluster(t *testing.T) {
+	cluster := Cluster{
+		Source:               "rancher",
+		ClusterName:          "cluster1",
+		HarvesterClusterName: "harvester1",
+		HarvesterNetworkName: "vlan10",
+		Labels:               map[string]string{"env": "prod"},
+	}
+
+	fipRangeList := &KubefipV2.FloatingIPRangeList{Items: []KubefipV2.FloatingIPRange{
+		newTestFipRange("vlan10", "harvester1", "vlan10", nil),
+		newTestFipRange("vlan10-extra", "harvester1", "vlan10", nil),
+		newTestFipRange("imported", "", "", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "imported"}}),
+	}}
+	fipRangeList.Items[1].Spec.Priority = 1
+
+	tests := []struct {
+		name   string
+		rules  []config.ClusterRangeRule
+		ranges []testIpamRange
+		want   string
+	}{
+		{
+			name:   "candidate with the highest priority",
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 10, 0}},
+			want:   "vlan10-extra",
+		},
+		{
+			name:   "rule is checked before the candidates",
+			rules:  []config.ClusterRangeRule{{FipRange: "imported", MatchLabels: map[string]string{"env": "prod"}}},
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 10, 0}, {"imported", 10, 0}},
+			want:   "imported",
+		},
+		{
+			name:   "rule which doesn't match",
+			rules:  []config.ClusterRangeRule{{FipRange: "imported", MatchLabels: map[string]string{"env": "dev"}}},
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 10, 0}, {"imported", 10, 0}},
+			want:   "vlan10-extra",
+		},
+		{
+			name: "rule with a missing fiprange falls through to the next rule",
+			rules: []config.ClusterRangeRule{
+				{FipRange: "removed", MatchLabels: map[string]string{"env": "prod"}},
+				{FipRange: "imported", MatchLabels: map[string]string{"env": "prod"}},
+			},
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 10, 0}, {"imported", 10, 0}},
+			want:   "imported",
+		},
+		{
+			name:   "rule with a missing fiprange falls through to the candidates",
+			rules:  []config.ClusterRangeRule{{FipRange: "removed", MatchLabels: map[string]string{"env": "prod"}}},
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 10, 0}},
+			want:   "vlan10-extra",
+		},
+		{
+			name:   "full candidate is skipped",
+			ranges: []testIpamRange{{"vlan10", 10, 0}, {"vlan10-extra", 2, 2}},
+			want:   "vlan10",
+		},
+		{
+			name:   "all candidates are full",
+			ranges: []testIpamRange{{"vlan10", 2, 2}, {"vlan10-extra", 2, 2}},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestIpam(t, tt.ranges...)
+
+			kubefipConfig := config.KubefipConfigStruct{ClusterRangeRules: tt.rules, FipRangeSelectionPolicy: "priority"}
+
+			got, reason := getFipRangeForCluster(cluster, fipRangeList, &kubefipConfig)
+			if got != tt.want {
+				t.Errorf("expected fiprange [%s], got [%s] (%s)", tt.want, got, reason)
+			}
+		})
+	}
+
+	noMatch := Cluster{ClusterName: "cluster2", HarvesterClusterName: "harvester2"}
+	if got, _ := getFipRangeForCluster(noMatch, fipRangeList, &config.KubefipConfigStruct{}); got != "" {
+		t.Errorf("expected no fiprange for a cluster without candidates, got [%s]", got)
+	}
+}
+
+// TestSelectFipRangeCandidateRace selects a fiprange while the fip informer allocates and releases ip addresses in the
+// ipam prefixes, run it with `go test -race`
+func TestSelectFipRangeCandidateRace(t *testing.T) {
+	setupTestIpam(t, testIpamRange{"a", 100, 0}, testIpamRange{"b", 100, 0})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			for i := 0; i < 200; i++ {
+				ip, err := kubefip.IpamGetIP(name, "")
+				if err != nil {
+					t.Errorf("error allocating an ip address in range [%s]: %s", name, err.Error())
+
+					return
+				}
+				if err := kubefip.IpamReleaseIP(name, ip); err != nil {
+					t.Errorf("error releasing ip address [%s] in range [%s]: %s", ip, name, err.Error())
+
+					return
+				}
+			}
+		}(name)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			candidates := []fipRangeCandidate{{Name: "a", Weight: 1}, {Name: "b", Weight: 2}}
+			if selected, _ := selectFipRangeCandidate(candidates, "weighted"); selected.Name == "" {
+				t.Fatalf("expected a fiprange to be selected")
+			}
+		}
+	}
+}
+
+func TestSelectClusterNetwork(t *testing.T) {
+	fipRangeList := &KubefipV2.FloatingIPRangeList{Items: []KubefipV2.FloatingIPRange{
+		newTestFipRange("vlan20", "harvester1", "vlan20", nil),
+		newTestFipRange("vlan30", "harvester1", "vlan30", nil),
+	}}
+
+	tests := []struct {
+		name              string
+		networkNames      []string
+		requestedFipRange string
+		wantNetwork       string
+		wantInterface     int
+	}{
+		{"single interface", []string{"vlan10"}, "", "vlan10", 0},
+		{"first interface matches", []string{"vlan20", "vlan30"}, "", "vlan20", 0},
+		{"second interface matches", []string{"vlan10", "vlan30"}, "", "vlan30", 1},
+		{"requested fiprange on the second interface", []string{"vlan20", "vlan30"}, "vlan30", "vlan30", 1},
+		{"interface without network is skipped", []string{"", "vlan20"}, "", "vlan20", 1},
+		{"no interface matches", []string{"vlan10", "vlan40"}, "", "vlan10", 0},
+		{"requested fiprange doesn't match", []string{"vlan10", "vlan20"}, "vlan30", "vlan10", 0},
+	}
+
+	for _, tt := range tests {
+		cluster := Cluster{
+			ClusterName:           "cluster1",
+			HarvesterClusterName:  "harvester1",
+			HarvesterNetworkName:  tt.networkNames[0],
+			HarvesterNetworkNames: tt.networkNames,
+		}
+
+		got := selectClusterNetwork(cluster, fipRangeList, tt.requestedFipRange)
+		if got.HarvesterNetworkName != tt.wantNetwork || got.HarvesterNetworkInterface != tt.wantInterface {
+			t.Errorf("%s: expected network [%s] on interface [%d], got [%s] on interface [%d]", tt.name, tt.wantNetwork,
+				tt.wantInterface, got.HarvesterNetworkName, got.HarvesterNetworkInterface)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// the fips of the guest clusters which are reconciled right away instead of at the next operateTicker tick, the
+// periodic guest cluster cycle stays as a resync for the missed events. The fips are queued with their
+// <namespace>/<name> key, the new cluster namespaces with their name, a namespace name can't contain a slash.
+var guestClusterQueue workqueue.TypedRateLimitingInterface[string]
+
+// the number of times a new cluster namespace is requeued while its cluster objects are not complete, with the rate
+// limiter this is about 5 minutes
+const maxClusterNamespaceRequeues = 10
+
+// enqueueGuestCluster schedules the reconciliation of the guest cluster of the fip, the fip is queued only once when
+// it's enqueued more than once before a worker picks it up
+func enqueueGuestCluster(fip *KubefipV2.FloatingIP, reason string) {
+	if guestClusterQueue == nil {
+		return
+	}
+
+	fipKey := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	log.Debugf("(enqueueGuestCluster) queue cluster [%s] of fip [%s] for reconciliation: %s", fip.Spec.ClusterName, fipKey, reason)
+
+	guestClusterQueue.Add(fipKey)
+}
+
+// enqueueGuestClustersInNamespace schedules the reconciliation of the guest clusters of all fips in the namespace
+func enqueueGuestClustersInNamespace(namespace string, reason string) {
+	allFipsCopy := kubefip.GetAllFips()
+
+	for i := 0; i < len(allFipsCopy); i++ {
+		if allFipsCopy[i].ObjectMeta.Namespace == namespace {
+			enqueueGuestCluster(&allFipsCopy[i], reason)
+		}
+	}
+}
+
+// enqueueClusterNamespace schedules the fip creation of the cluster of a new cluster namespace
+func enqueueClusterNamespace(namespace string) {
+	if guestClusterQueue == nil {
+		return
+	}
+
+	log.Debugf("(enqueueClusterNamespace) queue cluster namespace [%s] for the fip creation", namespace)
+
+	guestClusterQueue.Add(namespace)
+}
+
+// processClusterNamespace creates the fip of a new cluster namespace, the namespace is requeued until its cluster
+// objects are complete
+func processClusterNamespace(namespace string, kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset,
+	kubefipConfig *config.KubefipConfigStruct) {
+	final := guestClusterQueue.NumRequeues(namespace) >= maxClusterNamespaceRequeues
+
+	if !resolveClusterNamespace(namespace, final, kubefip_clientset, k8s_clientset, getKubefipConfig(kubefipConfig)) {
+		log.Debugf("(processClusterNamespace) cluster of namespace [%s] is not complete yet, requeue it", namespace)
+
+		guestClusterQueue.AddRateLimited(namespace)
+
+		return
+	}
+
+	if final {
+		log.Debugf("(processClusterNamespace) stopped requeueing cluster namespace [%s]", namespace)
+	}
+
+	guestClusterQueue.Forget(namespace)
+}
+
+// getFipByKey returns the fip with the <namespace>/<name> key from the fips list
+func getFipByKey(fipKey string) (KubefipV2.FloatingIP, bool) {
+	allFipsCopy := kubefip.GetAllFips()
+
+	for i := 0; i < len(allFipsCopy); i++ {
+		if fmt.Sprintf("%s/%s", allFipsCopy[i].ObjectMeta.Namespace, allFipsCopy[i].ObjectMeta.Name) == fipKey {
+			return allFipsCopy[i], true
+		}
+	}
+
+	return KubefipV2.FloatingIP{}, false
+}
+
+func processGuestClusterQueue(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset,
+	kubefipConfig *config.KubefipConfigStruct) bool {
+	fipKey, quit := guestClusterQueue.Get()
+	if quit {
+		return false
+	}
+	defer guestClusterQueue.Done(fipKey)
+
+	if !strings.Contains(fipKey, "/") {
+		processClusterNamespace(fipKey, kubefip_clientset, k8s_clientset, kubefipConfig)
+
+		return true
+	}
+
+	fip, found := getFipByKey(fipKey)
+	if !found || fip.Spec.IPAddress == "" {
+		log.Debugf("(processGuestClusterQueue) fip [%s] is removed or has no ip address yet, nothing to reconcile", fipKey)
+
+		guestClusterQueue.Forget(fipKey)
+
+		return true
+	}
+
+	log.Infof("(processGuestClusterQueue) reconciling cluster [%s] of fip [%s] with ip address [%s]",
+		fip.Spec.ClusterName, fipKey, fip.Spec.IPAddress)
+
+	kubefipConfig = getKubefipConfig(kubefipConfig)
+
+	_, err := operateGuestClusterWithTimeout(fip, kubefip_clientset, kubefipConfig)
+	if errors.Is(err, errGuestClusterInProgress) {
+		// the cluster is operated by the guest cluster cycle or a timed out worker, try again when it's finished
+		log.Debugf("(processGuestClusterQueue) cluster [%s] of fip [%s] is still operated, requeue it", fip.Spec.ClusterName, fipKey)
+
+		guestClusterQueue.AddRateLimited(fipKey)
+
+		return true
+	}
+
+	// orphaned fips are handled and reported by the guest cluster cycle
+	guestClusterQueue.Forget(fipKey)
+
+	// the reconciled cluster can complete the current wave of the kube-vip rollout
+	progressRollout(kubefipConfig, time.Time{})
+
+	return true
+}
+
+// startGuestClusterReconciler starts the workers which reconcile the queued guest clusters and cluster namespaces
+func startGuestClusterReconciler(kubefip_clientset *kubefipclientset.Clientset, k8s_clientset *kubernetes.Clientset,
+	kubefipConfig *config.KubefipConfigStruct) {
+	workers := max(kubefipConfig.GuestClusterWorkers, 1)
+
+	log.Infof("(startGuestClusterReconciler) start %d workers for the event driven guest cluster reconciliation", workers)
+
+	guestClusterQueue = workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Second, time.Minute),
+		workqueue.TypedRateLimitingQueueConfig[string]{Name: "guestclusters"},
+	)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for processGuestClusterQueue(kubefip_clientset, k8s_clientset, kubefipConfig) {
+			}
+		}()
+	}
+}
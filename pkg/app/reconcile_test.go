@@ -0,0 +1,187 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// setTestGuestClusterQueue replaces the guest cluster queue for the duration of the test, the rate limited keys are
+// added after an hour so they stay out of the queue during the test
+func setTestGuestClusterQueue(t *testing.T) workqueue.TypedRateLimitingInterface[string] {
+	t.Helper()
+
+	oldQueue := guestClusterQueue
+	guestClusterQueue = workqueue.NewTypedRateLimitingQueue(workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Hour, time.Hour))
+	queue := guestClusterQueue
+
+	t.Cleanup(func() {
+		queue.ShutDown()
+		guestClusterQueue = oldQueue
+	})
+
+	return queue
+}
+
+// addTestFips adds the fips to the fips list for the duration of the test
+func addTestFips(t *testing.T, fips ...KubefipV2.FloatingIP) {
+	t.Helper()
+
+	for i := range fips {
+		if err := kubefip.UpdateAllFips(&fips[i]); err != nil {
+			t.Fatalf("error adding fip: %s", err.Error())
+		}
+	}
+
+	t.Cleanup(func() {
+		for i := range fips {
+			_ = kubefip.RemoveFipFromAllFips(&fips[i])
+		}
+	})
+}
+
+func newTestQueueFip(namespace string, clusterName string, ipAddress string) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName + "-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: clusterName, FipRange: "range1", IPAddress: ipAddress},
+	}
+}
+
+// getQueuedKeys takes all the keys from the queue
+func getQueuedKeys(queue workqueue.TypedRateLimitingInterface[string]) []string {
+	keys := []string{}
+	for queue.Len() > 0 {
+		key, _ := queue.Get()
+		queue.Done(key)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestEnqueueGuestCluster(t *testing.T) {
+	fip1 := newTestQueueFip("queue-ns1", "cluster1", "192.168.10.1")
+	fip2 := newTestQueueFip("queue-ns1", "cluster2", "192.168.10.2")
+	fip3 := newTestQueueFip("queue-ns2", "cluster3", "192.168.10.3")
+	addTestFips(t, fip1, fip2, fip3)
+
+	// nothing is queued before the reconciler is started
+	oldQueue := guestClusterQueue
+	guestClusterQueue = nil
+	enqueueGuestCluster(&fip1, "test")
+	enqueueClusterNamespace("queue-ns3")
+	guestClusterQueue = oldQueue
+
+	tests := []struct {
+		name     string
+		enqueue  func()
+		wantKeys []string
+	}{
+		{
+			name: "fip",
+			enqueue: func() {
+				enqueueGuestCluster(&fip1, "test")
+			},
+			wantKeys: []string{"queue-ns1/cluster1-kubevip"},
+		},
+		{
+			name: "fip enqueued twice",
+			enqueue: func() {
+				enqueueGuestCluster(&fip1, "test")
+				enqueueGuestCluster(&fip1, "test")
+			},
+			wantKeys: []string{"queue-ns1/cluster1-kubevip"},
+		},
+		{
+			name: "fips in a namespace",
+			enqueue: func() {
+				enqueueGuestClustersInNamespace("queue-ns1", "test")
+			},
+			wantKeys: []string{"queue-ns1/cluster1-kubevip", "queue-ns1/cluster2-kubevip"},
+		},
+		{
+			name: "namespace without fips",
+			enqueue: func() {
+				enqueueGuestClustersInNamespace("queue-ns3", "test")
+			},
+			wantKeys: []string{},
+		},
+		{
+			name: "cluster namespace",
+			enqueue: func() {
+				enqueueClusterNamespace("queue-ns3")
+				enqueueGuestCluster(&fip3, "test")
+			},
+			wantKeys: []string{"queue-ns2/cluster3-kubevip", "queue-ns3"},
+		},
+	}
+
+	for _, tt := range tests {
+		queue := setTestGuestClusterQueue(t)
+
+		tt.enqueue()
+
+		if keys := getQueuedKeys(queue); !reflect.DeepEqual(keys, tt.wantKeys) {
+			t.Errorf("%s: expected queued keys %v, got %v", tt.name, tt.wantKeys, keys)
+		}
+	}
+}
+
+func TestGetFipByKey(t *testing.T) {
+	addTestFips(t, newTestQueueFip("queue-ns1", "cluster1", "192.168.10.1"), newTestQueueFip("queue-ns2", "cluster1", "192.168.10.2"))
+
+	tests := []struct {
+		name          string
+		fipKey        string
+		wantFound     bool
+		wantIPAddress string
+	}{
+		{"fip", "queue-ns2/cluster1-kubevip", true, "192.168.10.2"},
+		{"fip in another namespace", "queue-ns3/cluster1-kubevip", false, ""},
+		{"namespace key", "queue-ns1", false, ""},
+	}
+
+	for _, tt := range tests {
+		fip, found := getFipByKey(tt.fipKey)
+		if found != tt.wantFound || fip.Spec.IPAddress != tt.wantIPAddress {
+			t.Errorf("%s: expected found [%t] with ip address [%s], got [%t] with [%s]", tt.name, tt.wantFound, tt.wantIPAddress,
+				found, fip.Spec.IPAddress)
+		}
+	}
+}
+
+func TestProcessGuestClusterQueueWithoutIPAddress(t *testing.T) {
+	addTestFips(t, newTestQueueFip("queue-ns1", "cluster1", ""))
+
+	kubefipConfig := config.ParseKubfipConfigMap(nil)
+
+	for _, fipKey := range []string{"queue-ns1/cluster1-kubevip", "queue-ns1/removed-kubevip"} {
+		queue := setTestGuestClusterQueue(t)
+		// a requeued fip which is picked up again
+		queue.AddRateLimited(fipKey)
+		queue.Add(fipKey)
+
+		// the fip isn't operated, so no clientsets are needed
+		if !processGuestClusterQueue(nil, nil, &kubefipConfig) {
+			t.Errorf("expected the worker to continue after fip [%s]", fipKey)
+		}
+
+		if queue.Len() != 0 || queue.NumRequeues(fipKey) != 0 {
+			t.Errorf("expected fip [%s] to be forgotten, got [%d] queued and [%d] requeues", fipKey, queue.Len(), queue.NumRequeues(fipKey))
+		}
+
+		queue.ShutDown()
+		if processGuestClusterQueue(nil, nil, &kubefipConfig) {
+			t.Errorf("expected the worker to stop after the queue is shut down")
+		}
+	}
+}
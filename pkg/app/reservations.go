@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// getFipReservationForCluster returns the active reservation for a new cluster, reservations with a matching
+// clusterName win from reservations with a matching clusterSelector
+func getFipReservationForCluster(cluster Cluster) (KubefipV1.FloatingIPReservation, string) {
+	var selectorMatches []KubefipV1.FloatingIPReservation
+
+	clusterLabels := getClusterSelectorLabels(cluster)
+
+	for _, fipReservation := range kubefip.GetAllFipReservations() {
+		if !kubefip.IsFipReservationActive(&fipReservation) {
+			continue
+		}
+
+		if fipReservation.Spec.ClusterName != "" {
+			if fipReservation.Spec.ClusterName == cluster.ClusterName {
+				return fipReservation, fmt.Sprintf("fipreservation [%s] clusterName matches", fipReservation.ObjectMeta.Name)
+			}
+
+			continue
+		}
+
+		if matchFipReservationSelector(fipReservation, clusterLabels) {
+			selectorMatches = append(selectorMatches, fipReservation)
+		}
+	}
+
+	if len(selectorMatches) == 0 {
+		return KubefipV1.FloatingIPReservation{}, ""
+	}
+
+	sort.SliceStable(selectorMatches, func(i, j int) bool {
+		return selectorMatches[i].ObjectMeta.Name < selectorMatches[j].ObjectMeta.Name
+	})
+
+	return selectorMatches[0], fmt.Sprintf("fipreservation [%s] clusterSelector matches", selectorMatches[0].ObjectMeta.Name)
+}
+
+// matchFipReservationSelector checks if the clusterSelector of the reservation matches the cluster labels, a
+// reservation without a clusterSelector matches no cluster
+func matchFipReservationSelector(fipReservation KubefipV1.FloatingIPReservation, clusterLabels labels.Set) bool {
+	if fipReservation.Spec.ClusterSelector == nil {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(fipReservation.Spec.ClusterSelector)
+	if err != nil {
+		log.Errorf("(matchFipReservationSelector) fipreservation [%s] has an invalid clusterSelector: %s",
+			fipReservation.ObjectMeta.Name, err.Error())
+
+		return false
+	}
+
+	return selector.Matches(clusterLabels)
+}
+
+// bindFipReservation records the created fip in the status of the reservation, the reserved ip address is taken over
+// by the fip add event so it stays allocated in the ipam prefix
+func bindFipReservation(fipReservation KubefipV1.FloatingIPReservation, fip KubefipV2.FloatingIP, kubefip_clientset *kubefipclientset.Clientset) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		fipReservationObj, err := kubefip_clientset.KubefipV1().FloatingIPReservations().Get(context.TODO(), fipReservation.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		newFipReservation := fipReservationObj.DeepCopy()
+		newFipReservation.Status.BoundTo = fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+		_, err = kubefip_clientset.KubefipV1().FloatingIPReservations().Update(context.TODO(), newFipReservation, metav1.UpdateOptions{})
+
+		return err
+	})
+}
+
+// expireFipReservations deletes the reservations which are not bound before their expiry time, the delete event
+// releases the reserved ip address
+func expireFipReservations(kubefip_clientset *kubefipclientset.Clientset) {
+	for _, fipReservation := range kubefip.GetAllFipReservations() {
+		if fipReservation.Status.BoundTo != "" || fipReservation.Spec.Expires == nil || kubefip.IsFipReservationActive(&fipReservation) {
+			continue
+		}
+
+		log.Infof("(expireFipReservations) fipreservation [%s] for ip address [%s] is expired since [%s], deleting it",
+			fipReservation.ObjectMeta.Name, fipReservation.Spec.IPAddress, fipReservation.Spec.Expires.String())
+
+		if err := kubefip_clientset.KubefipV1().FloatingIPReservations().Delete(context.TODO(), fipReservation.ObjectMeta.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("(expireFipReservations) error while deleting fipreservation [%s]: %s", fipReservation.ObjectMeta.Name, err.Error())
+		}
+	}
+}
+
+// isFipOfFipReservation checks if the fip is the one the operator created for the reservation: it has the reserved ip
+// address and the fipreservation annotation, and its cluster matches the clusterName or the clusterSelector
+func isFipOfFipReservation(fipReservation KubefipV1.FloatingIPReservation, fip KubefipV2.FloatingIP) bool {
+	if fip.Spec.FipRange != fipReservation.Spec.FipRange || fip.Spec.IPAddress != fipReservation.Spec.IPAddress ||
+		fip.ObjectMeta.Annotations["fipreservation"] != fipReservation.ObjectMeta.Name {
+		return false
+	}
+
+	if fipReservation.Spec.ClusterName != "" {
+		return fipReservation.Spec.ClusterName == fip.Spec.ClusterName
+	}
+
+	source, err := getClusterSource(fip)
+	if err != nil {
+		log.Errorf("(isFipOfFipReservation) %s", err.Error())
+
+		return false
+	}
+
+	cluster, err := source.GetCluster(fip)
+	if err != nil {
+		log.Errorf("(isFipOfFipReservation) cannot get the cluster of fip [%s/%s]: %s", fip.ObjectMeta.Namespace,
+			fip.ObjectMeta.Name, err.Error())
+
+		return false
+	}
+
+	return matchFipReservationSelector(fipReservation, getClusterSelectorLabels(cluster))
+}
+
+// rebindFipReservations binds the active reservations whose ip address is already used by the fip of a matching
+// cluster, the binding is missing when the operator restarted between the fip creation and bindFipReservation
+func rebindFipReservations(kubefip_clientset *kubefipclientset.Clientset) {
+	fips := kubefip.GetAllFips()
+
+	for _, fipReservation := range kubefip.GetAllFipReservations() {
+		if !kubefip.IsFipReservationActive(&fipReservation) {
+			continue
+		}
+
+		for i := range fips {
+			if !isFipOfFipReservation(fipReservation, fips[i]) {
+				continue
+			}
+
+			log.Infof("(rebindFipReservations) ip address [%s] of fipreservation [%s] is used by fip [%s/%s], binding it",
+				fipReservation.Spec.IPAddress, fipReservation.ObjectMeta.Name, fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name)
+
+			if err := bindFipReservation(fipReservation, fips[i], kubefip_clientset); err != nil {
+				log.Errorf("(rebindFipReservations) error binding fipreservation [%s] to fip [%s/%s]: %s", fipReservation.ObjectMeta.Name,
+					fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name, err.Error())
+
+				break
+			}
+
+			// the bound reservation is no longer handed to a new cluster, the update event can come later
+			fipReservation.Status.BoundTo = fmt.Sprintf("%s/%s", fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name)
+			if err := kubefip.AllocateFipReservation(&fipReservation); err != nil {
+				log.Errorf("(rebindFipReservations) error updating fipreservation [%s]: %s", fipReservation.ObjectMeta.Name, err.Error())
+			}
+
+			break
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"testing"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestReservedFip(clusterName string, ipAddress string, fipReservationName string, clusterSource string) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns1",
+			Name:        clusterName + "-kubevip",
+			Annotations: map[string]string{"fipreservation": fipReservationName, "clustersource": clusterSource},
+		},
+		Spec: KubefipV2.FloatingIPSpec{ClusterName: clusterName, FipRange: "range1", IPAddress: ipAddress},
+	}
+}
+
+func TestIsFipOfFipReservation(t *testing.T) {
+	byName := KubefipV1.FloatingIPReservation{
+		ObjectMeta: metav1.ObjectMeta{Name: "by-name"},
+		Spec:       KubefipV1.FloatingIPReservationSpec{FipRange: "range1", IPAddress: "192.168.10.20", ClusterName: "cluster1"},
+	}
+	bySelector := KubefipV1.FloatingIPReservation{
+		ObjectMeta: metav1.ObjectMeta{Name: "by-selector"},
+		Spec: KubefipV1.FloatingIPReservationSpec{FipRange: "range1", IPAddress: "192.168.10.20",
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+	}
+
+	tests := []struct {
+		name           string
+		fipReservation KubefipV1.FloatingIPReservation
+		fip            KubefipV2.FloatingIP
+		want           bool
+	}{
+		{"fip of the reservation", byName, newTestReservedFip("cluster1", "192.168.10.20", "by-name", ""), true},
+		{"fip of another cluster", byName, newTestReservedFip("cluster2", "192.168.10.20", "by-name", ""), false},
+		{"fip with another ip address", byName, newTestReservedFip("cluster1", "192.168.10.21", "by-name", ""), false},
+		{"fip without the fipreservation annotation", byName, newTestReservedFip("cluster1", "192.168.10.20", "", ""), false},
+		{"fip of another reservation", byName, newTestReservedFip("cluster1", "192.168.10.20", "other", ""), false},
+		// the cluster of the fip can't be found, so the selector can't be matched
+		{"selector with an unknown cluster source", bySelector, newTestReservedFip("cluster1", "192.168.10.20", "by-selector", "unknown"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isFipOfFipReservation(tt.fipReservation, tt.fip); got != tt.want {
+			t.Errorf("%s: expected [%t], got [%t]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestMatchFipReservationSelector(t *testing.T) {
+	cluster := Cluster{ClusterName: "cluster1", Source: ClusterSourceRancher, Labels: map[string]string{"env": "prod"}}
+
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		want     bool
+	}{
+		{"no selector", nil, false},
+		{"matching selector", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}, true},
+		{"other label value", &metav1.LabelSelector{MatchLabels: map[string]string{"env": "test"}}, false},
+		{"invalid selector", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Invalid"}}}, false},
+	}
+
+	for _, tt := range tests {
+		fipReservation := KubefipV1.FloatingIPReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: "reservation"},
+			Spec:       KubefipV1.FloatingIPReservationSpec{ClusterSelector: tt.selector},
+		}
+
+		if got := matchFipReservationSelector(fipReservation, getClusterSelectorLabels(cluster)); got != tt.want {
+			t.Errorf("%s: expected [%t], got [%t]", tt.name, tt.want, got)
+		}
+	}
+}
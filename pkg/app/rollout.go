@@ -0,0 +1,596 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the rollout state is stored next to the kube-fip-config, so a halted rollout stays halted after a restart
+	rolloutConfigMapNamespace = "kube-fip"
+	rolloutConfigMapName      = "kube-fip-rollout"
+
+	// the clusters of the current wave are checked again after this delay until they are healthy
+	rolloutHealthCheckDelay = 30 * time.Second
+
+	vipDialTimeout = 5 * time.Second
+)
+
+// rolloutState is the persisted state of the kube-vip rollout
+type rolloutState struct {
+	Revision    string    `json:"revision"`
+	Phase       string    `json:"phase"`
+	CurrentWave int       `json:"currentWave"`
+	WaveStarted time.Time `json:"waveStarted"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// rolloutCluster is the state of a guest cluster in the rollout, it's refreshed every time the cluster is operated
+type rolloutCluster struct {
+	fipKey      string
+	clusterName string
+	wave        int
+	inSync      bool
+	healthy     bool
+	upgraded    []string
+	rolledBack  bool
+	message     string
+	seen        time.Time
+}
+
+// rolloutGate tells the guest cluster operations what they may do with the kube-vip releases
+type rolloutGate struct {
+	allowUpgrade bool
+	rollback     bool
+	upgraded     []string
+}
+
+// rolloutClusterUpdate collects the results of the kube-vip releases of a guest cluster
+type rolloutClusterUpdate struct {
+	inSync   bool
+	upgraded []string
+}
+
+var (
+	rollout         = rolloutState{Phase: status.RolloutPhaseDisabled}
+	rolloutClusters = make(map[string]*rolloutCluster)
+	rolloutMutex    sync.Mutex
+
+	// the waves are only progressed when all the clusters are registered by a guest cluster cycle
+	rolloutClustersSynced bool
+
+	rollout_clientset *kubernetes.Clientset
+)
+
+// getRolloutRevision returns the hash of the options and kubevipprofiles which are rolled out, a new revision starts a
+// new rollout
+func getRolloutRevision(kubefipConfig *config.KubefipConfigStruct) string {
+	h := sha256.New()
+
+	for _, v := range []string{
+		kubefipConfig.KubevipChartRepoUrl,
+		kubefipConfig.KubevipChartRef,
+		kubefipConfig.KubevipChartVersion,
+		kubefipConfig.KubevipChartValues,
+		kubefipConfig.KubevipCloudProviderChartRef,
+		kubefipConfig.KubevipCloudProviderChartVersion,
+		kubefipConfig.KubevipCloudProviderChartValues,
+		getKubeVipProfilesRevision(),
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// getRolloutWave returns the wave of a cluster. The waves with matchLabels are matched in order on the cluster labels,
+// the other clusters are spread over the percentage waves by the hash of the fip. The clusters which are not selected
+// are in the last (remaining) wave.
+func getRolloutWave(cluster Cluster, fipKey string, waves []config.RolloutWave) int {
+	for i, wave := range waves {
+		if len(wave.MatchLabels) == 0 {
+			continue
+		}
+
+		match := true
+		for k, v := range wave.MatchLabels {
+			if cluster.Labels[k] != v {
+				match = false
+
+				break
+			}
+		}
+
+		if match {
+			return i
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(fipKey))
+	bucket := int(h.Sum32() % 100)
+
+	percentage := 0
+	for i, wave := range waves {
+		if len(wave.MatchLabels) > 0 || wave.Percentage <= 0 {
+			continue
+		}
+
+		percentage += wave.Percentage
+		if bucket < percentage {
+			return i
+		}
+	}
+
+	return len(waves)
+}
+
+func getRolloutWaveName(wave int, waves []config.RolloutWave) string {
+	if wave >= len(waves) {
+		return "remaining"
+	}
+
+	if waves[wave].Name == "" {
+		return fmt.Sprintf("wave-%d", wave)
+	}
+
+	return waves[wave].Name
+}
+
+// loadRolloutState reads the rollout state of the previous run of the operator
+func loadRolloutState(k8s_clientset *kubernetes.Clientset) {
+	rollout_clientset = k8s_clientset
+
+	cm, err := k8s_clientset.CoreV1().ConfigMaps(rolloutConfigMapNamespace).Get(context.TODO(), rolloutConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Errorf("(loadRolloutState) cannot get configmap [%s/%s]: %s", rolloutConfigMapNamespace, rolloutConfigMapName, err.Error())
+		}
+
+		return
+	}
+
+	state := rolloutState{}
+	if err := json.Unmarshal([]byte(cm.Data["state"]), &state); err != nil {
+		log.Errorf("(loadRolloutState) cannot parse the rollout state: %s", err.Error())
+
+		return
+	}
+
+	rolloutMutex.Lock()
+	rollout = state
+	rolloutMutex.Unlock()
+
+	log.Infof("(loadRolloutState) loaded kube-vip rollout [%s] in phase [%s] at wave [%d]", state.Revision, state.Phase, state.CurrentWave)
+}
+
+// saveRolloutState stores the rollout state in the kube-fip-rollout configmap, it's called with the rolloutMutex locked
+func saveRolloutState() {
+	if rollout_clientset == nil {
+		return
+	}
+
+	b, err := json.Marshal(rollout)
+	if err != nil {
+		log.Errorf("(saveRolloutState) cannot encode the rollout state: %s", err.Error())
+
+		return
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rolloutConfigMapName,
+			Namespace: rolloutConfigMapNamespace,
+			Labels:    map[string]string{"app": "kube-fip"},
+		},
+		Data: map[string]string{"state": string(b)},
+	}
+
+	_, err = rollout_clientset.CoreV1().ConfigMaps(rolloutConfigMapNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = rollout_clientset.CoreV1().ConfigMaps(rolloutConfigMapNamespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		log.Errorf("(saveRolloutState) cannot store the rollout state in configmap [%s/%s]: %s",
+			rolloutConfigMapNamespace, rolloutConfigMapName, err.Error())
+	}
+}
+
+// syncRolloutRevision starts a new rollout when the rolled out options are changed, it's called with the rolloutMutex
+// locked
+func syncRolloutRevision(kubefipConfig *config.KubefipConfigStruct) {
+	if len(kubefipConfig.KubevipRolloutWaves) == 0 {
+		if rollout.Phase != status.RolloutPhaseDisabled {
+			log.Infof("(syncRolloutRevision) no kubevipRolloutWaves configured, the kube-vip releases are upgraded on all guest clusters at once")
+
+			rollout = rolloutState{Phase: status.RolloutPhaseDisabled}
+			rolloutClusters = make(map[string]*rolloutCluster)
+			saveRolloutState()
+		}
+
+		return
+	}
+
+	revision := getRolloutRevision(kubefipConfig)
+	if rollout.Revision == revision && rollout.Phase != status.RolloutPhaseDisabled {
+		return
+	}
+
+	log.Infof("(syncRolloutRevision) starting kube-vip rollout [%s] with %d waves", revision, len(kubefipConfig.KubevipRolloutWaves)+1)
+
+	rollout = rolloutState{
+		Revision:    revision,
+		Phase:       status.RolloutPhaseProgressing,
+		CurrentWave: 0,
+		WaveStarted: time.Now(),
+	}
+
+	// the health and upgrades of the previous rollout don't count for this one
+	for _, c := range rolloutClusters {
+		c.inSync = false
+		c.healthy = false
+		c.upgraded = nil
+		c.rolledBack = false
+		c.message = ""
+	}
+
+	saveRolloutState()
+}
+
+// getRolloutGate registers the wave of the cluster and returns if its drifted kube-vip releases may be upgraded or must
+// be rolled back
+func getRolloutGate(fip KubefipV2.FloatingIP, cluster Cluster, kubefipConfig *config.KubefipConfigStruct) rolloutGate {
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+
+	syncRolloutRevision(kubefipConfig)
+
+	if rollout.Phase == status.RolloutPhaseDisabled {
+		return rolloutGate{allowUpgrade: true}
+	}
+
+	fipKey := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	c, ok := rolloutClusters[fipKey]
+	if !ok {
+		c = &rolloutCluster{fipKey: fipKey}
+		rolloutClusters[fipKey] = c
+	}
+	c.clusterName = fip.Spec.ClusterName
+	c.wave = getRolloutWave(cluster, fipKey, kubefipConfig.KubevipRolloutWaves)
+	c.seen = time.Now()
+
+	switch rollout.Phase {
+	case status.RolloutPhaseCompleted:
+		return rolloutGate{allowUpgrade: true}
+	case status.RolloutPhaseProgressing:
+		return rolloutGate{allowUpgrade: c.wave <= rollout.CurrentWave}
+	case status.RolloutPhaseHalted:
+		if kubefipConfig.KubevipRolloutRollback && c.wave == rollout.CurrentWave && len(c.upgraded) > 0 && !c.rolledBack {
+			return rolloutGate{rollback: true, upgraded: c.upgraded}
+		}
+
+		return rolloutGate{allowUpgrade: c.wave < rollout.CurrentWave}
+	}
+
+	return rolloutGate{}
+}
+
+// trackRolloutRelease adds the result of a kube-vip release to the rollout update of the cluster
+func trackRolloutRelease(update *rolloutClusterUpdate, drift *status.HelmReleaseDrift, err error) {
+	if err != nil {
+		update.inSync = false
+
+		return
+	}
+
+	if drift == nil {
+		return
+	}
+
+	if drift.Upgraded {
+		update.upgraded = append(update.upgraded, drift.Release)
+	} else if hasVersionDrift(drift) || hasValuesDrift(drift) {
+		update.inSync = false
+	}
+}
+
+// checkKubevipHealth checks if the kube-vip pods are ready and if the vip is reachable on the port of a loadbalancer
+// service which uses it
+func checkKubevipHealth(guestClient *guestClient, fip KubefipV2.FloatingIP, kubefipConfig *config.KubefipConfigStruct) (string, error) {
+	ready, err := checkPodsReady(guestClient, kubefipConfig.KubevipNamespace, fmt.Sprintf("app.kubernetes.io/name=%s", kubefipConfig.KubevipReleaseName))
+	if err != nil {
+		return "", err
+	}
+
+	services, err := guestClient.clientset.CoreV1().Services(corev1.NamespaceAll).List(guestClient.ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, svc := range services.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer || len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].Protocol != corev1.ProtocolTCP {
+			continue
+		}
+
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != fip.Spec.IPAddress {
+				continue
+			}
+
+			address := net.JoinHostPort(fip.Spec.IPAddress, fmt.Sprintf("%d", svc.Spec.Ports[0].Port))
+
+			conn, err := net.DialTimeout("tcp", address, vipDialTimeout)
+			if err != nil {
+				return "", fmt.Errorf("vip [%s] of service [%s/%s] is not reachable: %s", address, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name, err.Error())
+			}
+			conn.Close()
+
+			return fmt.Sprintf("%d pods ready, vip [%s] reachable", ready, address), nil
+		}
+	}
+
+	return fmt.Sprintf("%d pods ready, no loadbalancer service uses the vip", ready), nil
+}
+
+// recordRolloutCluster stores the result of the kube-vip releases of the cluster and checks the health of the clusters
+// in the current wave
+func recordRolloutCluster(fip KubefipV2.FloatingIP, guestClient *guestClient, kubefipConfig *config.KubefipConfigStruct, update rolloutClusterUpdate) {
+	fipKey := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	rolloutMutex.Lock()
+	c, ok := rolloutClusters[fipKey]
+	if !ok || rollout.Phase != status.RolloutPhaseProgressing {
+		rolloutMutex.Unlock()
+
+		return
+	}
+
+	c.inSync = update.inSync
+	for _, release := range update.upgraded {
+		if !slices.Contains(c.upgraded, release) {
+			c.upgraded = append(c.upgraded, release)
+		}
+	}
+
+	checkHealth := c.wave == rollout.CurrentWave && c.inSync
+	rolloutMutex.Unlock()
+
+	if !checkHealth {
+		return
+	}
+
+	message, err := checkKubevipHealth(guestClient, fip, kubefipConfig)
+
+	rolloutMutex.Lock()
+	c.healthy = err == nil
+	if err != nil {
+		c.message = err.Error()
+	} else {
+		c.message = message
+	}
+	rolloutMutex.Unlock()
+
+	if err != nil {
+		log.Warnf("(recordRolloutCluster) kube-vip in guest cluster [%s] is not healthy yet: %s", fip.Spec.ClusterName, err.Error())
+
+		// check the cluster again before the next guest cluster cycle
+		if guestClusterQueue != nil {
+			guestClusterQueue.AddAfter(fipKey, rolloutHealthCheckDelay)
+		}
+	}
+}
+
+// rollbackKubevipReleases rolls back the kube-vip releases which are upgraded by the halted rollout
+func rollbackKubevipReleases(guestClient *guestClient, kubefipConfig *config.KubefipConfigStruct, fip KubefipV2.FloatingIP, releases []string) {
+	fipKey := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+
+	helmClient, err := newGuestHelmClient(guestClient, kubefipConfig.KubevipNamespace)
+	if err != nil {
+		log.Errorf("(rollbackKubevipReleases) cannot create the helm client for guest cluster [%s]: %s", fip.Spec.ClusterName, err.Error())
+
+		return
+	}
+
+	var errs []string
+	for _, release := range releases {
+		if err := helmClient.RollbackRelease(&helmclient.ChartSpec{ReleaseName: release, Namespace: kubefipConfig.KubevipNamespace}); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", release, err.Error()))
+
+			continue
+		}
+
+		log.Infof("(rollbackKubevipReleases) rolled back %s release in guest cluster [%s]", release, fip.Spec.ClusterName)
+	}
+
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+
+	if c, ok := rolloutClusters[fipKey]; ok {
+		if len(errs) > 0 {
+			c.message = fmt.Sprintf("rollback failed: %s", strings.Join(errs, ", "))
+
+			log.Errorf("(rollbackKubevipReleases) rollback in guest cluster [%s] failed: %s", fip.Spec.ClusterName, strings.Join(errs, ", "))
+		} else {
+			c.rolledBack = true
+			c.message = "rolled back"
+		}
+	}
+}
+
+// progressRollout moves the rollout to the next wave when all the clusters of the current wave are in sync and healthy,
+// and halts it when they are not healthy within the kubevipRolloutHealthTimeout. The clusters which are not operated
+// since cycleStart (down or removed) are dropped from the rollout, a zero cycleStart keeps them.
+func progressRollout(kubefipConfig *config.KubefipConfigStruct, cycleStart time.Time) {
+	var nextWaveFips []string
+
+	rolloutMutex.Lock()
+	defer func() {
+		publishRolloutStatus(kubefipConfig)
+		rolloutMutex.Unlock()
+
+		for _, fipKey := range nextWaveFips {
+			if guestClusterQueue != nil {
+				guestClusterQueue.Add(fipKey)
+			}
+		}
+	}()
+
+	syncRolloutRevision(kubefipConfig)
+
+	if !cycleStart.IsZero() {
+		for fipKey, c := range rolloutClusters {
+			if c.seen.Before(cycleStart) {
+				delete(rolloutClusters, fipKey)
+			}
+		}
+
+		rolloutClustersSynced = true
+	}
+
+	if rollout.Phase != status.RolloutPhaseProgressing || !rolloutClustersSynced {
+		return
+	}
+
+	waves := kubefipConfig.KubevipRolloutWaves
+	changed := false
+
+	for rollout.Phase == status.RolloutPhaseProgressing {
+		var pending []string
+		for _, c := range rolloutClusters {
+			if c.wave == rollout.CurrentWave && (!c.inSync || !c.healthy) {
+				pending = append(pending, c.clusterName)
+			}
+		}
+		sort.Strings(pending)
+
+		if len(pending) == 0 {
+			log.Infof("(progressRollout) wave [%s] of kube-vip rollout [%s] is healthy", getRolloutWaveName(rollout.CurrentWave, waves), rollout.Revision)
+
+			changed = true
+
+			if rollout.CurrentWave >= len(waves) {
+				rollout.Phase = status.RolloutPhaseCompleted
+				rollout.Message = "all waves are healthy"
+
+				log.Infof("(progressRollout) kube-vip rollout [%s] is completed", rollout.Revision)
+
+				break
+			}
+
+			rollout.CurrentWave++
+			rollout.WaveStarted = time.Now()
+			rollout.Message = ""
+
+			for fipKey, c := range rolloutClusters {
+				if c.wave == rollout.CurrentWave {
+					nextWaveFips = append(nextWaveFips, fipKey)
+				}
+			}
+
+			continue
+		}
+
+		if time.Since(rollout.WaveStarted) > time.Duration(kubefipConfig.KubevipRolloutHealthTimeout)*time.Second {
+			rollout.Phase = status.RolloutPhaseHalted
+			rollout.Message = fmt.Sprintf("wave [%s] is not healthy within %d seconds, clusters: [%s]",
+				getRolloutWaveName(rollout.CurrentWave, waves), kubefipConfig.KubevipRolloutHealthTimeout, strings.Join(pending, ", "))
+
+			log.Errorf("(progressRollout) halted kube-vip rollout [%s]: %s", rollout.Revision, rollout.Message)
+
+			changed = true
+
+			// the upgraded clusters of the failed wave are rolled back when they are operated
+			if kubefipConfig.KubevipRolloutRollback {
+				for fipKey, c := range rolloutClusters {
+					if c.wave == rollout.CurrentWave && len(c.upgraded) > 0 {
+						nextWaveFips = append(nextWaveFips, fipKey)
+					}
+				}
+			}
+		}
+
+		break
+	}
+
+	if changed {
+		saveRolloutState()
+	}
+}
+
+// publishRolloutStatus updates the rollout status and metric, it's called with the rolloutMutex locked
+func publishRolloutStatus(kubefipConfig *config.KubefipConfigStruct) {
+	waves := kubefipConfig.KubevipRolloutWaves
+
+	s := status.RolloutStatus{
+		Revision: rollout.Revision,
+		Phase:    rollout.Phase,
+		Message:  rollout.Message,
+		Waves:    []status.RolloutWaveStatus{},
+		Clusters: []status.RolloutClusterStatus{},
+	}
+
+	if rollout.Phase != status.RolloutPhaseDisabled {
+		waveStarted := rollout.WaveStarted
+		s.CurrentWave = getRolloutWaveName(rollout.CurrentWave, waves)
+		s.WaveStarted = &waveStarted
+
+		for i := 0; i <= len(waves); i++ {
+			s.Waves = append(s.Waves, status.RolloutWaveStatus{Name: getRolloutWaveName(i, waves)})
+		}
+
+		for _, c := range rolloutClusters {
+			if c.wave < len(s.Waves) {
+				s.Waves[c.wave].Clusters++
+				if c.inSync {
+					s.Waves[c.wave].InSync++
+				}
+				if c.healthy {
+					s.Waves[c.wave].Healthy++
+				}
+			}
+
+			s.Clusters = append(s.Clusters, status.RolloutClusterStatus{
+				Fip:         c.fipKey,
+				ClusterName: c.clusterName,
+				Wave:        getRolloutWaveName(c.wave, waves),
+				InSync:      c.inSync,
+				Healthy:     c.healthy,
+				Upgraded:    c.upgraded,
+				RolledBack:  c.rolledBack,
+				Message:     c.message,
+			})
+		}
+
+		sort.Slice(s.Clusters, func(i, j int) bool {
+			return s.Clusters[i].Fip < s.Clusters[j].Fip
+		})
+	}
+
+	status.SetRolloutStatus(s)
+
+	metrics.SetKubevipRollout(rollout.Revision, rollout.Phase, rollout.CurrentWave)
+}
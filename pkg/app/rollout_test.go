@@ -0,0 +1,319 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/config"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+	"github.com/joeyloman/kube-fip-operator/pkg/status"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the fnv buckets of the fip keys are: cluster1 54, cluster3 24, cluster4 38, cluster5 65, cluster6 12
+var testRolloutWaves = []config.RolloutWave{
+	{Name: "canary", MatchLabels: map[string]string{"env": "dev"}},
+	{Name: "first", Percentage: 30},
+	{Percentage: 30},
+}
+
+// setTestRollout replaces the rollout state for the duration of the test, the clusters are registered as synced
+func setTestRollout(t *testing.T, state rolloutState, clusters ...*rolloutCluster) {
+	t.Helper()
+
+	metrics.AppMetrics = metrics.NewMetrics(prometheus.NewRegistry())
+
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+
+	rolloutMutex.Lock()
+	oldRollout, oldClusters, oldSynced, oldClientset := rollout, rolloutClusters, rolloutClustersSynced, rollout_clientset
+	oldStatus := status.GetRolloutStatus()
+
+	rollout = state
+	rolloutClusters = make(map[string]*rolloutCluster)
+	for _, c := range clusters {
+		rolloutClusters[c.fipKey] = c
+	}
+	rolloutClustersSynced = true
+	rollout_clientset = nil
+	rolloutMutex.Unlock()
+
+	t.Cleanup(func() {
+		rolloutMutex.Lock()
+		rollout, rolloutClusters, rolloutClustersSynced, rollout_clientset = oldRollout, oldClusters, oldSynced, oldClientset
+		rolloutMutex.Unlock()
+
+		status.SetRolloutStatus(oldStatus)
+		log.SetOutput(logOutput)
+	})
+}
+
+func newTestRolloutFip(namespace string, clusterName string) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName + "-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: clusterName},
+	}
+}
+
+func TestGetRolloutWave(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		fipKey string
+		waves  []config.RolloutWave
+		want   int
+	}{
+		{name: "matched by labels", labels: map[string]string{"env": "dev"}, fipKey: "ns2/cluster5-kubevip",
+			waves: testRolloutWaves, want: 0},
+		{name: "labels of the wave partly matched", labels: map[string]string{"tier": "dev"}, fipKey: "ns1/cluster3-kubevip",
+			waves: []config.RolloutWave{{MatchLabels: map[string]string{"env": "dev", "tier": "dev"}}, {Percentage: 30}}, want: 1},
+		{name: "first percentage wave", fipKey: "ns1/cluster3-kubevip", waves: testRolloutWaves, want: 1},
+		{name: "second percentage wave", fipKey: "ns1/cluster1-kubevip", waves: testRolloutWaves, want: 2},
+		{name: "remaining wave", fipKey: "ns2/cluster5-kubevip", waves: testRolloutWaves, want: 3},
+		{name: "percentage wave without percentage", fipKey: "ns2/cluster6-kubevip",
+			waves: []config.RolloutWave{{Name: "empty"}, {Percentage: 20}}, want: 1},
+		{name: "first matching labels wave", labels: map[string]string{"env": "dev", "tier": "web"}, fipKey: "ns2/cluster6-kubevip",
+			waves: []config.RolloutWave{{MatchLabels: map[string]string{"tier": "web"}}, {MatchLabels: map[string]string{"env": "dev"}}}, want: 0},
+		{name: "no waves", fipKey: "ns2/cluster6-kubevip", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := getRolloutWave(Cluster{Labels: tt.labels}, tt.fipKey, tt.waves); got != tt.want {
+			t.Errorf("%s: expected wave [%d], got [%d]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGetRolloutWaveName(t *testing.T) {
+	tests := []struct {
+		wave int
+		want string
+	}{
+		{0, "canary"},
+		{1, "first"},
+		{2, "wave-2"},
+		{3, "remaining"},
+	}
+
+	for _, tt := range tests {
+		if got := getRolloutWaveName(tt.wave, testRolloutWaves); got != tt.want {
+			t.Errorf("wave %d: expected name [%s], got [%s]", tt.wave, tt.want, got)
+		}
+	}
+}
+
+func TestGetRolloutGate(t *testing.T) {
+	kubefipConfig := &config.KubefipConfigStruct{KubevipRolloutWaves: testRolloutWaves, KubevipRolloutRollback: true}
+	revision := getRolloutRevision(kubefipConfig)
+
+	// cluster3 is in the first wave
+	fip := newTestRolloutFip("ns1", "cluster3")
+
+	tests := []struct {
+		name         string
+		config       *config.KubefipConfigStruct
+		state        rolloutState
+		upgraded     []string
+		rolledBack   bool
+		want         rolloutGate
+		wantPhase    string
+		wantRevision string
+	}{
+		{name: "rollout disabled", config: &config.KubefipConfigStruct{},
+			state: rolloutState{Phase: status.RolloutPhaseDisabled}, want: rolloutGate{allowUpgrade: true},
+			wantPhase: status.RolloutPhaseDisabled},
+		{name: "new revision starts at the first wave", config: kubefipConfig,
+			state: rolloutState{Revision: "old", Phase: status.RolloutPhaseCompleted, CurrentWave: 3},
+			want:  rolloutGate{}, wantPhase: status.RolloutPhaseProgressing, wantRevision: revision},
+		{name: "wave is progressing", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 1},
+			want:  rolloutGate{allowUpgrade: true}, wantPhase: status.RolloutPhaseProgressing, wantRevision: revision},
+		{name: "wave is done", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 2},
+			want:  rolloutGate{allowUpgrade: true}, wantPhase: status.RolloutPhaseProgressing, wantRevision: revision},
+		{name: "rollout completed", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseCompleted, CurrentWave: 3},
+			want:  rolloutGate{allowUpgrade: true}, wantPhase: status.RolloutPhaseCompleted, wantRevision: revision},
+		{name: "halted in the wave of the cluster", config: kubefipConfig,
+			state:    rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 1},
+			upgraded: []string{"kube-vip"}, want: rolloutGate{rollback: true, upgraded: []string{"kube-vip"}},
+			wantPhase: status.RolloutPhaseHalted, wantRevision: revision},
+		{name: "halted in the wave of the cluster which is rolled back", config: kubefipConfig,
+			state:    rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 1},
+			upgraded: []string{"kube-vip"}, rolledBack: true, want: rolloutGate{},
+			wantPhase: status.RolloutPhaseHalted, wantRevision: revision},
+		{name: "halted in the wave of the cluster without upgrades", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 1},
+			want:  rolloutGate{}, wantPhase: status.RolloutPhaseHalted, wantRevision: revision},
+		{name: "halted in a later wave", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 2},
+			want:  rolloutGate{allowUpgrade: true}, wantPhase: status.RolloutPhaseHalted, wantRevision: revision},
+		{name: "halted in an earlier wave", config: kubefipConfig,
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 0},
+			want:  rolloutGate{}, wantPhase: status.RolloutPhaseHalted, wantRevision: revision},
+	}
+
+	for _, tt := range tests {
+		setTestRollout(t, tt.state, &rolloutCluster{fipKey: "ns1/cluster3-kubevip", upgraded: tt.upgraded, rolledBack: tt.rolledBack})
+
+		if got := getRolloutGate(fip, Cluster{}, tt.config); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected gate [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+
+		rolloutMutex.Lock()
+		if rollout.Phase != tt.wantPhase || rollout.Revision != tt.wantRevision {
+			t.Errorf("%s: expected rollout [%s] in phase [%s], got [%s] in phase [%s]", tt.name, tt.wantRevision, tt.wantPhase,
+				rollout.Revision, rollout.Phase)
+		}
+
+		if tt.wantPhase != status.RolloutPhaseDisabled {
+			c := rolloutClusters["ns1/cluster3-kubevip"]
+			if c == nil || c.wave != 1 || c.clusterName != "cluster3" || c.seen.IsZero() {
+				t.Errorf("%s: expected cluster [cluster3] to be registered in wave [1], got [%+v]", tt.name, c)
+			}
+		}
+		rolloutMutex.Unlock()
+	}
+}
+
+func TestTrackRolloutRelease(t *testing.T) {
+	tests := []struct {
+		name         string
+		drift        *status.HelmReleaseDrift
+		err          error
+		wantInSync   bool
+		wantUpgraded []string
+	}{
+		{name: "release in sync", drift: &status.HelmReleaseDrift{Release: "kube-vip", DeployedChart: "kube-vip-0.6.0",
+			DesiredChart: "kube-vip-0.6.0"}, wantInSync: true},
+		{name: "release not found", wantInSync: true},
+		{name: "release upgraded", drift: &status.HelmReleaseDrift{Release: "kube-vip", DeployedChart: "kube-vip-0.5.0",
+			DesiredChart: "kube-vip-0.6.0", Upgraded: true}, wantInSync: true, wantUpgraded: []string{"kube-vip"}},
+		{name: "version drift held back", drift: &status.HelmReleaseDrift{Release: "kube-vip", DeployedChart: "kube-vip-0.5.0",
+			DesiredChart: "kube-vip-0.6.0"}},
+		{name: "values drift held back", drift: &status.HelmReleaseDrift{Release: "kube-vip", DeployedChart: "kube-vip-0.6.0",
+			DesiredChart: "kube-vip-0.6.0", ValuesDiff: []string{"config.address"}}},
+		{name: "release failed", err: errors.New("timeout")},
+	}
+
+	for _, tt := range tests {
+		update := rolloutClusterUpdate{inSync: true}
+		trackRolloutRelease(&update, tt.drift, tt.err)
+
+		if update.inSync != tt.wantInSync || !reflect.DeepEqual(update.upgraded, tt.wantUpgraded) {
+			t.Errorf("%s: expected inSync [%t] and upgraded %v, got [%t] and %v", tt.name, tt.wantInSync, tt.wantUpgraded,
+				update.inSync, update.upgraded)
+		}
+	}
+}
+
+func TestProgressRollout(t *testing.T) {
+	kubefipConfig := &config.KubefipConfigStruct{
+		KubevipRolloutWaves:         testRolloutWaves,
+		KubevipRolloutHealthTimeout: 600,
+		KubevipRolloutRollback:      true,
+	}
+	revision := getRolloutRevision(kubefipConfig)
+
+	cycleStart := time.Now()
+	hourAgo := cycleStart.Add(-time.Hour)
+
+	newCluster := func(name string, wave int, inSync bool, healthy bool) *rolloutCluster {
+		return &rolloutCluster{fipKey: "ns1/" + name + "-kubevip", clusterName: name, wave: wave, inSync: inSync,
+			healthy: healthy, seen: cycleStart.Add(time.Second)}
+	}
+	upgraded := newCluster("cluster2", 1, true, false)
+	upgraded.upgraded = []string{"kube-vip"}
+	removed := newCluster("cluster3", 1, false, false)
+	removed.seen = hourAgo
+
+	tests := []struct {
+		name        string
+		state       rolloutState
+		clusters    []*rolloutCluster
+		cycleStart  time.Time
+		notSynced   bool
+		wantPhase   string
+		wantWave    int
+		wantMessage string
+		wantQueued  []string
+	}{
+		{name: "wave not healthy yet",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 0, WaveStarted: cycleStart},
+			clusters:  []*rolloutCluster{newCluster("cluster1", 0, true, false), newCluster("cluster2", 1, false, false)},
+			wantPhase: status.RolloutPhaseProgressing, wantWave: 0, wantQueued: []string{}},
+		{name: "healthy wave moves to the next wave",
+			state: rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 0, WaveStarted: hourAgo},
+			clusters: []*rolloutCluster{newCluster("cluster1", 0, true, true), newCluster("cluster2", 1, false, false),
+				newCluster("cluster4", 2, false, false)},
+			wantPhase: status.RolloutPhaseProgressing, wantWave: 1, wantQueued: []string{"ns1/cluster2-kubevip"}},
+		{name: "empty waves are skipped",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 0, WaveStarted: hourAgo},
+			clusters:  []*rolloutCluster{newCluster("cluster1", 0, true, true), newCluster("cluster5", 3, false, false)},
+			wantPhase: status.RolloutPhaseProgressing, wantWave: 3, wantQueued: []string{"ns1/cluster5-kubevip"}},
+		{name: "healthy last wave completes the rollout",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 2, WaveStarted: hourAgo},
+			clusters:  []*rolloutCluster{newCluster("cluster1", 0, true, true), newCluster("cluster4", 2, true, true)},
+			wantPhase: status.RolloutPhaseCompleted, wantWave: 3, wantMessage: "all waves are healthy", wantQueued: []string{}},
+		{name: "unhealthy wave halts the rollout after the timeout",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 1, WaveStarted: hourAgo},
+			clusters:  []*rolloutCluster{upgraded, newCluster("cluster6", 1, false, false)},
+			wantPhase: status.RolloutPhaseHalted, wantWave: 1,
+			wantMessage: "wave [first] is not healthy within 600 seconds, clusters: [cluster2, cluster6]",
+			wantQueued:  []string{"ns1/cluster2-kubevip"}},
+		{name: "clusters which are not registered yet",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 0, WaveStarted: hourAgo},
+			notSynced: true, wantPhase: status.RolloutPhaseProgressing, wantWave: 0, wantQueued: []string{}},
+		{name: "clusters which are not operated in the cycle are removed",
+			state:      rolloutState{Revision: revision, Phase: status.RolloutPhaseProgressing, CurrentWave: 1, WaveStarted: hourAgo},
+			clusters:   []*rolloutCluster{removed, newCluster("cluster6", 1, true, true), newCluster("cluster4", 2, false, false)},
+			cycleStart: cycleStart, notSynced: true, wantPhase: status.RolloutPhaseProgressing, wantWave: 2,
+			wantQueued: []string{"ns1/cluster4-kubevip"}},
+		{name: "halted rollout stays halted",
+			state:     rolloutState{Revision: revision, Phase: status.RolloutPhaseHalted, CurrentWave: 1, WaveStarted: hourAgo},
+			clusters:  []*rolloutCluster{newCluster("cluster6", 1, true, true)},
+			wantPhase: status.RolloutPhaseHalted, wantWave: 1, wantQueued: []string{}},
+	}
+
+	for _, tt := range tests {
+		queue := setTestGuestClusterQueue(t)
+		setTestRollout(t, tt.state, tt.clusters...)
+
+		if tt.notSynced {
+			rolloutMutex.Lock()
+			rolloutClustersSynced = false
+			rolloutMutex.Unlock()
+		}
+
+		progressRollout(kubefipConfig, tt.cycleStart)
+
+		rolloutMutex.Lock()
+		if rollout.Phase != tt.wantPhase || rollout.CurrentWave != tt.wantWave || rollout.Message != tt.wantMessage {
+			t.Errorf("%s: expected phase [%s] at wave [%d] with message [%s], got [%s] at wave [%d] with message [%s]", tt.name,
+				tt.wantPhase, tt.wantWave, tt.wantMessage, rollout.Phase, rollout.CurrentWave, rollout.Message)
+		}
+		if _, ok := rolloutClusters[removed.fipKey]; ok && !tt.cycleStart.IsZero() {
+			t.Errorf("%s: expected cluster [%s] to be removed from the rollout", tt.name, removed.clusterName)
+		}
+		rolloutMutex.Unlock()
+
+		if got := getQueuedKeys(queue); !reflect.DeepEqual(got, tt.wantQueued) {
+			t.Errorf("%s: expected queued fips %v, got %v", tt.name, tt.wantQueued, got)
+		}
+
+		s := status.GetRolloutStatus()
+		if s.Phase != tt.wantPhase || s.CurrentWave != getRolloutWaveName(tt.wantWave, testRolloutWaves) ||
+			!strings.Contains(s.Message, tt.wantMessage) {
+			t.Errorf("%s: expected the rollout status to be published, got [%+v]", tt.name, s)
+		}
+	}
+}
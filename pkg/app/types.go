@@ -5,6 +5,7 @@ type HarvesterConfigStruct struct {
 	Metadata    MetadataStruct `json:"metadata"`
 	NetworkInfo string         `json:"networkInfo"`
 	NetworkName string         `json:"networkName"`
+	VmNamespace string         `json:"vmNamespace"`
 }
 
 // for mapping harvesterconfigs.rke-machine-config.cattle.io
@@ -54,8 +55,11 @@ type SpecStruct struct {
 
 // for mapping cluster.provisioning.cattle.io
 type MetadataStruct struct {
-	Name   string            `json:"name"`
-	Labels map[string]string `json:"labels"`
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	Labels            map[string]string `json:"labels"`
+	Annotations       map[string]string `json:"annotations"`
+	DeletionTimestamp string            `json:"deletionTimestamp"`
 }
 
 // for mapping cluster.provisioning.cattle.io
@@ -86,11 +90,76 @@ type ClusterManagementStruct struct {
 	Spec       SpecManagementStruct `json:"spec"`
 }
 
+// for mapping cluster.cluster.x-k8s.io
+type CapiClusterStatusStruct struct {
+	Phase string `json:"phase"`
+}
+
+// for mapping cluster.cluster.x-k8s.io
+type CapiClusterStruct struct {
+	Metadata MetadataStruct          `json:"metadata"`
+	Status   CapiClusterStatusStruct `json:"status"`
+}
+
+// for mapping cluster.cluster.x-k8s.io
+type CapiClustersStruct struct {
+	Items []CapiClusterStruct `json:"items"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmiMultusStruct struct {
+	NetworkName string `json:"networkName"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmiNetworkStruct struct {
+	Name   string          `json:"name"`
+	Multus VmiMultusStruct `json:"multus"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmiSpecStruct struct {
+	Networks []VmiNetworkStruct `json:"networks"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io, the interfaceName is reported by the qemu guest agent
+type VmiInterfaceStruct struct {
+	Name          string `json:"name"`
+	Mac           string `json:"mac"`
+	InterfaceName string `json:"interfaceName"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmiStatusStruct struct {
+	Interfaces []VmiInterfaceStruct `json:"interfaces"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmiStruct struct {
+	Metadata MetadataStruct  `json:"metadata"`
+	Spec     VmiSpecStruct   `json:"spec"`
+	Status   VmiStatusStruct `json:"status"`
+}
+
+// for mapping virtualmachineinstances.kubevirt.io
+type VmisStruct struct {
+	Items []VmiStruct `json:"items"`
+}
+
 // kube-fip internal
 type Cluster struct {
+	Source                    string            `json:"Source"`
+	Namespace                 string            `json:"Namespace"`
 	CloudCredentialSecretName string            `json:"CloudCredentialSecretName"`
 	HarvesterClusterName      string            `json:"HarvesterClusterName"`
+	HarvesterNetworkName      string            `json:"HarvesterNetworkName"`
+	HarvesterNetworkNames     []string          `json:"HarvesterNetworkNames"`
+	HarvesterNetworkInterface int               `json:"HarvesterNetworkInterface"`
+	HarvesterMacAddresses     []string          `json:"HarvesterMacAddresses"`
+	HarvesterVmNamespace      string            `json:"HarvesterVmNamespace"`
 	ClusterName               string            `json:"ClusterName"`
 	MachineConfigRefName      string            `json:"MachineConfigRefName"`
+	Project                   string            `json:"Project"`
 	Labels                    map[string]string `json:"Labels"`
+	Annotations               map[string]string `json:"Annotations"`
 }
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/configmap"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// the fip annotation which records if the lb backend is installed by the operator, an uninstalled cluster doesn't
+	// get the pool of the fip and the Harvester DaemonSet patch until it opts in again
+	AnnotationLBState  = "lbState"
+	LBStateInstalled   = "installed"
+	LBStateUninstalled = "uninstalled"
+
+	// the annotation of the kube-vip only releases, it's replaced by the lbState annotation
+	AnnotationKubevipState = "kubevipState"
+
+	// the Harvester DaemonSet annotation with the nodeSelector from before the patch
+	AnnotationOriginalNodeSelector = "kubefip.k8s.binbash.org/original-node-selector"
+)
+
+// getLBState returns the lbState annotation of the fip, or the kubevipState annotation of the fips from before the lb
+// backends existed
+func getLBState(fip KubefipV2.FloatingIP) string {
+	if state := fip.ObjectMeta.Annotations[AnnotationLBState]; state != "" {
+		return state
+	}
+
+	return fip.ObjectMeta.Annotations[AnnotationKubevipState]
+}
+
+// updateFipAnnotations sets the annotations of the fip, an annotation with an empty value is removed
+func updateFipAnnotations(fip KubefipV2.FloatingIP, annotations map[string]string, kubefip_clientset *kubefipclientset.Clientset) error {
+	fipObj, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	newFip := fipObj.DeepCopy()
+	if newFip.ObjectMeta.Annotations == nil {
+		newFip.ObjectMeta.Annotations = make(map[string]string)
+	}
+
+	for k, v := range annotations {
+		if v == "" {
+			delete(newFip.ObjectMeta.Annotations, k)
+		} else {
+			newFip.ObjectMeta.Annotations[k] = v
+		}
+	}
+
+	_, err = kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), newFip, metav1.UpdateOptions{})
+
+	return err
+}
+
+func updateFipLBState(fip KubefipV2.FloatingIP, state string, kubefip_clientset *kubefipclientset.Clientset) error {
+	return updateFipAnnotations(fip, map[string]string{
+		AnnotationLBState:      state,
+		AnnotationKubevipState: "",
+	}, kubefip_clientset)
+}
+
+// markLBInstalled records on the fip that the releases of the lb backend are installed by the operator
+func markLBInstalled(fip KubefipV2.FloatingIP, kubefip_clientset *kubefipclientset.Clientset) {
+	if fip.ObjectMeta.Annotations[AnnotationLBState] == LBStateInstalled {
+		return
+	}
+
+	if err := updateFipLBState(fip, LBStateInstalled, kubefip_clientset); err != nil {
+		log.Errorf("(markLBInstalled) error while updating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+	}
+}
+
+// uninstallHelmRelease removes a release from the guest cluster, a release which is already removed is no error
+func uninstallHelmRelease(guestClient *guestClient, namespace string, releaseName string) error {
+	helmClient, err := newGuestHelmClient(guestClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	if _, err := helmClient.GetRelease(releaseName); err != nil {
+		if err.Error() == "release: not found" {
+			return nil
+		}
+
+		return err
+	}
+
+	return helmClient.UninstallRelease(&helmclient.ChartSpec{
+		ReleaseName: releaseName,
+		Namespace:   namespace,
+		Wait:        false,
+	})
+}
+
+// removeKubevipConfigmapInGuestCluster removes the cidr-global key of the kubevip configmap when it contains the fip,
+// a configmap with another cidr is not created by the operator and is left alone. The configmap itself is only removed
+// when it has no other keys, so the keys of the guest admins are preserved. A configmap which changed in the meantime
+// is a conflict which is retried with the current configmap.
+func removeKubevipConfigmapInGuestCluster(guestClient *guestClient, fip KubefipV2.FloatingIP) (string, error) {
+	var kubevipConfigMapName string = "kubevip"
+	var kubevipConfigMapNamespace string = "kube-system"
+	var message string
+
+	err := retry.OnError(retry.DefaultBackoff, isRetryableApplyError, func() error {
+		cm, err := guestClient.clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Get(guestClient.ctx, kubevipConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				message = "configmap not found"
+
+				return nil
+			}
+
+			return err
+		}
+
+		cidr := configmap.KubevipCidr(&fip)
+		if cm.Data[configmap.KeyCidrGlobal] != cidr {
+			message = fmt.Sprintf("configmap kept, its cidr-global [%s] is not the fip", cm.Data[configmap.KeyCidrGlobal])
+
+			return nil
+		}
+
+		if len(cm.Data) == 1 && len(cm.BinaryData) == 0 {
+			// the precondition fails with a conflict when a key is added after the get
+			err := guestClient.clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Delete(guestClient.ctx, kubevipConfigMapName,
+				metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &cm.ObjectMeta.ResourceVersion}})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			message = "configmap removed"
+
+			return nil
+		}
+
+		// the test operation fails the patch when the cidr-global is changed after the get
+		patch, err := json.Marshal([]map[string]string{
+			{"op": "test", "path": "/data/" + configmap.KeyCidrGlobal, "value": cidr},
+			{"op": "remove", "path": "/data/" + configmap.KeyCidrGlobal},
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := guestClient.clientset.CoreV1().ConfigMaps(kubevipConfigMapNamespace).Patch(guestClient.ctx, kubevipConfigMapName,
+			types.JSONPatchType, patch, metav1.PatchOptions{FieldManager: configmap.FieldManager}); err != nil {
+			if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) {
+				// the cidr-global is changed or removed in the meantime, retry with the current configmap
+				return apierrors.NewConflict(corev1.Resource("configmaps"), kubevipConfigMapName, err)
+			}
+
+			return err
+		}
+
+		message = fmt.Sprintf("key [%s] removed, the other keys of the configmap are kept", configmap.KeyCidrGlobal)
+
+		return nil
+	})
+
+	return message, err
+}
+
+// restoreHarvesterKubeVipDaemonset reverts the patch of patchHarvesterKubeVipDaemonset, the nodeSelector from before
+// the patch is restored when it's recorded, otherwise only the nodeSelector of the patch is removed
+func restoreHarvesterKubeVipDaemonset(guestClient *guestClient, fip KubefipV2.FloatingIP) (string, error) {
+	var kubevipDsMapName string = "kube-vip"
+	var kubevipDsNamespace string = "kube-system"
+	var nodeSelectorName string = "node-role.kubernetes.io/harvester-kube-vip-disabled"
+
+	ds, err := guestClient.clientset.AppsV1().DaemonSets(kubevipDsNamespace).Get(guestClient.ctx, kubevipDsMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "daemonset not found", nil
+		}
+
+		return "", err
+	}
+
+	if _, ok := ds.Spec.Template.Spec.NodeSelector[nodeSelectorName]; !ok {
+		return "daemonset not patched", nil
+	}
+
+	newDs := ds.DeepCopy()
+
+	message := "daemonset nodeSelector restored"
+	if original, ok := newDs.ObjectMeta.Annotations[AnnotationOriginalNodeSelector]; ok {
+		nodeSelector := make(map[string]string)
+		if err := json.Unmarshal([]byte(original), &nodeSelector); err != nil {
+			return "", fmt.Errorf("error parsing the %s annotation: %s", AnnotationOriginalNodeSelector, err.Error())
+		}
+
+		newDs.Spec.Template.Spec.NodeSelector = nodeSelector
+		delete(newDs.ObjectMeta.Annotations, AnnotationOriginalNodeSelector)
+	} else {
+		delete(newDs.Spec.Template.Spec.NodeSelector, nodeSelectorName)
+
+		message = fmt.Sprintf("daemonset nodeSelector [%s] removed, the nodeSelector from before the patch is unknown", nodeSelectorName)
+	}
+
+	if _, err := guestClient.clientset.AppsV1().DaemonSets(kubevipDsNamespace).Update(guestClient.ctx, newDs, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+
+	log.Infof("(restoreHarvesterKubeVipDaemonset) %s in Harvester DaemonSet kube-vip in cluster [%s]", message, fip.Spec.ClusterName)
+
+	return message, nil
+}
+
+// uninstallLBBackend removes the lb backend and the Harvester DaemonSet patch from a cluster which opted out of the
+// guest installation. Every step is recorded in the cycle result and the events metric, the fip is marked as
+// uninstalled when all the steps succeeded, so failed steps are retried in the next cycle.
+func uninstallLBBackend(op *lbOperation, backend LBBackend) {
+	log.Infof("(uninstallLBBackend) cluster [%s] opted out of the %s installation, removing %s",
+		op.fip.Spec.ClusterName, backend.Name(), backend.Name())
+
+	var failed []string
+
+	if err := backend.Uninstall(op, true); err != nil {
+		failed = append(failed, err.Error())
+	}
+
+	message, err := restoreHarvesterKubeVipDaemonset(op.guestClient, op.fip)
+	if op.recordEvent(metrics.EventHarvesterDaemonsetRestore, updateMetrics, err) {
+		log.Debugf("(uninstallLBBackend) guest cluster [%s]: %s", op.fip.Spec.ClusterName, message)
+	} else {
+		failed = append(failed, metrics.EventHarvesterDaemonsetRestore)
+	}
+
+	if len(failed) > 0 {
+		op.result.Message = fmt.Sprintf("%s uninstall failed at [%s], retrying in the next cycle", backend.Name(), strings.Join(failed, ", "))
+
+		return
+	}
+
+	// the releases are gone, so there is no drift to report anymore
+	metrics.RemoveGuestClusterHelmDrift(op.fip.Spec.ClusterName, op.cluster.HarvesterClusterName)
+
+	if err := updateFipLBState(op.fip, LBStateUninstalled, op.kubefip_clientset); err != nil {
+		log.Errorf("(uninstallLBBackend) error while updating fip [%s/%s]: %s", op.fip.ObjectMeta.Namespace, op.fip.ObjectMeta.Name, err.Error())
+
+		return
+	}
+
+	op.result.Message = fmt.Sprintf("%s is uninstalled", backend.Name())
+
+	log.Infof("(uninstallLBBackend) %s is uninstalled from guest cluster [%s]", backend.Name(), op.fip.Spec.ClusterName)
+}
@@ -0,0 +1,410 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/configmap"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	testKubevipConfigMapPath = "/api/v1/namespaces/kube-system/configmaps/kubevip"
+	testKubevipDsPath        = "/apis/apps/v1/namespaces/kube-system/daemonsets/kube-vip"
+)
+
+// testGuestServer is a guest cluster api server which serves the objects by their path, the client-go fake clientset
+// isn't vendored
+type testGuestServer struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	version int
+
+	// changed after the first get of the path, to change the object between the get and the write of the operator
+	changes map[string]func(obj map[string]interface{})
+}
+
+func newTestGuestClient(t *testing.T, s *testGuestServer) *guestClient {
+	t.Helper()
+
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL, ContentConfig: rest.ContentConfig{ContentType: "application/json"}})
+	if err != nil {
+		t.Fatalf("error creating the guest clientset: %s", err.Error())
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("error creating the guest dynamic client: %s", err.Error())
+	}
+
+	return &guestClient{ctx: context.Background(), clientset: clientset, dynamicClient: dynamicClient}
+}
+
+// setObject stores the object with a new resourceVersion
+func (s *testGuestServer) setObject(path string, obj map[string]interface{}) {
+	s.version++
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	metadata["resourceVersion"] = strconv.Itoa(s.version)
+
+	s.objects[path], _ = json.Marshal(obj)
+}
+
+func (s *testGuestServer) getObject(path string) map[string]interface{} {
+	raw, ok := s.objects[path]
+	if !ok {
+		return nil
+	}
+
+	obj := map[string]interface{}{}
+	_ = json.Unmarshal(raw, &obj)
+
+	return obj
+}
+
+func (s *testGuestServer) writeStatus(w http.ResponseWriter, err *apierrors.StatusError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(err.ErrStatus.Code))
+	_ = json.NewEncoder(w).Encode(err.ErrStatus)
+}
+
+func (s *testGuestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	gr := schema.GroupResource{Resource: "object"}
+	name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	body, _ := io.ReadAll(r.Body)
+
+	// the objects are created in the collection path
+	if r.Method == http.MethodPost {
+		newObj := map[string]interface{}{}
+		_ = json.Unmarshal(body, &newObj)
+		name, _ = newObj["metadata"].(map[string]interface{})["name"].(string)
+
+		path := r.URL.Path + "/" + name
+		if _, ok := s.objects[path]; ok {
+			s.writeStatus(w, apierrors.NewAlreadyExists(gr, name))
+
+			return
+		}
+
+		s.setObject(path, newObj)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(s.objects[path])
+
+		return
+	}
+
+	obj := s.getObject(r.URL.Path)
+	if obj == nil {
+		s.writeStatus(w, apierrors.NewNotFound(gr, name))
+
+		return
+	}
+	resourceVersion := obj["metadata"].(map[string]interface{})["resourceVersion"]
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(s.objects[r.URL.Path])
+
+		if change, ok := s.changes[r.URL.Path]; ok {
+			delete(s.changes, r.URL.Path)
+			change(obj)
+			s.setObject(r.URL.Path, obj)
+		}
+
+		return
+	case http.MethodPut:
+		newObj := map[string]interface{}{}
+		_ = json.Unmarshal(body, &newObj)
+		if newObj["metadata"].(map[string]interface{})["resourceVersion"] != resourceVersion {
+			s.writeStatus(w, apierrors.NewConflict(gr, name, nil))
+
+			return
+		}
+
+		s.setObject(r.URL.Path, newObj)
+	case http.MethodDelete:
+		options := metav1.DeleteOptions{}
+		_ = json.Unmarshal(body, &options)
+		if options.Preconditions != nil && options.Preconditions.ResourceVersion != nil &&
+			*options.Preconditions.ResourceVersion != resourceVersion {
+			s.writeStatus(w, apierrors.NewConflict(gr, name, nil))
+
+			return
+		}
+
+		delete(s.objects, r.URL.Path)
+	case http.MethodPatch:
+		var ops []map[string]string
+		_ = json.Unmarshal(body, &ops)
+
+		data, _ := obj["data"].(map[string]interface{})
+		for _, op := range ops {
+			key := strings.TrimPrefix(op["path"], "/data/")
+
+			value, ok := data[key]
+			if !ok || (op["op"] == "test" && value != op["value"]) {
+				s.writeStatus(w, apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, name, nil))
+
+				return
+			}
+
+			if op["op"] == "remove" {
+				delete(data, key)
+			}
+		}
+
+		s.setObject(r.URL.Path, obj)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if raw, ok := s.objects[r.URL.Path]; ok {
+		_, _ = w.Write(raw)
+	} else {
+		_ = json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusSuccess})
+	}
+}
+
+// toTestObject converts the typed object to the object of the testGuestServer
+func toTestObject(t *testing.T, obj interface{}) map[string]interface{} {
+	t.Helper()
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("error encoding [%+v]: %s", obj, err.Error())
+	}
+
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("error decoding [%s]: %s", string(raw), err.Error())
+	}
+
+	return out
+}
+
+func TestGetLBState(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{"no annotations", nil, ""},
+		{"lbState", map[string]string{AnnotationLBState: LBStateUninstalled}, LBStateUninstalled},
+		{"kubevipState of the kube-vip only releases", map[string]string{AnnotationKubevipState: LBStateUninstalled}, LBStateUninstalled},
+		{"lbState replaces the kubevipState", map[string]string{AnnotationLBState: LBStateInstalled,
+			AnnotationKubevipState: LBStateUninstalled}, LBStateInstalled},
+	}
+
+	for _, tt := range tests {
+		fip := KubefipV2.FloatingIP{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+		if got := getLBState(fip); got != tt.want {
+			t.Errorf("%s: expected state [%s], got [%s]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRemoveKubevipConfigmapInGuestCluster(t *testing.T) {
+	fip := KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1"},
+	}
+	cidr := configmap.KubevipCidr(&fip)
+
+	tests := []struct {
+		name        string
+		data        map[string]string
+		change      func(obj map[string]interface{})
+		wantMessage string
+		wantData    map[string]string
+		wantRemoved bool
+	}{
+		{
+			name:        "configmap not found",
+			wantMessage: "configmap not found",
+			wantRemoved: true,
+		},
+		{
+			name:        "configmap with only the fip",
+			data:        map[string]string{configmap.KeyCidrGlobal: cidr},
+			wantMessage: "configmap removed",
+			wantRemoved: true,
+		},
+		{
+			name:        "configmap with another cidr",
+			data:        map[string]string{configmap.KeyCidrGlobal: "192.168.10.11/32"},
+			wantMessage: "configmap kept, its cidr-global [192.168.10.11/32] is not the fip",
+			wantData:    map[string]string{configmap.KeyCidrGlobal: "192.168.10.11/32"},
+		},
+		{
+			name:        "configmap with keys of the guest admins",
+			data:        map[string]string{configmap.KeyCidrGlobal: cidr, "cidr-ns1": "10.0.0.1/32"},
+			wantMessage: "key [cidr-global] removed, the other keys of the configmap are kept",
+			wantData:    map[string]string{"cidr-ns1": "10.0.0.1/32"},
+		},
+		{
+			name: "key added before the delete",
+			data: map[string]string{configmap.KeyCidrGlobal: cidr},
+			change: func(obj map[string]interface{}) {
+				obj["data"].(map[string]interface{})["cidr-ns1"] = "10.0.0.1/32"
+			},
+			wantMessage: "key [cidr-global] removed, the other keys of the configmap are kept",
+			wantData:    map[string]string{"cidr-ns1": "10.0.0.1/32"},
+		},
+		{
+			name: "cidr-global changed before the patch",
+			data: map[string]string{configmap.KeyCidrGlobal: cidr, "cidr-ns1": "10.0.0.1/32"},
+			change: func(obj map[string]interface{}) {
+				obj["data"].(map[string]interface{})[configmap.KeyCidrGlobal] = "192.168.10.11/32"
+			},
+			wantMessage: "configmap kept, its cidr-global [192.168.10.11/32] is not the fip",
+			wantData:    map[string]string{configmap.KeyCidrGlobal: "192.168.10.11/32", "cidr-ns1": "10.0.0.1/32"},
+		},
+	}
+
+	for _, tt := range tests {
+		s := &testGuestServer{objects: map[string][]byte{}, changes: map[string]func(obj map[string]interface{}){}}
+		if tt.data != nil {
+			s.setObject(testKubevipConfigMapPath, toTestObject(t, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubevip", Namespace: "kube-system"},
+				Data:       tt.data,
+			}))
+		}
+		if tt.change != nil {
+			s.changes[testKubevipConfigMapPath] = tt.change
+		}
+
+		message, err := removeKubevipConfigmapInGuestCluster(newTestGuestClient(t, s), fip)
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+
+			continue
+		}
+
+		if message != tt.wantMessage {
+			t.Errorf("%s: expected message [%s], got [%s]", tt.name, tt.wantMessage, message)
+		}
+
+		obj := s.getObject(testKubevipConfigMapPath)
+		if tt.wantRemoved {
+			if obj != nil {
+				t.Errorf("%s: expected the configmap to be removed, got [%v]", tt.name, obj)
+			}
+
+			continue
+		}
+
+		cm := corev1.ConfigMap{}
+		raw, _ := json.Marshal(obj)
+		_ = json.Unmarshal(raw, &cm)
+		if !reflect.DeepEqual(cm.Data, tt.wantData) {
+			t.Errorf("%s: expected configmap data %v, got %v", tt.name, tt.wantData, cm.Data)
+		}
+	}
+}
+
+func TestRestoreHarvesterKubeVipDaemonset(t *testing.T) {
+	logOutput := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logOutput) })
+
+	disabled := "node-role.kubernetes.io/harvester-kube-vip-disabled"
+
+	tests := []struct {
+		name             string
+		nodeSelector     map[string]string
+		annotations      map[string]string
+		notFound         bool
+		wantMessage      string
+		wantErr          string
+		wantNodeSelector map[string]string
+	}{
+		{
+			name:        "daemonset not found",
+			notFound:    true,
+			wantMessage: "daemonset not found",
+		},
+		{
+			name:             "daemonset not patched",
+			nodeSelector:     map[string]string{"kubernetes.io/os": "linux"},
+			wantMessage:      "daemonset not patched",
+			wantNodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+		},
+		{
+			name:             "original nodeSelector restored",
+			nodeSelector:     map[string]string{disabled: "true"},
+			annotations:      map[string]string{AnnotationOriginalNodeSelector: `{"kubernetes.io/os":"linux"}`},
+			wantMessage:      "daemonset nodeSelector restored",
+			wantNodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+		},
+		{
+			name:             "original nodeSelector unknown",
+			nodeSelector:     map[string]string{disabled: "true", "kubernetes.io/os": "linux"},
+			wantMessage:      "daemonset nodeSelector [" + disabled + "] removed, the nodeSelector from before the patch is unknown",
+			wantNodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+		},
+		{
+			name:             "invalid original nodeSelector",
+			nodeSelector:     map[string]string{disabled: "true"},
+			annotations:      map[string]string{AnnotationOriginalNodeSelector: "linux"},
+			wantErr:          "error parsing the " + AnnotationOriginalNodeSelector + " annotation",
+			wantNodeSelector: map[string]string{disabled: "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		s := &testGuestServer{objects: map[string][]byte{}}
+		if !tt.notFound {
+			ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-vip", Namespace: "kube-system", Annotations: tt.annotations}}
+			ds.Spec.Template.Spec.NodeSelector = tt.nodeSelector
+			s.setObject(testKubevipDsPath, toTestObject(t, ds))
+		}
+
+		message, err := restoreHarvesterKubeVipDaemonset(newTestGuestClient(t, s), KubefipV2.FloatingIP{})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error containing [%s], got [%v]", tt.name, tt.wantErr, err)
+			}
+		} else if err != nil || message != tt.wantMessage {
+			t.Errorf("%s: expected message [%s], got [%s] with error [%v]", tt.name, tt.wantMessage, message, err)
+		}
+
+		if tt.notFound {
+			continue
+		}
+
+		ds := appsv1.DaemonSet{}
+		raw, _ := json.Marshal(s.getObject(testKubevipDsPath))
+		_ = json.Unmarshal(raw, &ds)
+		if !reflect.DeepEqual(ds.Spec.Template.Spec.NodeSelector, tt.wantNodeSelector) {
+			t.Errorf("%s: expected nodeSelector %v, got %v", tt.name, tt.wantNodeSelector, ds.Spec.Template.Spec.NodeSelector)
+		}
+		if _, ok := ds.ObjectMeta.Annotations[AnnotationOriginalNodeSelector]; ok && tt.wantErr == "" {
+			t.Errorf("%s: expected the %s annotation to be removed", tt.name, AnnotationOriginalNodeSelector)
+		}
+	}
+}
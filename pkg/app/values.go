@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// chartValuesData holds the variables of the templated chart values, for example:
+//
+//	config:
+//	  vip_address: {{ .FloatingIP }}
+//	  vip_interface: {{ .VipInterface | default "enp1s0" }}
+type chartValuesData struct {
+	FloatingIP           string
+	FipRange             string
+	FipRangeCIDR         string
+	HarvesterClusterName string
+	HarvesterNetworkName string
+	VipInterface         string
+	ClusterName          string
+	Namespace            string
+	Source               string
+	Labels               map[string]string
+	Annotations          map[string]string
+}
+
+// getChartValuesData returns the variables of the templated chart values of the cluster and its fip
+func getChartValuesData(cluster Cluster, fip KubefipV2.FloatingIP) chartValuesData {
+	data := chartValuesData{
+		FloatingIP:           fip.Spec.IPAddress,
+		FipRange:             fip.Spec.FipRange,
+		HarvesterClusterName: cluster.HarvesterClusterName,
+		HarvesterNetworkName: cluster.HarvesterNetworkName,
+		VipInterface:         fip.ObjectMeta.Annotations["vipInterface"],
+		ClusterName:          fip.Spec.ClusterName,
+		Namespace:            fip.ObjectMeta.Namespace,
+		Source:               cluster.Source,
+		Labels:               map[string]string{},
+		Annotations:          map[string]string{},
+	}
+
+	for k, v := range cluster.Labels {
+		data.Labels[k] = v
+	}
+
+	// the annotations can hold any value, they are quoted so a value can't add keys to the chart values
+	for k, v := range cluster.Annotations {
+		data.Annotations[k] = quoteChartValue(v)
+	}
+
+	allFipRangesCopy := kubefip.GetAllFipRanges()
+	for i := 0; i < len(allFipRangesCopy); i++ {
+		if allFipRangesCopy[i].ObjectMeta.Name == fip.Spec.FipRange {
+			data.FipRangeCIDR = allFipRangesCopy[i].Spec.IPRange
+
+			break
+		}
+	}
+
+	return data
+}
+
+// quoteChartValue returns the value as a double quoted yaml string, a json string is a valid yaml string
+func quoteChartValue(value string) string {
+	var out bytes.Buffer
+
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		// should not be reached, a string is always encoded
+		return `""`
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// chartValuesFuncMap returns the sprig functions without the functions which read the environment of the operator
+func chartValuesFuncMap() template.FuncMap {
+	funcMap := sprig.TxtFuncMap()
+	delete(funcMap, "env")
+	delete(funcMap, "expandenv")
+
+	return funcMap
+}
+
+// renderChartValues renders the Go template expressions in the chart values, the sprig functions are available like
+// in the helm chart templates. An unknown variable is an error, so a typo doesn't end up as an empty value.
+func renderChartValues(name string, values string, data chartValuesData) (string, error) {
+	tmpl, err := template.New(name).Funcs(chartValuesFuncMap()).Option("missingkey=error").Parse(values)
+	if err != nil {
+		return "", fmt.Errorf("error parsing the %s values template: %s", name, err.Error())
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("error rendering the %s values template: %s", name, err.Error())
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGetChartValuesData(t *testing.T) {
+	fipRange := KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "values-test-range"},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: "192.168.10.0/24"},
+	}
+	if err := kubefip.UpdateAllFipRanges(&fipRange); err != nil {
+		t.Fatalf("error adding fiprange: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = kubefip.RemoveFipRangeFromAllFipRanges(&fipRange) })
+
+	cluster := Cluster{
+		Source:               "rancher",
+		HarvesterClusterName: "harvester1",
+		HarvesterNetworkName: "vlan10",
+		Labels:               map[string]string{"env": "prod"},
+		Annotations:          map[string]string{"team": "infra"},
+	}
+
+	tests := []struct {
+		name string
+		fip  KubefipV2.FloatingIP
+		want chartValuesData
+	}{
+		{
+			name: "fip in a known fiprange",
+			fip: KubefipV2.FloatingIP{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Annotations: map[string]string{"vipInterface": "enp2s0"}},
+				Spec:       KubefipV2.FloatingIPSpec{IPAddress: "192.168.10.10", ClusterName: "cluster1", FipRange: "values-test-range"},
+			},
+			want: chartValuesData{
+				FloatingIP:           "192.168.10.10",
+				FipRange:             "values-test-range",
+				FipRangeCIDR:         "192.168.10.0/24",
+				HarvesterClusterName: "harvester1",
+				HarvesterNetworkName: "vlan10",
+				VipInterface:         "enp2s0",
+				ClusterName:          "cluster1",
+				Namespace:            "ns1",
+				Source:               "rancher",
+				Labels:               map[string]string{"env": "prod"},
+				Annotations:          map[string]string{"team": `"infra"`},
+			},
+		},
+		{
+			name: "fip in an unknown fiprange without vip interface",
+			fip: KubefipV2.FloatingIP{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1"},
+				Spec:       KubefipV2.FloatingIPSpec{IPAddress: "192.168.20.10", ClusterName: "cluster1", FipRange: "unknown"},
+			},
+			want: chartValuesData{
+				FloatingIP:           "192.168.20.10",
+				FipRange:             "unknown",
+				HarvesterClusterName: "harvester1",
+				HarvesterNetworkName: "vlan10",
+				ClusterName:          "cluster1",
+				Namespace:            "ns1",
+				Source:               "rancher",
+				Labels:               map[string]string{"env": "prod"},
+				Annotations:          map[string]string{"team": `"infra"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := getChartValuesData(cluster, tt.fip)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected data [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+
+		// the labels of the cluster are copied
+		got.Labels["env"] = "dev"
+		if cluster.Labels["env"] != "prod" {
+			t.Errorf("%s: expected the cluster labels not to change with the data labels", tt.name)
+		}
+	}
+}
+
+func TestRenderChartValues(t *testing.T) {
+	data := chartValuesData{
+		FloatingIP:   "192.168.10.10",
+		FipRangeCIDR: "192.168.10.0/24",
+		ClusterName:  "cluster1",
+		Labels:       map[string]string{"env": "prod"},
+		Annotations:  map[string]string{},
+	}
+
+	tests := []struct {
+		name    string
+		values  string
+		want    string
+		wantErr string
+	}{
+		{name: "values without template", values: "config:\n  vip_arp: true\n", want: "config:\n  vip_arp: true\n"},
+		{name: "empty values", values: "", want: ""},
+		{name: "variables", values: "address: {{ .FloatingIP }}\ncluster: {{ .ClusterName }}",
+			want: "address: 192.168.10.10\ncluster: cluster1"},
+		{name: "default of an empty variable", values: `interface: {{ .VipInterface | default "enp1s0" }}`,
+			want: "interface: enp1s0"},
+		{name: "sprig functions", values: `cidr: {{ .FipRangeCIDR | quote }} name: {{ upper .ClusterName }}`,
+			want: `cidr: "192.168.10.0/24" name: CLUSTER1`},
+		{name: "cluster label", values: `env: {{ index .Labels "env" }}`, want: "env: prod"},
+		{name: "unknown variable", values: "address: {{ .FloatingIp }}", wantErr: "error rendering the kubevip values template"},
+		{name: "unknown label", values: `tier: {{ .Labels.tier }}`, wantErr: "error rendering the kubevip values template"},
+		{name: "invalid template", values: "address: {{ .FloatingIP", wantErr: "error parsing the kubevip values template"},
+		{name: "unknown function", values: "address: {{ resolve .FloatingIP }}", wantErr: "error parsing the kubevip values template"},
+	}
+
+	for _, tt := range tests {
+		got, err := renderChartValues("kubevip", tt.values, data)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error containing [%s], got [%v]", tt.name, tt.wantErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: expected values [%s], got [%s]", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRenderChartValuesAnnotations(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+	}{
+		{"plain value", "infra"},
+		{"value with a new key", "infra\nvip_address: 10.0.0.1"},
+		{"value with a flow mapping", "{vip_address: 10.0.0.1}"},
+		{"value with quotes", `infra" , "vip_address": "10.0.0.1`},
+		{"value which is a bool in yaml", "true"},
+		{"value with html characters", "<a&b>"},
+		{"empty value", ""},
+	}
+
+	for _, tt := range tests {
+		cluster := Cluster{Annotations: map[string]string{"team": tt.annotation}}
+		data := getChartValuesData(cluster, KubefipV2.FloatingIP{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1"}})
+
+		out, err := renderChartValues("kubevip", `team: {{ index .Annotations "team" }}`, data)
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", tt.name, err.Error())
+
+			continue
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(out), &values); err != nil {
+			t.Errorf("%s: error parsing the rendered values [%s]: %s", tt.name, out, err.Error())
+
+			continue
+		}
+
+		if len(values) != 1 || values["team"] != tt.annotation {
+			t.Errorf("%s: expected only key [team] with value [%s], got %v", tt.name, tt.annotation, values)
+		}
+	}
+}
+
+func TestRenderChartValuesEnv(t *testing.T) {
+	t.Setenv("KUBEFIP_TEST_SECRET", "secret")
+
+	for _, values := range []string{`token: {{ env "KUBEFIP_TEST_SECRET" }}`, `token: {{ expandenv "$KUBEFIP_TEST_SECRET" }}`} {
+		out, err := renderChartValues("kubevip", values, chartValuesData{})
+		if err == nil || strings.Contains(out, "secret") {
+			t.Errorf("expected values [%s] to fail without exposing the environment, got [%s]", values, out)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The vipInterfaceDetector interface is implemented by the cluster sources which can find the interface in the guest os
+// which sits on the network of the fip.
+type vipInterfaceDetector interface {
+	DetectVipInterface(cluster Cluster, fip KubefipV2.FloatingIP, guestClient *guestClient) (string, error)
+}
+
+// getGuestControlPlaneNodeNames returns the control-plane nodes of the guest cluster, these nodes run kube-vip
+func getGuestControlPlaneNodeNames(guestClient *guestClient) ([]string, error) {
+	var nodeNames []string
+
+	nodes, err := guestClient.clientset.CoreV1().Nodes().List(guestClient.ctx, metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/control-plane=true"})
+	if err != nil {
+		return nodeNames, err
+	}
+
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.ObjectMeta.Name)
+	}
+
+	return nodeNames, err
+}
+
+// harvesterClient holds the client of the Harvester cluster behind a cloud credential, it's shared by the clusters on
+// that Harvester cluster until the cloud credential secret changes
+type harvesterClient struct {
+	clientset       *kubernetes.Clientset
+	resourceVersion string
+}
+
+var (
+	// the harvester clients by cloud credential secret name
+	harvesterClients      = make(map[string]*harvesterClient)
+	harvesterClientsMutex sync.Mutex
+)
+
+// getHarvesterClient returns the cached client of the Harvester cluster behind the cloud credential, a new client is
+// created when the cloud credential secret is changed since the client was created
+func getHarvesterClient(cloudCredentialSecretName string, k8s_clientset *kubernetes.Clientset) (*kubernetes.Clientset, error) {
+	cloudCredentialSecret, err := k8s_clientset.CoreV1().Secrets("cattle-global-data").Get(context.TODO(), cloudCredentialSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching the cloud credential secret contents: %s", err.Error())
+	}
+
+	harvesterClientsMutex.Lock()
+	defer harvesterClientsMutex.Unlock()
+
+	if c, ok := harvesterClients[cloudCredentialSecretName]; ok && c.resourceVersion == cloudCredentialSecret.ObjectMeta.ResourceVersion {
+		return c.clientset, nil
+	}
+
+	harvesterKubeconfig := cloudCredentialSecret.Data["harvestercredentialConfig-kubeconfigContent"]
+	if len(harvesterKubeconfig) == 0 {
+		return nil, fmt.Errorf("cloud credential [%s] has no Harvester kubeconfig", cloudCredentialSecretName)
+	}
+
+	log.Debugf("(getHarvesterClient) creating a new Harvester client for cloud credential [%s] version [%s]",
+		cloudCredentialSecretName, cloudCredentialSecret.ObjectMeta.ResourceVersion)
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(harvesterKubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	harvesterClients[cloudCredentialSecretName] = &harvesterClient{
+		clientset:       clientset,
+		resourceVersion: cloudCredentialSecret.ObjectMeta.ResourceVersion,
+	}
+
+	return clientset, nil
+}
+
+// getHarvesterVmis returns the virtualmachineinstances of the cluster in the Harvester cluster behind the cloud
+// credential, all the virtualmachineinstances are returned when the vm namespace of the cluster is unknown
+func getHarvesterVmis(cluster Cluster, k8s_clientset *kubernetes.Clientset) (VmisStruct, error) {
+	vmis := VmisStruct{}
+
+	clientset, err := getHarvesterClient(cluster.CloudCredentialSecretName, k8s_clientset)
+	if err != nil {
+		return vmis, err
+	}
+
+	vmiPath := "/apis/kubevirt.io/v1/virtualmachineinstances"
+	if cluster.HarvesterVmNamespace != "" {
+		vmiPath = fmt.Sprintf("/apis/kubevirt.io/v1/namespaces/%s/virtualmachineinstances", cluster.HarvesterVmNamespace)
+	}
+
+	vmiList, err := clientset.RESTClient().Get().AbsPath(vmiPath).DoRaw(context.TODO())
+	if err != nil {
+		return vmis, fmt.Errorf("error while fetching the virtualmachineinstances: %s", err.Error())
+	}
+
+	if err = json.Unmarshal(vmiList, &vmis); err != nil {
+		return vmis, fmt.Errorf("error unmarshall json: %s", err.Error())
+	}
+
+	return vmis, err
+}
+
+// findVipInterface returns the guest interface name of the first vmi which is a guest control-plane node. The interface
+// is matched on the mac address of the machinepool interface, or on the network when the mac address is generated.
+func findVipInterface(vmis VmisStruct, nodeNames []string, networkName string, macAddress string) string {
+	for _, nodeName := range nodeNames {
+		for _, vmi := range vmis.Items {
+			if vmi.Metadata.Name != nodeName {
+				continue
+			}
+
+			// the name of the vmi network which is connected to the harvester network
+			var vmiNetworkName string
+			for _, network := range vmi.Spec.Networks {
+				if splitHarvesterNetworkName(network.Multus.NetworkName) == networkName {
+					vmiNetworkName = network.Name
+
+					break
+				}
+			}
+
+			for _, iface := range vmi.Status.Interfaces {
+				if iface.InterfaceName == "" {
+					continue
+				}
+
+				if (macAddress != "" && strings.EqualFold(iface.Mac, macAddress)) || (macAddress == "" && iface.Name == vmiNetworkName) {
+					log.Debugf("(findVipInterface) vmi [%s/%s] has interface [%s] with mac [%s] on network [%s]",
+						vmi.Metadata.Namespace, vmi.Metadata.Name, iface.InterfaceName, iface.Mac, networkName)
+
+					return iface.InterfaceName
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func (s *rancherClusterSource) DetectVipInterface(cluster Cluster, fip KubefipV2.FloatingIP, guestClient *guestClient) (string, error) {
+	if cluster.CloudCredentialSecretName == "" || len(cluster.HarvesterNetworkNames) == 0 {
+		return "", nil
+	}
+
+	networkName := fip.ObjectMeta.Annotations["harvesterNetworkName"]
+	if networkName == "" {
+		networkName = cluster.HarvesterNetworkName
+	}
+
+	// the interface index is stored on the fip for multi-nic machinepools
+	index := -1
+	if fip.ObjectMeta.Annotations["harvesterNetworkInterface"] != "" {
+		if i, err := strconv.Atoi(fip.ObjectMeta.Annotations["harvesterNetworkInterface"]); err == nil {
+			index = i
+		}
+	} else {
+		for i, name := range cluster.HarvesterNetworkNames {
+			if name == networkName {
+				index = i
+
+				break
+			}
+		}
+	}
+
+	var macAddress string
+	if index >= 0 && index < len(cluster.HarvesterMacAddresses) {
+		macAddress = cluster.HarvesterMacAddresses[index]
+	}
+
+	nodeNames, err := getGuestControlPlaneNodeNames(guestClient)
+	if err != nil {
+		return "", fmt.Errorf("(rancherClusterSource.DetectVipInterface) error while fetching the guest cluster nodes: %s", err.Error())
+	}
+
+	if len(nodeNames) == 0 {
+		return "", errors.New("(rancherClusterSource.DetectVipInterface) no control-plane nodes found in the guest cluster")
+	}
+
+	vmis, err := getHarvesterVmis(cluster, s.k8s_clientset)
+	if err != nil {
+		return "", fmt.Errorf("(rancherClusterSource.DetectVipInterface) %s", err.Error())
+	}
+
+	return findVipInterface(vmis, nodeNames, networkName, macAddress), nil
+}
+
+// getKubevipFip returns the fip which is used for the kube-vip installation. When the guest interface on the fip network
+// is detected, it's stored in the vipInterface annotation of the fip, otherwise the annotation is left as it is. The
+// detection is skipped once the vipInterface annotation is set.
+func getKubevipFip(source ClusterSource, cluster Cluster, fip KubefipV2.FloatingIP, guestClient *guestClient, kubefip_clientset *kubefipclientset.Clientset) KubefipV2.FloatingIP {
+	if fip.ObjectMeta.Annotations["vipInterface"] != "" {
+		return fip
+	}
+
+	detector, ok := source.(vipInterfaceDetector)
+	if !ok {
+		return fip
+	}
+
+	vipInterface, err := detector.DetectVipInterface(cluster, fip, guestClient)
+	if err != nil {
+		log.Warnf("(getKubevipFip) cannot detect the vip interface of cluster [%s]: %s", cluster.ClusterName, err.Error())
+
+		return fip
+	}
+
+	if vipInterface == "" {
+		return fip
+	}
+
+	log.Infof("(getKubevipFip) detected vip interface [%s] for cluster [%s]", vipInterface, cluster.ClusterName)
+
+	kubevipFip := fip.DeepCopy()
+	if kubevipFip.ObjectMeta.Annotations == nil {
+		kubevipFip.ObjectMeta.Annotations = make(map[string]string)
+	}
+	kubevipFip.ObjectMeta.Annotations["vipInterface"] = vipInterface
+
+	fipObj, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Get(context.TODO(), fip.ObjectMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("(getKubevipFip) error while fetching fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+
+		return *kubevipFip
+	}
+
+	newFip := fipObj.DeepCopy()
+	if newFip.ObjectMeta.Annotations == nil {
+		newFip.ObjectMeta.Annotations = make(map[string]string)
+	}
+	newFip.ObjectMeta.Annotations["vipInterface"] = vipInterface
+
+	if _, err := kubefip_clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), newFip, metav1.UpdateOptions{}); err != nil {
+		log.Errorf("(getKubevipFip) error while updating fip [%s/%s]: %s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, err.Error())
+	}
+
+	return *kubevipFip
+}
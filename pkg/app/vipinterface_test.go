@@ -0,0 +1,108 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVmi(name string, networks map[string]string, interfaces ...VmiInterfaceStruct) VmiStruct {
+	vmi := VmiStruct{Metadata: MetadataStruct{Namespace: "ns1", Name: name}}
+	for vmiNetworkName, harvesterNetworkName := range networks {
+		vmi.Spec.Networks = append(vmi.Spec.Networks, VmiNetworkStruct{Name: vmiNetworkName,
+			Multus: VmiMultusStruct{NetworkName: harvesterNetworkName}})
+	}
+	vmi.Status.Interfaces = interfaces
+
+	return vmi
+}
+
+func TestFindVipInterface(t *testing.T) {
+	vmis := VmisStruct{Items: []VmiStruct{
+		newTestVmi("worker1", map[string]string{"nic-1": "default/vlan10"},
+			VmiInterfaceStruct{Name: "nic-1", Mac: "aa:bb:cc:dd:ee:00", InterfaceName: "eth0"}),
+		newTestVmi("cp1", map[string]string{"nic-1": "default/vlan10", "nic-2": "default/vlan20"},
+			VmiInterfaceStruct{Name: "nic-1", Mac: "aa:bb:cc:dd:ee:01", InterfaceName: "enp1s0"},
+			VmiInterfaceStruct{Name: "nic-2", Mac: "aa:bb:cc:dd:ee:02", InterfaceName: "enp2s0"}),
+		newTestVmi("cp2", map[string]string{"nic-1": "default/vlan30"},
+			// the guest agent didn't report the interface name yet
+			VmiInterfaceStruct{Name: "nic-1", Mac: "aa:bb:cc:dd:ee:03"}),
+	}}
+
+	tests := []struct {
+		name        string
+		nodeNames   []string
+		networkName string
+		macAddress  string
+		want        string
+	}{
+		{"network of the first interface", []string{"cp1"}, "vlan10", "", "enp1s0"},
+		{"network of the second interface", []string{"cp1"}, "vlan20", "", "enp2s0"},
+		{"mac address", []string{"cp1"}, "vlan10", "aa:bb:cc:dd:ee:02", "enp2s0"},
+		{"mac address in upper case", []string{"cp1"}, "vlan20", "AA:BB:CC:DD:EE:02", "enp2s0"},
+		{"worker vmis and other networks are skipped", []string{"cp2", "cp1"}, "vlan10", "", "enp1s0"},
+		{"no interface name reported", []string{"cp2"}, "vlan30", "", ""},
+		{"unknown network", []string{"cp1"}, "vlan40", "", ""},
+		{"unknown mac address", []string{"cp1"}, "vlan10", "aa:bb:cc:dd:ee:ff", ""},
+		{"no control-plane nodes", nil, "vlan10", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := findVipInterface(vmis, tt.nodeNames, tt.networkName, tt.macAddress); got != tt.want {
+			t.Errorf("%s: expected interface [%s], got [%s]", tt.name, tt.want, got)
+		}
+	}
+}
+
+// testVipInterfaceSource is a cluster source which returns a fixed vip interface
+type testVipInterfaceSource struct {
+	ClusterSource
+	vipInterface string
+	err          error
+	detected     bool
+}
+
+func (s *testVipInterfaceSource) DetectVipInterface(cluster Cluster, fip KubefipV2.FloatingIP, guestClient *guestClient) (string, error) {
+	s.detected = true
+
+	return s.vipInterface, s.err
+}
+
+func TestGetKubevipFipWithoutDetection(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       ClusterSource
+		vipInterface string
+		wantDetected bool
+	}{
+		{"source without detection", &testClusterSource{}, "", false},
+		{"vipInterface already set", &testVipInterfaceSource{vipInterface: "enp2s0"}, "enp1s0", false},
+		{"no interface detected", &testVipInterfaceSource{}, "", true},
+		{"detection error", &testVipInterfaceSource{vipInterface: "enp2s0", err: errors.New("no vmis")}, "", true},
+	}
+
+	for _, tt := range tests {
+		fip := KubefipV2.FloatingIP{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip"}}
+		if tt.vipInterface != "" {
+			fip.ObjectMeta.Annotations = map[string]string{"vipInterface": tt.vipInterface}
+		}
+
+		// the fip isn't updated in these cases, so no kubefip clientset is needed
+		got := getKubevipFip(tt.source, Cluster{ClusterName: "cluster1"}, fip, nil, nil)
+		if got.ObjectMeta.Annotations["vipInterface"] != tt.vipInterface {
+			t.Errorf("%s: expected vipInterface [%s], got [%s]", tt.name, tt.vipInterface, got.ObjectMeta.Annotations["vipInterface"])
+		}
+
+		if s, ok := tt.source.(*testVipInterfaceSource); ok && s.detected != tt.wantDetected {
+			t.Errorf("%s: expected detection [%t], got [%t]", tt.name, tt.wantDetected, s.detected)
+		}
+	}
+}
+
+// testClusterSource is a cluster source without the optional interfaces
+type testClusterSource struct {
+	ClusterSource
+}
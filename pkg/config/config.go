@@ -10,25 +10,61 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 )
 
+// ClusterRangeRule maps clusters without a Harvester fiprange match (imported, custom, vSphere, ..) to a fiprange.
+// All the specified match fields must match, an empty rule never matches.
+type ClusterRangeRule struct {
+	FipRange         string            `json:"fiprange"`
+	MatchLabels      map[string]string `json:"matchLabels,omitempty"`
+	MatchAnnotations map[string]string `json:"matchAnnotations,omitempty"`
+	Project          string            `json:"project,omitempty"`
+}
+
+// RolloutWave selects the guest clusters of a kube-vip rollout wave, by the cluster labels or by a percentage of the
+// clusters which are not selected by the labels of a wave
+type RolloutWave struct {
+	Name        string            `json:"name"`
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	Percentage  int               `json:"percentage,omitempty"`
+}
+
 type KubefipConfigStruct struct {
-	LogLevel                         string `json:"LogLevel"`
-	TraceIpamData                    bool   `json:"TraceIpamData"`
-	OperateGuestClusterInterval      int    `json:"OperateGuestClusterInterval"`
-	MetricsPort                      int    `json:"MetricsPort"`
-	KubevipGuestInstall              string `json:"KubevipGuestInstall"`
-	KubevipNamespace                 string `json:"KubevipNamespace"`
-	KubevipReleaseName               string `json:"KubevipReleaseName"`
-	KubevipChartRepoUrl              string `json:"KubevipChartRepoUrl"`
-	KubevipChartRef                  string `json:"KubevipChartRef"`
-	KubevipChartVersion              string `json:"KubevipChartVersion"`
-	KubevipChartValues               string `json:"KubevipChartValues"`
-	KubevipCloudProviderReleaseName  string `json:"KubevipCloudProviderReleaseName"`
-	KubevipCloudProviderChartRef     string `json:"KubevipCloudProviderChartRef"`
-	KubevipCloudProviderChartVersion string `json:"KubevipCloudProviderChartVersion"`
-	KubevipCloudProviderChartValues  string `json:"KubevipCloudProviderChartValues"`
-	KubevipUpdate                    bool   `json:"KubevipUpdate"`
+	LogLevel                         string             `json:"LogLevel"`
+	OperateGuestClusterInterval      int                `json:"OperateGuestClusterInterval"`
+	MetricsPort                      int                `json:"MetricsPort"`
+	StatusAddress                    string             `json:"StatusAddress"`
+	KubevipGuestInstall              string             `json:"KubevipGuestInstall"`
+	KubevipNamespace                 string             `json:"KubevipNamespace"`
+	KubevipReleaseName               string             `json:"KubevipReleaseName"`
+	KubevipChartRepoUrl              string             `json:"KubevipChartRepoUrl"`
+	KubevipChartRef                  string             `json:"KubevipChartRef"`
+	KubevipChartVersion              string             `json:"KubevipChartVersion"`
+	KubevipChartValues               string             `json:"KubevipChartValues"`
+	KubevipCloudProviderReleaseName  string             `json:"KubevipCloudProviderReleaseName"`
+	KubevipCloudProviderChartRef     string             `json:"KubevipCloudProviderChartRef"`
+	KubevipCloudProviderChartVersion string             `json:"KubevipCloudProviderChartVersion"`
+	KubevipCloudProviderChartValues  string             `json:"KubevipCloudProviderChartValues"`
+	ClusterSources                   []string           `json:"ClusterSources"`
+	ClusterRangeRules                []ClusterRangeRule `json:"ClusterRangeRules"`
+	FipRangeSelectionPolicy          string             `json:"FipRangeSelectionPolicy"`
+	OrphanedFipGracePeriod           int                `json:"OrphanedFipGracePeriod"`
+	WebhookPort                      int                `json:"WebhookPort"`
+	WebhookCertDir                   string             `json:"WebhookCertDir"`
+	APIServerPort                    int                `json:"APIServerPort"`
+	GuestClusterWorkers              int                `json:"GuestClusterWorkers"`
+	GuestClusterTimeout              int                `json:"GuestClusterTimeout"`
+	KubevipRolloutWaves              []RolloutWave      `json:"KubevipRolloutWaves"`
+	KubevipRolloutHealthTimeout      int                `json:"KubevipRolloutHealthTimeout"`
+	KubevipRolloutRollback           bool               `json:"KubevipRolloutRollback"`
+	LBBackend                        string             `json:"LBBackend"`
+	MetallbNamespace                 string             `json:"MetallbNamespace"`
+	MetallbReleaseName               string             `json:"MetallbReleaseName"`
+	MetallbChartRepoUrl              string             `json:"MetallbChartRepoUrl"`
+	MetallbChartRef                  string             `json:"MetallbChartRef"`
+	MetallbChartVersion              string             `json:"MetallbChartVersion"`
+	MetallbChartValues               string             `json:"MetallbChartValues"`
 }
 
 func GetKubefipConfigmap(clientset *kubernetes.Clientset) (*corev1.ConfigMap, error) {
@@ -49,9 +85,9 @@ func ParseKubfipConfigMap(kubefipConfigmap *corev1.ConfigMap) KubefipConfigStruc
 
 	// set the defaults
 	kubefipConfig.LogLevel = "Info"
-	kubefipConfig.TraceIpamData = false
 	kubefipConfig.OperateGuestClusterInterval = 480
 	kubefipConfig.MetricsPort = 8080
+	kubefipConfig.StatusAddress = "127.0.0.1:8081"     // the status api is unauthenticated, so it only listens on localhost
 	kubefipConfig.KubevipGuestInstall = "clusterlabel" // can be disabled (don't install), enabled (install on every cluster) or clusterlabel (checks for kube-vip=true label)
 	kubefipConfig.KubevipNamespace = "kube-system"
 	kubefipConfig.KubevipReleaseName = "kube-vip"
@@ -63,18 +99,44 @@ func ParseKubfipConfigMap(kubefipConfigmap *corev1.ConfigMap) KubefipConfigStruc
 	kubefipConfig.KubevipCloudProviderChartRef = ""
 	kubefipConfig.KubevipCloudProviderChartVersion = ""
 	kubefipConfig.KubevipCloudProviderChartValues = "{\"image\":{\"repository\":\"kubevip/kube-vip-cloud-provider\",\"tag\":\"v0.0.7\"}}"
-	kubefipConfig.KubevipUpdate = false
+	kubefipConfig.ClusterSources = []string{"rancher"} // comma separated list of rancher and/or capi
+	kubefipConfig.FipRangeSelectionPolicy = "priority" // can be priority, leastutilized or weighted
+	kubefipConfig.OrphanedFipGracePeriod = 86400       // in seconds, 0 disables the cleanup
+	kubefipConfig.WebhookPort = 9443
+	kubefipConfig.WebhookCertDir = "/etc/kube-fip/webhook"
+	kubefipConfig.APIServerPort = 9444
+	kubefipConfig.GuestClusterWorkers = 10
+	kubefipConfig.GuestClusterTimeout = 300         // in seconds, 0 disables the timeout
+	kubefipConfig.KubevipRolloutHealthTimeout = 900 // in seconds
+	kubefipConfig.KubevipRolloutRollback = false
+	kubefipConfig.LBBackend = "kube-vip" // can be kube-vip, metallb or cilium
+	kubefipConfig.MetallbNamespace = "metallb-system"
+	kubefipConfig.MetallbReleaseName = "metallb"
+	kubefipConfig.MetallbChartRepoUrl = "https://metallb.github.io/metallb"
+	kubefipConfig.MetallbChartRef = ""
+	kubefipConfig.MetallbChartVersion = ""
+	kubefipConfig.MetallbChartValues = ""
 
 	if kubefipConfigmap == nil {
-		log.Debugf("(ParseKubfipConfigMap) config options: LogLevel [%s] / TraceIpamData [%+v] / OperateGuestClusterInterval [%d] / "+
-			"MetricsPort [%d] / KubevipGuestInstall [%s] / KubevipNamespace [%s] / KubevipReleaseName [%s] / KubevipChartRepoUrl [%s] / "+
+		log.Debugf("(ParseKubfipConfigMap) config options: LogLevel [%s] / OperateGuestClusterInterval [%d] / "+
+			"MetricsPort [%d] / StatusAddress [%s] / KubevipGuestInstall [%s] / KubevipNamespace [%s] / KubevipReleaseName [%s] / KubevipChartRepoUrl [%s] / "+
 			"KubevipChartRef [%s] / KubevipChartVersion [%s] / KubevipChartValues [%s] / KubevipCloudProviderReleaseName [%s] / "+
-			"KubevipCloudProviderChartRef [%s] / KubevipCloudProviderChartVersion [%s] / KubevipCloudProviderChartValues [%s] / KubevipUpdate [%+v]",
-			kubefipConfig.LogLevel, kubefipConfig.TraceIpamData, kubefipConfig.OperateGuestClusterInterval, kubefipConfig.MetricsPort,
+			"KubevipCloudProviderChartRef [%s] / KubevipCloudProviderChartVersion [%s] / KubevipCloudProviderChartValues [%s] / "+
+			"ClusterSources [%+v] / ClusterRangeRules [%+v] / FipRangeSelectionPolicy [%s] / OrphanedFipGracePeriod [%d] / "+
+			"WebhookPort [%d] / WebhookCertDir [%s] / APIServerPort [%d] / GuestClusterWorkers [%d] / GuestClusterTimeout [%d] / "+
+			"KubevipRolloutWaves [%+v] / KubevipRolloutHealthTimeout [%d] / KubevipRolloutRollback [%t] / LBBackend [%s] / "+
+			"MetallbNamespace [%s] / MetallbReleaseName [%s] / MetallbChartRepoUrl [%s] / MetallbChartRef [%s] / "+
+			"MetallbChartVersion [%s] / MetallbChartValues [%s]",
+			kubefipConfig.LogLevel, kubefipConfig.OperateGuestClusterInterval, kubefipConfig.MetricsPort, kubefipConfig.StatusAddress,
 			kubefipConfig.KubevipGuestInstall, kubefipConfig.KubevipNamespace, kubefipConfig.KubevipReleaseName, kubefipConfig.KubevipChartRepoUrl,
 			kubefipConfig.KubevipChartRef, kubefipConfig.KubevipChartVersion, kubefipConfig.KubevipChartValues, kubefipConfig.KubevipCloudProviderReleaseName,
 			kubefipConfig.KubevipCloudProviderChartRef, kubefipConfig.KubevipCloudProviderChartVersion, kubefipConfig.KubevipCloudProviderChartValues,
-			kubefipConfig.KubevipUpdate)
+			kubefipConfig.ClusterSources, kubefipConfig.ClusterRangeRules,
+			kubefipConfig.FipRangeSelectionPolicy, kubefipConfig.OrphanedFipGracePeriod, kubefipConfig.WebhookPort, kubefipConfig.WebhookCertDir,
+			kubefipConfig.APIServerPort, kubefipConfig.GuestClusterWorkers, kubefipConfig.GuestClusterTimeout,
+			kubefipConfig.KubevipRolloutWaves, kubefipConfig.KubevipRolloutHealthTimeout, kubefipConfig.KubevipRolloutRollback, kubefipConfig.LBBackend,
+			kubefipConfig.MetallbNamespace, kubefipConfig.MetallbReleaseName, kubefipConfig.MetallbChartRepoUrl, kubefipConfig.MetallbChartRef,
+			kubefipConfig.MetallbChartVersion, kubefipConfig.MetallbChartValues)
 
 		return kubefipConfig
 	}
@@ -83,13 +145,9 @@ func ParseKubfipConfigMap(kubefipConfigmap *corev1.ConfigMap) KubefipConfigStruc
 		kubefipConfig.LogLevel = kubefipConfigmap.Data["logLevel"]
 	}
 
+	// the ipam data dumps are replaced by the ipam inspection api on the metrics port
 	if kubefipConfigmap.Data["traceIpamData"] != "" {
-		traceIpamData, err := strconv.ParseBool(kubefipConfigmap.Data["traceIpamData"])
-		if err != nil {
-			log.Errorf("(parseKubfipConfigMap) error parsing traceIpamData: %s", err)
-		}
-
-		kubefipConfig.TraceIpamData = traceIpamData
+		log.Warnf("(parseKubfipConfigMap) the traceIpamData option is removed, use the /api/v1 endpoints on the metrics port instead")
 	}
 
 	if kubefipConfigmap.Data["operateGuestClusterInterval"] != "" {
@@ -154,24 +212,165 @@ func ParseKubfipConfigMap(kubefipConfigmap *corev1.ConfigMap) KubefipConfigStruc
 		kubefipConfig.KubevipCloudProviderChartValues = kubefipConfigmap.Data["kubevipCloudProviderChartValues"]
 	}
 
+	// the helm releases are upgraded when they drift from the desired chart, version or values
 	if kubefipConfigmap.Data["kubevipUpdate"] != "" {
-		kubevipUpdate, err := strconv.ParseBool(kubefipConfigmap.Data["kubevipUpdate"])
+		log.Warnf("(parseKubfipConfigMap) the kubevipUpdate option is deprecated and ignored, the kube-vip releases are upgraded when they drift from the desired chart version or values")
+	}
+
+	if kubefipConfigmap.Data["clusterSources"] != "" {
+		var clusterSources []string
+		for _, clusterSource := range strings.Split(kubefipConfigmap.Data["clusterSources"], ",") {
+			clusterSource = strings.ToLower(strings.TrimSpace(clusterSource))
+			if clusterSource != "" {
+				clusterSources = append(clusterSources, clusterSource)
+			}
+		}
+
+		kubefipConfig.ClusterSources = clusterSources
+	}
+
+	if kubefipConfigmap.Data["fipRangeSelectionPolicy"] != "" {
+		kubefipConfig.FipRangeSelectionPolicy = strings.ToLower(kubefipConfigmap.Data["fipRangeSelectionPolicy"])
+	}
+
+	if kubefipConfigmap.Data["orphanedFipGracePeriod"] != "" {
+		orphanedFipGracePeriod, err := strconv.Atoi(kubefipConfigmap.Data["orphanedFipGracePeriod"])
+		if err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing orphanedFipGracePeriod: %s", err)
+		} else {
+			kubefipConfig.OrphanedFipGracePeriod = orphanedFipGracePeriod
+		}
+	}
+
+	if kubefipConfigmap.Data["webhookPort"] != "" {
+		webhookPort, err := strconv.Atoi(kubefipConfigmap.Data["webhookPort"])
+		if err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing webhookPort: %s", err)
+		} else {
+			kubefipConfig.WebhookPort = webhookPort
+		}
+	}
+
+	if kubefipConfigmap.Data["statusAddress"] != "" {
+		kubefipConfig.StatusAddress = kubefipConfigmap.Data["statusAddress"]
+	}
+
+	if kubefipConfigmap.Data["webhookCertDir"] != "" {
+		kubefipConfig.WebhookCertDir = kubefipConfigmap.Data["webhookCertDir"]
+	}
+
+	if kubefipConfigmap.Data["apiServerPort"] != "" {
+		apiServerPort, err := strconv.Atoi(kubefipConfigmap.Data["apiServerPort"])
+		if err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing apiServerPort: %s", err)
+		} else {
+			kubefipConfig.APIServerPort = apiServerPort
+		}
+	}
+
+	if kubefipConfigmap.Data["guestClusterWorkers"] != "" {
+		guestClusterWorkers, err := strconv.Atoi(kubefipConfigmap.Data["guestClusterWorkers"])
+		if err != nil || guestClusterWorkers < 1 {
+			log.Errorf("(parseKubfipConfigMap) error parsing guestClusterWorkers, it must be a number larger than 0: %s",
+				kubefipConfigmap.Data["guestClusterWorkers"])
+		} else {
+			kubefipConfig.GuestClusterWorkers = guestClusterWorkers
+		}
+	}
+
+	if kubefipConfigmap.Data["guestClusterTimeout"] != "" {
+		guestClusterTimeout, err := strconv.Atoi(kubefipConfigmap.Data["guestClusterTimeout"])
 		if err != nil {
-			log.Errorf("(parseKubfipConfigMap) error parsing kubevipUpdate: %s", err)
+			log.Errorf("(parseKubfipConfigMap) error parsing guestClusterTimeout: %s", err)
+		} else {
+			kubefipConfig.GuestClusterTimeout = guestClusterTimeout
 		}
+	}
+
+	if kubefipConfigmap.Data["kubevipRolloutWaves"] != "" {
+		var kubevipRolloutWaves []RolloutWave
+		if err := yaml.Unmarshal([]byte(kubefipConfigmap.Data["kubevipRolloutWaves"]), &kubevipRolloutWaves); err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing kubevipRolloutWaves: %s", err)
+		} else {
+			kubefipConfig.KubevipRolloutWaves = kubevipRolloutWaves
+		}
+	}
+
+	if kubefipConfigmap.Data["kubevipRolloutHealthTimeout"] != "" {
+		kubevipRolloutHealthTimeout, err := strconv.Atoi(kubefipConfigmap.Data["kubevipRolloutHealthTimeout"])
+		if err != nil || kubevipRolloutHealthTimeout < 1 {
+			log.Errorf("(parseKubfipConfigMap) error parsing kubevipRolloutHealthTimeout, it must be a positive number: [%s]",
+				kubefipConfigmap.Data["kubevipRolloutHealthTimeout"])
+		} else {
+			kubefipConfig.KubevipRolloutHealthTimeout = kubevipRolloutHealthTimeout
+		}
+	}
+
+	if kubefipConfigmap.Data["kubevipRolloutRollback"] != "" {
+		kubevipRolloutRollback, err := strconv.ParseBool(kubefipConfigmap.Data["kubevipRolloutRollback"])
+		if err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing kubevipRolloutRollback: %s", err)
+		} else {
+			kubefipConfig.KubevipRolloutRollback = kubevipRolloutRollback
+		}
+	}
 
-		kubefipConfig.KubevipUpdate = kubevipUpdate
+	if kubefipConfigmap.Data["lbBackend"] != "" {
+		kubefipConfig.LBBackend = strings.ToLower(kubefipConfigmap.Data["lbBackend"])
+	}
+
+	if kubefipConfigmap.Data["metallbNamespace"] != "" {
+		kubefipConfig.MetallbNamespace = kubefipConfigmap.Data["metallbNamespace"]
+	}
+
+	if kubefipConfigmap.Data["metallbReleaseName"] != "" {
+		kubefipConfig.MetallbReleaseName = kubefipConfigmap.Data["metallbReleaseName"]
+	}
+
+	if kubefipConfigmap.Data["metallbChartRepoUrl"] != "" {
+		kubefipConfig.MetallbChartRepoUrl = kubefipConfigmap.Data["metallbChartRepoUrl"]
+	}
+
+	if kubefipConfigmap.Data["metallbChartRef"] != "" {
+		kubefipConfig.MetallbChartRef = kubefipConfigmap.Data["metallbChartRef"]
+	}
+
+	if kubefipConfigmap.Data["metallbChartVersion"] != "" {
+		kubefipConfig.MetallbChartVersion = kubefipConfigmap.Data["metallbChartVersion"]
+	}
+
+	if kubefipConfigmap.Data["metallbChartValues"] != "" {
+		kubefipConfig.MetallbChartValues = kubefipConfigmap.Data["metallbChartValues"]
+	}
+
+	if kubefipConfigmap.Data["clusterRangeRules"] != "" {
+		var clusterRangeRules []ClusterRangeRule
+		if err := yaml.Unmarshal([]byte(kubefipConfigmap.Data["clusterRangeRules"]), &clusterRangeRules); err != nil {
+			log.Errorf("(parseKubfipConfigMap) error parsing clusterRangeRules: %s", err)
+		} else {
+			kubefipConfig.ClusterRangeRules = clusterRangeRules
+		}
 	}
 
-	log.Debugf("(ParseKubfipConfigMap) config options: LogLevel [%s] / TraceIpamData [%+v] / OperateGuestClusterInterval [%d] / "+
-		"MetricsPort [%d] / KubevipGuestInstall [%s] / KubevipNamespace [%s] / KubevipReleaseName [%s] / KubevipChartRepoUrl [%s] / "+
+	log.Debugf("(ParseKubfipConfigMap) config options: LogLevel [%s] / OperateGuestClusterInterval [%d] / "+
+		"MetricsPort [%d] / StatusAddress [%s] / KubevipGuestInstall [%s] / KubevipNamespace [%s] / KubevipReleaseName [%s] / KubevipChartRepoUrl [%s] / "+
 		"KubevipChartRef [%s] / KubevipChartVersion [%s] / KubevipChartValues [%s] / KubevipCloudProviderReleaseName [%s] / "+
-		"KubevipCloudProviderChartRef [%s] / KubevipCloudProviderChartVersion [%s] / KubevipCloudProviderChartValues [%s] / KubevipUpdate [%+v]",
-		kubefipConfig.LogLevel, kubefipConfig.TraceIpamData, kubefipConfig.OperateGuestClusterInterval, kubefipConfig.MetricsPort,
+		"KubevipCloudProviderChartRef [%s] / KubevipCloudProviderChartVersion [%s] / KubevipCloudProviderChartValues [%s] / "+
+		"ClusterSources [%+v] / ClusterRangeRules [%+v] / FipRangeSelectionPolicy [%s] / OrphanedFipGracePeriod [%d] / "+
+		"WebhookPort [%d] / WebhookCertDir [%s] / APIServerPort [%d] / GuestClusterWorkers [%d] / GuestClusterTimeout [%d] / "+
+		"KubevipRolloutWaves [%+v] / KubevipRolloutHealthTimeout [%d] / KubevipRolloutRollback [%t] / LBBackend [%s] / "+
+		"MetallbNamespace [%s] / MetallbReleaseName [%s] / MetallbChartRepoUrl [%s] / MetallbChartRef [%s] / "+
+		"MetallbChartVersion [%s] / MetallbChartValues [%s]",
+		kubefipConfig.LogLevel, kubefipConfig.OperateGuestClusterInterval, kubefipConfig.MetricsPort, kubefipConfig.StatusAddress,
 		kubefipConfig.KubevipGuestInstall, kubefipConfig.KubevipNamespace, kubefipConfig.KubevipReleaseName, kubefipConfig.KubevipChartRepoUrl,
 		kubefipConfig.KubevipChartRef, kubefipConfig.KubevipChartVersion, kubefipConfig.KubevipChartValues, kubefipConfig.KubevipCloudProviderReleaseName,
 		kubefipConfig.KubevipCloudProviderChartRef, kubefipConfig.KubevipCloudProviderChartVersion, kubefipConfig.KubevipCloudProviderChartValues,
-		kubefipConfig.KubevipUpdate)
+		kubefipConfig.ClusterSources, kubefipConfig.ClusterRangeRules,
+		kubefipConfig.FipRangeSelectionPolicy, kubefipConfig.OrphanedFipGracePeriod, kubefipConfig.WebhookPort, kubefipConfig.WebhookCertDir,
+		kubefipConfig.APIServerPort, kubefipConfig.GuestClusterWorkers, kubefipConfig.GuestClusterTimeout,
+		kubefipConfig.KubevipRolloutWaves, kubefipConfig.KubevipRolloutHealthTimeout, kubefipConfig.KubevipRolloutRollback, kubefipConfig.LBBackend,
+		kubefipConfig.MetallbNamespace, kubefipConfig.MetallbReleaseName, kubefipConfig.MetallbChartRepoUrl, kubefipConfig.MetallbChartRef,
+		kubefipConfig.MetallbChartVersion, kubefipConfig.MetallbChartValues)
 
 	return kubefipConfig
 }
@@ -5,32 +5,34 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
 
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 )
 
-func NewKubevipConfigmap(fip *KubefipV1.FloatingIP, kubevipConfigMapName string, kubevipConfigMapNamespace string) corev1.ConfigMap {
+const (
+	// the field manager of the server-side apply, it only owns the keys in the apply configurations below
+	FieldManager = "kube-fip-operator"
+
+	KeyCidrGlobal = "cidr-global"
+)
+
+// KubevipCidr returns the cidr-global value of the fip
+func KubevipCidr(fip *KubefipV2.FloatingIP) string {
+	return fmt.Sprintf("%s/32", fip.Spec.IPAddress)
+}
+
+// NewKubevipConfigmap returns the apply configuration with the keys the operator manages in the kubevip configmap, the
+// other keys (like the cidr-<namespace> and range-<namespace> keys of the guest admins) are left alone by the apply
+func NewKubevipConfigmap(fip *KubefipV2.FloatingIP, kubevipConfigMapName string, kubevipConfigMapNamespace string) *corev1ac.ConfigMapApplyConfiguration {
 	log.Debugf("(generateKubevipConfigmap) generating new kubevip configmap")
 
 	// generate the data objects
 	configMapData := make(map[string]string)
-	cidr := fmt.Sprintf("%s/32", fip.Spec.IPAddress)
-	configMapData["cidr-global"] = cidr
-
-	// create the corev1.ConfigMap type
-	kubevipConfigMap := corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      kubevipConfigMapName,
-			Namespace: kubevipConfigMapNamespace,
-		},
-		Data: configMapData,
-	}
+	configMapData[KeyCidrGlobal] = KubevipCidr(fip)
+
+	kubevipConfigMap := corev1ac.ConfigMap(kubevipConfigMapName, kubevipConfigMapNamespace).
+		WithData(configMapData)
 
 	log.Tracef("(generateKubevipConfigmap) generated configmap [%+v]", kubevipConfigMap)
 
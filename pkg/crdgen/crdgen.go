@@ -0,0 +1,294 @@
+// Package crdgen generates the CustomResourceDefinitions of deployments/crds.yaml from the types in pkg/apis. The
+// types are annotated with the kubebuilder markers, so the schema, printer columns and CEL validation rules live next
+// to the Go fields they belong to.
+package crdgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// the api packages relative to the repository root, the versions of a kind are ordered like the packages
+var APIDirs = []string{
+	"pkg/apis/kubefip.k8s.binbash.org/v1",
+	"pkg/apis/kubefip.k8s.binbash.org/v2",
+}
+
+// the conversion webhook of the kinds which are served in more than one version, see deployments/webhook.yaml
+const (
+	conversionServiceName      = "kube-fip-webhook"
+	conversionServiceNamespace = "kube-fip"
+	conversionServicePath      = "/convert"
+	conversionCertificate      = "kube-fip/kube-fip-webhook"
+)
+
+const header = "# Code generated by hack/crdgen from the types in pkg/apis. DO NOT EDIT.\n"
+
+// Generate returns the contents of deployments/crds.yaml, root is the path of the repository root
+func Generate(root string) ([]byte, error) {
+	var packages []*apiPackage
+
+	for _, dir := range APIDirs {
+		p, err := parsePackage(filepath.Join(root, dir))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", dir, err.Error())
+		}
+
+		packages = append(packages, p)
+	}
+
+	var kinds []string
+	for _, p := range packages {
+		for _, name := range p.Order {
+			if isRootType(p.Types[name]) && !contains(kinds, name) {
+				kinds = append(kinds, name)
+			}
+		}
+	}
+
+	out := bytes.NewBufferString(header)
+	for i, kind := range kinds {
+		crd, err := generateCRD(kind, packages)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", kind, err.Error())
+		}
+
+		doc, err := marshalCRD(crd)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(doc)
+	}
+
+	return out.Bytes(), nil
+}
+
+func parsePackage(dir string) (*apiPackage, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && !strings.HasPrefix(info.Name(), "zz_generated")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package, found %d", len(pkgs))
+	}
+
+	p := &apiPackage{Types: make(map[string]*apiType)}
+
+	for _, pkg := range pkgs {
+		p.Version = pkg.Name
+
+		var fileNames []string
+		for fileName := range pkg.Files {
+			fileNames = append(fileNames, fileName)
+		}
+		sort.Strings(fileNames)
+
+		for _, fileName := range fileNames {
+			file := pkg.Files[fileName]
+
+			fileMarkers, _ := parseComments(file.Doc)
+			for _, m := range fileMarkers {
+				if m.Name == "groupName" {
+					p.Group = m.Value
+				}
+			}
+
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					markers, description := parseComments(typeComments(fset, file, genDecl)...)
+
+					p.Types[typeSpec.Name.Name] = &apiType{
+						Name:        typeSpec.Name.Name,
+						Expr:        typeSpec.Type,
+						Markers:     markers,
+						Description: description,
+					}
+					p.Order = append(p.Order, typeSpec.Name.Name)
+				}
+			}
+		}
+	}
+
+	if p.Group == "" {
+		return nil, fmt.Errorf("no +groupName marker found")
+	}
+
+	return p, nil
+}
+
+// isRootType returns true for the types which have a client, those are the kinds of the crds
+func isRootType(t *apiType) bool {
+	return hasMarker(t.Markers, "genclient")
+}
+
+func generateCRD(kind string, packages []*apiPackage) (apiextensionsv1.CustomResourceDefinition, error) {
+	plural := strings.ToLower(kind) + "s"
+
+	crd := apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   plural,
+				Singular: strings.ToLower(kind),
+				Kind:     kind,
+				ListKind: kind + "List",
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+		},
+	}
+
+	storageVersions := 0
+	for _, p := range packages {
+		t, ok := p.Types[kind]
+		if !ok || !isRootType(t) {
+			continue
+		}
+
+		crd.Spec.Group = p.Group
+		crd.ObjectMeta.Name = fmt.Sprintf("%s.%s", plural, p.Group)
+
+		if hasMarker(t.Markers, "genclient:nonNamespaced") {
+			crd.Spec.Scope = apiextensionsv1.ClusterScoped
+		}
+
+		version := apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    p.Version,
+			Served:  true,
+			Storage: hasMarker(t.Markers, "kubebuilder:storageversion"),
+		}
+		if version.Storage {
+			storageVersions++
+		}
+
+		schema, err := p.schemaFor(&ast.Ident{Name: kind})
+		if err != nil {
+			return crd, err
+		}
+		schema.Description = t.Description
+		version.Schema = &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &schema}
+
+		for _, m := range t.Markers {
+			switch m.Name {
+			case "kubebuilder:resource":
+				args, err := parseArgs(m.Value)
+				if err != nil {
+					return crd, err
+				}
+				if args["shortName"] != "" {
+					crd.Spec.Names.ShortNames = strings.Split(args["shortName"], ";")
+				}
+			case "kubebuilder:printcolumn":
+				args, err := parseArgs(m.Value)
+				if err != nil {
+					return crd, err
+				}
+				version.AdditionalPrinterColumns = append(version.AdditionalPrinterColumns, apiextensionsv1.CustomResourceColumnDefinition{
+					Name:     args["name"],
+					Type:     args["type"],
+					JSONPath: args["JSONPath"],
+				})
+			}
+		}
+
+		crd.Spec.Versions = append(crd.Spec.Versions, version)
+	}
+
+	if len(crd.Spec.Versions) == 1 {
+		crd.Spec.Versions[0].Storage = true
+
+		return crd, nil
+	}
+
+	if storageVersions != 1 {
+		return crd, fmt.Errorf("expected one +kubebuilder:storageversion marker, found %d", storageVersions)
+	}
+
+	path := conversionServicePath
+	crd.ObjectMeta.Annotations = map[string]string{
+		"cert-manager.io/inject-ca-from": conversionCertificate,
+	}
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Name:      conversionServiceName,
+					Namespace: conversionServiceNamespace,
+					Path:      &path,
+				},
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+
+	return crd, nil
+}
+
+// marshalCRD returns the crd as yaml without the status and the empty creationTimestamp
+func marshalCRD(crd apiextensionsv1.CustomResourceDefinition) ([]byte, error) {
+	data, err := json.Marshal(crd)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+
+	return yaml.Marshal(obj)
+}
+
+func hasMarker(markers []marker, name string) bool {
+	for _, m := range markers {
+		if m.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
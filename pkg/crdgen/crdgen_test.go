@@ -0,0 +1,26 @@
+package crdgen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCRDsUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+
+	generated, err := Generate(root)
+	if err != nil {
+		t.Fatalf("error generating the crds: %s", err.Error())
+	}
+
+	checkedIn, err := os.ReadFile(filepath.Join(root, "deployments", "crds.yaml"))
+	if err != nil {
+		t.Fatalf("error reading deployments/crds.yaml: %s", err.Error())
+	}
+
+	if !bytes.Equal(generated, checkedIn) {
+		t.Fatalf("deployments/crds.yaml differs from the types in pkg/apis, run `go run ./hack/crdgen` to regenerate it")
+	}
+}
@@ -0,0 +1,161 @@
+package crdgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// the markers which have a list of key=value arguments instead of a single value
+var argMarkers = []string{
+	"kubebuilder:resource",
+	"kubebuilder:printcolumn",
+	"kubebuilder:validation:XValidation",
+}
+
+type marker struct {
+	Name  string
+	Value string
+}
+
+// parseComments splits a comment group in the +markers and the description lines
+func parseComments(groups ...*ast.CommentGroup) ([]marker, string) {
+	var markers []marker
+	var description []string
+
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+
+		for _, line := range strings.Split(group.Text(), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, "+") {
+				markers = append(markers, parseMarker(strings.TrimPrefix(line, "+")))
+
+				continue
+			}
+
+			description = append(description, line)
+		}
+	}
+
+	return markers, strings.Join(description, " ")
+}
+
+func parseMarker(line string) marker {
+	for _, name := range argMarkers {
+		if strings.HasPrefix(line, name+":") {
+			return marker{Name: name, Value: strings.TrimPrefix(line, name+":")}
+		}
+	}
+
+	if i := strings.Index(line, "="); i != -1 {
+		return marker{Name: line[:i], Value: line[i+1:]}
+	}
+
+	return marker{Name: line}
+}
+
+// typeComments returns the comment groups of a type, which are the doc comment plus the comment groups right above
+// it which are separated by a single empty line, like the +genclient markers
+func typeComments(fset *token.FileSet, file *ast.File, decl *ast.GenDecl) []*ast.CommentGroup {
+	var groups []*ast.CommentGroup
+
+	line := fset.Position(decl.Pos()).Line
+	if decl.Doc != nil {
+		groups = append(groups, decl.Doc)
+		line = fset.Position(decl.Doc.Pos()).Line
+	}
+
+	for {
+		var found *ast.CommentGroup
+		for _, group := range file.Comments {
+			if group == decl.Doc {
+				continue
+			}
+
+			end := fset.Position(group.End()).Line
+			if end == line-1 || end == line-2 {
+				found = group
+			}
+		}
+
+		if found == nil {
+			return groups
+		}
+
+		groups = append(groups, found)
+		line = fset.Position(found.Pos()).Line
+	}
+}
+
+// parseArgs parses the key=value,key="value" arguments of a marker
+func parseArgs(value string) (map[string]string, error) {
+	args := make(map[string]string)
+
+	for value != "" {
+		i := strings.Index(value, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("missing value for argument [%s]", value)
+		}
+
+		key := value[:i]
+		value = value[i+1:]
+
+		var arg string
+		if strings.HasPrefix(value, "\"") {
+			end := 1
+			for ; end < len(value); end++ {
+				if value[end] == '\\' {
+					end++
+				} else if value[end] == '"' {
+					break
+				}
+			}
+			if end >= len(value) {
+				return nil, fmt.Errorf("unterminated string for argument [%s]", key)
+			}
+
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string for argument [%s]: %s", key, err.Error())
+			}
+
+			arg = unquoted
+			value = value[end+1:]
+		} else {
+			end := strings.Index(value, ",")
+			if end == -1 {
+				end = len(value)
+			}
+
+			arg = value[:end]
+			value = value[end:]
+		}
+
+		args[key] = arg
+		value = strings.TrimPrefix(value, ",")
+	}
+
+	return args, nil
+}
+
+// unquoteValue strips the quotes or backticks of a single marker value
+func unquoteValue(value string) (string, error) {
+	if strings.HasPrefix(value, "`") && strings.HasSuffix(value, "`") && len(value) > 1 {
+		return value[1 : len(value)-1], nil
+	}
+
+	if strings.HasPrefix(value, "\"") {
+		return strconv.Unquote(value)
+	}
+
+	return value, nil
+}
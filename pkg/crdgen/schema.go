@@ -0,0 +1,304 @@
+package crdgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// apiType is a named type of an api package
+type apiType struct {
+	Name        string
+	Expr        ast.Expr
+	Markers     []marker
+	Description string
+}
+
+type apiPackage struct {
+	Group   string
+	Version string
+	Types   map[string]*apiType
+	Order   []string
+}
+
+// the schemas of the types which are used from other packages
+var knownTypes = map[string]func() apiextensionsv1.JSONSchemaProps{
+	"metav1.ObjectMeta": func() apiextensionsv1.JSONSchemaProps {
+		return apiextensionsv1.JSONSchemaProps{Type: "object"}
+	},
+	"metav1.Time": func() apiextensionsv1.JSONSchemaProps {
+		return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "date-time"}
+	},
+	"metav1.LabelSelector": labelSelectorSchema,
+}
+
+func labelSelectorSchema() apiextensionsv1.JSONSchemaProps {
+	var operators []apiextensionsv1.JSON
+	for _, operator := range []string{"In", "NotIn", "Exists", "DoesNotExist"} {
+		operators = append(operators, apiextensionsv1.JSON{Raw: []byte(strconv.Quote(operator))})
+	}
+
+	return apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"matchLabels": {
+				Type: "object",
+				AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+					Allows: true,
+					Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"},
+				},
+			},
+			"matchExpressions": {
+				Type: "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"key", "operator"},
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"key":      {Type: "string"},
+							"operator": {Type: "string", Enum: operators},
+							"values": {
+								Type:  "array",
+								Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *apiPackage) schemaFor(expr ast.Expr) (apiextensionsv1.JSONSchemaProps, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return apiextensionsv1.JSONSchemaProps{Type: "string"}, nil
+		case "bool":
+			return apiextensionsv1.JSONSchemaProps{Type: "boolean"}, nil
+		case "int":
+			return apiextensionsv1.JSONSchemaProps{Type: "integer"}, nil
+		case "int32":
+			return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int32"}, nil
+		case "int64":
+			return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int64"}, nil
+		case "float64":
+			return apiextensionsv1.JSONSchemaProps{Type: "number"}, nil
+		}
+
+		namedType, ok := p.Types[t.Name]
+		if !ok {
+			return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unknown type [%s]", t.Name)
+		}
+
+		schema, err := p.schemaFor(namedType.Expr)
+		if err != nil {
+			return schema, fmt.Errorf("%s: %s", t.Name, err.Error())
+		}
+
+		return schema, applyMarkers(&schema, namedType.Markers)
+	case *ast.StarExpr:
+		return p.schemaFor(t.X)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "byte"}, nil
+		}
+
+		items, err := p.schemaFor(t.Elt)
+		if err != nil {
+			return items, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{Type: "array", Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items}}, nil
+	case *ast.MapType:
+		values, err := p.schemaFor(t.Value)
+		if err != nil {
+			return values, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{
+			Type:                 "object",
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values},
+		}, nil
+	case *ast.SelectorExpr:
+		name := fmt.Sprintf("%s.%s", t.X.(*ast.Ident).Name, t.Sel.Name)
+		if knownType, ok := knownTypes[name]; ok {
+			return knownType(), nil
+		}
+
+		return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unsupported external type [%s]", name)
+	case *ast.StructType:
+		return p.structSchema(t)
+	}
+
+	return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unsupported type expression [%T]", expr)
+}
+
+func (p *apiPackage) structSchema(st *ast.StructType) (apiextensionsv1.JSONSchemaProps, error) {
+	schema := apiextensionsv1.JSONSchemaProps{
+		Type:       "object",
+		Properties: make(map[string]apiextensionsv1.JSONSchemaProps),
+	}
+
+	for _, field := range st.Fields.List {
+		var tag string
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return schema, err
+			}
+			tag = reflect.StructTag(unquoted).Get("json")
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		tagName, tagOptions, _ := strings.Cut(tag, ",")
+		omitEmpty := strings.Contains(tagOptions, "omitempty")
+
+		// embedded structs
+		if len(field.Names) == 0 {
+			if err := p.embedField(&schema, field.Type); err != nil {
+				return schema, err
+			}
+
+			continue
+		}
+
+		name := tagName
+		if name == "" {
+			name = field.Names[0].Name
+		}
+
+		fieldSchema, err := p.schemaFor(field.Type)
+		if err != nil {
+			return schema, fmt.Errorf("field [%s]: %s", name, err.Error())
+		}
+
+		markers, description := parseComments(field.Doc)
+		fieldSchema.Description = description
+		if err := applyMarkers(&fieldSchema, markers); err != nil {
+			return schema, fmt.Errorf("field [%s]: %s", name, err.Error())
+		}
+
+		required := !omitEmpty
+		for _, m := range markers {
+			switch m.Name {
+			case "optional", "kubebuilder:validation:Optional":
+				required = false
+			case "required", "kubebuilder:validation:Required":
+				required = true
+			}
+		}
+
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema, nil
+}
+
+func (p *apiPackage) embedField(schema *apiextensionsv1.JSONSchemaProps, expr ast.Expr) error {
+	if selector, ok := expr.(*ast.SelectorExpr); ok {
+		switch selector.Sel.Name {
+		case "TypeMeta":
+			schema.Properties["apiVersion"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+			schema.Properties["kind"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+
+			return nil
+		case "ObjectMeta":
+			schema.Properties["metadata"] = apiextensionsv1.JSONSchemaProps{Type: "object"}
+
+			return nil
+		case "ListMeta":
+			return nil
+		}
+	}
+
+	embedded, err := p.schemaFor(expr)
+	if err != nil {
+		return err
+	}
+
+	for name, property := range embedded.Properties {
+		schema.Properties[name] = property
+	}
+	schema.Required = append(schema.Required, embedded.Required...)
+
+	return nil
+}
+
+// applyMarkers adds the kubebuilder validation markers to the schema
+func applyMarkers(schema *apiextensionsv1.JSONSchemaProps, markers []marker) error {
+	for _, m := range markers {
+		if !strings.HasPrefix(m.Name, "kubebuilder:validation:") && m.Name != "kubebuilder:default" {
+			continue
+		}
+
+		switch m.Name {
+		case "kubebuilder:validation:Optional", "kubebuilder:validation:Required":
+		case "kubebuilder:validation:Pattern":
+			pattern, err := unquoteValue(m.Value)
+			if err != nil {
+				return fmt.Errorf("invalid pattern [%s]: %s", m.Value, err.Error())
+			}
+			schema.Pattern = pattern
+		case "kubebuilder:validation:Format":
+			schema.Format = m.Value
+		case "kubebuilder:validation:MinLength", "kubebuilder:validation:MaxLength":
+			length, err := strconv.ParseInt(m.Value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s [%s]", m.Name, m.Value)
+			}
+			if m.Name == "kubebuilder:validation:MinLength" {
+				schema.MinLength = &length
+			} else {
+				schema.MaxLength = &length
+			}
+		case "kubebuilder:validation:Minimum", "kubebuilder:validation:Maximum":
+			limit, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s [%s]", m.Name, m.Value)
+			}
+			if m.Name == "kubebuilder:validation:Minimum" {
+				schema.Minimum = &limit
+			} else {
+				schema.Maximum = &limit
+			}
+		case "kubebuilder:validation:Enum":
+			for _, value := range strings.Split(m.Value, ";") {
+				schema.Enum = append(schema.Enum, apiextensionsv1.JSON{Raw: []byte(strconv.Quote(value))})
+			}
+		case "kubebuilder:default":
+			if !json.Valid([]byte(m.Value)) {
+				return fmt.Errorf("default [%s] is not a json value", m.Value)
+			}
+			schema.Default = &apiextensionsv1.JSON{Raw: []byte(m.Value)}
+		case "kubebuilder:validation:XValidation":
+			args, err := parseArgs(m.Value)
+			if err != nil {
+				return fmt.Errorf("invalid XValidation: %s", err.Error())
+			}
+			if args["rule"] == "" {
+				return fmt.Errorf("XValidation without a rule")
+			}
+			schema.XValidations = append(schema.XValidations, apiextensionsv1.ValidationRule{
+				Rule:    args["rule"],
+				Message: args["message"],
+			})
+		default:
+			return fmt.Errorf("unsupported marker [+%s]", m.Name)
+		}
+	}
+
+	return nil
+}
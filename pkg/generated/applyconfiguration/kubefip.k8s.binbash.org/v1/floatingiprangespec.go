@@ -18,10 +18,17 @@ limitations under the License.
 
 package v1
 
+import (
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
 // FloatingIPRangeSpecApplyConfiguration represents a declarative configuration of the FloatingIPRangeSpec type for use
 // with apply.
 type FloatingIPRangeSpecApplyConfiguration struct {
-	IPRange *string `json:"iprange,omitempty"`
+	IPRange         *string                                 `json:"iprange,omitempty"`
+	ClusterSelector *metav1.LabelSelectorApplyConfiguration `json:"clusterSelector,omitempty"`
+	Priority        *int                                    `json:"priority,omitempty"`
+	Weight          *int                                    `json:"weight,omitempty"`
 }
 
 // FloatingIPRangeSpecApplyConfiguration constructs a declarative configuration of the FloatingIPRangeSpec type for use with
@@ -37,3 +44,27 @@ func (b *FloatingIPRangeSpecApplyConfiguration) WithIPRange(value string) *Float
 	b.IPRange = &value
 	return b
 }
+
+// WithClusterSelector sets the ClusterSelector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterSelector field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithClusterSelector(value *metav1.LabelSelectorApplyConfiguration) *FloatingIPRangeSpecApplyConfiguration {
+	b.ClusterSelector = value
+	return b
+}
+
+// WithPriority sets the Priority field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Priority field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithPriority(value int) *FloatingIPRangeSpecApplyConfiguration {
+	b.Priority = &value
+	return b
+}
+
+// WithWeight sets the Weight field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Weight field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithWeight(value int) *FloatingIPRangeSpecApplyConfiguration {
+	b.Weight = &value
+	return b
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// FloatingIPReservationSpecApplyConfiguration represents a declarative configuration of the FloatingIPReservationSpec type for use
+// with apply.
+type FloatingIPReservationSpecApplyConfiguration struct {
+	IPAddress       *string                                 `json:"ipaddress,omitempty"`
+	FipRange        *string                                 `json:"fiprange,omitempty"`
+	ClusterName     *string                                 `json:"clusterName,omitempty"`
+	ClusterSelector *metav1.LabelSelectorApplyConfiguration `json:"clusterSelector,omitempty"`
+	Expires         *apismetav1.Time                        `json:"expires,omitempty"`
+}
+
+// FloatingIPReservationSpecApplyConfiguration constructs a declarative configuration of the FloatingIPReservationSpec type for use with
+// apply.
+func FloatingIPReservationSpec() *FloatingIPReservationSpecApplyConfiguration {
+	return &FloatingIPReservationSpecApplyConfiguration{}
+}
+
+// WithIPAddress sets the IPAddress field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the IPAddress field is set to the value of the last call.
+func (b *FloatingIPReservationSpecApplyConfiguration) WithIPAddress(value string) *FloatingIPReservationSpecApplyConfiguration {
+	b.IPAddress = &value
+	return b
+}
+
+// WithFipRange sets the FipRange field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the FipRange field is set to the value of the last call.
+func (b *FloatingIPReservationSpecApplyConfiguration) WithFipRange(value string) *FloatingIPReservationSpecApplyConfiguration {
+	b.FipRange = &value
+	return b
+}
+
+// WithClusterName sets the ClusterName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterName field is set to the value of the last call.
+func (b *FloatingIPReservationSpecApplyConfiguration) WithClusterName(value string) *FloatingIPReservationSpecApplyConfiguration {
+	b.ClusterName = &value
+	return b
+}
+
+// WithClusterSelector sets the ClusterSelector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterSelector field is set to the value of the last call.
+func (b *FloatingIPReservationSpecApplyConfiguration) WithClusterSelector(value *metav1.LabelSelectorApplyConfiguration) *FloatingIPReservationSpecApplyConfiguration {
+	b.ClusterSelector = value
+	return b
+}
+
+// WithExpires sets the Expires field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Expires field is set to the value of the last call.
+func (b *FloatingIPReservationSpecApplyConfiguration) WithExpires(value apismetav1.Time) *FloatingIPReservationSpecApplyConfiguration {
+	b.Expires = &value
+	return b
+}
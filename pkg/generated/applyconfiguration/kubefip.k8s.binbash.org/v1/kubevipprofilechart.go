@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+// KubeVipProfileChartApplyConfiguration represents a declarative configuration of the KubeVipProfileChart type for use
+// with apply.
+type KubeVipProfileChartApplyConfiguration struct {
+	ChartRef     *string `json:"chartRef,omitempty"`
+	ChartVersion *string `json:"chartVersion,omitempty"`
+	Values       *string `json:"values,omitempty"`
+}
+
+// KubeVipProfileChartApplyConfiguration constructs a declarative configuration of the KubeVipProfileChart type for use with
+// apply.
+func KubeVipProfileChart() *KubeVipProfileChartApplyConfiguration {
+	return &KubeVipProfileChartApplyConfiguration{}
+}
+
+// WithChartRef sets the ChartRef field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartRef field is set to the value of the last call.
+func (b *KubeVipProfileChartApplyConfiguration) WithChartRef(value string) *KubeVipProfileChartApplyConfiguration {
+	b.ChartRef = &value
+	return b
+}
+
+// WithChartVersion sets the ChartVersion field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ChartVersion field is set to the value of the last call.
+func (b *KubeVipProfileChartApplyConfiguration) WithChartVersion(value string) *KubeVipProfileChartApplyConfiguration {
+	b.ChartVersion = &value
+	return b
+}
+
+// WithValues sets the Values field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Values field is set to the value of the last call.
+func (b *KubeVipProfileChartApplyConfiguration) WithValues(value string) *KubeVipProfileChartApplyConfiguration {
+	b.Values = &value
+	return b
+}
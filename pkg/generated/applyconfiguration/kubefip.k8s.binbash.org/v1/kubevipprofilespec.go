@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// KubeVipProfileSpecApplyConfiguration represents a declarative configuration of the KubeVipProfileSpec type for use
+// with apply.
+type KubeVipProfileSpecApplyConfiguration struct {
+	ClusterSelector      *metav1.LabelSelectorApplyConfiguration `json:"clusterSelector,omitempty"`
+	HarvesterClusterName *string                                 `json:"harvesterClusterName,omitempty"`
+	Kubevip              *KubeVipProfileChartApplyConfiguration  `json:"kubevip,omitempty"`
+	CloudProvider        *KubeVipProfileChartApplyConfiguration  `json:"cloudProvider,omitempty"`
+}
+
+// KubeVipProfileSpecApplyConfiguration constructs a declarative configuration of the KubeVipProfileSpec type for use with
+// apply.
+func KubeVipProfileSpec() *KubeVipProfileSpecApplyConfiguration {
+	return &KubeVipProfileSpecApplyConfiguration{}
+}
+
+// WithClusterSelector sets the ClusterSelector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterSelector field is set to the value of the last call.
+func (b *KubeVipProfileSpecApplyConfiguration) WithClusterSelector(value *metav1.LabelSelectorApplyConfiguration) *KubeVipProfileSpecApplyConfiguration {
+	b.ClusterSelector = value
+	return b
+}
+
+// WithHarvesterClusterName sets the HarvesterClusterName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the HarvesterClusterName field is set to the value of the last call.
+func (b *KubeVipProfileSpecApplyConfiguration) WithHarvesterClusterName(value string) *KubeVipProfileSpecApplyConfiguration {
+	b.HarvesterClusterName = &value
+	return b
+}
+
+// WithKubevip sets the Kubevip field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Kubevip field is set to the value of the last call.
+func (b *KubeVipProfileSpecApplyConfiguration) WithKubevip(value *KubeVipProfileChartApplyConfiguration) *KubeVipProfileSpecApplyConfiguration {
+	b.Kubevip = value
+	return b
+}
+
+// WithCloudProvider sets the CloudProvider field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the CloudProvider field is set to the value of the last call.
+func (b *KubeVipProfileSpecApplyConfiguration) WithCloudProvider(value *KubeVipProfileChartApplyConfiguration) *KubeVipProfileSpecApplyConfiguration {
+	b.CloudProvider = value
+	return b
+}
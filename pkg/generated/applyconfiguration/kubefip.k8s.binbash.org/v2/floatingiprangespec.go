@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	v1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// FloatingIPRangeSpecApplyConfiguration represents a declarative configuration of the FloatingIPRangeSpec type for use
+// with apply.
+type FloatingIPRangeSpecApplyConfiguration struct {
+	IPRange              *string                             `json:"ipRange,omitempty"`
+	HarvesterClusterName *string                             `json:"harvesterClusterName,omitempty"`
+	HarvesterNetworkName *string                             `json:"harvesterNetworkName,omitempty"`
+	ClusterSelector      *v1.LabelSelectorApplyConfiguration `json:"clusterSelector,omitempty"`
+	Priority             *int                                `json:"priority,omitempty"`
+	Weight               *int                                `json:"weight,omitempty"`
+}
+
+// FloatingIPRangeSpecApplyConfiguration constructs a declarative configuration of the FloatingIPRangeSpec type for use with
+// apply.
+func FloatingIPRangeSpec() *FloatingIPRangeSpecApplyConfiguration {
+	return &FloatingIPRangeSpecApplyConfiguration{}
+}
+
+// WithIPRange sets the IPRange field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the IPRange field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithIPRange(value string) *FloatingIPRangeSpecApplyConfiguration {
+	b.IPRange = &value
+	return b
+}
+
+// WithHarvesterClusterName sets the HarvesterClusterName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the HarvesterClusterName field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithHarvesterClusterName(value string) *FloatingIPRangeSpecApplyConfiguration {
+	b.HarvesterClusterName = &value
+	return b
+}
+
+// WithHarvesterNetworkName sets the HarvesterNetworkName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the HarvesterNetworkName field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithHarvesterNetworkName(value string) *FloatingIPRangeSpecApplyConfiguration {
+	b.HarvesterNetworkName = &value
+	return b
+}
+
+// WithClusterSelector sets the ClusterSelector field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterSelector field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithClusterSelector(value *v1.LabelSelectorApplyConfiguration) *FloatingIPRangeSpecApplyConfiguration {
+	b.ClusterSelector = value
+	return b
+}
+
+// WithPriority sets the Priority field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Priority field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithPriority(value int) *FloatingIPRangeSpecApplyConfiguration {
+	b.Priority = &value
+	return b
+}
+
+// WithWeight sets the Weight field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Weight field is set to the value of the last call.
+func (b *FloatingIPRangeSpecApplyConfiguration) WithWeight(value int) *FloatingIPRangeSpecApplyConfiguration {
+	b.Weight = &value
+	return b
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v2
+
+// FloatingIPSpecApplyConfiguration represents a declarative configuration of the FloatingIPSpec type for use
+// with apply.
+type FloatingIPSpecApplyConfiguration struct {
+	IPAddress       *string `json:"ipAddress,omitempty"`
+	ClusterName     *string `json:"clusterName,omitempty"`
+	FipRange        *string `json:"fipRange,omitempty"`
+	UpdateConfigMap *bool   `json:"updateConfigMap,omitempty"`
+}
+
+// FloatingIPSpecApplyConfiguration constructs a declarative configuration of the FloatingIPSpec type for use with
+// apply.
+func FloatingIPSpec() *FloatingIPSpecApplyConfiguration {
+	return &FloatingIPSpecApplyConfiguration{}
+}
+
+// WithIPAddress sets the IPAddress field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the IPAddress field is set to the value of the last call.
+func (b *FloatingIPSpecApplyConfiguration) WithIPAddress(value string) *FloatingIPSpecApplyConfiguration {
+	b.IPAddress = &value
+	return b
+}
+
+// WithClusterName sets the ClusterName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ClusterName field is set to the value of the last call.
+func (b *FloatingIPSpecApplyConfiguration) WithClusterName(value string) *FloatingIPSpecApplyConfiguration {
+	b.ClusterName = &value
+	return b
+}
+
+// WithFipRange sets the FipRange field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the FipRange field is set to the value of the last call.
+func (b *FloatingIPSpecApplyConfiguration) WithFipRange(value string) *FloatingIPSpecApplyConfiguration {
+	b.FipRange = &value
+	return b
+}
+
+// WithUpdateConfigMap sets the UpdateConfigMap field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the UpdateConfigMap field is set to the value of the last call.
+func (b *FloatingIPSpecApplyConfiguration) WithUpdateConfigMap(value bool) *FloatingIPSpecApplyConfiguration {
+	b.UpdateConfigMap = &value
+	return b
+}
@@ -20,8 +20,10 @@ package applyconfiguration
 
 import (
 	v1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	v2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	internal "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/internal"
 	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v1"
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v2"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	testing "k8s.io/client-go/testing"
@@ -38,8 +40,30 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 		return &kubefipk8sbinbashorgv1.FloatingIPRangeApplyConfiguration{}
 	case v1.SchemeGroupVersion.WithKind("FloatingIPRangeSpec"):
 		return &kubefipk8sbinbashorgv1.FloatingIPRangeSpecApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("FloatingIPReservation"):
+		return &kubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("FloatingIPReservationSpec"):
+		return &kubefipk8sbinbashorgv1.FloatingIPReservationSpecApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("FloatingIPReservationStatus"):
+		return &kubefipk8sbinbashorgv1.FloatingIPReservationStatusApplyConfiguration{}
 	case v1.SchemeGroupVersion.WithKind("FloatingIPSpec"):
 		return &kubefipk8sbinbashorgv1.FloatingIPSpecApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("KubeVipProfile"):
+		return &kubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("KubeVipProfileChart"):
+		return &kubefipk8sbinbashorgv1.KubeVipProfileChartApplyConfiguration{}
+	case v1.SchemeGroupVersion.WithKind("KubeVipProfileSpec"):
+		return &kubefipk8sbinbashorgv1.KubeVipProfileSpecApplyConfiguration{}
+
+		// Group=kubefip.k8s.binbash.org, Version=v2
+	case v2.SchemeGroupVersion.WithKind("FloatingIP"):
+		return &kubefipk8sbinbashorgv2.FloatingIPApplyConfiguration{}
+	case v2.SchemeGroupVersion.WithKind("FloatingIPRange"):
+		return &kubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration{}
+	case v2.SchemeGroupVersion.WithKind("FloatingIPRangeSpec"):
+		return &kubefipk8sbinbashorgv2.FloatingIPRangeSpecApplyConfiguration{}
+	case v2.SchemeGroupVersion.WithKind("FloatingIPSpec"):
+		return &kubefipk8sbinbashorgv2.FloatingIPSpecApplyConfiguration{}
 
 	}
 	return nil
@@ -23,6 +23,7 @@ import (
 	http "net/http"
 
 	kubefipv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v1"
+	kubefipv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v2"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -31,12 +32,14 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	KubefipV1() kubefipv1.KubefipV1Interface
+	KubefipV2() kubefipv2.KubefipV2Interface
 }
 
 // Clientset contains the clients for groups.
 type Clientset struct {
 	*discovery.DiscoveryClient
 	kubefipV1 *kubefipv1.KubefipV1Client
+	kubefipV2 *kubefipv2.KubefipV2Client
 }
 
 // KubefipV1 retrieves the KubefipV1Client
@@ -44,6 +47,11 @@ func (c *Clientset) KubefipV1() kubefipv1.KubefipV1Interface {
 	return c.kubefipV1
 }
 
+// KubefipV2 retrieves the KubefipV2Client
+func (c *Clientset) KubefipV2() kubefipv2.KubefipV2Interface {
+	return c.kubefipV2
+}
+
 // Discovery retrieves the DiscoveryClient
 func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -92,6 +100,10 @@ func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset,
 	if err != nil {
 		return nil, err
 	}
+	cs.kubefipV2, err = kubefipv2.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
 
 	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
@@ -114,6 +126,7 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.kubefipV1 = kubefipv1.New(c)
+	cs.kubefipV2 = kubefipv2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -23,6 +23,8 @@ import (
 	clientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	kubefipv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v1"
 	fakekubefipv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v1/fake"
+	kubefipv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v2"
+	fakekubefipv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v2/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -120,3 +122,8 @@ var (
 func (c *Clientset) KubefipV1() kubefipv1.KubefipV1Interface {
 	return &fakekubefipv1.FakeKubefipV1{Fake: &c.Fake}
 }
+
+// KubefipV2 retrieves the KubefipV2Client
+func (c *Clientset) KubefipV2() kubefipv2.KubefipV2Interface {
+	return &fakekubefipv2.FakeKubefipV2{Fake: &c.Fake}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v1"
+	typedkubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeFloatingIPReservations implements FloatingIPReservationInterface
+type fakeFloatingIPReservations struct {
+	*gentype.FakeClientWithListAndApply[*v1.FloatingIPReservation, *v1.FloatingIPReservationList, *kubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration]
+	Fake *FakeKubefipV1
+}
+
+func newFakeFloatingIPReservations(fake *FakeKubefipV1) typedkubefipk8sbinbashorgv1.FloatingIPReservationInterface {
+	return &fakeFloatingIPReservations{
+		gentype.NewFakeClientWithListAndApply[*v1.FloatingIPReservation, *v1.FloatingIPReservationList, *kubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration](
+			fake.Fake,
+			"",
+			v1.SchemeGroupVersion.WithResource("floatingipreservations"),
+			v1.SchemeGroupVersion.WithKind("FloatingIPReservation"),
+			func() *v1.FloatingIPReservation { return &v1.FloatingIPReservation{} },
+			func() *v1.FloatingIPReservationList { return &v1.FloatingIPReservationList{} },
+			func(dst, src *v1.FloatingIPReservationList) { dst.ListMeta = src.ListMeta },
+			func(list *v1.FloatingIPReservationList) []*v1.FloatingIPReservation {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1.FloatingIPReservationList, items []*v1.FloatingIPReservation) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
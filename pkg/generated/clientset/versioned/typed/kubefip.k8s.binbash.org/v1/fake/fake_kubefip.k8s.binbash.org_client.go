@@ -29,11 +29,19 @@ type FakeKubefipV1 struct {
 }
 
 func (c *FakeKubefipV1) FloatingIPs(namespace string) v1.FloatingIPInterface {
-	return &FakeFloatingIPs{c, namespace}
+	return newFakeFloatingIPs(c, namespace)
 }
 
 func (c *FakeKubefipV1) FloatingIPRanges() v1.FloatingIPRangeInterface {
-	return &FakeFloatingIPRanges{c}
+	return newFakeFloatingIPRanges(c)
+}
+
+func (c *FakeKubefipV1) FloatingIPReservations() v1.FloatingIPReservationInterface {
+	return newFakeFloatingIPReservations(c)
+}
+
+func (c *FakeKubefipV1) KubeVipProfiles() v1.KubeVipProfileInterface {
+	return newFakeKubeVipProfiles(c)
 }
 
 // RESTClient returns a RESTClient that is used to communicate
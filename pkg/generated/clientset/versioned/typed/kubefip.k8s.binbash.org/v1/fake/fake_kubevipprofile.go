@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v1"
+	typedkubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeKubeVipProfiles implements KubeVipProfileInterface
+type fakeKubeVipProfiles struct {
+	*gentype.FakeClientWithListAndApply[*v1.KubeVipProfile, *v1.KubeVipProfileList, *kubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration]
+	Fake *FakeKubefipV1
+}
+
+func newFakeKubeVipProfiles(fake *FakeKubefipV1) typedkubefipk8sbinbashorgv1.KubeVipProfileInterface {
+	return &fakeKubeVipProfiles{
+		gentype.NewFakeClientWithListAndApply[*v1.KubeVipProfile, *v1.KubeVipProfileList, *kubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration](
+			fake.Fake,
+			"",
+			v1.SchemeGroupVersion.WithResource("kubevipprofiles"),
+			v1.SchemeGroupVersion.WithKind("KubeVipProfile"),
+			func() *v1.KubeVipProfile { return &v1.KubeVipProfile{} },
+			func() *v1.KubeVipProfileList { return &v1.KubeVipProfileList{} },
+			func(dst, src *v1.KubeVipProfileList) { dst.ListMeta = src.ListMeta },
+			func(list *v1.KubeVipProfileList) []*v1.KubeVipProfile { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1.KubeVipProfileList, items []*v1.KubeVipProfile) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -68,6 +68,7 @@ func newFloatingIPs(c *KubefipV1Client, namespace string) *floatingIPs {
 			scheme.ParameterCodec,
 			namespace,
 			func() *kubefipk8sbinbashorgv1.FloatingIP { return &kubefipk8sbinbashorgv1.FloatingIP{} },
-			func() *kubefipk8sbinbashorgv1.FloatingIPList { return &kubefipk8sbinbashorgv1.FloatingIPList{} }),
+			func() *kubefipk8sbinbashorgv1.FloatingIPList { return &kubefipk8sbinbashorgv1.FloatingIPList{} },
+		),
 	}
 }
@@ -70,6 +70,7 @@ func newFloatingIPRanges(c *KubefipV1Client) *floatingIPRanges {
 			func() *kubefipk8sbinbashorgv1.FloatingIPRange { return &kubefipk8sbinbashorgv1.FloatingIPRange{} },
 			func() *kubefipk8sbinbashorgv1.FloatingIPRangeList {
 				return &kubefipk8sbinbashorgv1.FloatingIPRangeList{}
-			}),
+			},
+		),
 	}
 }
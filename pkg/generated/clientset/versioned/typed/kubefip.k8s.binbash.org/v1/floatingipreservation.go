@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	context "context"
+
+	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	applyconfigurationkubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v1"
+	scheme "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// FloatingIPReservationsGetter has a method to return a FloatingIPReservationInterface.
+// A group's client should implement this interface.
+type FloatingIPReservationsGetter interface {
+	FloatingIPReservations() FloatingIPReservationInterface
+}
+
+// FloatingIPReservationInterface has methods to work with FloatingIPReservation resources.
+type FloatingIPReservationInterface interface {
+	Create(ctx context.Context, floatingIPReservation *kubefipk8sbinbashorgv1.FloatingIPReservation, opts metav1.CreateOptions) (*kubefipk8sbinbashorgv1.FloatingIPReservation, error)
+	Update(ctx context.Context, floatingIPReservation *kubefipk8sbinbashorgv1.FloatingIPReservation, opts metav1.UpdateOptions) (*kubefipk8sbinbashorgv1.FloatingIPReservation, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, floatingIPReservation *kubefipk8sbinbashorgv1.FloatingIPReservation, opts metav1.UpdateOptions) (*kubefipk8sbinbashorgv1.FloatingIPReservation, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*kubefipk8sbinbashorgv1.FloatingIPReservation, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*kubefipk8sbinbashorgv1.FloatingIPReservationList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *kubefipk8sbinbashorgv1.FloatingIPReservation, err error)
+	Apply(ctx context.Context, floatingIPReservation *applyconfigurationkubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration, opts metav1.ApplyOptions) (result *kubefipk8sbinbashorgv1.FloatingIPReservation, err error)
+	// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+	ApplyStatus(ctx context.Context, floatingIPReservation *applyconfigurationkubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration, opts metav1.ApplyOptions) (result *kubefipk8sbinbashorgv1.FloatingIPReservation, err error)
+	FloatingIPReservationExpansion
+}
+
+// floatingIPReservations implements FloatingIPReservationInterface
+type floatingIPReservations struct {
+	*gentype.ClientWithListAndApply[*kubefipk8sbinbashorgv1.FloatingIPReservation, *kubefipk8sbinbashorgv1.FloatingIPReservationList, *applyconfigurationkubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration]
+}
+
+// newFloatingIPReservations returns a FloatingIPReservations
+func newFloatingIPReservations(c *KubefipV1Client) *floatingIPReservations {
+	return &floatingIPReservations{
+		gentype.NewClientWithListAndApply[*kubefipk8sbinbashorgv1.FloatingIPReservation, *kubefipk8sbinbashorgv1.FloatingIPReservationList, *applyconfigurationkubefipk8sbinbashorgv1.FloatingIPReservationApplyConfiguration](
+			"floatingipreservations",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *kubefipk8sbinbashorgv1.FloatingIPReservation {
+				return &kubefipk8sbinbashorgv1.FloatingIPReservation{}
+			},
+			func() *kubefipk8sbinbashorgv1.FloatingIPReservationList {
+				return &kubefipk8sbinbashorgv1.FloatingIPReservationList{}
+			},
+		),
+	}
+}
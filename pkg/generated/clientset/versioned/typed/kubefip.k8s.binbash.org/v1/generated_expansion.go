@@ -21,3 +21,7 @@ package v1
 type FloatingIPExpansion interface{}
 
 type FloatingIPRangeExpansion interface{}
+
+type FloatingIPReservationExpansion interface{}
+
+type KubeVipProfileExpansion interface{}
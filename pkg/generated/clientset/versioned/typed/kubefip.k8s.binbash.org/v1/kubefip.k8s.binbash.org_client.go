@@ -30,6 +30,8 @@ type KubefipV1Interface interface {
 	RESTClient() rest.Interface
 	FloatingIPsGetter
 	FloatingIPRangesGetter
+	FloatingIPReservationsGetter
+	KubeVipProfilesGetter
 }
 
 // KubefipV1Client is used to interact with features provided by the kubefip.k8s.binbash.org group.
@@ -45,6 +47,14 @@ func (c *KubefipV1Client) FloatingIPRanges() FloatingIPRangeInterface {
 	return newFloatingIPRanges(c)
 }
 
+func (c *KubefipV1Client) FloatingIPReservations() FloatingIPReservationInterface {
+	return newFloatingIPReservations(c)
+}
+
+func (c *KubefipV1Client) KubeVipProfiles() KubeVipProfileInterface {
+	return newKubeVipProfiles(c)
+}
+
 // NewForConfig creates a new KubefipV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -93,7 +103,7 @@ func setConfigDefaults(config *rest.Config) error {
 	gv := kubefipk8sbinbashorgv1.SchemeGroupVersion
 	config.GroupVersion = &gv
 	config.APIPath = "/apis"
-	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	config.NegotiatedSerializer = rest.CodecFactoryForGeneratedClient(scheme.Scheme, scheme.Codecs).WithoutConversion()
 
 	if config.UserAgent == "" {
 		config.UserAgent = rest.DefaultKubernetesUserAgent()
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	context "context"
+
+	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	applyconfigurationkubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v1"
+	scheme "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// KubeVipProfilesGetter has a method to return a KubeVipProfileInterface.
+// A group's client should implement this interface.
+type KubeVipProfilesGetter interface {
+	KubeVipProfiles() KubeVipProfileInterface
+}
+
+// KubeVipProfileInterface has methods to work with KubeVipProfile resources.
+type KubeVipProfileInterface interface {
+	Create(ctx context.Context, kubeVipProfile *kubefipk8sbinbashorgv1.KubeVipProfile, opts metav1.CreateOptions) (*kubefipk8sbinbashorgv1.KubeVipProfile, error)
+	Update(ctx context.Context, kubeVipProfile *kubefipk8sbinbashorgv1.KubeVipProfile, opts metav1.UpdateOptions) (*kubefipk8sbinbashorgv1.KubeVipProfile, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*kubefipk8sbinbashorgv1.KubeVipProfile, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*kubefipk8sbinbashorgv1.KubeVipProfileList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *kubefipk8sbinbashorgv1.KubeVipProfile, err error)
+	Apply(ctx context.Context, kubeVipProfile *applyconfigurationkubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration, opts metav1.ApplyOptions) (result *kubefipk8sbinbashorgv1.KubeVipProfile, err error)
+	KubeVipProfileExpansion
+}
+
+// kubeVipProfiles implements KubeVipProfileInterface
+type kubeVipProfiles struct {
+	*gentype.ClientWithListAndApply[*kubefipk8sbinbashorgv1.KubeVipProfile, *kubefipk8sbinbashorgv1.KubeVipProfileList, *applyconfigurationkubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration]
+}
+
+// newKubeVipProfiles returns a KubeVipProfiles
+func newKubeVipProfiles(c *KubefipV1Client) *kubeVipProfiles {
+	return &kubeVipProfiles{
+		gentype.NewClientWithListAndApply[*kubefipk8sbinbashorgv1.KubeVipProfile, *kubefipk8sbinbashorgv1.KubeVipProfileList, *applyconfigurationkubefipk8sbinbashorgv1.KubeVipProfileApplyConfiguration](
+			"kubevipprofiles",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *kubefipk8sbinbashorgv1.KubeVipProfile { return &kubefipk8sbinbashorgv1.KubeVipProfile{} },
+			func() *kubefipk8sbinbashorgv1.KubeVipProfileList { return &kubefipk8sbinbashorgv1.KubeVipProfileList{} },
+		),
+	}
+}
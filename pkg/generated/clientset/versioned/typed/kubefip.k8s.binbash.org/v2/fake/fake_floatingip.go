@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v2"
+	typedkubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v2"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeFloatingIPs implements FloatingIPInterface
+type fakeFloatingIPs struct {
+	*gentype.FakeClientWithListAndApply[*v2.FloatingIP, *v2.FloatingIPList, *kubefipk8sbinbashorgv2.FloatingIPApplyConfiguration]
+	Fake *FakeKubefipV2
+}
+
+func newFakeFloatingIPs(fake *FakeKubefipV2, namespace string) typedkubefipk8sbinbashorgv2.FloatingIPInterface {
+	return &fakeFloatingIPs{
+		gentype.NewFakeClientWithListAndApply[*v2.FloatingIP, *v2.FloatingIPList, *kubefipk8sbinbashorgv2.FloatingIPApplyConfiguration](
+			fake.Fake,
+			namespace,
+			v2.SchemeGroupVersion.WithResource("floatingips"),
+			v2.SchemeGroupVersion.WithKind("FloatingIP"),
+			func() *v2.FloatingIP { return &v2.FloatingIP{} },
+			func() *v2.FloatingIPList { return &v2.FloatingIPList{} },
+			func(dst, src *v2.FloatingIPList) { dst.ListMeta = src.ListMeta },
+			func(list *v2.FloatingIPList) []*v2.FloatingIP { return gentype.ToPointerSlice(list.Items) },
+			func(list *v2.FloatingIPList, items []*v2.FloatingIP) { list.Items = gentype.FromPointerSlice(items) },
+		),
+		fake,
+	}
+}
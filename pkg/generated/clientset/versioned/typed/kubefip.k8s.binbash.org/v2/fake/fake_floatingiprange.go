@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v2"
+	typedkubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/typed/kubefip.k8s.binbash.org/v2"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeFloatingIPRanges implements FloatingIPRangeInterface
+type fakeFloatingIPRanges struct {
+	*gentype.FakeClientWithListAndApply[*v2.FloatingIPRange, *v2.FloatingIPRangeList, *kubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration]
+	Fake *FakeKubefipV2
+}
+
+func newFakeFloatingIPRanges(fake *FakeKubefipV2) typedkubefipk8sbinbashorgv2.FloatingIPRangeInterface {
+	return &fakeFloatingIPRanges{
+		gentype.NewFakeClientWithListAndApply[*v2.FloatingIPRange, *v2.FloatingIPRangeList, *kubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration](
+			fake.Fake,
+			"",
+			v2.SchemeGroupVersion.WithResource("floatingipranges"),
+			v2.SchemeGroupVersion.WithKind("FloatingIPRange"),
+			func() *v2.FloatingIPRange { return &v2.FloatingIPRange{} },
+			func() *v2.FloatingIPRangeList { return &v2.FloatingIPRangeList{} },
+			func(dst, src *v2.FloatingIPRangeList) { dst.ListMeta = src.ListMeta },
+			func(list *v2.FloatingIPRangeList) []*v2.FloatingIPRange { return gentype.ToPointerSlice(list.Items) },
+			func(list *v2.FloatingIPRangeList, items []*v2.FloatingIPRange) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	context "context"
+
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	applyconfigurationkubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v2"
+	scheme "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// FloatingIPsGetter has a method to return a FloatingIPInterface.
+// A group's client should implement this interface.
+type FloatingIPsGetter interface {
+	FloatingIPs(namespace string) FloatingIPInterface
+}
+
+// FloatingIPInterface has methods to work with FloatingIP resources.
+type FloatingIPInterface interface {
+	Create(ctx context.Context, floatingIP *kubefipk8sbinbashorgv2.FloatingIP, opts v1.CreateOptions) (*kubefipk8sbinbashorgv2.FloatingIP, error)
+	Update(ctx context.Context, floatingIP *kubefipk8sbinbashorgv2.FloatingIP, opts v1.UpdateOptions) (*kubefipk8sbinbashorgv2.FloatingIP, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, floatingIP *kubefipk8sbinbashorgv2.FloatingIP, opts v1.UpdateOptions) (*kubefipk8sbinbashorgv2.FloatingIP, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*kubefipk8sbinbashorgv2.FloatingIP, error)
+	List(ctx context.Context, opts v1.ListOptions) (*kubefipk8sbinbashorgv2.FloatingIPList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *kubefipk8sbinbashorgv2.FloatingIP, err error)
+	Apply(ctx context.Context, floatingIP *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPApplyConfiguration, opts v1.ApplyOptions) (result *kubefipk8sbinbashorgv2.FloatingIP, err error)
+	// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+	ApplyStatus(ctx context.Context, floatingIP *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPApplyConfiguration, opts v1.ApplyOptions) (result *kubefipk8sbinbashorgv2.FloatingIP, err error)
+	FloatingIPExpansion
+}
+
+// floatingIPs implements FloatingIPInterface
+type floatingIPs struct {
+	*gentype.ClientWithListAndApply[*kubefipk8sbinbashorgv2.FloatingIP, *kubefipk8sbinbashorgv2.FloatingIPList, *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPApplyConfiguration]
+}
+
+// newFloatingIPs returns a FloatingIPs
+func newFloatingIPs(c *KubefipV2Client, namespace string) *floatingIPs {
+	return &floatingIPs{
+		gentype.NewClientWithListAndApply[*kubefipk8sbinbashorgv2.FloatingIP, *kubefipk8sbinbashorgv2.FloatingIPList, *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPApplyConfiguration](
+			"floatingips",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *kubefipk8sbinbashorgv2.FloatingIP { return &kubefipk8sbinbashorgv2.FloatingIP{} },
+			func() *kubefipk8sbinbashorgv2.FloatingIPList { return &kubefipk8sbinbashorgv2.FloatingIPList{} },
+		),
+	}
+}
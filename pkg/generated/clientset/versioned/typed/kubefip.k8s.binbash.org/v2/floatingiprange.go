@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	context "context"
+
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	applyconfigurationkubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/generated/applyconfiguration/kubefip.k8s.binbash.org/v2"
+	scheme "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// FloatingIPRangesGetter has a method to return a FloatingIPRangeInterface.
+// A group's client should implement this interface.
+type FloatingIPRangesGetter interface {
+	FloatingIPRanges() FloatingIPRangeInterface
+}
+
+// FloatingIPRangeInterface has methods to work with FloatingIPRange resources.
+type FloatingIPRangeInterface interface {
+	Create(ctx context.Context, floatingIPRange *kubefipk8sbinbashorgv2.FloatingIPRange, opts v1.CreateOptions) (*kubefipk8sbinbashorgv2.FloatingIPRange, error)
+	Update(ctx context.Context, floatingIPRange *kubefipk8sbinbashorgv2.FloatingIPRange, opts v1.UpdateOptions) (*kubefipk8sbinbashorgv2.FloatingIPRange, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, floatingIPRange *kubefipk8sbinbashorgv2.FloatingIPRange, opts v1.UpdateOptions) (*kubefipk8sbinbashorgv2.FloatingIPRange, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*kubefipk8sbinbashorgv2.FloatingIPRange, error)
+	List(ctx context.Context, opts v1.ListOptions) (*kubefipk8sbinbashorgv2.FloatingIPRangeList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *kubefipk8sbinbashorgv2.FloatingIPRange, err error)
+	Apply(ctx context.Context, floatingIPRange *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration, opts v1.ApplyOptions) (result *kubefipk8sbinbashorgv2.FloatingIPRange, err error)
+	// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+	ApplyStatus(ctx context.Context, floatingIPRange *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration, opts v1.ApplyOptions) (result *kubefipk8sbinbashorgv2.FloatingIPRange, err error)
+	FloatingIPRangeExpansion
+}
+
+// floatingIPRanges implements FloatingIPRangeInterface
+type floatingIPRanges struct {
+	*gentype.ClientWithListAndApply[*kubefipk8sbinbashorgv2.FloatingIPRange, *kubefipk8sbinbashorgv2.FloatingIPRangeList, *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration]
+}
+
+// newFloatingIPRanges returns a FloatingIPRanges
+func newFloatingIPRanges(c *KubefipV2Client) *floatingIPRanges {
+	return &floatingIPRanges{
+		gentype.NewClientWithListAndApply[*kubefipk8sbinbashorgv2.FloatingIPRange, *kubefipk8sbinbashorgv2.FloatingIPRangeList, *applyconfigurationkubefipk8sbinbashorgv2.FloatingIPRangeApplyConfiguration](
+			"floatingipranges",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *kubefipk8sbinbashorgv2.FloatingIPRange { return &kubefipk8sbinbashorgv2.FloatingIPRange{} },
+			func() *kubefipk8sbinbashorgv2.FloatingIPRangeList {
+				return &kubefipk8sbinbashorgv2.FloatingIPRangeList{}
+			},
+		),
+	}
+}
@@ -22,6 +22,7 @@ import (
 	fmt "fmt"
 
 	v1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	v2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -57,6 +58,16 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V1().FloatingIPs().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("floatingipranges"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V1().FloatingIPRanges().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("floatingipreservations"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V1().FloatingIPReservations().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("kubevipprofiles"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V1().KubeVipProfiles().Informer()}, nil
+
+		// Group=kubefip.k8s.binbash.org, Version=v2
+	case v2.SchemeGroupVersion.WithResource("floatingips"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V2().FloatingIPs().Informer()}, nil
+	case v2.SchemeGroupVersion.WithResource("floatingipranges"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefip().V2().FloatingIPRanges().Informer()}, nil
 
 	}
 
@@ -28,6 +28,10 @@ type Interface interface {
 	FloatingIPs() FloatingIPInformer
 	// FloatingIPRanges returns a FloatingIPRangeInformer.
 	FloatingIPRanges() FloatingIPRangeInformer
+	// FloatingIPReservations returns a FloatingIPReservationInformer.
+	FloatingIPReservations() FloatingIPReservationInformer
+	// KubeVipProfiles returns a KubeVipProfileInformer.
+	KubeVipProfiles() KubeVipProfileInformer
 }
 
 type version struct {
@@ -50,3 +54,13 @@ func (v *version) FloatingIPs() FloatingIPInformer {
 func (v *version) FloatingIPRanges() FloatingIPRangeInformer {
 	return &floatingIPRangeInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// FloatingIPReservations returns a FloatingIPReservationInformer.
+func (v *version) FloatingIPReservations() FloatingIPReservationInformer {
+	return &floatingIPReservationInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// KubeVipProfiles returns a KubeVipProfileInformer.
+func (v *version) KubeVipProfiles() KubeVipProfileInformer {
+	return &kubeVipProfileInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
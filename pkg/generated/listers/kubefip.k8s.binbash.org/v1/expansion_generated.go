@@ -29,3 +29,11 @@ type FloatingIPNamespaceListerExpansion interface{}
 // FloatingIPRangeListerExpansion allows custom methods to be added to
 // FloatingIPRangeLister.
 type FloatingIPRangeListerExpansion interface{}
+
+// FloatingIPReservationListerExpansion allows custom methods to be added to
+// FloatingIPReservationLister.
+type FloatingIPReservationListerExpansion interface{}
+
+// KubeVipProfileListerExpansion allows custom methods to be added to
+// KubeVipProfileLister.
+type KubeVipProfileListerExpansion interface{}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	kubefipk8sbinbashorgv1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// FloatingIPReservationLister helps list FloatingIPReservations.
+// All objects returned here must be treated as read-only.
+type FloatingIPReservationLister interface {
+	// List lists all FloatingIPReservations in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*kubefipk8sbinbashorgv1.FloatingIPReservation, err error)
+	// Get retrieves the FloatingIPReservation from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*kubefipk8sbinbashorgv1.FloatingIPReservation, error)
+	FloatingIPReservationListerExpansion
+}
+
+// floatingIPReservationLister implements the FloatingIPReservationLister interface.
+type floatingIPReservationLister struct {
+	listers.ResourceIndexer[*kubefipk8sbinbashorgv1.FloatingIPReservation]
+}
+
+// NewFloatingIPReservationLister returns a new FloatingIPReservationLister.
+func NewFloatingIPReservationLister(indexer cache.Indexer) FloatingIPReservationLister {
+	return &floatingIPReservationLister{listers.New[*kubefipk8sbinbashorgv1.FloatingIPReservation](indexer, kubefipk8sbinbashorgv1.Resource("floatingipreservation"))}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// FloatingIPLister helps list FloatingIPs.
+// All objects returned here must be treated as read-only.
+type FloatingIPLister interface {
+	// List lists all FloatingIPs in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*kubefipk8sbinbashorgv2.FloatingIP, err error)
+	// FloatingIPs returns an object that can list and get FloatingIPs.
+	FloatingIPs(namespace string) FloatingIPNamespaceLister
+	FloatingIPListerExpansion
+}
+
+// floatingIPLister implements the FloatingIPLister interface.
+type floatingIPLister struct {
+	listers.ResourceIndexer[*kubefipk8sbinbashorgv2.FloatingIP]
+}
+
+// NewFloatingIPLister returns a new FloatingIPLister.
+func NewFloatingIPLister(indexer cache.Indexer) FloatingIPLister {
+	return &floatingIPLister{listers.New[*kubefipk8sbinbashorgv2.FloatingIP](indexer, kubefipk8sbinbashorgv2.Resource("floatingip"))}
+}
+
+// FloatingIPs returns an object that can list and get FloatingIPs.
+func (s *floatingIPLister) FloatingIPs(namespace string) FloatingIPNamespaceLister {
+	return floatingIPNamespaceLister{listers.NewNamespaced[*kubefipk8sbinbashorgv2.FloatingIP](s.ResourceIndexer, namespace)}
+}
+
+// FloatingIPNamespaceLister helps list and get FloatingIPs.
+// All objects returned here must be treated as read-only.
+type FloatingIPNamespaceLister interface {
+	// List lists all FloatingIPs in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*kubefipk8sbinbashorgv2.FloatingIP, err error)
+	// Get retrieves the FloatingIP from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*kubefipk8sbinbashorgv2.FloatingIP, error)
+	FloatingIPNamespaceListerExpansion
+}
+
+// floatingIPNamespaceLister implements the FloatingIPNamespaceLister
+// interface.
+type floatingIPNamespaceLister struct {
+	listers.ResourceIndexer[*kubefipk8sbinbashorgv2.FloatingIP]
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	kubefipk8sbinbashorgv2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// FloatingIPRangeLister helps list FloatingIPRanges.
+// All objects returned here must be treated as read-only.
+type FloatingIPRangeLister interface {
+	// List lists all FloatingIPRanges in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*kubefipk8sbinbashorgv2.FloatingIPRange, err error)
+	// Get retrieves the FloatingIPRange from the index for a given name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*kubefipk8sbinbashorgv2.FloatingIPRange, error)
+	FloatingIPRangeListerExpansion
+}
+
+// floatingIPRangeLister implements the FloatingIPRangeLister interface.
+type floatingIPRangeLister struct {
+	listers.ResourceIndexer[*kubefipk8sbinbashorgv2.FloatingIPRange]
+}
+
+// NewFloatingIPRangeLister returns a new FloatingIPRangeLister.
+func NewFloatingIPRangeLister(indexer cache.Indexer) FloatingIPRangeLister {
+	return &floatingIPRangeLister{listers.New[*kubefipk8sbinbashorgv2.FloatingIPRange](indexer, kubefipk8sbinbashorgv2.Resource("floatingiprange"))}
+}
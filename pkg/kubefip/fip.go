@@ -3,45 +3,29 @@ package kubefip
 import (
 	"context"
 	"errors"
-	"fmt"
 
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientset) error {
+func AllocateFip(fip *KubefipV2.FloatingIP, clientset *kubefipclientset.Clientset) error {
 	var err error
 
 	log.Tracef("(AllocateFip) fipobj added: [%+v]", fip)
 
-	// get the clustername from the fip object annotation and check if it exists
-	cName := fip.ObjectMeta.Annotations["clustername"]
-	if cName == "" {
-		errMsg := fmt.Sprintf("clustername not found in annotations for [%s/%s]",
-			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
-		return errors.New(errMsg)
-	}
-
-	// get the fiprange from the fip object annotation
-	frName := fip.ObjectMeta.Annotations["fiprange"]
-	if frName == "" {
-		errMsg := fmt.Sprintf("fiprange not found in annotations for [%s/%s]",
-			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
-		return errors.New(errMsg)
-	}
-
-	// check if the fiprange exists
-	_, err = GetFipRange(frName)
-	if err != nil {
+	// check the clusterName and fipRange and the ip address, these checks are shared with the webhook
+	if err := ValidateFip(fip); err != nil {
 		return err
 	}
 
+	frName := fip.Spec.FipRange
+
 	// check if the spec has an IPAddress specified
 	if fip.Spec.IPAddress == "" {
-		ip, err := IPAM.GetIP(frName, "")
+		ip, err := IpamGetIP(frName, "")
 		if err != nil {
 			log.Errorf("(AllocateFip) cannot acquire new ip address for [%s/%s]",
 				fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
@@ -53,7 +37,7 @@ func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientse
 
 		// update the fip object in kubernetes
 		fip.Spec.IPAddress = ip
-		updatedFip, err := clientset.KubefipV1().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), fip, metav1.UpdateOptions{})
+		updatedFip, err := clientset.KubefipV2().FloatingIPs(fip.ObjectMeta.Namespace).Update(context.TODO(), fip, metav1.UpdateOptions{})
 		if err != nil {
 			return err
 		}
@@ -65,8 +49,8 @@ func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientse
 		if err != nil {
 			log.Errorf("(AllocateFip) could not increment Fipranges metrics: %s", err)
 		} else {
-			metrics.IncrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.ObjectMeta.Annotations["harvesterClusterName"],
-				fipRange.ObjectMeta.Annotations["harvesterNetworkName"])
+			metrics.IncrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.Spec.HarvesterClusterName,
+				fipRange.Spec.HarvesterNetworkName)
 		}
 
 		// add/update the fip in the allFips list
@@ -74,15 +58,20 @@ func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientse
 			return err
 		}
 	} else {
-		// register the allocated fip in the prefix
-		ip, err := IPAM.GetIP(frName, fip.Spec.IPAddress)
-		if err != nil {
-			log.Errorf("(AllocateFip) cannot acquire existing IP address [%s] for [%s/%s]",
-				fip.Spec.IPAddress, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
-			return err
+		// register the allocated fip in the prefix, the ip address of a reservation is already allocated
+		if TakeOverFipReservation(fip) {
+			log.Infof("(AllocateFip) successfully allocated fip [%s/%s] with reserved IP address: %s",
+				fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, fip.Spec.IPAddress)
 		} else {
-			log.Infof("(AllocateFip) successfully allocated fip [%s/%s] with new IP address: %s",
-				fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, ip)
+			ip, err := IpamGetIP(frName, fip.Spec.IPAddress)
+			if err != nil {
+				log.Errorf("(AllocateFip) cannot acquire existing IP address [%s] for [%s/%s]",
+					fip.Spec.IPAddress, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+				return err
+			} else {
+				log.Infof("(AllocateFip) successfully allocated fip [%s/%s] with new IP address: %s",
+					fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, ip)
+			}
 		}
 
 		// update the metrics
@@ -90,8 +79,8 @@ func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientse
 		if err != nil {
 			log.Errorf("(AllocateFip) could not increment Fipranges metrics: %s", err)
 		} else {
-			metrics.IncrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.ObjectMeta.Annotations["harvesterClusterName"],
-				fipRange.ObjectMeta.Annotations["harvesterNetworkName"])
+			metrics.IncrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.Spec.HarvesterClusterName,
+				fipRange.Spec.HarvesterNetworkName)
 		}
 
 		// add the fip in the allFips list
@@ -103,15 +92,15 @@ func AllocateFip(fip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientse
 	return err
 }
 
-func RemoveFip(fip *KubefipV1.FloatingIP) error {
+func RemoveFip(fip *KubefipV2.FloatingIP) error {
 	var err error
 
 	log.Tracef("(RemoveFip) fipobj removed: [%+v]", fip)
 
-	// get the fiprange from the fip object annotation
-	frName := fip.ObjectMeta.Annotations["fiprange"]
+	// get the fiprange from the fip object spec
+	frName := fip.Spec.FipRange
 	if frName == "" {
-		return errors.New("fiprange not found in annotations")
+		return errors.New("fipRange not found in spec")
 	}
 
 	// check if the fiprange exists
@@ -119,7 +108,7 @@ func RemoveFip(fip *KubefipV1.FloatingIP) error {
 	if err != nil {
 		log.Errorf("%s", err.Error())
 	} else {
-		if err := IPAM.ReleaseIP(frName, fip.Spec.IPAddress); err != nil {
+		if err := IpamReleaseIP(frName, fip.Spec.IPAddress); err != nil {
 			log.Errorf("(RemoveFip) error while removing fip [%s] with ip [%s] from subnet [%s]: %s",
 				fip.ObjectMeta.Name, fip.Spec.IPAddress, frName, err.Error())
 		} else {
@@ -131,8 +120,8 @@ func RemoveFip(fip *KubefipV1.FloatingIP) error {
 			if err != nil {
 				log.Errorf("(RemoveFip) could not decrement Fipranges metrics: %s", err)
 			} else {
-				metrics.DecrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.ObjectMeta.Annotations["harvesterClusterName"],
-					fipRange.ObjectMeta.Annotations["harvesterNetworkName"])
+				metrics.DecrementFiprangesReserved(frName, fipRange.Spec.IPRange, fipRange.Spec.HarvesterClusterName,
+					fipRange.Spec.HarvesterNetworkName)
 			}
 		}
 	}
@@ -144,11 +133,18 @@ func RemoveFip(fip *KubefipV1.FloatingIP) error {
 	return err
 }
 
-func UpdateFip(oldFip *KubefipV1.FloatingIP, newFip *KubefipV1.FloatingIP, clientset *kubefipclientset.Clientset) error {
+func UpdateFip(oldFip *KubefipV2.FloatingIP, newFip *KubefipV2.FloatingIP, clientset *kubefipclientset.Clientset) error {
 	var err error
 
 	log.Tracef("(UpdateFip) fipobj removed: oldFip [%+v] / newFip [%+v]", oldFip, newFip)
 
+	// the ip address is still allocated when only the metadata or the other spec fields are changed, so only the
+	// allFips list is updated and the ipam and the metrics are left alone
+	if newFip.Spec.IPAddress != "" && oldFip.Spec.IPAddress == newFip.Spec.IPAddress &&
+		oldFip.Spec.FipRange == newFip.Spec.FipRange {
+		return UpdateAllFips(newFip)
+	}
+
 	// remove the FIP
 	if err := RemoveFip(oldFip); err != nil {
 		log.Errorf("(updateFip) Error removing fip: %s", err.Error())
@@ -6,42 +6,45 @@ import (
 	"net"
 	"net/netip"
 
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	"github.com/joeyloman/kube-fip-operator/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
-func GetFipRange(fipRangeName string) (KubefipV1.FloatingIPRange, error) {
+func GetFipRange(fipRangeName string) (KubefipV2.FloatingIPRange, error) {
 	log.Debugf("(GetFipRange) retrieving fipRangeName: [%s]", fipRangeName)
 
-	for i := 0; i < len(AllFipRanges); i++ {
+	fipRangesMutex.RLock()
+	defer fipRangesMutex.RUnlock()
+
+	for i := 0; i < len(allFipRanges); i++ {
 		// check if the fiprange has a match
-		if fipRangeName == AllFipRanges[i].ObjectMeta.Name {
+		if fipRangeName == allFipRanges[i].ObjectMeta.Name {
 			log.Debugf("(GetFipRange) fiprange match found, returning object")
 
-			return AllFipRanges[i], nil
+			return allFipRanges[i], nil
 		}
 	}
 
 	errMsg := fmt.Sprintf("(GetFipRange) fiprange [%s] not found!", fipRangeName)
 
-	return KubefipV1.FloatingIPRange{}, errors.New(errMsg)
+	return KubefipV2.FloatingIPRange{}, errors.New(errMsg)
 }
 
-func AllocateFipRange(fipRange *KubefipV1.FloatingIPRange) error {
+func AllocateFipRange(fipRange *KubefipV2.FloatingIPRange) error {
 	var err error
 
 	log.Tracef("(AllocateFipRange) fiprangeobj added: [%+v]", fipRange)
 
-	// get the fiprange from the fiprange object
-	if fipRange.Spec.IPRange == "" {
-		return errors.New("fiprange not found in spec")
+	// check the cidr, this check is shared with the webhook
+	if err := ValidateFipRange(fipRange); err != nil {
+		return err
 	}
 
 	// get the start and end addresses
 	ipnet, err := netip.ParsePrefix(fipRange.Spec.IPRange)
 	if err != nil {
-		fmt.Printf("%s", err.Error())
+		return err
 	}
 	subnetStart := net.IP(ipnet.Addr().AsSlice())
 	subnetMask := net.CIDRMask(ipnet.Bits(), 32)
@@ -57,7 +60,7 @@ func AllocateFipRange(fipRange *KubefipV1.FloatingIPRange) error {
 	log.Debugf("(AllocateFipRange) subnet=%s, startaddr=%s, endaddr=%s", fipRange.Spec.IPRange, subnetStart, subnetEnd)
 
 	// register the new subnet in ipam
-	if err = IPAM.NewSubnet(
+	if err = IpamNewSubnet(
 		fipRange.ObjectMeta.Name,
 		fipRange.Spec.IPRange,
 		subnetStart.String(),
@@ -69,8 +72,8 @@ func AllocateFipRange(fipRange *KubefipV1.FloatingIPRange) error {
 	log.Infof("(AllocateFipRange) successfully allocated fiprange [%s] with cidr [%s]",
 		fipRange.ObjectMeta.Name, fipRange.Spec.IPRange)
 
-	metrics.SetFiprangesCapacity(fipRange.ObjectMeta.Name, fipRange.Spec.IPRange, fipRange.ObjectMeta.Annotations["harvesterClusterName"],
-		fipRange.ObjectMeta.Annotations["harvesterNetworkName"])
+	metrics.SetFiprangesCapacity(fipRange.ObjectMeta.Name, fipRange.Spec.IPRange, fipRange.Spec.HarvesterClusterName,
+		fipRange.Spec.HarvesterNetworkName)
 
 	// add/update the fiprange in the allFipRanges list
 	if err := UpdateAllFipRanges(fipRange); err != nil {
@@ -80,7 +83,7 @@ func AllocateFipRange(fipRange *KubefipV1.FloatingIPRange) error {
 	return err
 }
 
-func RemoveFipRange(fipRange *KubefipV1.FloatingIPRange) error {
+func RemoveFipRange(fipRange *KubefipV2.FloatingIPRange) error {
 	var err error
 
 	log.Tracef("(RemoveFipRange) fiprangeobj removed: [%+v]", fipRange)
@@ -92,13 +95,13 @@ func RemoveFipRange(fipRange *KubefipV1.FloatingIPRange) error {
 	}
 
 	// delete the prefix from the IPAM object
-	IPAM.DeleteSubnet(fipRange.ObjectMeta.Name)
+	IpamDeleteSubnet(fipRange.ObjectMeta.Name)
 
 	log.Infof("(RemoveFipRange) successfully removed fiprange [%s] with cidr [%s]",
 		fipRange.ObjectMeta.Name, fipRange.Spec.IPRange)
 
-	metrics.RemoveFiprangeMetrics(fipRange.ObjectMeta.Name, fipRange.Spec.IPRange, fipRange.ObjectMeta.Annotations["harvesterClusterName"],
-		fipRange.ObjectMeta.Annotations["harvesterNetworkName"])
+	metrics.RemoveFiprangeMetrics(fipRange.ObjectMeta.Name, fipRange.Spec.IPRange, fipRange.Spec.HarvesterClusterName,
+		fipRange.Spec.HarvesterNetworkName)
 
 	if err := RemoveFipRangeFromAllFipRanges(fipRange); err != nil {
 		return err
@@ -107,7 +110,7 @@ func RemoveFipRange(fipRange *KubefipV1.FloatingIPRange) error {
 	return err
 }
 
-func UpdateFipRange(oldFipRange *KubefipV1.FloatingIPRange, newFipRange *KubefipV1.FloatingIPRange) error {
+func UpdateFipRange(oldFipRange *KubefipV2.FloatingIPRange, newFipRange *KubefipV2.FloatingIPRange) error {
 	var err error
 
 	log.Tracef("(UpdateFipRange) fiprangeobj removed: oldFipRange [%+v] / newFipRange [%+v]",
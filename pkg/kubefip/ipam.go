@@ -4,11 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 
-	"k8s.io/client-go/kubernetes"
-
-	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
 	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
 	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,42 +15,74 @@ import (
 )
 
 var (
-	AllFipRanges []KubefipV1.FloatingIPRange
-	AllFips      []KubefipV1.FloatingIP
-	IPAM         *ipam.IPAllocator
+	allFipRanges []KubefipV2.FloatingIPRange
+	allFips      []KubefipV2.FloatingIP
+	ipamPrefixes *ipam.IPAllocator
+
+	// guard the lists, they are changed by the informers and read by the guest cluster workers, the webhooks and the apis
+	fipRangesMutex sync.RWMutex
+	fipsMutex      sync.RWMutex
+
+	// guards the ipam prefixes, the ipam only locks parts of the allocations and none of the reads so every call goes
+	// through the Ipam functions below
+	ipamMutex sync.Mutex
 )
 
-func GatherAllFipRanges(clientset *kubefipclientset.Clientset) error {
+// GetAllFipRanges returns a copy of the fipranges list
+func GetAllFipRanges() []KubefipV2.FloatingIPRange {
+	fipRangesMutex.RLock()
+	defer fipRangesMutex.RUnlock()
+
+	return append([]KubefipV2.FloatingIPRange(nil), allFipRanges...)
+}
+
+// GetAllFips returns a copy of the fips list
+func GetAllFips() []KubefipV2.FloatingIP {
+	fipsMutex.RLock()
+	defer fipsMutex.RUnlock()
+
+	return append([]KubefipV2.FloatingIP(nil), allFips...)
+}
+
+func GatherAllFipRanges(clientset kubefipclientset.Interface) error {
 	var err error
 
 	log.Infof("(GatherAllFipRanges) gathering and storing al floatingipranges..")
 
-	fipRangeList, err := clientset.KubefipV1().FloatingIPRanges().List(context.TODO(), metav1.ListOptions{})
+	fipRangeList, err := clientset.KubefipV2().FloatingIPRanges().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
 
+	var fipRanges []KubefipV2.FloatingIPRange
 	for _, fiprange := range fipRangeList.Items {
 		log.Infof("(GatherAllFipRanges) fiprange found: %s", fiprange.Name)
 		log.Tracef("(GatherAllFipRanges) fiprange object: %+v", fiprange)
 
-		AllFipRanges = append(AllFipRanges, fiprange)
+		fipRanges = append(fipRanges, fiprange)
 	}
 
+	fipRangesMutex.Lock()
+	allFipRanges = fipRanges
+	fipRangesMutex.Unlock()
+
 	return err
 }
 
-func UpdateAllFipRanges(fipRange *KubefipV1.FloatingIPRange) error {
+func UpdateAllFipRanges(fipRange *KubefipV2.FloatingIPRange) error {
 	var err error
 	var updatedFipRangeFound bool = false
 
+	fipRangesMutex.Lock()
+	defer fipRangesMutex.Unlock()
+
 	log.Debugf("(UpdateAllFipRanges) updating fiprange [%s] in allFipRanges list", fipRange.ObjectMeta.Name)
 
-	var newAllFipRanges []KubefipV1.FloatingIPRange
+	var newAllFipRanges []KubefipV2.FloatingIPRange
 
-	for i := 0; i < len(AllFipRanges); i++ {
+	for i := 0; i < len(allFipRanges); i++ {
 		// if the updated fiprange matches the one in the list, add the new fiprange to the new list
-		if fipRange.ObjectMeta.Name == AllFipRanges[i].ObjectMeta.Name {
+		if fipRange.ObjectMeta.Name == allFipRanges[i].ObjectMeta.Name {
 			// if the updated fiprange matches the one in the list, add the new fip to the new list
 			log.Debugf("(UpdateAllFipRanges) fiprange to update found, adding new fiprange to the list")
 
@@ -63,7 +93,7 @@ func UpdateAllFipRanges(fipRange *KubefipV1.FloatingIPRange) error {
 			// if there is no match, add the fip to the new list
 			log.Debugf("(UpdateAllFipRanges) adding existing fiprange to the list")
 
-			newAllFipRanges = append(newAllFipRanges, AllFipRanges[i])
+			newAllFipRanges = append(newAllFipRanges, allFipRanges[i])
 		}
 	}
 
@@ -75,31 +105,34 @@ func UpdateAllFipRanges(fipRange *KubefipV1.FloatingIPRange) error {
 	}
 
 	// all good, assign the new list
-	AllFipRanges = newAllFipRanges
+	allFipRanges = newAllFipRanges
 
 	return err
 }
 
-func RemoveFipRangeFromAllFipRanges(fipRange *KubefipV1.FloatingIPRange) error {
+func RemoveFipRangeFromAllFipRanges(fipRange *KubefipV2.FloatingIPRange) error {
 	var err error
 	var FipRangeFound bool = false
 
+	fipRangesMutex.Lock()
+	defer fipRangesMutex.Unlock()
+
 	log.Debugf("(RemoveFipRangeFromAllFipRanges) removing fiprange [%s] from allFipRanges list", fipRange.ObjectMeta.Name)
 
-	var newAllFipRanges []KubefipV1.FloatingIPRange
+	var newAllFipRanges []KubefipV2.FloatingIPRange
 
-	for i := 0; i < len(AllFipRanges); i++ {
+	for i := 0; i < len(allFipRanges); i++ {
 		// if the fiprange matches the one in the list, skip it
-		if fipRange.ObjectMeta.Name == AllFipRanges[i].ObjectMeta.Name {
+		if fipRange.ObjectMeta.Name == allFipRanges[i].ObjectMeta.Name {
 			// if the updated fiprange matches the one in the list, add the new fiprange to the new list
 			log.Debugf("(RemoveFipRangeFromAllFipRanges) fiprange to remove found, skip appending fiprange to the list")
 
 			FipRangeFound = true
 		} else {
 			// if there is no match, add the fiprange to the new list
-			log.Debugf("(RemoveFipRangeFromAllFipRanges) adding existing fiprange [%s] to the list", AllFipRanges[i].ObjectMeta.Name)
+			log.Debugf("(RemoveFipRangeFromAllFipRanges) adding existing fiprange [%s] to the list", allFipRanges[i].ObjectMeta.Name)
 
-			newAllFipRanges = append(newAllFipRanges, AllFipRanges[i])
+			newAllFipRanges = append(newAllFipRanges, allFipRanges[i])
 		}
 	}
 
@@ -113,54 +146,51 @@ func RemoveFipRangeFromAllFipRanges(fipRange *KubefipV1.FloatingIPRange) error {
 	log.Debugf("(RemoveFipRangeFromAllFipRanges) successfully removed fiprange [%s] from allFipRanges list", fipRange.Spec.IPRange)
 
 	// all good, assign the new list
-	AllFipRanges = newAllFipRanges
+	allFipRanges = newAllFipRanges
 
 	return err
 }
 
-func GatherAllFips(k8s_clientset *kubernetes.Clientset, kubefip_clientset *kubefipclientset.Clientset) error {
+func GatherAllFips(kubefip_clientset kubefipclientset.Interface) error {
 	var err error
 
 	log.Infof("(GatherAllFips) gathering and storing al floatingips..")
 
-	// TODO namespaces are also labeled, so maybe do a label selection here?
-	// get all namespaces and check in the c-m-* names if there are floatingip objects
-	nsList, err := k8s_clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	// the fips of the rancher clusters are in the c-m-* and c-* namespaces, the fips of other cluster sources live next to their cluster
+	fipList, err := kubefip_clientset.KubefipV2().FloatingIPs(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
-	for _, ns := range nsList.Items {
-		log.Tracef("(GatherAllFips) namespace found: %s", ns.Name)
-		if strings.HasPrefix(ns.Name, "c-m-") {
-			// guest cluster namespace found
-			fipList, err := kubefip_clientset.KubefipV1().FloatingIPs(ns.Name).List(context.TODO(), metav1.ListOptions{})
-			if err != nil {
-				return err
-			}
-
-			for _, fip := range fipList.Items {
-				log.Infof("(GatherAllFips) fip [%s] found in namespace [%s]", fip.Name, ns.Name)
-				log.Tracef("(GatherAllFips) fip object: %+v", fip)
-
-				AllFips = append(AllFips, fip)
-			}
-		}
+
+	var fips []KubefipV2.FloatingIP
+	for _, fip := range fipList.Items {
+		log.Infof("(GatherAllFips) fip [%s] found in namespace [%s]", fip.Name, fip.Namespace)
+		log.Tracef("(GatherAllFips) fip object: %+v", fip)
+
+		fips = append(fips, fip)
 	}
 
+	fipsMutex.Lock()
+	allFips = fips
+	fipsMutex.Unlock()
+
 	return err
 }
 
-func UpdateAllFips(fip *KubefipV1.FloatingIP) error {
+func UpdateAllFips(fip *KubefipV2.FloatingIP) error {
 	var err error
 	var updatedFipFound bool = false
 
+	fipsMutex.Lock()
+	defer fipsMutex.Unlock()
+
 	log.Debugf("(UpdateAllFips) updating fip [%s/%s] in allFips list", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
 
-	var newAllFips []KubefipV1.FloatingIP
+	var newAllFips []KubefipV2.FloatingIP
 
-	for i := 0; i < len(AllFips); i++ {
+	for i := 0; i < len(allFips); i++ {
 		// if the updated fip matches the one in the list, add the new fip to the new list
-		if fip.ObjectMeta.Namespace == AllFips[i].ObjectMeta.Namespace && fip.ObjectMeta.Name == AllFips[i].ObjectMeta.Name {
+		if fip.ObjectMeta.Namespace == allFips[i].ObjectMeta.Namespace && fip.ObjectMeta.Name == allFips[i].ObjectMeta.Name {
 			// if the updated fip matches the one in the list, add the new fip to the new list
 			log.Debugf("(UpdateAllFips) fip to update found, adding new fip to the list")
 
@@ -171,7 +201,7 @@ func UpdateAllFips(fip *KubefipV1.FloatingIP) error {
 			// if there is no match, add the fip to the new list
 			log.Debugf("(UpdateAllFips) adding existing fip to the list")
 
-			newAllFips = append(newAllFips, AllFips[i])
+			newAllFips = append(newAllFips, allFips[i])
 		}
 	}
 
@@ -183,31 +213,34 @@ func UpdateAllFips(fip *KubefipV1.FloatingIP) error {
 	}
 
 	// all good, assign the new list
-	AllFips = newAllFips
+	allFips = newAllFips
 
 	return err
 }
 
-func RemoveFipFromAllFips(fip *KubefipV1.FloatingIP) error {
+func RemoveFipFromAllFips(fip *KubefipV2.FloatingIP) error {
 	var err error
 	var FipFound bool = false
 
+	fipsMutex.Lock()
+	defer fipsMutex.Unlock()
+
 	log.Debugf("(RemoveFipFromAllFips) removing fip [%s/%s] from allFips list", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
 
-	var newAllFips []KubefipV1.FloatingIP
+	var newAllFips []KubefipV2.FloatingIP
 
-	for i := 0; i < len(AllFips); i++ {
+	for i := 0; i < len(allFips); i++ {
 		// if the fip matches the one in the list, skip it
-		if fip.ObjectMeta.Namespace == AllFips[i].ObjectMeta.Namespace && fip.ObjectMeta.Name == AllFips[i].ObjectMeta.Name {
+		if fip.ObjectMeta.Namespace == allFips[i].ObjectMeta.Namespace && fip.ObjectMeta.Name == allFips[i].ObjectMeta.Name {
 			// if the updated fip matches the one in the list, add the new fip to the new list
 			log.Debugf("(RemoveFipFromAllFips) fip to remove found, skip appending fip to the list")
 
 			FipFound = true
 		} else {
 			// if there is no match, add the fip to the new list
-			log.Debugf("(RemoveFipFromAllFips) adding existing fip [%s/%s] to the list", AllFips[i].ObjectMeta.Namespace, AllFips[i].ObjectMeta.Name)
+			log.Debugf("(RemoveFipFromAllFips) adding existing fip [%s/%s] to the list", allFips[i].ObjectMeta.Namespace, allFips[i].ObjectMeta.Name)
 
-			newAllFips = append(newAllFips, AllFips[i])
+			newAllFips = append(newAllFips, allFips[i])
 		}
 	}
 
@@ -221,23 +254,84 @@ func RemoveFipFromAllFips(fip *KubefipV1.FloatingIP) error {
 	log.Debugf("(RemoveFipFromAllFips) successfully removed fip [%s/%s] from allFips list", fip.ObjectMeta.Name, fip.Spec.IPAddress)
 
 	// all good, assign the new list
-	AllFips = newAllFips
+	allFips = newAllFips
 
 	return err
 }
 
 func InitIpam() {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
 	// initialize the ipam service
-	IPAM = ipam.New()
+	ipamPrefixes = ipam.New()
+}
+
+// IpamNewSubnet creates the ipam prefix of a fiprange
+func IpamNewSubnet(fipRangeName string, subnet string, start string, end string) error {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	return ipamPrefixes.NewSubnet(fipRangeName, subnet, start, end)
+}
+
+// IpamDeleteSubnet deletes the ipam prefix of a fiprange
+func IpamDeleteSubnet(fipRangeName string) {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	ipamPrefixes.DeleteSubnet(fipRangeName)
+}
+
+// IpamGetIP allocates the ip address in the ipam prefix of the fiprange, a free ip address is allocated when the ip
+// address is empty
+func IpamGetIP(fipRangeName string, ipAddress string) (string, error) {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	return ipamPrefixes.GetIP(fipRangeName, ipAddress)
+}
+
+// IpamReleaseIP releases the ip address in the ipam prefix of the fiprange
+func IpamReleaseIP(fipRangeName string, ipAddress string) error {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	return ipamPrefixes.ReleaseIP(fipRangeName, ipAddress)
+}
+
+// IpamUsed returns the number of allocated ip addresses in the ipam prefix of the fiprange
+func IpamUsed(fipRangeName string) int {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	return ipamPrefixes.Used(fipRangeName)
+}
+
+// IpamAvailable returns the number of free ip addresses in the ipam prefix of the fiprange
+func IpamAvailable(fipRangeName string) int {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	return ipamPrefixes.Available(fipRangeName)
+}
+
+// IpamUsage logs the allocated ip addresses of the ipam prefix of the fiprange
+func IpamUsage(fipRangeName string) {
+	ipamMutex.Lock()
+	defer ipamMutex.Unlock()
+
+	ipamPrefixes.Usage(fipRangeName)
 }
 
 func CreateIpamPrefixesFromFipRanges() {
 	log.Debugf("(CreateIpamPrefixesFromFipRanges) start creating ipam prefixes from fipranges..")
 
-	for i := 0; i < len(AllFipRanges); i++ {
-		log.Tracef("(CreateIpamPrefixesFromFipRanges) fiprange obj: [%+v]", AllFipRanges[i])
+	fipRanges := GetAllFipRanges()
+	for i := 0; i < len(fipRanges); i++ {
+		log.Tracef("(CreateIpamPrefixesFromFipRanges) fiprange obj: [%+v]", fipRanges[i])
 
-		if err := AllocateFipRange(&AllFipRanges[i]); err != nil {
+		if err := AllocateFipRange(&fipRanges[i]); err != nil {
 			log.Errorf("(watchFipRangeEvents) error allocating fiprange: %s", err.Error())
 		}
 	}
@@ -246,10 +340,11 @@ func CreateIpamPrefixesFromFipRanges() {
 func StoreAllocatedIpsInIpamPrefixes(clientset *kubefipclientset.Clientset) {
 	log.Debugf("(StoreAllocatedIpsInIpamPrefixes) start storing fips in ipam prefixes..")
 
-	for i := 0; i < len(AllFips); i++ {
-		log.Tracef("(StoreAllocatedIpsInIpamPrefixes) fip obj: [%+v]", AllFips[i])
+	fips := GetAllFips()
+	for i := 0; i < len(fips); i++ {
+		log.Tracef("(StoreAllocatedIpsInIpamPrefixes) fip obj: [%+v]", fips[i])
 
-		if err := AllocateFip(&AllFips[i], clientset); err != nil {
+		if err := AllocateFip(&fips[i], clientset); err != nil {
 			log.Errorf("(StoreAllocatedIpsInIpamPrefixes) error allocating fip: %s", err.Error())
 		}
 	}
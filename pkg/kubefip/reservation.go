@@ -0,0 +1,309 @@
+package kubefip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	kubefipclientset "github.com/joeyloman/kube-fip-operator/pkg/generated/clientset/versioned"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	allFipReservations []KubefipV1.FloatingIPReservation
+
+	// the reservations which currently hold their ip address in the ipam prefix
+	heldFipReservations = make(map[string]KubefipV1.FloatingIPReservation)
+
+	// the fips which the operator is creating for a reservation (reservation name -> <namespace>/<name> of the fip), the
+	// fipreservation annotation can be set by anyone so it only counts for the fip which is registered here until the
+	// reservation is bound to it
+	pendingFipReservations = make(map[string]string)
+
+	// guards allFipReservations, heldFipReservations and pendingFipReservations, they are changed by the reservation
+	// informer and by the cluster creation of the namespace informer and the cluster source discovery
+	fipReservationsMutex sync.RWMutex
+)
+
+// GetAllFipReservations returns a copy of the reservations list
+func GetAllFipReservations() []KubefipV1.FloatingIPReservation {
+	fipReservationsMutex.RLock()
+	defer fipReservationsMutex.RUnlock()
+
+	return append([]KubefipV1.FloatingIPReservation(nil), allFipReservations...)
+}
+
+// IsFipReservationActive returns true if the reservation isn't bound to a fip and isn't expired
+func IsFipReservationActive(fipReservation *KubefipV1.FloatingIPReservation) bool {
+	if fipReservation.Status.BoundTo != "" {
+		return false
+	}
+
+	if fipReservation.Spec.Expires != nil && fipReservation.Spec.Expires.Time.Before(time.Now()) {
+		return false
+	}
+
+	return true
+}
+
+// SetPendingFipReservation registers the fip which the operator creates for the reservation, it must be called before
+// the fip is created so the webhook and the fip add event accept the reserved ip address for it
+func SetPendingFipReservation(fipReservationName string, fip *KubefipV2.FloatingIP) {
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	pendingFipReservations[fipReservationName] = fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+}
+
+// RemovePendingFipReservation removes the registered fip of the reservation, for example when the fip create fails
+func RemovePendingFipReservation(fipReservationName string) {
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	delete(pendingFipReservations, fipReservationName)
+}
+
+// IsFipReservationOwner returns true if the fip may use the ip address of the reservation
+func IsFipReservationOwner(fipReservation *KubefipV1.FloatingIPReservation, fip *KubefipV2.FloatingIP) bool {
+	fipReservationsMutex.RLock()
+	defer fipReservationsMutex.RUnlock()
+
+	return isFipReservationOwner(fipReservation, fip)
+}
+
+// isFipReservationOwner checks if the fip is the one the reservation is bound to, or the one the operator is creating
+// for the reservation. The fipreservation annotation alone is not enough, because it can be set by anyone.
+func isFipReservationOwner(fipReservation *KubefipV1.FloatingIPReservation, fip *KubefipV2.FloatingIP) bool {
+	if fip.ObjectMeta.Annotations["fipreservation"] != fipReservation.ObjectMeta.Name {
+		return false
+	}
+
+	if fipReservation.Spec.ClusterName != "" && fipReservation.Spec.ClusterName != fip.Spec.ClusterName {
+		return false
+	}
+
+	fipName := fmt.Sprintf("%s/%s", fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+	if fipReservation.Status.BoundTo != "" {
+		return fipReservation.Status.BoundTo == fipName
+	}
+
+	return pendingFipReservations[fipReservation.ObjectMeta.Name] == fipName
+}
+
+// getFipReservation returns the reservation from the allFipReservations list
+func getFipReservation(fipReservationName string) (KubefipV1.FloatingIPReservation, bool) {
+	for i := 0; i < len(allFipReservations); i++ {
+		if allFipReservations[i].ObjectMeta.Name == fipReservationName {
+			return allFipReservations[i], true
+		}
+	}
+
+	return KubefipV1.FloatingIPReservation{}, false
+}
+
+func GatherAllFipReservations(clientset kubefipclientset.Interface) error {
+	var err error
+
+	log.Infof("(GatherAllFipReservations) gathering and storing al floatingipreservations..")
+
+	fipReservationList, err := clientset.KubefipV1().FloatingIPReservations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var fipReservations []KubefipV1.FloatingIPReservation
+	for _, fipReservation := range fipReservationList.Items {
+		log.Infof("(GatherAllFipReservations) fipreservation found: %s", fipReservation.Name)
+		log.Tracef("(GatherAllFipReservations) fipreservation object: %+v", fipReservation)
+
+		fipReservations = append(fipReservations, fipReservation)
+	}
+
+	fipReservationsMutex.Lock()
+	allFipReservations = fipReservations
+	fipReservationsMutex.Unlock()
+
+	return err
+}
+
+func StoreReservedIpsInIpamPrefixes() {
+	log.Debugf("(StoreReservedIpsInIpamPrefixes) start storing reserved ips in ipam prefixes..")
+
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	// allocateFipReservation rebuilds the allFipReservations list
+	fipReservations := allFipReservations
+	allFipReservations = nil
+
+	for i := 0; i < len(fipReservations); i++ {
+		if err := allocateFipReservation(&fipReservations[i]); err != nil {
+			log.Errorf("(StoreReservedIpsInIpamPrefixes) error allocating fipreservation: %s", err.Error())
+		}
+	}
+}
+
+// AllocateFipReservation holds the reserved ip address in the ipam prefix, so it's never handed out dynamically
+func AllocateFipReservation(fipReservation *KubefipV1.FloatingIPReservation) error {
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	return allocateFipReservation(fipReservation)
+}
+
+func allocateFipReservation(fipReservation *KubefipV1.FloatingIPReservation) error {
+	var err error
+
+	log.Tracef("(AllocateFipReservation) fipreservationobj added: [%+v]", fipReservation)
+
+	allFipReservations = append(removeFipReservationFromList(allFipReservations, fipReservation.ObjectMeta.Name), *fipReservation)
+
+	if !IsFipReservationActive(fipReservation) {
+		log.Debugf("(AllocateFipReservation) fipreservation [%s] is bound or expired, not holding ip address [%s]",
+			fipReservation.ObjectMeta.Name, fipReservation.Spec.IPAddress)
+
+		return err
+	}
+
+	if _, held := heldFipReservations[fipReservation.ObjectMeta.Name]; held {
+		return err
+	}
+
+	if fipReservation.Spec.IPAddress == "" || fipReservation.Spec.FipRange == "" {
+		errMsg := fmt.Sprintf("ipaddress or fiprange not found in spec of fipreservation [%s]", fipReservation.ObjectMeta.Name)
+		return errors.New(errMsg)
+	}
+
+	// check if the fiprange exists
+	_, err = GetFipRange(fipReservation.Spec.FipRange)
+	if err != nil {
+		return err
+	}
+
+	ip, err := IpamGetIP(fipReservation.Spec.FipRange, fipReservation.Spec.IPAddress)
+	if err != nil {
+		return fmt.Errorf("cannot reserve ip address [%s] in fiprange [%s] for fipreservation [%s]: %s",
+			fipReservation.Spec.IPAddress, fipReservation.Spec.FipRange, fipReservation.ObjectMeta.Name, err.Error())
+	}
+
+	heldFipReservations[fipReservation.ObjectMeta.Name] = *fipReservation
+
+	log.Infof("(AllocateFipReservation) successfully reserved ip address [%s] in fiprange [%s] for fipreservation [%s]",
+		ip, fipReservation.Spec.FipRange, fipReservation.ObjectMeta.Name)
+
+	return err
+}
+
+// TakeOverFipReservation hands the held ip address of the reservation in the fipreservation annotation over to the fip,
+// the ip address stays allocated in the ipam prefix so it's never free for a dynamic allocation in between. It returns
+// false when the reservation doesn't hold the ip address of the fip or when the fip is not the owner of the reservation.
+func TakeOverFipReservation(fip *KubefipV2.FloatingIP) bool {
+	fipReservationName := fip.ObjectMeta.Annotations["fipreservation"]
+	if fipReservationName == "" {
+		return false
+	}
+
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	fipReservation, held := heldFipReservations[fipReservationName]
+	if !held || fipReservation.Spec.FipRange != fip.Spec.FipRange || fipReservation.Spec.IPAddress != fip.Spec.IPAddress {
+		return false
+	}
+
+	// the held copy is stored when the reservation became active, the list has the current binding
+	if current, found := getFipReservation(fipReservationName); found {
+		fipReservation = current
+	}
+
+	if !isFipReservationOwner(&fipReservation, fip) {
+		log.Warnf("(TakeOverFipReservation) fip [%s/%s] of cluster [%s] is not the owner of fipreservation [%s], not handing over ip address [%s]",
+			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, fip.Spec.ClusterName, fipReservationName, fip.Spec.IPAddress)
+
+		return false
+	}
+
+	delete(heldFipReservations, fipReservationName)
+	delete(pendingFipReservations, fipReservationName)
+
+	log.Infof("(TakeOverFipReservation) fip [%s/%s] took over ip address [%s] of fipreservation [%s] in fiprange [%s]",
+		fip.ObjectMeta.Namespace, fip.ObjectMeta.Name, fip.Spec.IPAddress, fipReservationName, fip.Spec.FipRange)
+
+	return true
+}
+
+// releaseFipReservation releases the held ip address of a changed, expired or removed reservation
+func releaseFipReservation(fipReservationName string) error {
+	var err error
+
+	fipReservation, held := heldFipReservations[fipReservationName]
+	if !held {
+		return err
+	}
+
+	if err = IpamReleaseIP(fipReservation.Spec.FipRange, fipReservation.Spec.IPAddress); err != nil {
+		return fmt.Errorf("error while releasing ip address [%s] of fipreservation [%s] from fiprange [%s]: %s",
+			fipReservation.Spec.IPAddress, fipReservationName, fipReservation.Spec.FipRange, err.Error())
+	}
+
+	delete(heldFipReservations, fipReservationName)
+
+	log.Infof("(ReleaseFipReservation) released ip address [%s] of fipreservation [%s] from fiprange [%s]",
+		fipReservation.Spec.IPAddress, fipReservationName, fipReservation.Spec.FipRange)
+
+	return err
+}
+
+func RemoveFipReservation(fipReservation *KubefipV1.FloatingIPReservation) error {
+	log.Tracef("(RemoveFipReservation) fipreservationobj removed: [%+v]", fipReservation)
+
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	allFipReservations = removeFipReservationFromList(allFipReservations, fipReservation.ObjectMeta.Name)
+	delete(pendingFipReservations, fipReservation.ObjectMeta.Name)
+
+	return releaseFipReservation(fipReservation.ObjectMeta.Name)
+}
+
+func UpdateFipReservation(oldFipReservation *KubefipV1.FloatingIPReservation, newFipReservation *KubefipV1.FloatingIPReservation) error {
+	var err error
+
+	log.Tracef("(UpdateFipReservation) fipreservationobj updated: oldFipReservation [%+v] / newFipReservation [%+v]",
+		oldFipReservation, newFipReservation)
+
+	fipReservationsMutex.Lock()
+	defer fipReservationsMutex.Unlock()
+
+	// only release the held ip address when the reservation itself changes or expires, a bound reservation keeps it until
+	// the fip add event takes it over
+	if oldFipReservation.Spec.IPAddress != newFipReservation.Spec.IPAddress || oldFipReservation.Spec.FipRange != newFipReservation.Spec.FipRange ||
+		(!IsFipReservationActive(newFipReservation) && newFipReservation.Status.BoundTo == "") {
+		if err := releaseFipReservation(oldFipReservation.ObjectMeta.Name); err != nil {
+			log.Errorf("(UpdateFipReservation) Error releasing oldFipReservation: %s", err.Error())
+		}
+	}
+
+	if err := allocateFipReservation(newFipReservation); err != nil {
+		log.Errorf("(UpdateFipReservation) Error allocating newFipReservation: %s", err.Error())
+	}
+
+	return err
+}
+
+func removeFipReservationFromList(fipReservations []KubefipV1.FloatingIPReservation, fipReservationName string) []KubefipV1.FloatingIPReservation {
+	var newFipReservations []KubefipV1.FloatingIPReservation
+
+	for i := 0; i < len(fipReservations); i++ {
+		if fipReservations[i].ObjectMeta.Name != fipReservationName {
+			newFipReservations = append(newFipReservations, fipReservations[i])
+		}
+	}
+
+	return newFipReservations
+}
@@ -0,0 +1,107 @@
+package kubefip
+
+import (
+	"testing"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+)
+
+func newTestReservationFip(namespace string, name string, clusterName string, fipReservationName string) KubefipV2.FloatingIP {
+	fip := newTestFip(namespace, name, "range1", "192.168.10.20")
+	fip.Spec.ClusterName = clusterName
+	if fipReservationName != "" {
+		fip.ObjectMeta.Annotations = map[string]string{"fipreservation": fipReservationName}
+	}
+
+	return fip
+}
+
+func TestIsFipReservationOwner(t *testing.T) {
+	byName := newTestFipReservation("by-name", "range1", "192.168.10.20")
+	byName.Spec.ClusterName = "cluster1"
+
+	bySelector := newTestFipReservation("by-selector", "range1", "192.168.10.20")
+
+	bound := newTestFipReservation("bound", "range1", "192.168.10.20")
+	bound.Status.BoundTo = "ns1/cluster1-kubevip"
+
+	pendingFip := newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", "by-selector")
+	setTestPendingFipReservation(t, "by-selector", &pendingFip)
+
+	tests := []struct {
+		name           string
+		fipReservation KubefipV1.FloatingIPReservation
+		fip            KubefipV2.FloatingIP
+		want           bool
+	}{
+		{"no fipreservation annotation", bySelector, newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", ""), false},
+		{"annotation of another reservation", bySelector, newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", "by-name"), false},
+		{"pending fip", bySelector, pendingFip, true},
+		{"annotation without a pending fip", byName, newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", "by-name"), false},
+		{"other fip than the pending fip", bySelector, newTestReservationFip("ns2", "cluster2-kubevip", "cluster2", "by-selector"), false},
+		{"bound fip", bound, newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", "bound"), true},
+		{"other fip than the bound fip", bound, newTestReservationFip("ns1", "cluster2-kubevip", "cluster2", "bound"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsFipReservationOwner(&tt.fipReservation, &tt.fip); got != tt.want {
+			t.Errorf("%s: expected [%t], got [%t]", tt.name, tt.want, got)
+		}
+	}
+
+	// the clusterName of the reservation must match, also for the pending fip
+	otherCluster := newTestReservationFip("ns1", "cluster2-kubevip", "cluster2", "by-name")
+	setTestPendingFipReservation(t, "by-name", &otherCluster)
+	if IsFipReservationOwner(&byName, &otherCluster) {
+		t.Errorf("expected fip of cluster [cluster2] not to be the owner of fipreservation [by-name] for cluster [cluster1]")
+	}
+}
+
+func TestTakeOverFipReservation(t *testing.T) {
+	setTestObjects(t, []KubefipV2.FloatingIPRange{newTestFipRange("range1", "192.168.10.0/24")}, nil, nil)
+
+	InitIpam()
+	t.Cleanup(InitIpam)
+	if err := IpamNewSubnet("range1", "192.168.10.0/24", "192.168.10.0", "192.168.10.254"); err != nil {
+		t.Fatalf("error creating the ipam subnet: %s", err.Error())
+	}
+
+	fipReservation := newTestFipReservation("reserved", "range1", "192.168.10.20")
+	fipReservation.Spec.ClusterName = "cluster1"
+	if err := AllocateFipReservation(&fipReservation); err != nil {
+		t.Fatalf("error allocating the fipreservation: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		fipReservationsMutex.Lock()
+		delete(heldFipReservations, fipReservation.ObjectMeta.Name)
+		fipReservationsMutex.Unlock()
+	})
+
+	// a fip which only sets the annotation doesn't get the reserved ip address
+	userFip := newTestReservationFip("ns2", "cluster1-kubevip", "cluster1", "reserved")
+	if TakeOverFipReservation(&userFip) {
+		t.Fatalf("expected fip [ns2/cluster1-kubevip] without a pending binding not to take over the fipreservation")
+	}
+
+	operatorFip := newTestReservationFip("ns1", "cluster1-kubevip", "cluster1", "reserved")
+	setTestPendingFipReservation(t, "reserved", &operatorFip)
+
+	wrongCluster := newTestReservationFip("ns1", "cluster1-kubevip", "cluster2", "reserved")
+	if TakeOverFipReservation(&wrongCluster) {
+		t.Fatalf("expected fip of cluster [cluster2] not to take over the fipreservation of cluster [cluster1]")
+	}
+
+	if !TakeOverFipReservation(&operatorFip) {
+		t.Fatalf("expected the pending fip to take over the fipreservation")
+	}
+
+	// the ip address stays allocated for the fip
+	if _, err := IpamGetIP("range1", "192.168.10.20"); err == nil {
+		t.Errorf("expected ip address [192.168.10.20] to stay allocated after the take over")
+	}
+
+	if TakeOverFipReservation(&operatorFip) {
+		t.Errorf("expected the fipreservation to be taken over only once")
+	}
+}
@@ -0,0 +1,100 @@
+package kubefip
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+)
+
+// FipRangeUsage is the capacity and usage of a fiprange
+type FipRangeUsage struct {
+	Capacity int64 `json:"capacity"`
+	Used     int64 `json:"used"`
+	Reserved int64 `json:"reserved"`
+	Free     int64 `json:"free"`
+}
+
+// fipRangeHosts returns the first and the last host address of the range, the first and the last address in the
+// range are reserved for gateway and broadcast purposes
+func fipRangeHosts(fipRange *KubefipV2.FloatingIPRange) (netip.Addr, netip.Addr, bool) {
+	prefix, err := netip.ParsePrefix(fipRange.Spec.IPRange)
+	if err != nil || !prefix.Addr().Is4() || prefix.Bits() > 30 {
+		return netip.Addr{}, netip.Addr{}, false
+	}
+	prefix = prefix.Masked()
+
+	network := prefix.Addr().As4()
+	hostBits := uint32(1)<<(32-prefix.Bits()) - 1
+
+	var broadcast [4]byte
+	binary.BigEndian.PutUint32(broadcast[:], binary.BigEndian.Uint32(network[:])|hostBits)
+
+	return prefix.Addr().Next(), netip.AddrFrom4(broadcast).Prev(), true
+}
+
+// GetFipRangeUsage counts the fips and the active reservations of a fiprange
+func GetFipRangeUsage(fipRange *KubefipV2.FloatingIPRange) FipRangeUsage {
+	usage := FipRangeUsage{}
+
+	if first, last, ok := fipRangeHosts(fipRange); ok {
+		firstIP, lastIP := first.As4(), last.As4()
+		usage.Capacity = int64(binary.BigEndian.Uint32(lastIP[:])-binary.BigEndian.Uint32(firstIP[:])) + 1
+	}
+
+	fips := GetAllFips()
+	for i := range fips {
+		if fips[i].Spec.FipRange == fipRange.ObjectMeta.Name && fips[i].Spec.IPAddress != "" {
+			usage.Used++
+		}
+	}
+
+	fipReservations := GetAllFipReservations()
+	for i := range fipReservations {
+		if fipReservations[i].Spec.FipRange == fipRange.ObjectMeta.Name && IsFipReservationActive(&fipReservations[i]) {
+			usage.Reserved++
+		}
+	}
+
+	usage.Free = max(usage.Capacity-usage.Used-usage.Reserved, 0)
+
+	return usage
+}
+
+// GetFreeIPAddresses returns the addresses of a fiprange which are not used by a fip or held by an active reservation,
+// at most limit addresses are returned when limit is larger than 0
+func GetFreeIPAddresses(fipRange *KubefipV2.FloatingIPRange, limit int) []string {
+	free := []string{}
+
+	first, last, ok := fipRangeHosts(fipRange)
+	if !ok {
+		return free
+	}
+
+	taken := make(map[string]bool)
+	fips := GetAllFips()
+	for i := range fips {
+		if fips[i].Spec.FipRange == fipRange.ObjectMeta.Name && fips[i].Spec.IPAddress != "" {
+			taken[fips[i].Spec.IPAddress] = true
+		}
+	}
+	fipReservations := GetAllFipReservations()
+	for i := range fipReservations {
+		if fipReservations[i].Spec.FipRange == fipRange.ObjectMeta.Name && IsFipReservationActive(&fipReservations[i]) {
+			taken[fipReservations[i].Spec.IPAddress] = true
+		}
+	}
+
+	for ip := first; ip.Compare(last) <= 0; ip = ip.Next() {
+		if taken[ip.String()] {
+			continue
+		}
+
+		free = append(free, ip.String())
+		if limit > 0 && len(free) >= limit {
+			break
+		}
+	}
+
+	return free
+}
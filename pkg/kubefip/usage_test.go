@@ -0,0 +1,88 @@
+package kubefip
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetFipRangeUsage(t *testing.T) {
+	expired := newTestFipReservation("expired", "range1", "192.168.10.3")
+	expired.Spec.Expires = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+	bound := newTestFipReservation("bound", "range1", "192.168.10.1")
+	bound.Status.BoundTo = "ns1/cluster1"
+
+	setTestObjects(t, nil, []KubefipV2.FloatingIP{
+		newTestFip("ns1", "cluster1", "range1", "192.168.10.1"),
+		newTestFip("ns1", "cluster2", "range1", ""),
+		newTestFip("ns1", "cluster3", "range2", "192.168.20.9"),
+		newTestFip("ns1", "cluster4", "small", "192.168.30.1"),
+		newTestFip("ns1", "cluster5", "small", "192.168.30.2"),
+	}, []KubefipV1.FloatingIPReservation{
+		newTestFipReservation("reserved", "range1", "192.168.10.2"),
+		expired,
+		bound,
+		newTestFipReservation("small", "small", "192.168.30.1"),
+	})
+
+	tests := []struct {
+		name      string
+		fipRange  KubefipV2.FloatingIPRange
+		want      FipRangeUsage
+		wantFree  []string
+		freeLimit int
+	}{
+		{
+			name:      "/24",
+			fipRange:  newTestFipRange("range1", "192.168.10.0/24"),
+			want:      FipRangeUsage{Capacity: 254, Used: 1, Reserved: 1, Free: 252},
+			wantFree:  []string{"192.168.10.3", "192.168.10.4"},
+			freeLimit: 2,
+		},
+		{
+			name:     "range which is not masked",
+			fipRange: newTestFipRange("range2", "192.168.20.9/30"),
+			want:     FipRangeUsage{Capacity: 2, Used: 1, Free: 1},
+			wantFree: []string{"192.168.20.10"},
+		},
+		{
+			name:     "more fips and reservations than addresses",
+			fipRange: newTestFipRange("small", "192.168.30.0/30"),
+			want:     FipRangeUsage{Capacity: 2, Used: 2, Reserved: 1, Free: 0},
+			wantFree: []string{},
+		},
+		{
+			name:      "/16",
+			fipRange:  newTestFipRange("large", "10.0.0.0/16"),
+			want:      FipRangeUsage{Capacity: 65534, Free: 65534},
+			wantFree:  []string{"10.0.0.1"},
+			freeLimit: 1,
+		},
+		{
+			name:     "/31",
+			fipRange: newTestFipRange("range3", "192.168.40.0/31"),
+			wantFree: []string{},
+		},
+		{
+			name:     "invalid range",
+			fipRange: newTestFipRange("range3", "invalid"),
+			wantFree: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := GetFipRangeUsage(&tt.fipRange); got != tt.want {
+			t.Errorf("%s: expected usage [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+
+		if got := GetFreeIPAddresses(&tt.fipRange, tt.freeLimit); !reflect.DeepEqual(got, tt.wantFree) {
+			t.Errorf("%s: expected free addresses %v, got %v", tt.name, tt.wantFree, got)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package kubefip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+)
+
+// ValidateFip checks if the fip has a clustername and an existing fiprange, and if the ip address is a part of the fiprange
+func ValidateFip(fip *KubefipV2.FloatingIP) error {
+	// check if the clustername is set
+	if fip.Spec.ClusterName == "" {
+		errMsg := fmt.Sprintf("clusterName not found in spec for [%s/%s]",
+			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+		return errors.New(errMsg)
+	}
+
+	// get the fiprange from the fip object spec
+	frName := fip.Spec.FipRange
+	if frName == "" {
+		errMsg := fmt.Sprintf("fipRange not found in spec for [%s/%s]",
+			fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+		return errors.New(errMsg)
+	}
+
+	// check if the fiprange exists
+	fipRange, err := GetFipRange(frName)
+	if err != nil {
+		return fmt.Errorf("fiprange [%s] of [%s/%s] does not exist", frName, fip.ObjectMeta.Namespace, fip.ObjectMeta.Name)
+	}
+
+	if fip.Spec.IPAddress == "" {
+		return nil
+	}
+
+	return validateIPAddressInFipRange(fip.Spec.IPAddress, fipRange)
+}
+
+// ValidateFipAddressAvailable checks if the ip address of the fip isn't used by another fip or held by a reservation
+func ValidateFipAddressAvailable(fip *KubefipV2.FloatingIP) error {
+	if fip.Spec.IPAddress == "" {
+		return nil
+	}
+
+	fips := GetAllFips()
+	for i := 0; i < len(fips); i++ {
+		if fips[i].ObjectMeta.Namespace == fip.ObjectMeta.Namespace && fips[i].ObjectMeta.Name == fip.ObjectMeta.Name {
+			continue
+		}
+
+		if fips[i].Spec.IPAddress == fip.Spec.IPAddress {
+			return fmt.Errorf("ip address [%s] is already taken by fip [%s/%s]", fip.Spec.IPAddress,
+				fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name)
+		}
+	}
+
+	fipReservations := GetAllFipReservations()
+	for i := 0; i < len(fipReservations); i++ {
+		// the fip which the operator creates for the reservation
+		if IsFipReservationOwner(&fipReservations[i], fip) {
+			continue
+		}
+
+		if IsFipReservationActive(&fipReservations[i]) && fipReservations[i].Spec.IPAddress == fip.Spec.IPAddress {
+			return fmt.Errorf("ip address [%s] is reserved by fipreservation [%s]", fip.Spec.IPAddress, fipReservations[i].ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateFipReservation checks if the reserved ip address is a part of an existing fiprange and isn't used by a fip
+// or held by another reservation
+func ValidateFipReservation(fipReservation *KubefipV1.FloatingIPReservation) error {
+	fipRange, err := GetFipRange(fipReservation.Spec.FipRange)
+	if err != nil {
+		return fmt.Errorf("fiprange [%s] of fipreservation [%s] does not exist", fipReservation.Spec.FipRange, fipReservation.ObjectMeta.Name)
+	}
+
+	if err := validateIPAddressInFipRange(fipReservation.Spec.IPAddress, fipRange); err != nil {
+		return err
+	}
+
+	fips := GetAllFips()
+	for i := 0; i < len(fips); i++ {
+		// the fip which got the address from this reservation
+		if IsFipReservationOwner(fipReservation, &fips[i]) {
+			continue
+		}
+
+		if fips[i].Spec.IPAddress == fipReservation.Spec.IPAddress {
+			return fmt.Errorf("ip address [%s] is already taken by fip [%s/%s]", fipReservation.Spec.IPAddress,
+				fips[i].ObjectMeta.Namespace, fips[i].ObjectMeta.Name)
+		}
+	}
+
+	fipReservations := GetAllFipReservations()
+	for i := 0; i < len(fipReservations); i++ {
+		if fipReservations[i].ObjectMeta.Name == fipReservation.ObjectMeta.Name {
+			continue
+		}
+
+		if IsFipReservationActive(&fipReservations[i]) && fipReservations[i].Spec.IPAddress == fipReservation.Spec.IPAddress {
+			return fmt.Errorf("ip address [%s] is reserved by fipreservation [%s]", fipReservation.Spec.IPAddress, fipReservations[i].ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateFipRange checks if the fiprange has a valid ipv4 cidr with at least one usable ip address
+func ValidateFipRange(fipRange *KubefipV2.FloatingIPRange) error {
+	// get the fiprange from the fiprange object
+	if fipRange.Spec.IPRange == "" {
+		return errors.New("fiprange not found in spec")
+	}
+
+	prefix, err := netip.ParsePrefix(fipRange.Spec.IPRange)
+	if err != nil {
+		return fmt.Errorf("iprange [%s] of fiprange [%s] is not a valid cidr: %s", fipRange.Spec.IPRange, fipRange.ObjectMeta.Name, err.Error())
+	}
+
+	if !prefix.Addr().Is4() {
+		return fmt.Errorf("iprange [%s] of fiprange [%s] is not an ipv4 cidr", fipRange.Spec.IPRange, fipRange.ObjectMeta.Name)
+	}
+
+	if prefix.Bits() > 30 {
+		return fmt.Errorf("iprange [%s] of fiprange [%s] has no usable ip addresses, the prefix length must be 30 or less",
+			fipRange.Spec.IPRange, fipRange.ObjectMeta.Name)
+	}
+
+	return nil
+}
+
+// ValidateFipRangeOverlap checks if the fiprange doesn't overlap with one of the other fipranges
+func ValidateFipRangeOverlap(fipRange *KubefipV2.FloatingIPRange) error {
+	prefix, err := netip.ParsePrefix(fipRange.Spec.IPRange)
+	if err != nil {
+		return err
+	}
+
+	fipRanges := GetAllFipRanges()
+	for i := 0; i < len(fipRanges); i++ {
+		if fipRanges[i].ObjectMeta.Name == fipRange.ObjectMeta.Name {
+			continue
+		}
+
+		otherPrefix, err := netip.ParsePrefix(fipRanges[i].Spec.IPRange)
+		if err != nil {
+			continue
+		}
+
+		if prefix.Overlaps(otherPrefix) {
+			return fmt.Errorf("iprange [%s] of fiprange [%s] overlaps with iprange [%s] of fiprange [%s]", fipRange.Spec.IPRange,
+				fipRange.ObjectMeta.Name, fipRanges[i].Spec.IPRange, fipRanges[i].ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateIPAddressInFipRange(ipAddress string, fipRange KubefipV2.FloatingIPRange) error {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil || !addr.Is4() {
+		return fmt.Errorf("ip address [%s] is not a valid ipv4 address", ipAddress)
+	}
+
+	prefix, err := netip.ParsePrefix(fipRange.Spec.IPRange)
+	if err != nil {
+		return fmt.Errorf("iprange [%s] of fiprange [%s] is not a valid cidr: %s", fipRange.Spec.IPRange, fipRange.ObjectMeta.Name, err.Error())
+	}
+	prefix = prefix.Masked()
+
+	if !prefix.Contains(addr) {
+		return fmt.Errorf("ip address [%s] is not a part of iprange [%s] of fiprange [%s]", ipAddress, fipRange.Spec.IPRange, fipRange.ObjectMeta.Name)
+	}
+
+	// the last address in the range is the broadcast address
+	network := prefix.Addr().As4()
+	hostBits := uint32(1)<<(32-prefix.Bits()) - 1
+	broadcast := binary.BigEndian.Uint32(network[:]) | hostBits
+	ip := addr.As4()
+
+	if binary.BigEndian.Uint32(ip[:]) == broadcast {
+		return fmt.Errorf("ip address [%s] is the broadcast address of fiprange [%s]", ipAddress, fipRange.ObjectMeta.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,291 @@
+package kubefip
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setTestObjects replaces the gathered fipranges, fips and fipreservations for the duration of the test
+func setTestObjects(t *testing.T, fipRanges []KubefipV2.FloatingIPRange, fips []KubefipV2.FloatingIP,
+	fipReservations []KubefipV1.FloatingIPReservation) {
+	t.Helper()
+
+	fipRangesMutex.Lock()
+	fipsMutex.Lock()
+	fipReservationsMutex.Lock()
+	oldFipRanges, oldFips, oldFipReservations := allFipRanges, allFips, allFipReservations
+	allFipRanges, allFips, allFipReservations = fipRanges, fips, fipReservations
+	fipReservationsMutex.Unlock()
+	fipsMutex.Unlock()
+	fipRangesMutex.Unlock()
+
+	t.Cleanup(func() {
+		fipRangesMutex.Lock()
+		fipsMutex.Lock()
+		fipReservationsMutex.Lock()
+		allFipRanges, allFips, allFipReservations = oldFipRanges, oldFips, oldFipReservations
+		fipReservationsMutex.Unlock()
+		fipsMutex.Unlock()
+		fipRangesMutex.Unlock()
+	})
+}
+
+// setTestPendingFipReservation registers the fip as the one which the operator creates for the reservation
+func setTestPendingFipReservation(t *testing.T, fipReservationName string, fip *KubefipV2.FloatingIP) {
+	t.Helper()
+
+	SetPendingFipReservation(fipReservationName, fip)
+	t.Cleanup(func() { RemovePendingFipReservation(fipReservationName) })
+}
+
+func newTestFipRange(name string, ipRange string) KubefipV2.FloatingIPRange {
+	return KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: ipRange},
+	}
+}
+
+func newTestFip(namespace string, name string, fipRange string, ipAddress string) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: KubefipV2.FloatingIPSpec{
+			ClusterName: name,
+			FipRange:    fipRange,
+			IPAddress:   ipAddress,
+		},
+	}
+}
+
+func newTestFipReservation(name string, fipRange string, ipAddress string) KubefipV1.FloatingIPReservation {
+	return KubefipV1.FloatingIPReservation{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: KubefipV1.FloatingIPReservationSpec{
+			FipRange:  fipRange,
+			IPAddress: ipAddress,
+		},
+	}
+}
+
+// checkError checks if err is nil when wantErr is empty, or if err contains wantErr
+func checkError(t *testing.T, name string, err error, wantErr string) {
+	t.Helper()
+
+	if wantErr == "" {
+		if err != nil {
+			t.Errorf("%s: expected no error, got [%s]", name, err.Error())
+		}
+
+		return
+	}
+
+	if err == nil {
+		t.Errorf("%s: expected an error containing [%s], got no error", name, wantErr)
+	} else if !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("%s: expected an error containing [%s], got [%s]", name, wantErr, err.Error())
+	}
+}
+
+func TestValidateIPAddressInFipRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		ipRange   string
+		ipAddress string
+		wantErr   string
+	}{
+		{"address in range", "192.168.10.0/24", "192.168.10.10", ""},
+		{"network address", "192.168.10.0/24", "192.168.10.0", ""},
+		{"last usable address", "192.168.10.0/24", "192.168.10.254", ""},
+		{"broadcast address", "192.168.10.0/24", "192.168.10.255", "is the broadcast address"},
+		{"broadcast address of a range which is not masked", "192.168.10.64/26", "192.168.10.127", "is the broadcast address"},
+		{"address outside the range", "192.168.10.0/24", "192.168.11.10", "is not a part of iprange"},
+		{"/30 usable address", "192.168.10.4/30", "192.168.10.6", ""},
+		{"/30 broadcast address", "192.168.10.4/30", "192.168.10.7", "is the broadcast address"},
+		{"/31 first address", "192.168.10.0/31", "192.168.10.0", ""},
+		{"/31 last address", "192.168.10.0/31", "192.168.10.1", "is the broadcast address"},
+		{"/32 address", "192.168.10.1/32", "192.168.10.1", "is the broadcast address"},
+		{"/32 other address", "192.168.10.1/32", "192.168.10.2", "is not a part of iprange"},
+		{"ipv6 address", "192.168.10.0/24", "fd00::10", "is not a valid ipv4 address"},
+		{"ipv4 mapped ipv6 address", "192.168.10.0/24", "::ffff:192.168.10.10", "is not a valid ipv4 address"},
+		{"invalid address", "192.168.10.0/24", "192.168.10", "is not a valid ipv4 address"},
+		{"empty address", "192.168.10.0/24", "", "is not a valid ipv4 address"},
+		{"invalid range", "192.168.10.0", "192.168.10.10", "is not a valid cidr"},
+	}
+
+	for _, tt := range tests {
+		err := validateIPAddressInFipRange(tt.ipAddress, newTestFipRange("range1", tt.ipRange))
+		checkError(t, tt.name, err, tt.wantErr)
+	}
+}
+
+func TestValidateFipRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		ipRange string
+		wantErr string
+	}{
+		{"/24", "192.168.10.0/24", ""},
+		{"/30", "192.168.10.4/30", ""},
+		{"range which is not masked", "192.168.10.10/24", ""},
+		{"/31", "192.168.10.0/31", "has no usable ip addresses"},
+		{"/32", "192.168.10.1/32", "has no usable ip addresses"},
+		{"ipv6", "fd00::/64", "is not an ipv4 cidr"},
+		{"ipv4 mapped ipv6", "::ffff:192.168.10.0/120", "is not an ipv4 cidr"},
+		{"address without prefix length", "192.168.10.0", "is not a valid cidr"},
+		{"invalid prefix length", "192.168.10.0/33", "is not a valid cidr"},
+		{"empty", "", "fiprange not found in spec"},
+	}
+
+	for _, tt := range tests {
+		fipRange := newTestFipRange("range1", tt.ipRange)
+		checkError(t, tt.name, ValidateFipRange(&fipRange), tt.wantErr)
+	}
+}
+
+func TestValidateFipRangeOverlap(t *testing.T) {
+	setTestObjects(t, []KubefipV2.FloatingIPRange{
+		newTestFipRange("range1", "192.168.10.0/24"),
+		newTestFipRange("range2", "10.0.0.0/16"),
+		newTestFipRange("broken", "invalid"),
+	}, nil, nil)
+
+	tests := []struct {
+		name     string
+		fipRange KubefipV2.FloatingIPRange
+		wantErr  string
+	}{
+		{"no overlap", newTestFipRange("new", "192.168.11.0/24"), ""},
+		{"same range", newTestFipRange("new", "192.168.10.0/24"), "overlaps with iprange [192.168.10.0/24] of fiprange [range1]"},
+		{"smaller range inside", newTestFipRange("new", "10.0.1.0/24"), "overlaps with iprange [10.0.0.0/16] of fiprange [range2]"},
+		{"larger range around", newTestFipRange("new", "192.168.0.0/16"), "overlaps with iprange [192.168.10.0/24] of fiprange [range1]"},
+		{"update of the range itself", newTestFipRange("range1", "192.168.10.0/25"), ""},
+		{"invalid range", newTestFipRange("new", "invalid"), "no '/'"},
+	}
+
+	for _, tt := range tests {
+		checkError(t, tt.name, ValidateFipRangeOverlap(&tt.fipRange), tt.wantErr)
+	}
+}
+
+func TestValidateFip(t *testing.T) {
+	setTestObjects(t, []KubefipV2.FloatingIPRange{
+		newTestFipRange("range1", "192.168.10.0/24"),
+	}, nil, nil)
+
+	tests := []struct {
+		name    string
+		fip     KubefipV2.FloatingIP
+		wantErr string
+	}{
+		{"fip with ip address", newTestFip("ns1", "cluster1", "range1", "192.168.10.10"), ""},
+		{"fip without ip address", newTestFip("ns1", "cluster1", "range1", ""), ""},
+		{"fip without clustername", KubefipV2.FloatingIP{Spec: KubefipV2.FloatingIPSpec{FipRange: "range1"}}, "clusterName not found in spec"},
+		{"fip without fiprange", newTestFip("ns1", "cluster1", "", ""), "fipRange not found in spec"},
+		{"fip with unknown fiprange", newTestFip("ns1", "cluster1", "range2", ""), "fiprange [range2] of [ns1/cluster1] does not exist"},
+		{"fip with broadcast address", newTestFip("ns1", "cluster1", "range1", "192.168.10.255"), "is the broadcast address"},
+		{"fip with address outside the range", newTestFip("ns1", "cluster1", "range1", "192.168.11.10"), "is not a part of iprange"},
+		{"fip with ipv6 address", newTestFip("ns1", "cluster1", "range1", "fd00::10"), "is not a valid ipv4 address"},
+	}
+
+	for _, tt := range tests {
+		checkError(t, tt.name, ValidateFip(&tt.fip), tt.wantErr)
+	}
+}
+
+func TestValidateFipAddressAvailable(t *testing.T) {
+	expired := newTestFipReservation("expired", "range1", "192.168.10.30")
+	expired.Spec.Expires = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+	bound := newTestFipReservation("bound", "range1", "192.168.10.40")
+	bound.Status.BoundTo = "ns1/cluster4"
+
+	setTestObjects(t, []KubefipV2.FloatingIPRange{
+		newTestFipRange("range1", "192.168.10.0/24"),
+	}, []KubefipV2.FloatingIP{
+		newTestFip("ns1", "cluster1", "range1", "192.168.10.10"),
+	}, []KubefipV1.FloatingIPReservation{
+		newTestFipReservation("reserved", "range1", "192.168.10.20"),
+		expired,
+		bound,
+	})
+
+	reservedFip := newTestFip("ns1", "cluster2", "range1", "192.168.10.20")
+	reservedFip.ObjectMeta.Annotations = map[string]string{"fipreservation": "reserved"}
+
+	pendingFip := newTestFip("ns1", "cluster3", "range1", "192.168.10.20")
+	pendingFip.ObjectMeta.Annotations = map[string]string{"fipreservation": "reserved"}
+	setTestPendingFipReservation(t, "reserved", &pendingFip)
+
+	tests := []struct {
+		name    string
+		fip     KubefipV2.FloatingIP
+		wantErr string
+	}{
+		{"free address", newTestFip("ns1", "cluster2", "range1", "192.168.10.11"), ""},
+		{"fip without ip address", newTestFip("ns1", "cluster2", "range1", ""), ""},
+		{"address of another fip", newTestFip("ns2", "cluster1", "range1", "192.168.10.10"), "is already taken by fip [ns1/cluster1]"},
+		{"update of the fip itself", newTestFip("ns1", "cluster1", "range1", "192.168.10.10"), ""},
+		{"address of an active reservation", newTestFip("ns1", "cluster2", "range1", "192.168.10.20"), "is reserved by fipreservation [reserved]"},
+		{"fip with only the fipreservation annotation", reservedFip, "is reserved by fipreservation [reserved]"},
+		{"fip which the operator creates for the reservation", pendingFip, ""},
+		{"address of an expired reservation", newTestFip("ns1", "cluster2", "range1", "192.168.10.30"), ""},
+		{"address of a bound reservation", newTestFip("ns1", "cluster2", "range1", "192.168.10.40"), ""},
+	}
+
+	for _, tt := range tests {
+		checkError(t, tt.name, ValidateFipAddressAvailable(&tt.fip), tt.wantErr)
+	}
+}
+
+func TestValidateFipReservation(t *testing.T) {
+	bound := newTestFipReservation("bound", "range1", "192.168.10.20")
+	bound.Status.BoundTo = "ns1/cluster2"
+
+	boundFip := newTestFip("ns1", "cluster2", "range1", "192.168.10.20")
+	boundFip.ObjectMeta.Annotations = map[string]string{"fipreservation": "bound"}
+
+	expired := newTestFipReservation("expired", "range1", "192.168.10.40")
+	expired.Spec.Expires = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+
+	// a fip which claims the reservation with only the annotation
+	claimed := newTestFipReservation("claimed", "range1", "192.168.10.10")
+	claimedFip := newTestFip("ns1", "cluster1", "range1", "192.168.10.10")
+	claimedFip.ObjectMeta.Annotations = map[string]string{"fipreservation": "claimed"}
+
+	setTestObjects(t, []KubefipV2.FloatingIPRange{
+		newTestFipRange("range1", "192.168.10.0/24"),
+	}, []KubefipV2.FloatingIP{
+		claimedFip,
+		boundFip,
+	}, []KubefipV1.FloatingIPReservation{
+		bound,
+		newTestFipReservation("reserved", "range1", "192.168.10.30"),
+		expired,
+	})
+
+	tests := []struct {
+		name           string
+		fipReservation KubefipV1.FloatingIPReservation
+		wantErr        string
+	}{
+		{"free address", newTestFipReservation("new", "range1", "192.168.10.11"), ""},
+		{"unknown fiprange", newTestFipReservation("new", "range2", "192.168.10.11"), "fiprange [range2] of fipreservation [new] does not exist"},
+		{"broadcast address", newTestFipReservation("new", "range1", "192.168.10.255"), "is the broadcast address"},
+		{"address outside the range", newTestFipReservation("new", "range1", "192.168.11.11"), "is not a part of iprange"},
+		{"address of a fip", newTestFipReservation("new", "range1", "192.168.10.10"), "is already taken by fip [ns1/cluster1]"},
+		{"address of the bound fip", bound, ""},
+		{"address of a fip with only the annotation", claimed, "is already taken by fip [ns1/cluster1]"},
+		{"address of an active reservation", newTestFipReservation("new", "range1", "192.168.10.30"), "is reserved by fipreservation [reserved]"},
+		{"update of the reservation itself", newTestFipReservation("reserved", "range1", "192.168.10.30"), ""},
+		{"address of an expired reservation", newTestFipReservation("new", "range1", "192.168.10.40"), ""},
+	}
+
+	for _, tt := range tests {
+		checkError(t, tt.name, ValidateFipReservation(&tt.fipReservation), tt.wantErr)
+	}
+}
@@ -0,0 +1,106 @@
+package lbpool
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+)
+
+const (
+	// the name of the pool objects in the guest cluster
+	PoolName = "kube-fip"
+
+	// the pool objects are labeled, so the operator doesn't remove a pool with the same name which isn't created by it
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	ManagedBy      = "kube-fip-operator"
+)
+
+var (
+	MetallbIPAddressPoolResource = schema.GroupVersionResource{
+		Group:    "metallb.io",
+		Version:  "v1beta1",
+		Resource: "ipaddresspools",
+	}
+
+	MetallbL2AdvertisementResource = schema.GroupVersionResource{
+		Group:    "metallb.io",
+		Version:  "v1beta1",
+		Resource: "l2advertisements",
+	}
+
+	CiliumLoadBalancerIPPoolResource = schema.GroupVersionResource{
+		Group:    "cilium.io",
+		Version:  "v2alpha1",
+		Resource: "ciliumloadbalancerippools",
+	}
+)
+
+func newPoolObject(apiVersion string, kind string, namespace string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"spec":       spec,
+		},
+	}
+	obj.SetName(PoolName)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(map[string]string{LabelManagedBy: ManagedBy})
+
+	return obj
+}
+
+// NewMetallbIPAddressPool returns the MetalLB pool with the fip, it's assigned to the loadbalancer services like the
+// cidr-global of the kubevip configmap
+func NewMetallbIPAddressPool(fip *KubefipV2.FloatingIP, namespace string) *unstructured.Unstructured {
+	log.Debugf("(NewMetallbIPAddressPool) generating new metallb ipaddresspool")
+
+	pool := newPoolObject("metallb.io/v1beta1", "IPAddressPool", namespace, map[string]interface{}{
+		"addresses":  []interface{}{fmt.Sprintf("%s/32", fip.Spec.IPAddress)},
+		"autoAssign": true,
+	})
+
+	log.Tracef("(NewMetallbIPAddressPool) generated ipaddresspool [%+v]", pool)
+
+	return pool
+}
+
+// NewMetallbL2Advertisement returns the MetalLB L2 advertisement of the fip pool, it's limited to the vip interface of
+// the fip when it's detected
+func NewMetallbL2Advertisement(fip *KubefipV2.FloatingIP, namespace string) *unstructured.Unstructured {
+	log.Debugf("(NewMetallbL2Advertisement) generating new metallb l2advertisement")
+
+	spec := map[string]interface{}{
+		"ipAddressPools": []interface{}{PoolName},
+	}
+
+	if vipInterface := fip.ObjectMeta.Annotations["vipInterface"]; vipInterface != "" {
+		spec["interfaces"] = []interface{}{vipInterface}
+	}
+
+	advertisement := newPoolObject("metallb.io/v1beta1", "L2Advertisement", namespace, spec)
+
+	log.Tracef("(NewMetallbL2Advertisement) generated l2advertisement [%+v]", advertisement)
+
+	return advertisement
+}
+
+// NewCiliumLoadBalancerIPPool returns the cluster scoped Cilium LB-IPAM pool with the fip
+func NewCiliumLoadBalancerIPPool(fip *KubefipV2.FloatingIP) *unstructured.Unstructured {
+	log.Debugf("(NewCiliumLoadBalancerIPPool) generating new cilium loadbalancer ippool")
+
+	pool := newPoolObject("cilium.io/v2alpha1", "CiliumLoadBalancerIPPool", "", map[string]interface{}{
+		"blocks": []interface{}{
+			map[string]interface{}{"cidr": fmt.Sprintf("%s/32", fip.Spec.IPAddress)},
+		},
+	})
+
+	log.Tracef("(NewCiliumLoadBalancerIPPool) generated ciliumloadbalancerippool [%+v]", pool)
+
+	return pool
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 
@@ -17,6 +19,10 @@ type appMetricsStruct struct {
 	kubefipoperatorFiprangesReserved  *prometheus.GaugeVec
 	kubefipoperatorGuestclusterStatus *prometheus.GaugeVec
 	kubefipoperatorGuestclusterEvents *prometheus.CounterVec
+	kubefipoperatorOrphanedFips       *prometheus.GaugeVec
+	kubefipoperatorCycleDuration      prometheus.Gauge
+	kubefipoperatorHelmDrift          *prometheus.GaugeVec
+	kubefipoperatorKubevipRollout     *prometheus.GaugeVec
 }
 
 type clusterMetricLabels struct {
@@ -30,18 +36,25 @@ var (
 	LabelFipRangeName = "fiprangename"
 	LabelFipRange     = "fiprange"
 
-	LabelFipName = "fipname"
-	LabelFip     = "fip"
+	LabelFipName      = "fipname"
+	LabelFip          = "fip"
+	LabelFipNamespace = "fipnamespace"
 
 	LabelGuestClusterName     = "guestclustername"
 	LabelHarvesterClusterName = "harvesterclustername"
 	LabelHarvesterNetworkName = "harvesternetworkname"
 	LabelEvent                = "event"
 	LabelStatus               = "status"
+	LabelRelease              = "release"
+	LabelDrift                = "drift"
+	LabelRevision             = "revision"
+	LabelPhase                = "phase"
 
-	InOperationMode bool = false
+	// the cleanup is skipped while the guest clusters are operated
+	inOperationMode atomic.Bool
 
-	metricsCleanupQueue []clusterMetricLabels
+	metricsCleanupQueue      []clusterMetricLabels
+	metricsCleanupQueueMutex sync.Mutex
 )
 
 const (
@@ -50,16 +63,29 @@ const (
 	KubevipInstallError              = 3
 	KubevipCloudproviderInstallError = 4
 
-	EventApiConnection               = "api_connection"
-	EventConfigmapManagement         = "configmap_management"
-	EventKubevipInstall              = "kubevip_install"
-	EventKubevipCloudproviderInstall = "kubevipcloudprovider_install"
+	EventApiConnection                 = "api_connection"
+	EventConfigmapManagement           = "configmap_management"
+	EventKubevipInstall                = "kubevip_install"
+	EventKubevipCloudproviderInstall   = "kubevipcloudprovider_install"
+	EventOperateTimeout                = "operate_timeout"
+	EventKubevipUninstall              = "kubevip_uninstall"
+	EventKubevipCloudproviderUninstall = "kubevipcloudprovider_uninstall"
+	EventConfigmapRemoval              = "configmap_removal"
+	EventHarvesterDaemonsetRestore     = "harvester_daemonset_restore"
+	EventMetallbInstall                = "metallb_install"
+	EventMetallbUninstall              = "metallb_uninstall"
+	EventPoolManagement                = "pool_management"
+	EventPoolRemoval                   = "pool_removal"
+	EventLoadbalancerVerify            = "loadbalancer_verify"
 
 	StatusSuccess = "success"
 	StatusError   = "error"
 
 	StatusUp   = 1
 	StatusDown = 0
+
+	DriftVersion = "version"
+	DriftValues  = "values"
 )
 
 func NewMetrics(reg prometheus.Registerer) *appMetricsStruct {
@@ -110,12 +136,56 @@ func NewMetrics(reg prometheus.Registerer) *appMetricsStruct {
 				LabelStatus,
 			},
 		),
+		kubefipoperatorOrphanedFips: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "kubefipoperator_orphaned_fips",
+				Help: "Fips of deleted clusters which are waiting for the cleanup, the value is the unix time since the fip is orphaned",
+			},
+			[]string{
+				LabelFipNamespace,
+				LabelFipName,
+				LabelGuestClusterName,
+				LabelFip,
+			},
+		),
+		kubefipoperatorCycleDuration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kubefipoperator_guestcluster_cycle_duration_seconds",
+				Help: "Duration of the last run of the guest cluster operations over all clusters",
+			},
+		),
+		kubefipoperatorHelmDrift: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "kubefipoperator_guestcluster_helm_drift",
+				Help: "Keeps track if the deployed helm releases in the Guest Clusters differ 1(drift) or not 0(in sync) from the desired chart version or values",
+			},
+			[]string{
+				LabelGuestClusterName,
+				LabelHarvesterClusterName,
+				LabelRelease,
+				LabelDrift,
+			},
+		),
+		kubefipoperatorKubevipRollout: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "kubefipoperator_kubevip_rollout_wave",
+				Help: "The current wave of the kube-vip rollout, the phase label is progressing, halted or completed",
+			},
+			[]string{
+				LabelRevision,
+				LabelPhase,
+			},
+		),
 	}
 
 	reg.MustRegister(m.kubefipoperatorFiprangesCapacity)
 	reg.MustRegister(m.kubefipoperatorFiprangesReserved)
 	reg.MustRegister(m.kubefipoperatorGuestclusterStatus)
 	reg.MustRegister(m.kubefipoperatorGuestclusterEvents)
+	reg.MustRegister(m.kubefipoperatorOrphanedFips)
+	reg.MustRegister(m.kubefipoperatorCycleDuration)
+	reg.MustRegister(m.kubefipoperatorHelmDrift)
+	reg.MustRegister(m.kubefipoperatorKubevipRollout)
 
 	return m
 }
@@ -206,6 +276,47 @@ func IncrementGuestClusterEventsMetric(guestClusterName string, harvesterCluster
 	}).Inc()
 }
 
+func SetGuestClusterCycleDuration(seconds float64) {
+	log.Debugf("(SetGuestClusterCycleDuration) changing cycle duration metric: seconds=%f", seconds)
+
+	AppMetrics.kubefipoperatorCycleDuration.Set(seconds)
+}
+
+func SetGuestClusterHelmDrift(guestClusterName string, harvesterClusterName string, release string, versionDrift bool, valuesDrift bool) {
+	log.Debugf("(SetGuestClusterHelmDrift) changing helm drift metric: guestClusterName=%s, harvesterClusterName=%s, release=%s, versionDrift=%t, valuesDrift=%t",
+		guestClusterName, harvesterClusterName, release, versionDrift, valuesDrift)
+
+	for drift, value := range map[string]bool{DriftVersion: versionDrift, DriftValues: valuesDrift} {
+		var v float64
+		if value {
+			v = 1
+		}
+
+		AppMetrics.kubefipoperatorHelmDrift.With(prometheus.Labels{
+			LabelGuestClusterName:     guestClusterName,
+			LabelHarvesterClusterName: harvesterClusterName,
+			LabelRelease:              release,
+			LabelDrift:                drift,
+		}).Set(v)
+	}
+}
+
+func SetKubevipRollout(revision string, phase string, wave int) {
+	log.Debugf("(SetKubevipRollout) changing kube-vip rollout metric: revision=%s, phase=%s, wave=%d", revision, phase, wave)
+
+	// only the current revision and phase are exposed
+	AppMetrics.kubefipoperatorKubevipRollout.Reset()
+
+	if revision == "" {
+		return
+	}
+
+	AppMetrics.kubefipoperatorKubevipRollout.With(prometheus.Labels{
+		LabelRevision: revision,
+		LabelPhase:    phase,
+	}).Set(float64(wave))
+}
+
 func RemoveGuestClusterEventsFromMetrics(guestClusterName string, harvesterClusterName string) {
 	log.Debugf("(RemoveGuestClusterEventsFromMetrics) removing metrics: guestClusterName=%s, harvesterClusterName=%s",
 		guestClusterName, harvesterClusterName)
@@ -270,6 +381,61 @@ func RemoveGuestClusterEventsFromMetrics(guestClusterName string, harvesterClust
 		LabelEvent:                EventKubevipCloudproviderInstall,
 		LabelStatus:               StatusSuccess,
 	})
+
+	AppMetrics.kubefipoperatorGuestclusterEvents.Delete(prometheus.Labels{
+		LabelGuestClusterName:     guestClusterName,
+		LabelHarvesterClusterName: harvesterClusterName,
+		LabelEvent:                EventOperateTimeout,
+		LabelStatus:               StatusError,
+	})
+
+	for _, event := range []string{EventKubevipUninstall, EventKubevipCloudproviderUninstall, EventConfigmapRemoval, EventHarvesterDaemonsetRestore,
+		EventMetallbInstall, EventMetallbUninstall, EventPoolManagement, EventPoolRemoval, EventLoadbalancerVerify} {
+		for _, status := range []string{StatusSuccess, StatusError} {
+			AppMetrics.kubefipoperatorGuestclusterEvents.Delete(prometheus.Labels{
+				LabelGuestClusterName:     guestClusterName,
+				LabelHarvesterClusterName: harvesterClusterName,
+				LabelEvent:                event,
+				LabelStatus:               status,
+			})
+		}
+	}
+
+	RemoveGuestClusterHelmDrift(guestClusterName, harvesterClusterName)
+}
+
+func RemoveGuestClusterHelmDrift(guestClusterName string, harvesterClusterName string) {
+	log.Debugf("(RemoveGuestClusterHelmDrift) removing helm drift metrics: guestClusterName=%s, harvesterClusterName=%s",
+		guestClusterName, harvesterClusterName)
+
+	AppMetrics.kubefipoperatorHelmDrift.DeletePartialMatch(prometheus.Labels{
+		LabelGuestClusterName:     guestClusterName,
+		LabelHarvesterClusterName: harvesterClusterName,
+	})
+}
+
+func SetOrphanedFip(fipNamespace string, fipName string, guestClusterName string, fip string, orphanedSince float64) {
+	log.Debugf("(SetOrphanedFip) changing orphaned fip metric: fipNamespace=%s, fipName=%s, guestClusterName=%s, fip=%s",
+		fipNamespace, fipName, guestClusterName, fip)
+
+	AppMetrics.kubefipoperatorOrphanedFips.With(prometheus.Labels{
+		LabelFipNamespace:     fipNamespace,
+		LabelFipName:          fipName,
+		LabelGuestClusterName: guestClusterName,
+		LabelFip:              fip,
+	}).Set(orphanedSince)
+}
+
+func RemoveOrphanedFip(fipNamespace string, fipName string, guestClusterName string, fip string) {
+	log.Debugf("(RemoveOrphanedFip) removing orphaned fip metric: fipNamespace=%s, fipName=%s, guestClusterName=%s, fip=%s",
+		fipNamespace, fipName, guestClusterName, fip)
+
+	AppMetrics.kubefipoperatorOrphanedFips.Delete(prometheus.Labels{
+		LabelFipNamespace:     fipNamespace,
+		LabelFipName:          fipName,
+		LabelGuestClusterName: guestClusterName,
+		LabelFip:              fip,
+	})
 }
 
 func AddClusterToMetricsCleanupQueue(guestClusterName string, harvesterClusterName string) {
@@ -280,23 +446,30 @@ func AddClusterToMetricsCleanupQueue(guestClusterName string, harvesterClusterNa
 	m.guestClusterName = guestClusterName
 	m.harvesterClustername = harvesterClusterName
 
+	metricsCleanupQueueMutex.Lock()
 	metricsCleanupQueue = append(metricsCleanupQueue, m)
+	metricsCleanupQueueMutex.Unlock()
+}
+
+// SetInOperationMode marks the start and the end of the guest cluster operations
+func SetInOperationMode(operating bool) {
+	inOperationMode.Store(operating)
 }
 
 func CleanupMetrics() {
 	log.Debugf("(CleanupMetrics) starting cleanup of removed metrics")
 
-	if InOperationMode {
+	if inOperationMode.Load() {
 		log.Debugf("(CleanupMetrics) operator mode is running, skipping cleanup session")
 
 		return
 	}
 
-	// copy the queue to the in progress queue
+	// copy the queue to the in progress queue and reset the metricsCleanupQueue queue
+	metricsCleanupQueueMutex.Lock()
 	metricsCleanupQueueInProgress := metricsCleanupQueue
-
-	// reset the metricsCleanupQueue queue
 	metricsCleanupQueue = nil
+	metricsCleanupQueueMutex.Unlock()
 
 	for i := 0; i < len(metricsCleanupQueueInProgress); i++ {
 		RemoveGuestClusterEventsFromMetrics(metricsCleanupQueueInProgress[i].guestClusterName, metricsCleanupQueueInProgress[i].harvesterClustername)
@@ -0,0 +1,276 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// the number of free addresses which are returned when the limit parameter is not set
+const defaultFreeIPAddressesLimit = 256
+
+type fipRangeInfo struct {
+	Name                 string `json:"name"`
+	IPRange              string `json:"ipRange"`
+	HarvesterClusterName string `json:"harvesterClusterName,omitempty"`
+	HarvesterNetworkName string `json:"harvesterNetworkName,omitempty"`
+	Priority             int    `json:"priority"`
+	Weight               int    `json:"weight"`
+	kubefip.FipRangeUsage
+}
+
+type freeIPAddresses struct {
+	FipRange    string   `json:"fipRange"`
+	Free        int64    `json:"free"`
+	IPAddresses []string `json:"ipAddresses"`
+}
+
+type fipInfo struct {
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	ClusterName   string `json:"clusterName"`
+	ClusterSource string `json:"clusterSource,omitempty"`
+	FipRange      string `json:"fipRange"`
+	IPAddress     string `json:"ipAddress"`
+	OrphanedSince string `json:"orphanedSince,omitempty"`
+}
+
+type fipReservationInfo struct {
+	Name        string `json:"name"`
+	FipRange    string `json:"fipRange"`
+	IPAddress   string `json:"ipAddress"`
+	ClusterName string `json:"clusterName,omitempty"`
+	BoundTo     string `json:"boundTo,omitempty"`
+	Expires     string `json:"expires,omitempty"`
+	Active      bool   `json:"active"`
+}
+
+type ipAddressInfo struct {
+	IPAddress       string               `json:"ipAddress"`
+	FipRanges       []string             `json:"fipRanges"`
+	Fips            []fipInfo            `json:"fips"`
+	FipReservations []fipReservationInfo `json:"fipReservations"`
+}
+
+type clusterInfo struct {
+	ClusterName string             `json:"clusterName"`
+	Fips        []fipInfo          `json:"fips"`
+	Results     []GuestCycleResult `json:"results"`
+}
+
+// StartStatusServer serves the ipam inspection endpoints on their own listener, the endpoints are unauthenticated so
+// the address should be a localhost address unless the network access to the pod is restricted
+func StartStatusServer(statusAddress string) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+
+	log.Infof("(StartStatusServer) start the ipam inspection api on [%s]", statusAddress)
+
+	if err := http.ListenAndServe(statusAddress, mux); err != nil {
+		log.Errorf("(StartStatusServer) error serving the ipam inspection api on [%s]: %s", statusAddress, err.Error())
+	}
+}
+
+// RegisterHandlers adds the ipam inspection endpoints to the mux
+func RegisterHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/fipranges", serveFipRanges)
+	mux.HandleFunc("GET /api/v1/fipranges/{name}", serveFipRange)
+	mux.HandleFunc("GET /api/v1/fipranges/{name}/free", serveFreeIPAddresses)
+	mux.HandleFunc("GET /api/v1/ips/{ip}", serveIPAddress)
+	mux.HandleFunc("GET /api/v1/clusters", serveClusters)
+	mux.HandleFunc("GET /api/v1/clusters/{name}", serveCluster)
+	mux.HandleFunc("GET /api/v1/rollout", serveRollout)
+}
+
+func newFipRangeInfo(fipRange *KubefipV2.FloatingIPRange) fipRangeInfo {
+	return fipRangeInfo{
+		Name:                 fipRange.ObjectMeta.Name,
+		IPRange:              fipRange.Spec.IPRange,
+		HarvesterClusterName: fipRange.Spec.HarvesterClusterName,
+		HarvesterNetworkName: fipRange.Spec.HarvesterNetworkName,
+		Priority:             fipRange.Spec.Priority,
+		Weight:               fipRange.Spec.Weight,
+		FipRangeUsage:        kubefip.GetFipRangeUsage(fipRange),
+	}
+}
+
+func newFipInfo(fip *KubefipV2.FloatingIP) fipInfo {
+	return fipInfo{
+		Name:          fip.ObjectMeta.Name,
+		Namespace:     fip.ObjectMeta.Namespace,
+		ClusterName:   fip.Spec.ClusterName,
+		ClusterSource: fip.ObjectMeta.Annotations["clustersource"],
+		FipRange:      fip.Spec.FipRange,
+		IPAddress:     fip.Spec.IPAddress,
+		OrphanedSince: fip.ObjectMeta.Annotations["orphanedSince"],
+	}
+}
+
+func newFipReservationInfo(fipReservation *KubefipV1.FloatingIPReservation) fipReservationInfo {
+	info := fipReservationInfo{
+		Name:        fipReservation.ObjectMeta.Name,
+		FipRange:    fipReservation.Spec.FipRange,
+		IPAddress:   fipReservation.Spec.IPAddress,
+		ClusterName: fipReservation.Spec.ClusterName,
+		BoundTo:     fipReservation.Status.BoundTo,
+		Active:      kubefip.IsFipReservationActive(fipReservation),
+	}
+	if fipReservation.Spec.Expires != nil {
+		info.Expires = fipReservation.Spec.Expires.UTC().Format("2006-01-02T15:04:05Z")
+	}
+
+	return info
+}
+
+func serveFipRanges(w http.ResponseWriter, r *http.Request) {
+	fipRanges := kubefip.GetAllFipRanges()
+
+	infos := []fipRangeInfo{}
+	for i := range fipRanges {
+		infos = append(infos, newFipRangeInfo(&fipRanges[i]))
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func serveFipRange(w http.ResponseWriter, r *http.Request) {
+	fipRange, err := kubefip.GetFipRange(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("fiprange [%s] not found", r.PathValue("name")))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newFipRangeInfo(&fipRange))
+}
+
+func serveFreeIPAddresses(w http.ResponseWriter, r *http.Request) {
+	fipRange, err := kubefip.GetFipRange(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("fiprange [%s] not found", r.PathValue("name")))
+
+		return
+	}
+
+	limit := defaultFreeIPAddressesLimit
+	if r.URL.Query().Get("limit") != "" {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit [%s] is not a positive number", r.URL.Query().Get("limit")))
+
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, freeIPAddresses{
+		FipRange:    fipRange.ObjectMeta.Name,
+		Free:        kubefip.GetFipRangeUsage(&fipRange).Free,
+		IPAddresses: kubefip.GetFreeIPAddresses(&fipRange, limit),
+	})
+}
+
+func serveIPAddress(w http.ResponseWriter, r *http.Request) {
+	addr, err := netip.ParseAddr(r.PathValue("ip"))
+	if err != nil || !addr.Is4() {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("[%s] is not a valid ipv4 address", r.PathValue("ip")))
+
+		return
+	}
+
+	info := ipAddressInfo{
+		IPAddress:       addr.String(),
+		FipRanges:       []string{},
+		Fips:            []fipInfo{},
+		FipReservations: []fipReservationInfo{},
+	}
+
+	fipRanges := kubefip.GetAllFipRanges()
+	for i := range fipRanges {
+		if prefix, err := netip.ParsePrefix(fipRanges[i].Spec.IPRange); err == nil && prefix.Masked().Contains(addr) {
+			info.FipRanges = append(info.FipRanges, fipRanges[i].ObjectMeta.Name)
+		}
+	}
+
+	fips := kubefip.GetAllFips()
+	for i := range fips {
+		if fips[i].Spec.IPAddress == info.IPAddress {
+			info.Fips = append(info.Fips, newFipInfo(&fips[i]))
+		}
+	}
+
+	fipReservations := kubefip.GetAllFipReservations()
+	for i := range fipReservations {
+		if fipReservations[i].Spec.IPAddress == info.IPAddress {
+			info.FipReservations = append(info.FipReservations, newFipReservationInfo(&fipReservations[i]))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func serveClusters(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, GetGuestCycleResults())
+}
+
+func serveCluster(w http.ResponseWriter, r *http.Request) {
+	info := clusterInfo{
+		ClusterName: r.PathValue("name"),
+		Fips:        []fipInfo{},
+		Results:     []GuestCycleResult{},
+	}
+
+	// the same cluster name can be used in more than one namespace, the namespace parameter selects one of them
+	namespace := r.URL.Query().Get("namespace")
+
+	fips := kubefip.GetAllFips()
+	for i := range fips {
+		if fips[i].Spec.ClusterName == info.ClusterName && (namespace == "" || fips[i].ObjectMeta.Namespace == namespace) {
+			info.Fips = append(info.Fips, newFipInfo(&fips[i]))
+		}
+	}
+
+	for _, result := range GetGuestCycleResults() {
+		if result.ClusterName == info.ClusterName && (namespace == "" || result.Namespace == namespace) {
+			info.Results = append(info.Results, result)
+		}
+	}
+
+	if len(info.Fips) == 0 && len(info.Results) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("cluster [%s] not found", info.ClusterName))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func serveRollout(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, GetRolloutStatus())
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot encode the response: %s", err.Error()), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("(writeJSON) error while writing the response: %s", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
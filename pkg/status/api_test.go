@@ -0,0 +1,189 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setTestObjects adds the fipranges and fips to the kubefip package lists for the duration of the test
+func setTestObjects(t *testing.T, fipRanges []KubefipV2.FloatingIPRange, fips []KubefipV2.FloatingIP) {
+	t.Helper()
+
+	for i := range fipRanges {
+		if err := kubefip.UpdateAllFipRanges(&fipRanges[i]); err != nil {
+			t.Fatalf("error adding fiprange: %s", err.Error())
+		}
+	}
+	for i := range fips {
+		if err := kubefip.UpdateAllFips(&fips[i]); err != nil {
+			t.Fatalf("error adding fip: %s", err.Error())
+		}
+	}
+
+	t.Cleanup(func() {
+		for i := range fipRanges {
+			_ = kubefip.RemoveFipRangeFromAllFipRanges(&fipRanges[i])
+		}
+		for i := range fips {
+			_ = kubefip.RemoveFipFromAllFips(&fips[i])
+		}
+	})
+}
+
+func newTestFip(namespace string, clusterName string, ipAddress string) KubefipV2.FloatingIP {
+	return KubefipV2.FloatingIP{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: clusterName + "-kubevip"},
+		Spec:       KubefipV2.FloatingIPSpec{ClusterName: clusterName, FipRange: "range1", IPAddress: ipAddress},
+	}
+}
+
+// get requests the path and decodes the json response into v, it returns the status code
+func get(t *testing.T, url string, v interface{}) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("error requesting [%s]: %s", url, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected content type [application/json] for [%s], got [%s]", url, resp.Header.Get("Content-Type"))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("error decoding the response of [%s]: %s", url, err.Error())
+	}
+
+	return resp.StatusCode
+}
+
+func TestServeIpamApi(t *testing.T) {
+	setTestObjects(t, []KubefipV2.FloatingIPRange{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "range1"},
+			Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: "192.168.10.0/29", HarvesterClusterName: "harvester1", Priority: 5},
+		},
+	}, []KubefipV2.FloatingIP{
+		newTestFip("ns1", "cluster1", "192.168.10.1"),
+		newTestFip("ns2", "cluster1", "192.168.10.2"),
+	})
+
+	SetGuestCycleResult(GuestCycleResult{ClusterName: "cluster1", Namespace: "ns1", Status: ClusterStatusUp})
+	t.Cleanup(func() { RemoveGuestCycleResult("ns1", "cluster1") })
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var fipRanges []fipRangeInfo
+	if code := get(t, server.URL+"/api/v1/fipranges", &fipRanges); code != http.StatusOK {
+		t.Errorf("fipranges: expected status code [%d], got [%d]", http.StatusOK, code)
+	}
+	wantFipRange := fipRangeInfo{Name: "range1", IPRange: "192.168.10.0/29", HarvesterClusterName: "harvester1", Priority: 5,
+		FipRangeUsage: kubefip.FipRangeUsage{Capacity: 6, Used: 2, Free: 4}}
+	if !reflect.DeepEqual(fipRanges, []fipRangeInfo{wantFipRange}) {
+		t.Errorf("fipranges: expected [%+v], got [%+v]", []fipRangeInfo{wantFipRange}, fipRanges)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     interface{}
+	}{
+		{
+			name:     "fiprange",
+			path:     "/api/v1/fipranges/range1",
+			wantCode: http.StatusOK,
+			want:     &wantFipRange,
+		},
+		{
+			name:     "unknown fiprange",
+			path:     "/api/v1/fipranges/range2",
+			wantCode: http.StatusNotFound,
+			want:     &map[string]string{"error": "fiprange [range2] not found"},
+		},
+		{
+			name:     "free addresses",
+			path:     "/api/v1/fipranges/range1/free",
+			wantCode: http.StatusOK,
+			want:     &freeIPAddresses{FipRange: "range1", Free: 4, IPAddresses: []string{"192.168.10.3", "192.168.10.4", "192.168.10.5", "192.168.10.6"}},
+		},
+		{
+			name:     "free addresses with a limit",
+			path:     "/api/v1/fipranges/range1/free?limit=1",
+			wantCode: http.StatusOK,
+			want:     &freeIPAddresses{FipRange: "range1", Free: 4, IPAddresses: []string{"192.168.10.3"}},
+		},
+		{
+			name:     "free addresses with an invalid limit",
+			path:     "/api/v1/fipranges/range1/free?limit=-1",
+			wantCode: http.StatusBadRequest,
+			want:     &map[string]string{"error": "limit [-1] is not a positive number"},
+		},
+		{
+			name:     "ip address of a fip",
+			path:     "/api/v1/ips/192.168.10.2",
+			wantCode: http.StatusOK,
+			want: &ipAddressInfo{IPAddress: "192.168.10.2", FipRanges: []string{"range1"},
+				Fips:            []fipInfo{{Name: "cluster1-kubevip", Namespace: "ns2", ClusterName: "cluster1", FipRange: "range1", IPAddress: "192.168.10.2"}},
+				FipReservations: []fipReservationInfo{}},
+		},
+		{
+			name:     "ip address outside the fipranges",
+			path:     "/api/v1/ips/10.0.0.1",
+			wantCode: http.StatusOK,
+			want:     &ipAddressInfo{IPAddress: "10.0.0.1", FipRanges: []string{}, Fips: []fipInfo{}, FipReservations: []fipReservationInfo{}},
+		},
+		{
+			name:     "ipv6 address",
+			path:     "/api/v1/ips/fd00::1",
+			wantCode: http.StatusBadRequest,
+			want:     &map[string]string{"error": "[fd00::1] is not a valid ipv4 address"},
+		},
+		{
+			name:     "cluster in a namespace",
+			path:     "/api/v1/clusters/cluster1?namespace=ns2",
+			wantCode: http.StatusOK,
+			want: &clusterInfo{ClusterName: "cluster1",
+				Fips:    []fipInfo{{Name: "cluster1-kubevip", Namespace: "ns2", ClusterName: "cluster1", FipRange: "range1", IPAddress: "192.168.10.2"}},
+				Results: []GuestCycleResult{}},
+		},
+		{
+			name:     "unknown cluster",
+			path:     "/api/v1/clusters/cluster2",
+			wantCode: http.StatusNotFound,
+			want:     &map[string]string{"error": "cluster [cluster2] not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := reflect.New(reflect.TypeOf(tt.want).Elem()).Interface()
+		if code := get(t, server.URL+tt.path, got); code != tt.wantCode {
+			t.Errorf("%s: expected status code [%d], got [%d]", tt.name, tt.wantCode, code)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected [%+v], got [%+v]", tt.name, tt.want, got)
+		}
+	}
+
+	// the cycle result time is set when the result is stored, so the results are checked without the response body
+	var cluster clusterInfo
+	if code := get(t, server.URL+"/api/v1/clusters/cluster1", &cluster); code != http.StatusOK {
+		t.Errorf("cluster: expected status code [%d], got [%d]", http.StatusOK, code)
+	}
+	if len(cluster.Fips) != 2 || len(cluster.Results) != 1 || cluster.Results[0].Status != ClusterStatusUp {
+		t.Errorf("cluster: expected 2 fips and the cycle result, got [%+v]", cluster)
+	}
+}
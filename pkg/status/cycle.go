@@ -0,0 +1,112 @@
+// Package status keeps the results of the guest cluster cycles and serves the read-only ipam inspection api on the
+// metrics port.
+package status
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ClusterStatusUp        = "up"
+	ClusterStatusDown      = "down"
+	ClusterStatusDeploying = "deploying"
+	ClusterStatusSkipped   = "skipped"
+	ClusterStatusNotFound  = "notfound"
+	ClusterStatusTimeout   = "timeout"
+)
+
+// GuestCycleEvent is the result of one of the guest cluster operations, the events are the same as the event label
+// of the guestcluster events metric
+type GuestCycleEvent struct {
+	Event  string `json:"event"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HelmReleaseDrift is the difference between a deployed helm release and the desired chart, version and values. The
+// values diff only contains the paths of the values, so no secrets end up in the api.
+type HelmReleaseDrift struct {
+	Release       string   `json:"release"`
+	DeployedChart string   `json:"deployedChart"`
+	DesiredChart  string   `json:"desiredChart"`
+	ValuesDiff    []string `json:"valuesDiff,omitempty"`
+	Upgraded      bool     `json:"upgraded"`
+}
+
+// GuestCycleResult is the result of the last guest cycle of a cluster
+type GuestCycleResult struct {
+	ClusterName          string             `json:"clusterName"`
+	Namespace            string             `json:"namespace"`
+	HarvesterClusterName string             `json:"harvesterClusterName,omitempty"`
+	Fip                  string             `json:"fip"`
+	IPAddress            string             `json:"ipAddress"`
+	Status               string             `json:"status"`
+	Message              string             `json:"message,omitempty"`
+	Events               []GuestCycleEvent  `json:"events,omitempty"`
+	LBBackend            string             `json:"lbBackend,omitempty"`
+	KubevipProfile       string             `json:"kubevipProfile,omitempty"`
+	HelmDrift            []HelmReleaseDrift `json:"helmDrift,omitempty"`
+	Time                 time.Time          `json:"time"`
+}
+
+var (
+	guestCycleResults      = make(map[string]GuestCycleResult)
+	guestCycleResultsMutex sync.RWMutex
+)
+
+func guestCycleResultKey(namespace string, clusterName string) string {
+	return fmt.Sprintf("%s/%s", namespace, clusterName)
+}
+
+// AddEvent adds the result of a guest cluster operation, err is nil when the operation succeeded
+func (r *GuestCycleResult) AddEvent(event string, status string, err error) {
+	e := GuestCycleEvent{
+		Event:  event,
+		Status: status,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	r.Events = append(r.Events, e)
+}
+
+// SetGuestCycleResult stores the result of the guest cycle of a cluster, it replaces the result of the previous cycle
+func SetGuestCycleResult(result GuestCycleResult) {
+	if result.Time.IsZero() {
+		result.Time = time.Now()
+	}
+
+	guestCycleResultsMutex.Lock()
+	defer guestCycleResultsMutex.Unlock()
+
+	guestCycleResults[guestCycleResultKey(result.Namespace, result.ClusterName)] = result
+}
+
+// RemoveGuestCycleResult removes the result of a cluster when its fip is removed
+func RemoveGuestCycleResult(namespace string, clusterName string) {
+	guestCycleResultsMutex.Lock()
+	defer guestCycleResultsMutex.Unlock()
+
+	delete(guestCycleResults, guestCycleResultKey(namespace, clusterName))
+}
+
+// GetGuestCycleResults returns the results of all clusters sorted by namespace and cluster name
+func GetGuestCycleResults() []GuestCycleResult {
+	guestCycleResultsMutex.RLock()
+	defer guestCycleResultsMutex.RUnlock()
+
+	results := []GuestCycleResult{}
+	for _, result := range guestCycleResults {
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return guestCycleResultKey(results[i].Namespace, results[i].ClusterName) < guestCycleResultKey(results[j].Namespace, results[j].ClusterName)
+	})
+
+	return results
+}
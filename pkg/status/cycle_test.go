@@ -0,0 +1,52 @@
+package status
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGuestCycleResults(t *testing.T) {
+	t.Cleanup(func() {
+		RemoveGuestCycleResult("ns1", "cluster1")
+		RemoveGuestCycleResult("ns1", "cluster2")
+		RemoveGuestCycleResult("ns2", "cluster1")
+	})
+
+	SetGuestCycleResult(GuestCycleResult{ClusterName: "cluster2", Namespace: "ns1", Status: ClusterStatusDeploying})
+	SetGuestCycleResult(GuestCycleResult{ClusterName: "cluster1", Namespace: "ns2", Status: ClusterStatusUp})
+	SetGuestCycleResult(GuestCycleResult{ClusterName: "cluster1", Namespace: "ns1", Status: ClusterStatusDeploying})
+
+	// the result of the next cycle replaces the previous result
+	result := GuestCycleResult{ClusterName: "cluster1", Namespace: "ns1", Status: ClusterStatusDown}
+	result.AddEvent("kubevip", ClusterStatusUp, nil)
+	result.AddEvent("loadbalancer", ClusterStatusDown, errors.New("connection refused"))
+	SetGuestCycleResult(result)
+
+	var keys []string
+	for _, r := range GetGuestCycleResults() {
+		if r.Time.IsZero() {
+			t.Errorf("expected the time of result [%s/%s] to be set", r.Namespace, r.ClusterName)
+		}
+
+		keys = append(keys, r.Namespace+"/"+r.ClusterName+"="+r.Status)
+	}
+
+	wantKeys := []string{"ns1/cluster1=down", "ns1/cluster2=deploying", "ns2/cluster1=up"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("expected results %v, got %v", wantKeys, keys)
+	}
+
+	wantEvents := []GuestCycleEvent{
+		{Event: "kubevip", Status: ClusterStatusUp},
+		{Event: "loadbalancer", Status: ClusterStatusDown, Error: "connection refused"},
+	}
+	if events := GetGuestCycleResults()[0].Events; !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("expected events [%+v], got [%+v]", wantEvents, events)
+	}
+
+	RemoveGuestCycleResult("ns1", "cluster2")
+	if results := GetGuestCycleResults(); len(results) != 2 {
+		t.Errorf("expected 2 results after the remove, got [%+v]", results)
+	}
+}
@@ -0,0 +1,65 @@
+package status
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	RolloutPhaseDisabled    = "disabled"
+	RolloutPhaseProgressing = "progressing"
+	RolloutPhaseHalted      = "halted"
+	RolloutPhaseCompleted   = "completed"
+)
+
+// RolloutClusterStatus is the state of a guest cluster in the kube-vip rollout
+type RolloutClusterStatus struct {
+	Fip         string   `json:"fip"`
+	ClusterName string   `json:"clusterName"`
+	Wave        string   `json:"wave"`
+	InSync      bool     `json:"inSync"`
+	Healthy     bool     `json:"healthy"`
+	Upgraded    []string `json:"upgraded,omitempty"`
+	RolledBack  bool     `json:"rolledBack,omitempty"`
+	Message     string   `json:"message,omitempty"`
+}
+
+// RolloutWaveStatus is the progress of a wave, the clusters which are down are not counted
+type RolloutWaveStatus struct {
+	Name     string `json:"name"`
+	Clusters int    `json:"clusters"`
+	InSync   int    `json:"inSync"`
+	Healthy  int    `json:"healthy"`
+}
+
+// RolloutStatus is the state of the rollout of the kube-vip and kube-vip-cloud-provider releases
+type RolloutStatus struct {
+	Revision    string                 `json:"revision,omitempty"`
+	Phase       string                 `json:"phase"`
+	CurrentWave string                 `json:"currentWave,omitempty"`
+	WaveStarted *time.Time             `json:"waveStarted,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	Waves       []RolloutWaveStatus    `json:"waves"`
+	Clusters    []RolloutClusterStatus `json:"clusters"`
+}
+
+var (
+	rolloutStatus      = RolloutStatus{Phase: RolloutPhaseDisabled, Waves: []RolloutWaveStatus{}, Clusters: []RolloutClusterStatus{}}
+	rolloutStatusMutex sync.RWMutex
+)
+
+// SetRolloutStatus stores the state of the kube-vip rollout for the ipam inspection api
+func SetRolloutStatus(s RolloutStatus) {
+	rolloutStatusMutex.Lock()
+	defer rolloutStatusMutex.Unlock()
+
+	rolloutStatus = s
+}
+
+// GetRolloutStatus returns the state of the kube-vip rollout
+func GetRolloutStatus() RolloutStatus {
+	rolloutStatusMutex.RLock()
+	defer rolloutStatusMutex.RUnlock()
+
+	return rolloutStatus
+}
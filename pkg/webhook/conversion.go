@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	log "github.com/sirupsen/logrus"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// convertObject converts a FloatingIP or FloatingIPRange between the v1 and v2 api versions
+func convertObject(raw []byte, desiredAPIVersion string) (interface{}, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("cannot decode the object: %s", err.Error())
+	}
+
+	v1 := KubefipV1.SchemeGroupVersion.String()
+	v2 := KubefipV2.SchemeGroupVersion.String()
+
+	switch {
+	case typeMeta.Kind == "FloatingIP" && typeMeta.APIVersion == v1 && desiredAPIVersion == v2:
+		fip := KubefipV1.FloatingIP{}
+		if err := json.Unmarshal(raw, &fip); err != nil {
+			return nil, err
+		}
+
+		return KubefipV2.ConvertFloatingIPFromV1(&fip), nil
+	case typeMeta.Kind == "FloatingIP" && typeMeta.APIVersion == v2 && desiredAPIVersion == v1:
+		fip := KubefipV2.FloatingIP{}
+		if err := json.Unmarshal(raw, &fip); err != nil {
+			return nil, err
+		}
+
+		return KubefipV2.ConvertFloatingIPToV1(&fip), nil
+	case typeMeta.Kind == "FloatingIPRange" && typeMeta.APIVersion == v1 && desiredAPIVersion == v2:
+		fipRange := KubefipV1.FloatingIPRange{}
+		if err := json.Unmarshal(raw, &fipRange); err != nil {
+			return nil, err
+		}
+
+		return KubefipV2.ConvertFloatingIPRangeFromV1(&fipRange), nil
+	case typeMeta.Kind == "FloatingIPRange" && typeMeta.APIVersion == v2 && desiredAPIVersion == v1:
+		fipRange := KubefipV2.FloatingIPRange{}
+		if err := json.Unmarshal(raw, &fipRange); err != nil {
+			return nil, err
+		}
+
+		return KubefipV2.ConvertFloatingIPRangeToV1(&fipRange), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %s [%s] to [%s]", typeMeta.Kind, typeMeta.APIVersion, desiredAPIVersion)
+}
+
+func serveConvert(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read the request body: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	review := apiextensionsv1.ConversionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "cannot decode the ConversionReview request", http.StatusBadRequest)
+
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{
+		UID:    review.Request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+
+	for _, obj := range review.Request.Objects {
+		converted, err := convertObject(obj.Raw, review.Request.DesiredAPIVersion)
+		if err == nil {
+			var raw []byte
+			if raw, err = json.Marshal(converted); err == nil {
+				response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: raw})
+
+				continue
+			}
+		}
+
+		log.Errorf("(serveConvert) conversion failed: %s", err.Error())
+
+		// the apiserver rejects the whole request when one of the objects fails
+		response.ConvertedObjects = nil
+		response.Result = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+		}
+
+		break
+	}
+
+	review.Response = response
+	review.Request = nil
+
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot encode the ConversionReview response: %s", err.Error()), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("(serveConvert) error while writing the response: %s", err.Error())
+	}
+}
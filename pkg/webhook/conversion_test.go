@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	KubefipV1 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v1"
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func mustMarshal(t *testing.T, obj interface{}) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("error encoding [%+v]: %s", obj, err.Error())
+	}
+
+	return raw
+}
+
+// convertRaw converts the raw object to the api version and encodes the result, like serveConvert does
+func convertRaw(t *testing.T, raw []byte, desiredAPIVersion string) []byte {
+	t.Helper()
+
+	converted, err := convertObject(raw, desiredAPIVersion)
+	if err != nil {
+		t.Fatalf("error converting [%s] to [%s]: %s", string(raw), desiredAPIVersion, err.Error())
+	}
+
+	return mustMarshal(t, converted)
+}
+
+func TestConvertObjectRoundTrip(t *testing.T) {
+	v1 := KubefipV1.SchemeGroupVersion.String()
+	v2 := KubefipV2.SchemeGroupVersion.String()
+
+	tests := []struct {
+		name string
+		obj  interface{}
+	}{
+		{
+			name: "fip with all annotations",
+			obj: &KubefipV1.FloatingIP{
+				TypeMeta: metav1.TypeMeta{APIVersion: v1, Kind: "FloatingIP"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip", Annotations: map[string]string{
+					"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "true", "other": "value"}},
+				Spec: KubefipV1.FloatingIPSpec{IPAddress: "192.168.10.10"},
+			},
+		},
+		{
+			name: "fip with updateConfigMap which is not a bool",
+			obj: &KubefipV1.FloatingIP{
+				TypeMeta: metav1.TypeMeta{APIVersion: v1, Kind: "FloatingIP"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip", Annotations: map[string]string{
+					"clustername": "cluster1", "fiprange": "range1", "updateConfigMap": "yes"}},
+			},
+		},
+		{
+			name: "fip with empty annotation map",
+			obj: &KubefipV1.FloatingIP{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1, Kind: "FloatingIP"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip", Annotations: map[string]string{}},
+			},
+		},
+		{
+			name: "fiprange with selector, priority and weight",
+			obj: &KubefipV1.FloatingIPRange{
+				TypeMeta: metav1.TypeMeta{APIVersion: v1, Kind: "FloatingIPRange"},
+				ObjectMeta: metav1.ObjectMeta{Name: "range1", Annotations: map[string]string{
+					"harvesterClusterName": "harvester1", "harvesterNetworkName": "vlan10"}},
+				Spec: KubefipV1.FloatingIPRangeSpec{
+					IPRange: "192.168.10.0/24",
+					ClusterSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"env": "prod"},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "kubefip.k8s.binbash.org/cluster-source", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"capi"}},
+						},
+					},
+					Priority: 10,
+					Weight:   3,
+				},
+			},
+		},
+		{
+			name: "fiprange with empty annotation map",
+			obj: &KubefipV1.FloatingIPRange{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1, Kind: "FloatingIPRange"},
+				ObjectMeta: metav1.ObjectMeta{Name: "range1", Annotations: map[string]string{}},
+				Spec:       KubefipV1.FloatingIPRangeSpec{IPRange: "192.168.10.0/24"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		raw := mustMarshal(t, tt.obj)
+
+		rawV2 := convertRaw(t, raw, v2)
+		typeMeta := metav1.TypeMeta{}
+		if err := json.Unmarshal(rawV2, &typeMeta); err != nil || typeMeta.APIVersion != v2 {
+			t.Errorf("%s: expected a [%s] object, got [%s]", tt.name, v2, string(rawV2))
+		}
+
+		rawV1 := convertRaw(t, rawV2, v1)
+
+		// compare the decoded objects, an empty annotation map and no annotations encode the same
+		want := reflect.New(reflect.TypeOf(tt.obj).Elem()).Interface()
+		got := reflect.New(reflect.TypeOf(tt.obj).Elem()).Interface()
+		if err := json.Unmarshal(raw, want); err != nil {
+			t.Fatalf("%s: error decoding the original object: %s", tt.name, err.Error())
+		}
+		if err := json.Unmarshal(rawV1, got); err != nil {
+			t.Fatalf("%s: error decoding the round trip object: %s", tt.name, err.Error())
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected [%s] after the round trip, got [%s]", tt.name, string(raw), string(rawV1))
+		}
+	}
+}
+
+func TestConvertObjectErrors(t *testing.T) {
+	v1 := KubefipV1.SchemeGroupVersion.String()
+	v2 := KubefipV2.SchemeGroupVersion.String()
+
+	tests := []struct {
+		name              string
+		raw               string
+		desiredAPIVersion string
+		wantErr           string
+	}{
+		{"invalid object", "not json", v2, "cannot decode the object"},
+		{"unknown kind", `{"apiVersion":"` + v1 + `","kind":"FloatingIPReservation"}`, v2, "cannot convert FloatingIPReservation"},
+		{"same api version", `{"apiVersion":"` + v1 + `","kind":"FloatingIP"}`, v1, "cannot convert FloatingIP"},
+		{"unknown api version", `{"apiVersion":"` + v1 + `","kind":"FloatingIP"}`, "kubefip.k8s.binbash.org/v3", "cannot convert FloatingIP"},
+	}
+
+	for _, tt := range tests {
+		_, err := convertObject([]byte(tt.raw), tt.desiredAPIVersion)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected an error containing [%s], got [%v]", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestServeConvert(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(serveConvert))
+	defer server.Close()
+
+	fip := mustMarshal(t, &KubefipV1.FloatingIP{
+		TypeMeta: metav1.TypeMeta{APIVersion: KubefipV1.SchemeGroupVersion.String(), Kind: "FloatingIP"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cluster1-kubevip", Annotations: map[string]string{
+			"clustername": "cluster1", "fiprange": "range1"}},
+	})
+	fipRange := mustMarshal(t, &KubefipV1.FloatingIPRange{
+		TypeMeta:   metav1.TypeMeta{APIVersion: KubefipV1.SchemeGroupVersion.String(), Kind: "FloatingIPRange"},
+		ObjectMeta: metav1.ObjectMeta{Name: "range1"},
+		Spec:       KubefipV1.FloatingIPRangeSpec{IPRange: "192.168.10.0/24", Priority: 1},
+	})
+
+	tests := []struct {
+		name      string
+		objects   [][]byte
+		wantCount int
+		wantError string
+	}{
+		{"all objects converted", [][]byte{fip, fipRange}, 2, ""},
+		{"one object fails", [][]byte{fip, []byte(`{"apiVersion":"v1","kind":"Pod"}`)}, 0, "cannot convert Pod"},
+	}
+
+	for _, tt := range tests {
+		request := &apiextensionsv1.ConversionRequest{
+			UID:               types.UID("uid-" + strings.ReplaceAll(tt.name, " ", "-")),
+			DesiredAPIVersion: KubefipV2.SchemeGroupVersion.String(),
+		}
+		for _, obj := range tt.objects {
+			request.Objects = append(request.Objects, runtime.RawExtension{Raw: obj})
+		}
+
+		body := mustMarshal(t, apiextensionsv1.ConversionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "ConversionReview"},
+			Request:  request,
+		})
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: error posting the ConversionReview: %s", tt.name, err.Error())
+		}
+
+		review := apiextensionsv1.ConversionReview{}
+		err = json.NewDecoder(resp.Body).Decode(&review)
+		resp.Body.Close()
+		if err != nil || review.Response == nil {
+			t.Fatalf("%s: error decoding the ConversionReview response: %v", tt.name, err)
+		}
+
+		if review.Response.UID != request.UID {
+			t.Errorf("%s: expected response uid [%s], got [%s]", tt.name, request.UID, review.Response.UID)
+		}
+
+		if len(review.Response.ConvertedObjects) != tt.wantCount {
+			t.Errorf("%s: expected [%d] converted objects, got [%d]", tt.name, tt.wantCount, len(review.Response.ConvertedObjects))
+		}
+
+		if tt.wantError == "" {
+			if review.Response.Result.Status != metav1.StatusSuccess {
+				t.Errorf("%s: expected status [%s], got [%+v]", tt.name, metav1.StatusSuccess, review.Response.Result)
+			}
+
+			continue
+		}
+
+		if review.Response.Result.Status != metav1.StatusFailure || !strings.Contains(review.Response.Result.Message, tt.wantError) {
+			t.Errorf("%s: expected a failure with message [%s], got [%+v]", tt.name, tt.wantError, review.Response.Result)
+		}
+	}
+}
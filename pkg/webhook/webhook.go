@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/file"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the validation needs the gathered fipranges, fips and fipreservations, until then every object is allowed
+var validationReady atomic.Bool
+
+// SetValidationReady enables the validation after the operator has gathered all objects
+func SetValidationReady() {
+	validationReady.Store(true)
+}
+
+// validateFip runs the same checks as AllocateFip, plus the checks which AllocateFip leaves to the ipam
+func validateFip(req *admissionv1.AdmissionRequest) error {
+	fip := KubefipV2.FloatingIP{}
+	if err := json.Unmarshal(req.Object.Raw, &fip); err != nil {
+		return fmt.Errorf("cannot decode the FloatingIP object: %s", err.Error())
+	}
+
+	// don't block updates which don't touch the validated fields, like the annotations set by the operator itself
+	if req.Operation == admissionv1.Update {
+		oldFip := KubefipV2.FloatingIP{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldFip); err == nil &&
+			oldFip.Spec.IPAddress == fip.Spec.IPAddress &&
+			oldFip.Spec.FipRange == fip.Spec.FipRange &&
+			oldFip.Spec.ClusterName == fip.Spec.ClusterName {
+			return nil
+		}
+	}
+
+	if err := kubefip.ValidateFip(&fip); err != nil {
+		return err
+	}
+
+	return kubefip.ValidateFipAddressAvailable(&fip)
+}
+
+// validateFipRange runs the same checks as AllocateFipRange, plus the overlap check
+func validateFipRange(req *admissionv1.AdmissionRequest) error {
+	fipRange := KubefipV2.FloatingIPRange{}
+	if err := json.Unmarshal(req.Object.Raw, &fipRange); err != nil {
+		return fmt.Errorf("cannot decode the FloatingIPRange object: %s", err.Error())
+	}
+
+	// don't block updates which don't touch the iprange
+	if req.Operation == admissionv1.Update {
+		oldFipRange := KubefipV2.FloatingIPRange{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldFipRange); err == nil && oldFipRange.Spec.IPRange == fipRange.Spec.IPRange {
+			return nil
+		}
+	}
+
+	if err := kubefip.ValidateFipRange(&fipRange); err != nil {
+		return err
+	}
+
+	return kubefip.ValidateFipRangeOverlap(&fipRange)
+}
+
+func serveValidate(w http.ResponseWriter, r *http.Request, validate func(req *admissionv1.AdmissionRequest) error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read the request body: %s", err.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "cannot decode the AdmissionReview request", http.StatusBadRequest)
+
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+
+	// deletes are always allowed, the operator releases the ip addresses on the delete events
+	if review.Request.Operation == admissionv1.Delete {
+		log.Debugf("(serveValidate) allowing delete of %s [%s]", review.Request.Kind.Kind, review.Request.Name)
+	} else if !validationReady.Load() {
+		log.Debugf("(serveValidate) validation not ready yet, allowing %s of %s [%s]", strings.ToLower(string(review.Request.Operation)),
+			review.Request.Kind.Kind, review.Request.Name)
+	} else if err := validate(review.Request); err != nil {
+		log.Infof("(serveValidate) rejected %s of %s [%s]: %s", strings.ToLower(string(review.Request.Operation)),
+			review.Request.Kind.Kind, strings.TrimPrefix(review.Request.Namespace+"/"+review.Request.Name, "/"), err.Error())
+
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		}
+	}
+
+	review.Response = response
+	review.Request = nil
+
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot encode the AdmissionReview response: %s", err.Error()), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("(serveValidate) error while writing the response: %s", err.Error())
+	}
+}
+
+// StartWebhookServer starts the https server for the validating admission and the v1/v2 conversion webhooks. The objects
+// are stored as v1, so every v2 read of the operator goes through the conversion webhook of this process. It returns when
+// the listener accepts connections, so the objects can be gathered afterwards, or with an error when the certificate or
+// the port can't be used.
+func StartWebhookServer(webhookPort int, certDir string) error {
+	certFile := filepath.Join(certDir, "tls.crt")
+	keyFile := filepath.Join(certDir, "tls.key")
+
+	if !file.FileExists(certFile) || !file.FileExists(keyFile) {
+		return fmt.Errorf("no certificate found in [%s], the conversion webhook is required for the v2 api", certDir)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading the certificate in [%s]: %s", certDir, err.Error())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate-floatingip", func(w http.ResponseWriter, r *http.Request) {
+		serveValidate(w, r, validateFip)
+	})
+	mux.HandleFunc("/validate-floatingiprange", func(w http.ResponseWriter, r *http.Request) {
+		serveValidate(w, r, validateFipRange)
+	})
+	mux.HandleFunc("/convert", serveConvert)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", webhookPort))
+	if err != nil {
+		return fmt.Errorf("error listening on port [%d]: %s", webhookPort, err.Error())
+	}
+
+	log.Infof("(StartWebhookServer) start the validating and conversion webhooks on port [%d]", webhookPort)
+
+	server := &http.Server{
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		},
+	}
+
+	go func() {
+		log.Fatal(server.ServeTLS(listener, "", ""))
+	}()
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	KubefipV2 "github.com/joeyloman/kube-fip-operator/pkg/apis/kubefip.k8s.binbash.org/v2"
+	"github.com/joeyloman/kube-fip-operator/pkg/kubefip"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newFipRangeRaw(t *testing.T, name string, ipRange string) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(KubefipV2.FloatingIPRange{
+		TypeMeta:   metav1.TypeMeta{APIVersion: KubefipV2.SchemeGroupVersion.String(), Kind: "FloatingIPRange"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: ipRange},
+	})
+	if err != nil {
+		t.Fatalf("error encoding fiprange [%s]: %s", name, err.Error())
+	}
+
+	return raw
+}
+
+// postAdmissionReview sends the AdmissionReview to the server and returns the status code and the decoded response
+func postAdmissionReview(t *testing.T, url string, body []byte) (int, *admissionv1.AdmissionResponse) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("error posting the AdmissionReview: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		t.Fatalf("error decoding the AdmissionReview response: %s", err.Error())
+	}
+
+	if review.Request != nil {
+		t.Errorf("expected the request to be removed from the AdmissionReview response")
+	}
+
+	return resp.StatusCode, review.Response
+}
+
+func TestServeValidate(t *testing.T) {
+	existing := KubefipV2.FloatingIPRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook-test-existing"},
+		Spec:       KubefipV2.FloatingIPRangeSpec{IPRange: "192.168.10.0/24"},
+	}
+	if err := kubefip.UpdateAllFipRanges(&existing); err != nil {
+		t.Fatalf("error adding fiprange: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = kubefip.RemoveFipRangeFromAllFipRanges(&existing) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveValidate(w, r, validateFipRange)
+	}))
+	defer server.Close()
+
+	ready := validationReady.Load()
+	t.Cleanup(func() { validationReady.Store(ready) })
+
+	tests := []struct {
+		name      string
+		ready     bool
+		operation admissionv1.Operation
+		object    []byte
+		oldObject []byte
+		allowed   bool
+		message   string
+	}{
+		{
+			name:      "valid fiprange",
+			ready:     true,
+			operation: admissionv1.Create,
+			object:    newFipRangeRaw(t, "new", "192.168.11.0/24"),
+			allowed:   true,
+		},
+		{
+			name:      "overlapping fiprange",
+			ready:     true,
+			operation: admissionv1.Create,
+			object:    newFipRangeRaw(t, "new", "192.168.10.128/25"),
+			message:   "overlaps with iprange [192.168.10.0/24] of fiprange [webhook-test-existing]",
+		},
+		{
+			name:      "fiprange without usable addresses",
+			ready:     true,
+			operation: admissionv1.Create,
+			object:    newFipRangeRaw(t, "new", "192.168.11.1/32"),
+			message:   "has no usable ip addresses",
+		},
+		{
+			name:      "update which doesn't change the iprange",
+			ready:     true,
+			operation: admissionv1.Update,
+			object:    newFipRangeRaw(t, "new", "192.168.10.128/25"),
+			oldObject: newFipRangeRaw(t, "new", "192.168.10.128/25"),
+			allowed:   true,
+		},
+		{
+			name:      "update which changes the iprange",
+			ready:     true,
+			operation: admissionv1.Update,
+			object:    newFipRangeRaw(t, "new", "192.168.10.128/25"),
+			oldObject: newFipRangeRaw(t, "new", "192.168.11.0/24"),
+			message:   "overlaps with iprange",
+		},
+		{
+			name:      "delete",
+			ready:     true,
+			operation: admissionv1.Delete,
+			oldObject: newFipRangeRaw(t, "webhook-test-existing", "192.168.10.0/24"),
+			allowed:   true,
+		},
+		{
+			name:      "validation not ready",
+			ready:     false,
+			operation: admissionv1.Create,
+			object:    newFipRangeRaw(t, "new", "192.168.10.128/25"),
+			allowed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		validationReady.Store(tt.ready)
+
+		uid := types.UID("uid-" + strings.ReplaceAll(tt.name, " ", "-"))
+		body, err := json.Marshal(admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       uid,
+				Kind:      metav1.GroupVersionKind{Group: KubefipV2.SchemeGroupVersion.Group, Version: "v2", Kind: "FloatingIPRange"},
+				Name:      "new",
+				Operation: tt.operation,
+				Object:    runtime.RawExtension{Raw: tt.object},
+				OldObject: runtime.RawExtension{Raw: tt.oldObject},
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: error encoding the AdmissionReview: %s", tt.name, err.Error())
+		}
+
+		code, response := postAdmissionReview(t, server.URL, body)
+		if code != http.StatusOK || response == nil {
+			t.Errorf("%s: expected a response with status code [%d], got [%d]", tt.name, http.StatusOK, code)
+
+			continue
+		}
+
+		if response.UID != uid {
+			t.Errorf("%s: expected response uid [%s], got [%s]", tt.name, uid, response.UID)
+		}
+
+		if response.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed [%t], got [%t]", tt.name, tt.allowed, response.Allowed)
+		}
+
+		if tt.allowed {
+			continue
+		}
+
+		if response.Result == nil || response.Result.Code != http.StatusUnprocessableEntity ||
+			!strings.Contains(response.Result.Message, tt.message) {
+			t.Errorf("%s: expected a [%d] result with message [%s], got [%+v]", tt.name, http.StatusUnprocessableEntity,
+				tt.message, response.Result)
+		}
+	}
+
+	for _, body := range []string{"not json", `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`} {
+		if code, _ := postAdmissionReview(t, server.URL, []byte(body)); code != http.StatusBadRequest {
+			t.Errorf("expected status code [%d] for request body [%s], got [%d]", http.StatusBadRequest, body, code)
+		}
+	}
+}